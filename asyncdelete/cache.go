@@ -0,0 +1,424 @@
+// Package asyncdelete provides a cache implementation that deletes keys in
+// batches in the background.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// queueing Delete operations instead of running them immediately. Queued keys
+// are removed with a single gouache.DeleteMulti every flush interval, or as
+// soon as a full batch is queued, which saves a round trip per key on hot
+// invalidation paths of remote backends such as Redis.
+//
+// While a key is queued, Get reports it as missing, and a Set of the key
+// cancels its pending deletion, so a Set is never undone by a Delete issued
+// before it. Call Close on shutdown to flush the remaining keys.
+package asyncdelete
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*Cache)(nil)
+
+// options holds configuration options for the asynchronous delete cache.
+type options struct {
+	// FlushInterval is how often queued deletions are flushed.
+	FlushInterval time.Duration
+
+	// BatchSize is the number of queued keys that triggers an early flush,
+	// and the largest number of keys passed to a single DeleteMulti.
+	BatchSize int
+
+	// FlushTimeout is the timeout of a background flush.
+	FlushTimeout time.Duration
+
+	// ErrorHandler is called when a background flush fails.
+	ErrorHandler gouache.ErrorHandler
+
+	// Clock is used to wait for the flush interval.
+	Clock gouache.Clock
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithFlushInterval returns an Option that sets how often queued deletions
+// are flushed, which bounds how long a deleted key may linger in the
+// underlying cache.
+//
+// Parameters:
+//   - d: The flush interval
+//
+// Returns:
+//   - An Option function that sets the FlushInterval
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.FlushInterval = d
+	}
+}
+
+// WithBatchSize returns an Option that sets the number of queued keys that
+// triggers a flush before the interval has passed. It is also the largest
+// number of keys deleted by a single DeleteMulti.
+//
+// Parameters:
+//   - n: The batch size
+//
+// Returns:
+//   - An Option function that sets the BatchSize
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.BatchSize = n
+	}
+}
+
+// WithFlushTimeout returns an Option that sets the timeout of a background flush.
+//
+// Parameters:
+//   - d: The timeout duration for a flush
+//
+// Returns:
+//   - An Option function that sets the FlushTimeout
+func WithFlushTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.FlushTimeout = d
+	}
+}
+
+// WithErrorHandler returns an Option that sets a custom error handler for
+// errors that occur during a background flush.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f gouache.ErrorHandler) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// WithClock returns an Option that sets the clock used to wait for the flush interval.
+// It defaults to gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default flush interval to 100ms if not specified or invalid
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 100 * time.Millisecond
+	}
+
+	// Set default batch size to 100 if not specified or invalid
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+
+	// Set default flush timeout to 5s if not specified or invalid
+	if o.FlushTimeout <= 0 {
+		o.FlushTimeout = 5 * time.Second
+	}
+
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = gouache.DefaultErrorHandler("asyncdelete.Cache.Flush")
+	}
+	return o
+}
+
+// Cache is a cache implementation that queues deletions and flushes them in
+// batches.
+type Cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+
+	// flushMu serializes flushes.
+	flushMu sync.Mutex
+
+	// mu guards pending, inflight, inflightDone and closed.
+	mu sync.Mutex
+
+	// pending holds the keys queued for deletion.
+	pending map[string]struct{}
+
+	// inflight holds the keys being deleted by the running flush.
+	inflight map[string]struct{}
+
+	// inflightDone is closed when the running flush completes.
+	inflightDone chan struct{}
+
+	// closed reports whether Close was called.
+	closed bool
+
+	// full signals the background loop that a batch is full.
+	full chan struct{}
+
+	// stop stops the background loop.
+	stop chan struct{}
+
+	// done is closed when the background loop has exited.
+	done chan struct{}
+}
+
+// New creates a new asynchronous delete cache instance wrapping the specified
+// cache and starts its background flush loop.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache that deletes keys in batches; Close it to stop the background loop
+func New(c gouache.Cache, opts ...Option) *Cache {
+	cache := &Cache{
+		Options: newOptions(opts...),
+		Cache:   c,
+		pending: make(map[string]struct{}),
+		full:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go cache.loop()
+	return cache
+}
+
+// Get retrieves a value from the underlying cache by its key. A key queued
+// for deletion is reported as missing.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist or is queued for deletion
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	cache.mu.Lock()
+	_, pending := cache.pending[key]
+	_, inflight := cache.inflight[key]
+	cache.mu.Unlock()
+	if pending || inflight {
+		return nil, gouache.ErrCacheMiss
+	}
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set stores a value in the underlying cache, canceling any pending deletion
+// of the key. If the key is being deleted by a running flush, Set waits for
+// the flush to complete so that the deletion can't remove the new value.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails, or an error wrapping gouache.ErrContext if ctx is done while waiting for a flush
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	for {
+		cache.mu.Lock()
+		delete(cache.pending, key)
+		_, inflight := cache.inflight[key]
+		wait := cache.inflightDone
+		cache.mu.Unlock()
+		if !inflight {
+			break
+		}
+
+		// Wait for the running flush, then check again
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", gouache.ErrContext, ctx.Err())
+		}
+	}
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete queues the key for deletion and returns immediately. Once the cache
+// is closed, keys are deleted synchronously instead.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the cache is closed and the synchronous deletion fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	cache.mu.Lock()
+	if cache.closed {
+		cache.mu.Unlock()
+		return cache.Cache.Delete(ctx, key)
+	}
+	cache.pending[key] = struct{}{}
+	full := len(cache.pending) >= cache.Options.BatchSize
+	cache.mu.Unlock()
+
+	// Wake the background loop early when a batch is full
+	if full {
+		select {
+		case cache.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *Cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// Flush deletes all queued keys from the underlying cache, in batches of at
+// most BatchSize keys. Keys of a batch that fails to delete are not retried.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//
+// Returns:
+//   - The first error returned by DeleteMulti
+func (cache *Cache) Flush(ctx context.Context) error {
+	cache.flushMu.Lock()
+	defer cache.flushMu.Unlock()
+
+	// Take the queued keys, keeping them visible as deleted until the flush completes
+	cache.mu.Lock()
+	batch := cache.pending
+	if len(batch) == 0 {
+		cache.mu.Unlock()
+		return nil
+	}
+	cache.pending = make(map[string]struct{})
+	cache.inflight = batch
+	cache.inflightDone = make(chan struct{})
+	cache.mu.Unlock()
+
+	// Delete the keys in batches
+	var firstErr error
+	keys := make([]string, 0, cache.Options.BatchSize)
+	for key := range batch {
+		keys = append(keys, key)
+		if len(keys) < cache.Options.BatchSize {
+			continue
+		}
+		if err := gouache.DeleteMulti(ctx, cache.Cache, keys); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		keys = keys[:0]
+	}
+	if len(keys) > 0 {
+		if err := gouache.DeleteMulti(ctx, cache.Cache, keys); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// Release any Set waiting for the flush
+	cache.mu.Lock()
+	cache.inflight = nil
+	close(cache.inflightDone)
+	cache.mu.Unlock()
+	return firstErr
+}
+
+// Close stops the background loop and flushes the remaining queued keys.
+// Deletions issued after Close run synchronously.
+//
+// Parameters:
+//   - ctx: Context for the final flush
+//
+// Returns:
+//   - An error if the final flush fails
+func (cache *Cache) Close(ctx context.Context) error {
+	cache.mu.Lock()
+	if cache.closed {
+		cache.mu.Unlock()
+		return nil
+	}
+	cache.closed = true
+	cache.mu.Unlock()
+
+	// Stop the background loop before the final flush
+	close(cache.stop)
+	<-cache.done
+	return cache.Flush(ctx)
+}
+
+// loop flushes the queued keys every flush interval, or early when a batch
+// is full, until the cache is closed.
+func (cache *Cache) loop() {
+	defer close(cache.done)
+	for {
+		select {
+		case <-cache.stop:
+			return
+		case <-cache.Options.Clock.After(cache.Options.FlushInterval):
+		case <-cache.full:
+		}
+
+		// Flush with a bounded timeout, reporting failures to the error handler
+		ctx, cancel := context.WithTimeout(context.Background(), cache.Options.FlushTimeout)
+		if err := cache.Flush(ctx); err != nil {
+			cache.Options.ErrorHandler(err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,198 @@
+package asyncdelete
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// batchCache records the batches passed to DeleteMulti and can block them
+// until released.
+type batchCache struct {
+	sample.Cache
+	mu      sync.Mutex
+	batches [][]string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *batchCache) DeleteMulti(ctx context.Context, keys []string) error {
+	b.mu.Lock()
+	b.batches = append(b.batches, append([]string(nil), keys...))
+	b.mu.Unlock()
+	if b.started != nil {
+		b.started <- struct{}{}
+		<-b.release
+	}
+	return b.Cache.DeleteMulti(ctx, keys)
+}
+
+// TestCache_BatchedDelete tests that deletions are queued and flushed in batches.
+func TestCache_BatchedDelete(t *testing.T) {
+	backend := &batchCache{}
+	cache := New(backend, WithFlushInterval(time.Hour), WithBatchSize(2))
+	defer cache.Close(context.Background())
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		_ = backend.Set(ctx, key, key)
+	}
+	_ = cache.Delete(ctx, "a")
+	_ = cache.Delete(ctx, "b")
+	_ = cache.Delete(ctx, "c")
+
+	// Queued keys are hidden although they may still be in the backend
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := cache.Get(ctx, key); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected ErrCacheMiss for %s, but got: %v", key, err)
+		}
+	}
+
+	// Flushing removes them in batches of at most two keys
+	if err := cache.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := backend.Get(ctx, key); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected %s to be deleted, but got: %v", key, err)
+		}
+	}
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	for _, batch := range backend.batches {
+		if len(batch) > 2 {
+			t.Errorf("Expected batches of at most 2 keys, but got %v", batch)
+		}
+	}
+}
+
+// TestCache_SetCancelsDelete tests that a Set after a queued Delete cancels the deletion.
+func TestCache_SetCancelsDelete(t *testing.T) {
+	backend := &batchCache{}
+	cache := New(backend, WithFlushInterval(time.Hour))
+	defer cache.Close(context.Background())
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "old")
+	_ = cache.Delete(ctx, "key")
+	if err := cache.Set(ctx, "key", "new"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if err := cache.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	val, err := cache.Get(ctx, "key")
+	if err != nil || val != "new" {
+		t.Errorf("Expected new, but got %v, %v", val, err)
+	}
+}
+
+// TestCache_SetWaitsForFlush tests that a Set of a key being flushed waits for
+// the deletion so that it can't remove the new value.
+func TestCache_SetWaitsForFlush(t *testing.T) {
+	backend := &batchCache{started: make(chan struct{}), release: make(chan struct{})}
+	cache := New(backend, WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "old")
+	_ = cache.Delete(ctx, "key")
+
+	// Start a flush and hold it inside DeleteMulti
+	flushed := make(chan error)
+	go func() { flushed <- cache.Flush(ctx) }()
+	<-backend.started
+
+	// The Set blocks until the flush completes
+	set := make(chan error)
+	go func() { set <- cache.Set(ctx, "key", "new") }()
+	select {
+	case err := <-set:
+		t.Fatalf("Expected Set to wait for the flush, but it returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(backend.release)
+	if err := <-flushed; err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := <-set; err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	val, err := cache.Get(ctx, "key")
+	if err != nil || val != "new" {
+		t.Errorf("Expected new, but got %v, %v", val, err)
+	}
+}
+
+// TestCache_SetGivesUpOnFlush tests that a Set giving up while waiting for a
+// flush returns an error wrapping gouache.ErrContext
+func TestCache_SetGivesUpOnFlush(t *testing.T) {
+	backend := &batchCache{started: make(chan struct{}), release: make(chan struct{})}
+	cache := New(backend, WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	_ = cache.Delete(ctx, "key")
+	flushed := make(chan error)
+	go func() { flushed <- cache.Flush(ctx) }()
+	<-backend.started
+
+	setCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err := cache.Set(setCtx, "key", "new")
+	if !errors.Is(err, gouache.ErrContext) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected an error wrapping gouache.ErrContext and context.DeadlineExceeded, got %v", err)
+	}
+
+	close(backend.release)
+	if err := <-flushed; err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+}
+
+// TestCache_Close tests that Close flushes queued keys and later deletions are synchronous.
+func TestCache_Close(t *testing.T) {
+	backend := &batchCache{}
+	cache := New(backend, WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	_ = backend.Set(ctx, "a", "a")
+	_ = backend.Set(ctx, "b", "b")
+	_ = cache.Delete(ctx, "a")
+	if err := cache.Close(ctx); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if _, err := backend.Get(ctx, "a"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected a to be deleted on Close, but got: %v", err)
+	}
+
+	_ = cache.Delete(ctx, "b")
+	if _, err := backend.Get(ctx, "b"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected b to be deleted synchronously, but got: %v", err)
+	}
+}
+
+// TestCache_IntervalFlush tests that the background loop flushes queued keys.
+func TestCache_IntervalFlush(t *testing.T) {
+	backend := &batchCache{}
+	cache := New(backend, WithFlushInterval(10*time.Millisecond))
+	defer cache.Close(context.Background())
+	ctx := context.Background()
+
+	_ = backend.Set(ctx, "key", "value")
+	_ = cache.Delete(ctx, "key")
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := backend.Get(ctx, "key"); errors.Is(err, gouache.ErrCacheMiss) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("Expected the background loop to delete the key")
+}
@@ -0,0 +1,187 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// batchKey is the context key under which the write batch is stored.
+type batchKey struct{}
+
+// Batch buffers the writes made with a context returned by BeginBatch, so
+// that they can be flushed as one SetMulti and one DeleteMulti per cache.
+// Only the last write of each key is kept, so a Delete after a Set of the same
+// key cancels the Set, and vice versa. It is safe for concurrent use.
+type Batch struct {
+	// mu guards targets.
+	mu sync.Mutex
+
+	// targets holds the buffered writes of each cache, in first-write order.
+	targets []*batchTarget
+}
+
+// batchTarget holds the buffered writes of one cache.
+type batchTarget struct {
+	// cache is the cache the writes are flushed to.
+	cache Cache
+
+	// ops maps each key to its last buffered write.
+	ops map[string]batchOp
+}
+
+// batchOp is a buffered write.
+type batchOp struct {
+	// val is the value to store, unless deleted is set.
+	val any
+
+	// deleted marks a buffered Delete.
+	deleted bool
+}
+
+// BeginBatch returns a copy of ctx carrying a write batch, and a function
+// flushing it. Sets and Deletes made with the returned context through a
+// batching decorator, such as writebatch, are buffered instead of performed,
+// and flush writes them with one SetMulti and one DeleteMulti per cache,
+// using ctx. Call flush at the end of the request, typically deferred; writes
+// still buffered when the context is discarded are lost.
+//
+// If ctx already carries a batch, it is returned unchanged with a flush
+// function that does nothing, so the outermost caller flushes all writes.
+//
+// Parameters:
+//   - ctx: The parent context
+//
+// Returns:
+//   - A context carrying the batch
+//   - A function flushing the buffered writes, returning the errors of every failed batch operation
+func BeginBatch(ctx context.Context) (context.Context, func() error) {
+	if BatchFromContext(ctx) != nil {
+		return ctx, func() error { return nil }
+	}
+	b := &Batch{}
+	return context.WithValue(ctx, batchKey{}, b), func() error {
+		return b.flush(ctx)
+	}
+}
+
+// BatchFromContext returns the write batch carried by ctx.
+//
+// Parameters:
+//   - ctx: The context to read the batch from
+//
+// Returns:
+//   - The batch, or nil if ctx carries none
+func BatchFromContext(ctx context.Context) *Batch {
+	b, _ := ctx.Value(batchKey{}).(*Batch)
+	return b
+}
+
+// Set buffers a Set of key to val in c, replacing any earlier buffered write
+// of the key to c. Caches are told apart with ==, so c must be comparable.
+// Batching decorators pass themselves, as pointers always are, and write the
+// flushed batches through to the caches they wrap with their SetMulti and
+// DeleteMulti.
+//
+// Parameters:
+//   - c: The cache the write is flushed to
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+func (b *Batch) Set(c Cache, key string, val any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.target(c).ops[key] = batchOp{val: val}
+}
+
+// Delete buffers a Delete of key from c, replacing any earlier buffered write
+// of the key to c.
+//
+// Parameters:
+//   - c: The cache the write is flushed to
+//   - key: The key of the value to delete
+func (b *Batch) Delete(c Cache, key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.target(c).ops[key] = batchOp{deleted: true}
+}
+
+// Lookup returns the last buffered write of key to c, so that reads made
+// during the batch see its writes.
+//
+// Parameters:
+//   - c: The cache the write is flushed to
+//   - key: The key to look up
+//
+// Returns:
+//   - The buffered value, or nil for a buffered Delete
+//   - Whether the buffered write is a Delete
+//   - Whether a write of the key is buffered
+func (b *Batch) Lookup(c Cache, key string) (any, bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range b.targets {
+		if t.cache == c {
+			op, ok := t.ops[key]
+			return op.val, op.deleted, ok
+		}
+	}
+	return nil, false, false
+}
+
+// target returns the buffered writes of c, adding them if needed. b.mu must be held.
+//
+// Parameters:
+//   - c: The cache the writes are flushed to
+//
+// Returns:
+//   - The buffered writes of c
+func (b *Batch) target(c Cache) *batchTarget {
+	for _, t := range b.targets {
+		if t.cache == c {
+			return t
+		}
+	}
+	t := &batchTarget{cache: c, ops: make(map[string]batchOp)}
+	b.targets = append(b.targets, t)
+	return t
+}
+
+// flush writes the buffered writes of each cache with one DeleteMulti and
+// one SetMulti, and empties the batch. The two touch different keys, so
+// their order doesn't matter.
+//
+// Parameters:
+//   - ctx: Context for the write operations
+//
+// Returns:
+//   - The errors of every failed batch operation, joined
+func (b *Batch) flush(ctx context.Context) error {
+	b.mu.Lock()
+	targets := b.targets
+	b.targets = nil
+	b.mu.Unlock()
+
+	var errs []error
+	for _, t := range targets {
+		vals := make(map[string]any)
+		var deleted []string
+		for key, op := range t.ops {
+			if op.deleted {
+				deleted = append(deleted, key)
+			} else {
+				vals[key] = op.val
+			}
+		}
+		if len(deleted) > 0 {
+			if err := DeleteMulti(ctx, t.cache, deleted); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(vals) > 0 {
+			if err := SetMulti(ctx, t.cache, vals); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,355 @@
+// Package batcher provides a cache implementation that coalesces Gets
+// arriving within a short time window into a single GetMulti.
+//
+// This package implements the gouache.Cache interface by wrapping a cache
+// that implements gouache.BatchGetter. The first Get of a window starts a
+// timer; every Get arriving before it fires joins the batch, and when it
+// fires, or the batch is full, one GetMulti fetches all the keys and each
+// waiter receives its own result. Unlike singleflight, which only merges
+// identical keys requested at the same time, this merges different keys
+// requested close together, which cuts round trips to remote backends such
+// as Redis under bursty reads, at the cost of up to one window of latency.
+//
+// If the wrapped cache doesn't implement gouache.BatchGetter, Gets are passed
+// through unchanged. Set and Delete are always passed through.
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// options holds configuration options for the batching cache.
+type options struct {
+	// Window is how long the first Get of a batch waits for others to join.
+	Window time.Duration
+
+	// MaxBatchSize is the number of keys that flushes a batch before the
+	// window has passed.
+	MaxBatchSize int
+
+	// Timeout is the timeout of the GetMulti fetching a batch.
+	Timeout time.Duration
+
+	// Clock is used to wait for the window.
+	Clock gouache.Clock
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithWindow returns an Option that sets how long the first Get of a batch
+// waits for other Gets to join it.
+//
+// Parameters:
+//   - d: The batching window
+//
+// Returns:
+//   - An Option function that sets the Window
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.Window = d
+	}
+}
+
+// WithMaxBatchSize returns an Option that sets the number of distinct keys
+// that makes a batch fetch immediately, without waiting for the window.
+//
+// Parameters:
+//   - n: The maximum batch size
+//
+// Returns:
+//   - An Option function that sets the MaxBatchSize
+func WithMaxBatchSize(n int) Option {
+	return func(o *options) {
+		o.MaxBatchSize = n
+	}
+}
+
+// WithTimeout returns an Option that sets the timeout of the GetMulti
+// fetching a batch. The batch is shared by several callers, so it doesn't
+// run with any single caller's context.
+//
+// Parameters:
+//   - d: The timeout of a batch fetch
+//
+// Returns:
+//   - An Option function that sets the Timeout
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.Timeout = d
+	}
+}
+
+// WithClock returns an Option that sets the clock used to wait for the window.
+// It defaults to gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default window to 2ms if not specified or invalid
+	if o.Window <= 0 {
+		o.Window = 2 * time.Millisecond
+	}
+
+	// Set default maximum batch size to 100 if not specified or invalid
+	if o.MaxBatchSize <= 0 {
+		o.MaxBatchSize = 100
+	}
+
+	// Set default timeout to 5s if not specified or invalid
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+	return o
+}
+
+// batch is a set of keys fetched together by one GetMulti.
+type batch struct {
+	// ctx is the context of the Get that started the batch, whose values the
+	// fetch carries.
+	ctx context.Context
+
+	// keys holds the distinct keys of the batch, in arrival order.
+	keys []string
+
+	// seen holds the keys already in keys.
+	seen map[string]struct{}
+
+	// fetching reports whether the batch is being fetched; guarded by the
+	// cache's mu.
+	fetching bool
+
+	// done is closed once vals and err are set.
+	done chan struct{}
+
+	// vals holds the values found by GetMulti.
+	vals map[string]any
+
+	// err is the error returned by GetMulti. Values may be returned alongside
+	// it, such as with gouache.ErrPartialResult.
+	err error
+}
+
+// cache is a cache implementation that coalesces Gets into GetMulti calls.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+
+	// mu guards pending.
+	mu sync.Mutex
+
+	// pending is the batch collecting keys, or nil if there is none.
+	pending *batch
+}
+
+// New creates a new batching cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation, which should implement gouache.BatchGetter
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation that batches Gets
+func New(c gouache.Cache, opts ...Option) gouache.Cache {
+	return &cache{Options: newOptions(opts...), Cache: c}
+}
+
+// Get retrieves a value by its key, joining the pending batch or starting a
+// new one, and waits for the batch to be fetched. If GetMulti fails but still
+// returns the value of key, as with gouache.ErrPartialResult, the value is
+// returned; only Gets whose keys it didn't return get the error.
+//
+// Parameters:
+//   - ctx: Context for the operation; only the wait is bound to it, and the
+//     values of the context starting a batch, such as trace IDs, are passed on
+//     to its fetch
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the batch fails, an error wrapping gouache.ErrContext if ctx is done first, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	// Pass through when the underlying cache can't fetch batches
+	getter, ok := cache.Cache.(gouache.BatchGetter)
+	if !ok || !gouache.Capabilities(cache.Cache).BatchGet {
+		return cache.Cache.Get(ctx, key)
+	}
+
+	// Join the pending batch, starting one if needed
+	b := cache.join(ctx, getter, key)
+
+	// Wait for the batch to be fetched
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", gouache.ErrContext, ctx.Err())
+	}
+	if val, ok := b.vals[key]; ok {
+		return val, nil
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return nil, gouache.ErrCacheMiss
+}
+
+// Set stores a value in the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// join adds key to the pending batch and returns the batch. The first key of
+// a batch starts its window timer, and the key filling a batch fetches it
+// immediately.
+//
+// Parameters:
+//   - ctx: Context of the Get, which a new batch keeps for its fetch
+//   - getter: The underlying cache fetching batches
+//   - key: The key to add
+//
+// Returns:
+//   - The batch the key was added to
+func (cache *cache) join(ctx context.Context, getter gouache.BatchGetter, key string) *batch {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Start a new batch with its window timer
+	b := cache.pending
+	if b == nil {
+		b = &batch{ctx: ctx, seen: make(map[string]struct{}), done: make(chan struct{})}
+		cache.pending = b
+		after := cache.Options.Clock.After(cache.Options.Window)
+		go func() {
+			<-after
+			cache.flush(getter, b)
+		}()
+	}
+
+	// Add the key once, fetching the batch early when it is full
+	if _, ok := b.seen[key]; !ok {
+		b.seen[key] = struct{}{}
+		b.keys = append(b.keys, key)
+	}
+	if len(b.keys) >= cache.Options.MaxBatchSize {
+		cache.pending = nil
+		go cache.flush(getter, b)
+	}
+	return b
+}
+
+// flush detaches b if it is still pending and fetches its keys with a single
+// GetMulti. Only the first flush of a batch fetches it.
+//
+// Parameters:
+//   - getter: The underlying cache fetching batches
+//   - b: The batch to fetch
+func (cache *cache) flush(getter gouache.BatchGetter, b *batch) {
+	// Stop other Gets from joining the batch
+	cache.mu.Lock()
+	if cache.pending == b {
+		cache.pending = nil
+	}
+	// Skip batches already fetched by the other trigger
+	fetching := b.fetching
+	b.fetching = true
+	cache.mu.Unlock()
+	if fetching {
+		return
+	}
+
+	// Fetch all keys at once and release the waiters. The fetch serves every
+	// waiter, so it keeps the values of the first caller's context but not its
+	// cancellation or deadline, and is bounded by Timeout instead.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(b.ctx), cache.Options.Timeout)
+	defer cancel()
+	vals, err := getter.GetMulti(ctx, b.keys)
+	b.vals, b.err = vals, err
+	close(b.done)
+}
@@ -0,0 +1,190 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/clocktest"
+	"github.com/soyacen/gouache/sample"
+)
+
+// countingCache counts the GetMulti calls made to it.
+type countingCache struct {
+	sample.Cache
+	calls atomic.Int32
+}
+
+func (c *countingCache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	c.calls.Add(1)
+	return c.Cache.GetMulti(ctx, keys)
+}
+
+// waitPending waits until the pending batch holds n keys.
+func waitPending(t *testing.T, c *cache, n int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		size := 0
+		if c.pending != nil {
+			size = len(c.pending.keys)
+		}
+		c.mu.Unlock()
+		if size == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %d pending keys", n)
+}
+
+// TestCache_Coalesce tests that concurrent Gets within a window produce one GetMulti.
+func TestCache_Coalesce(t *testing.T) {
+	backend := &countingCache{}
+	clock := clocktest.NewClock(time.Now())
+	c := New(backend, WithWindow(time.Millisecond), WithClock(clock)).(*cache)
+	ctx := context.Background()
+
+	// Store half of the keys
+	const n = 10
+	for i := 0; i < n; i += 2 {
+		_ = backend.Set(ctx, fmt.Sprintf("key-%d", i), i)
+	}
+
+	// Issue concurrent Gets, including a duplicate key
+	var wg sync.WaitGroup
+	results := make([]any, n+1)
+	errs := make([]error, n+1)
+	for i := 0; i <= n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = c.Get(ctx, fmt.Sprintf("key-%d", i%n))
+		}(i)
+	}
+
+	// Fire the window once every key has joined the batch
+	waitPending(t, c, n)
+	clock.Advance(time.Millisecond)
+	wg.Wait()
+
+	if calls := backend.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 GetMulti call, but got %d", calls)
+	}
+	for i := 0; i <= n; i++ {
+		if i%n%2 == 0 && (errs[i] != nil || results[i] != i%n) {
+			t.Errorf("Expected %d, but got %v, %v", i%n, results[i], errs[i])
+		}
+		if i%n%2 == 1 && !errors.Is(errs[i], gouache.ErrCacheMiss) {
+			t.Errorf("Expected ErrCacheMiss for key-%d, but got: %v", i%n, errs[i])
+		}
+	}
+}
+
+// TestCache_ContextDone tests that a Get giving up on its batch returns an
+// error wrapping gouache.ErrContext
+func TestCache_ContextDone(t *testing.T) {
+	c := New(&countingCache{}, WithClock(clocktest.NewClock(time.Now())))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The window never passes, so the Get can only give up
+	_, err := c.Get(ctx, "key")
+	if !errors.Is(err, gouache.ErrContext) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected an error wrapping gouache.ErrContext and context.Canceled, got %v", err)
+	}
+}
+
+// TestCache_MaxBatchSize tests that a full batch is fetched before the window passes.
+func TestCache_MaxBatchSize(t *testing.T) {
+	backend := &countingCache{}
+	c := New(backend, WithWindow(time.Hour), WithMaxBatchSize(3))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = c.Get(ctx, fmt.Sprintf("key-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	if calls := backend.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 GetMulti call, but got %d", calls)
+	}
+}
+
+// TestCache_Passthrough tests that caches without GetMulti are used directly.
+func TestCache_Passthrough(t *testing.T) {
+	var backend struct{ gouache.Cache }
+	backend.Cache = &sample.Cache{}
+	c := New(backend, WithWindow(time.Hour))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "key", "value")
+	val, err := c.Get(ctx, "key")
+	if err != nil || val != "value" {
+		t.Errorf("Expected value, but got %v, %v", val, err)
+	}
+}
+
+// traceKey is a context key carrying a trace ID.
+type traceKey struct{}
+
+// partialCache returns the values it finds alongside gouache.ErrPartialResult,
+// and records the trace ID of the GetMulti context.
+type partialCache struct {
+	sample.Cache
+	trace atomic.Value
+}
+
+func (c *partialCache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	if id, ok := ctx.Value(traceKey{}).(string); ok {
+		c.trace.Store(id)
+	}
+	vals, _ := c.Cache.GetMulti(ctx, keys)
+	return vals, fmt.Errorf("%w: timeout", gouache.ErrPartialResult)
+}
+
+// TestCache_PartialResult tests that a failed GetMulti still serves the
+// values it returned, and that the fetch carries the values of the first
+// caller's context
+func TestCache_PartialResult(t *testing.T) {
+	backend := &partialCache{}
+	c := New(backend, WithWindow(time.Hour), WithMaxBatchSize(2))
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	_ = backend.Set(ctx, "found", "value")
+
+	var wg sync.WaitGroup
+	results := make(map[string]any)
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	for _, key := range []string{"found", "lost"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			val, err := c.Get(ctx, key)
+			mu.Lock()
+			results[key], errs[key] = val, err
+			mu.Unlock()
+		}(key)
+	}
+	wg.Wait()
+
+	if errs["found"] != nil || results["found"] != "value" {
+		t.Errorf("Expected value for the returned key, but got %v, %v", results["found"], errs["found"])
+	}
+	if !errors.Is(errs["lost"], gouache.ErrPartialResult) {
+		t.Errorf("Expected ErrPartialResult for the missing key, but got %v", errs["lost"])
+	}
+	if id, _ := backend.trace.Load().(string); id != "trace-1" {
+		t.Errorf("Expected the fetch to carry trace-1, but got %q", id)
+	}
+}
@@ -3,11 +3,29 @@
 //
 // This package enables high-performance caching capabilities by leveraging
 // BigCache's efficient memory management and concurrent access patterns.
+//
+// BigCache stores entries in a ring buffer per shard. Buffers grow on demand
+// up to the configured HardMaxCacheSize and never shrink; once a buffer is at
+// its maximum, every Set that doesn't fit silently evicts the oldest entries
+// of the shard, whatever their TTL. This keeps memory bounded and GC overhead
+// low at the cost of losing entries under pressure. Use Usage and
+// NearCapacity to learn when the cache is filling up, and LogNoSpace as the
+// OnRemoveWithReason callback to log the resulting evictions.
+//
+// BigCache itself only supports a single LifeWindow for all entries. Setting
+// the TTL function emulates per-key TTLs on top of it: each entry is stored
+// with its expiry time and Get reports entries past it as missing, even if
+// BigCache hasn't evicted them yet. Entries still expire no later than the
+// LifeWindow, so it should be at least as long as the longest TTL.
 package bc
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/allegro/bigcache/v3"
 	"github.com/soyacen/gouache"
@@ -16,6 +34,19 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// ErrNearCapacity is returned by Set, after the value has been stored, when
+// the cache's allocated capacity has reached NearCapacity of MaxCapacity.
+var ErrNearCapacity = errors.New("gouache: bigcache near capacity")
+
+// Usage reports how much of a BigCache is in use.
+type Usage struct {
+	// Capacity is the number of bytes allocated by the shards' ring buffers.
+	Capacity int
+
+	// Len is the number of entries stored.
+	Len int
+}
+
 // Cache is an implementation of gouache.Cache using BigCache as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // support for custom serialization and deserialization functions.
@@ -30,6 +61,152 @@ type Cache struct {
 	// Unmarshal is an optional function to deserialize bytes into objects.
 	// If not provided, raw bytes are returned.
 	Unmarshal func(key string, data []byte) (any, error)
+
+	// UnmarshalFallback is an optional function called when decoding a stored
+	// value fails, for example after its format changed, with the key, the
+	// raw data and the decoding error. Its result is returned instead, so it
+	// can delete the entry and return gouache.ErrCacheMiss to have the value
+	// recomputed, or return a default. If nil, the decoding error is returned.
+	UnmarshalFallback func(key string, data []byte, err error) (any, error)
+
+	// TTL is an optional function to determine the time-to-live duration for a
+	// cache entry, emulated on top of BigCache's global LifeWindow. When set,
+	// every entry is stored with an expiry header, so it must be set before
+	// any entry is stored and not be removed afterwards. A zero result means
+	// the entry only expires with the LifeWindow. A positive result is scaled
+	// by the context's gouache.TTLMultiplier, and any result is clamped to the
+	// context's gouache.TTLCeiling, before it is applied.
+	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+
+	// Clock is an optional clock used to compute and check the expiry of
+	// entries stored with a TTL. If not provided, gouache.RealClock is used.
+	Clock gouache.Clock
+
+	// MaxCapacity is the maximum number of bytes the cache may allocate,
+	// usually the HardMaxCacheSize of its config converted to bytes. It is
+	// only used together with NearCapacity.
+	MaxCapacity int
+
+	// NearCapacity is an optional fraction of MaxCapacity, such as 0.9. When
+	// both are positive, Set returns ErrNearCapacity once the allocated
+	// capacity reaches this fraction, so callers can react, for example by
+	// shortening TTLs. Since ring buffers never shrink, a cache that has grown
+	// to its maximum keeps reporting ErrNearCapacity.
+	NearCapacity float64
+}
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithTTL returns an Option that sets the function determining the TTL of
+// each entry. See the TTL field.
+//
+// Parameters:
+//   - f: The function returning the TTL of an entry
+//
+// Returns:
+//   - An Option function that sets TTL
+func WithTTL(f func(ctx context.Context, key string, val any) (time.Duration, error)) Option {
+	return func(cache *Cache) {
+		cache.TTL = f
+	}
+}
+
+// WithMarshal returns an Option that sets the function serializing values
+// that are not byte slices.
+//
+// Parameters:
+//   - f: The function encoding a value
+//
+// Returns:
+//   - An Option function that sets Marshal
+func WithMarshal(f func(key string, obj any) ([]byte, error)) Option {
+	return func(cache *Cache) {
+		cache.Marshal = f
+	}
+}
+
+// WithUnmarshal returns an Option that sets the function deserializing stored data.
+//
+// Parameters:
+//   - f: The function decoding a value
+//
+// Returns:
+//   - An Option function that sets Unmarshal
+func WithUnmarshal(f func(key string, data []byte) (any, error)) Option {
+	return func(cache *Cache) {
+		cache.Unmarshal = f
+	}
+}
+
+// WithUnmarshalFallback returns an Option that sets the function handling
+// values that fail to decode. See the UnmarshalFallback field.
+//
+// Parameters:
+//   - f: The function called with the key, raw data and decoding error
+//
+// Returns:
+//   - An Option function that sets UnmarshalFallback
+func WithUnmarshalFallback(f func(key string, data []byte, err error) (any, error)) Option {
+	return func(cache *Cache) {
+		cache.UnmarshalFallback = f
+	}
+}
+
+// WithClock returns an Option that sets the clock entries stored with a TTL
+// expire by. See the Clock field.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(cache *Cache) {
+		cache.Clock = clock
+	}
+}
+
+// New creates a new Cache backed by the specified BigCache instance. The
+// fields set by the options can still be set directly. New logs a warning
+// for each inconsistent setting reported by validate, such as Unmarshal
+// without Marshal.
+//
+// Parameters:
+//   - c: The BigCache instance used for storage operations
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache using c as its storage
+func New(c *bigcache.BigCache, opts ...Option) *Cache {
+	cache := &Cache{Cache: c}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	if err := cache.validate(); err != nil {
+		slog.Warn("bc.New: inconsistent configuration", slog.String("err", err.Error()))
+	}
+	return cache
+}
+
+// validate reports settings that are accepted but almost certainly a mistake:
+// a codec missing one direction, so values don't round-trip, and an
+// UnmarshalFallback that is never called because nothing is decoded.
+//
+// Returns:
+//   - An error listing the inconsistent settings, or nil
+func (cache *Cache) validate() error {
+	var errs []error
+	if cache.Unmarshal != nil && cache.Marshal == nil {
+		errs = append(errs, errors.New("Unmarshal is set without Marshal, so only raw values can be stored"))
+	}
+	if cache.Marshal != nil && cache.Unmarshal == nil {
+		errs = append(errs, errors.New("Marshal is set without Unmarshal, so Get returns serialized data"))
+	}
+	if cache.UnmarshalFallback != nil && cache.Unmarshal == nil {
+		errs = append(errs, errors.New("UnmarshalFallback is set without Unmarshal, so it is never called"))
+	}
+	return errors.Join(errs...)
 }
 
 // Get retrieves a value from the cache by its key.
@@ -56,14 +233,26 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 		return nil, err
 	}
 
+	// Strip the expiry header, treating logically expired entries as missing
+	if cache.TTL != nil {
+		var expired bool
+		data, expired = unwrapExpiry(data, cache.clock().Now())
+		if expired {
+			return nil, gouache.ErrCacheMiss
+		}
+	}
+
 	// If no unmarshal function is defined, return raw data
 	if cache.Unmarshal == nil {
 		return data, nil
 	}
 
-	// Use custom unmarshal function to decode the data
+	// Use custom unmarshal function to decode the data, falling back on failure
 	obj, err := cache.Unmarshal(key, data)
 	if err != nil {
+		if cache.UnmarshalFallback != nil {
+			return cache.UnmarshalFallback(key, data, err)
+		}
 		return nil, err
 	}
 
@@ -72,6 +261,7 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 
 // Set stores a value in the cache under the specified key.
 // It handles both raw byte slices and custom objects that require marshaling.
+// TTL can be determined dynamically by the TTL function if provided.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -79,27 +269,54 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 //   - val: The value to store, either as []byte or any other type requiring marshaling
 //
 // Returns:
-//   - An error if the operation fails, including when Marshal is nil for non-byte values
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-byte values
+//   - ErrNearCapacity if the value was stored but the cache is near capacity
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	// Resolve the TTL for the value
+	var expiresAt time.Time
+	if cache.TTL != nil {
+		ttl, err := cache.TTL(ctx, key, val)
+		if err != nil {
+			return err
+		}
+		if ttl = gouache.AdjustTTL(ctx, ttl); ttl > 0 {
+			expiresAt = cache.clock().Now().Add(ttl)
+		}
+	}
+
 	// Check if the value is already a byte slice
-	if data, ok := val.([]byte); ok {
-		// Directly store byte slices without marshaling
-		return cache.Cache.Set(key, data)
+	data, ok := val.([]byte)
+	if !ok {
+		// For non-byte values, ensure a marshal function is available
+		if cache.Marshal == nil {
+			return fmt.Errorf("%w: %T requires Marshal", gouache.ErrUnsupportedValue, val)
+		}
+
+		// Marshal the value into bytes using the custom marshal function
+		var err error
+		data, err = cache.Marshal(key, val)
+		if err != nil {
+			return fmt.Errorf("%w: %w", gouache.ErrMarshal, err)
+		}
 	}
 
-	// For non-byte values, ensure a marshal function is available
-	if cache.Marshal == nil {
-		return errors.New("gouache: Marshal is nil")
+	// Prefix the data with its expiry when per-key TTLs are emulated
+	if cache.TTL != nil {
+		data = wrapExpiry(data, expiresAt)
 	}
 
-	// Marshal the value into bytes using the custom marshal function
-	data, err := cache.Marshal(key, val)
-	if err != nil {
-		return err
+	// Store the data in BigCache
+	if err := cache.Cache.Set(key, data); err != nil {
+		return fmt.Errorf("%w: %w", gouache.ErrStorage, err)
 	}
 
-	// Store the marshaled data in BigCache
-	return cache.Cache.Set(key, data)
+	// Warn the caller if the cache is filling up
+	if cache.MaxCapacity > 0 && cache.NearCapacity > 0 &&
+		float64(cache.Cache.Capacity()) >= cache.NearCapacity*float64(cache.MaxCapacity) {
+		return ErrNearCapacity
+	}
+	return nil
 }
 
 // Delete removes a value from the cache by its key.
@@ -114,3 +331,82 @@ func (cache *Cache) Delete(ctx context.Context, key string) error {
 	// Delegate deletion to the underlying BigCache instance
 	return cache.Cache.Delete(key)
 }
+
+// clock returns the configured Clock, or gouache.RealClock if none is set.
+//
+// Returns:
+//   - The clock used for expiry
+func (cache *Cache) clock() gouache.Clock {
+	if cache.Clock == nil {
+		return gouache.RealClock
+	}
+	return cache.Clock
+}
+
+// expiryHeaderSize is the size of the expiry header stored in front of each
+// entry when per-key TTLs are emulated.
+const expiryHeaderSize = 8
+
+// wrapExpiry prefixes data with a header holding its expiry time.
+//
+// Parameters:
+//   - data: The serialized value
+//   - expiresAt: When the entry expires, or the zero time if it only expires with the LifeWindow
+//
+// Returns:
+//   - The header followed by data
+func wrapExpiry(data []byte, expiresAt time.Time) []byte {
+	var deadline int64
+	if !expiresAt.IsZero() {
+		deadline = expiresAt.UnixNano()
+	}
+	wrapped := make([]byte, expiryHeaderSize+len(data))
+	binary.BigEndian.PutUint64(wrapped, uint64(deadline))
+	copy(wrapped[expiryHeaderSize:], data)
+	return wrapped
+}
+
+// unwrapExpiry splits an entry stored by wrapExpiry into its data and
+// whether it has expired. Entries too short to hold a header are reported as
+// expired, since they can't have been stored with one.
+//
+// Parameters:
+//   - wrapped: The stored entry
+//   - now: The current time
+//
+// Returns:
+//   - The serialized value
+//   - true if the entry has expired
+func unwrapExpiry(wrapped []byte, now time.Time) ([]byte, bool) {
+	if len(wrapped) < expiryHeaderSize {
+		return nil, true
+	}
+	deadline := int64(binary.BigEndian.Uint64(wrapped))
+	if deadline != 0 && now.UnixNano() >= deadline {
+		return nil, true
+	}
+	return wrapped[expiryHeaderSize:], false
+}
+
+// Usage reports the allocated capacity and number of entries of the cache.
+//
+// Returns:
+//   - The current usage of the underlying BigCache
+func (cache *Cache) Usage() Usage {
+	return Usage{Capacity: cache.Cache.Capacity(), Len: cache.Cache.Len()}
+}
+
+// LogNoSpace is a callback for bigcache.Config.OnRemoveWithReason that logs
+// every entry evicted because the cache had no space left. Entries removed
+// for other reasons are ignored.
+//
+// Parameters:
+//   - key: The key of the removed entry
+//   - entry: The removed data
+//   - reason: Why the entry was removed
+func LogNoSpace(key string, entry []byte, reason bigcache.RemoveReason) {
+	if reason != bigcache.NoSpace {
+		return
+	}
+	slog.Warn("bc.Cache evicted entry: no space", slog.String("key", key), slog.Int("size", len(entry)))
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/allegro/bigcache/v3"
 	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
+	"github.com/soyacen/gouache/clocktest"
 )
 
 // TestNewCache tests the creation of a new Cache instance
@@ -180,8 +184,11 @@ func TestCache_SetUnsupportedTypeWithoutMarshal(t *testing.T) {
 
 	// Test Set with unsupported type and no Marshal function
 	err = cache.Set(ctx, key, value)
-	if err == nil {
-		t.Error("Expected error when setting unsupported type without Marshal function")
+	if !errors.Is(err, gouache.ErrUnsupportedValue) {
+		t.Errorf("Expected gouache.ErrUnsupportedValue, got %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "int") {
+		t.Errorf("Expected the value type in the message, got %v", err)
 	}
 }
 
@@ -221,3 +228,232 @@ func TestCache_GetWithoutUnmarshal(t *testing.T) {
 		t.Errorf("Expected %v, got %v", string(value), string(result.([]byte)))
 	}
 }
+
+// TestCache_ErrorClassification tests that marshal and storage failures are told apart
+func TestCache_ErrorClassification(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	config.Shards = 1
+	config.HardMaxCacheSize = 1
+	bigCache, err := bigcache.NewBigCache(config)
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+
+	marshalErr := errors.New("marshal error")
+	cache := &Cache{
+		Cache: bigCache,
+		Marshal: func(key string, obj any) ([]byte, error) {
+			return nil, marshalErr
+		},
+	}
+
+	ctx := context.Background()
+
+	// Test marshal failure
+	err = cache.Set(ctx, "test-key", map[string]string{"a": "b"})
+	if !errors.Is(err, gouache.ErrMarshal) || !errors.Is(err, marshalErr) {
+		t.Errorf("Expected gouache.ErrMarshal wrapping %v, got %v", marshalErr, err)
+	}
+
+	// Test storage failure with an entry larger than the shard
+	err = cache.Set(ctx, "large-key", make([]byte, 2*1024*1024))
+	if !errors.Is(err, gouache.ErrStorage) {
+		t.Errorf("Expected gouache.ErrStorage, got %v", err)
+	}
+}
+
+// TestCache_NearCapacity tests usage reporting and the near-capacity guard
+func TestCache_NearCapacity(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	config.Shards = 1
+	config.MaxEntriesInWindow = 10
+	config.MaxEntrySize = 100
+	config.HardMaxCacheSize = 1
+	evicted := 0
+	config.OnRemoveWithReason = func(key string, entry []byte, reason bigcache.RemoveReason) {
+		LogNoSpace(key, entry, reason)
+		if reason == bigcache.NoSpace {
+			evicted++
+		}
+	}
+	bigCache, err := bigcache.NewBigCache(config)
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+
+	cache := &Cache{
+		Cache:        bigCache,
+		MaxCapacity:  1024 * 1024,
+		NearCapacity: 0.5,
+	}
+
+	ctx := context.Background()
+
+	// A near-empty cache accepts values without warning
+	err = cache.Set(ctx, "key-0", make([]byte, 1024))
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if usage := cache.Usage(); usage.Len != 1 || usage.Capacity <= 0 {
+		t.Errorf("Expected 1 entry and a positive capacity, got %+v", usage)
+	}
+
+	// Filling the cache eventually trips the guard, while still storing the value
+	warned := false
+	for i := 1; i < 2000 && !warned; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		err = cache.Set(ctx, key, make([]byte, 1024))
+		if errors.Is(err, ErrNearCapacity) {
+			warned = true
+			if _, err := cache.Get(ctx, key); err != nil {
+				t.Errorf("Expected value to be stored despite the warning, got %v", err)
+			}
+		} else if err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+	if !warned {
+		t.Errorf("Expected ErrNearCapacity, usage %+v", cache.Usage())
+	}
+
+	// Overfilling the cache evicts the oldest entries for lack of space
+	for i := 0; i < 2000; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("more-%d", i), make([]byte, 1024))
+	}
+	if evicted == 0 {
+		t.Error("Expected entries to be evicted for lack of space")
+	}
+}
+
+// TestCache_TTL tests that entries expire with their own TTL before BigCache's LifeWindow
+func TestCache_TTL(t *testing.T) {
+	config := bigcache.DefaultConfig(5 * time.Minute)
+	bigCache, err := bigcache.NewBigCache(config)
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+
+	clock := clocktest.NewClock(time.Now())
+	cache := &Cache{
+		Cache: bigCache,
+		Clock: clock,
+		TTL: func(ctx context.Context, key string, val any) (time.Duration, error) {
+			if key == "short" {
+				return 50 * time.Millisecond, nil
+			}
+			return 0, nil
+		},
+	}
+
+	ctx := context.Background()
+	for _, key := range []string{"short", "long"} {
+		if err := cache.Set(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	// Both entries are visible before the short TTL passes
+	result, err := cache.Get(ctx, "short")
+	if err != nil || string(result.([]byte)) != "short" {
+		t.Errorf("Expected short, got %v, %v", result, err)
+	}
+
+	clock.Advance(100 * time.Millisecond)
+
+	// The short entry is logically expired although BigCache still holds it
+	if _, err := cache.Get(ctx, "short"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss for expired entry, got %v", err)
+	}
+	if _, err := bigCache.Get("short"); err != nil {
+		t.Errorf("Expected BigCache to still hold the entry, got %v", err)
+	}
+
+	// An entry without a TTL only expires with the LifeWindow
+	result, err = cache.Get(ctx, "long")
+	if err != nil || string(result.([]byte)) != "long" {
+		t.Errorf("Expected long, got %v, %v", result, err)
+	}
+}
+
+// TestCache_UnmarshalFallback tests that the fallback can delete an entry that fails to decode and report a miss
+func TestCache_UnmarshalFallback(t *testing.T) {
+	bigCache, err := bigcache.NewBigCache(bigcache.DefaultConfig(5 * time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+	cache := &Cache{
+		Cache: bigCache,
+		Unmarshal: func(key string, data []byte) (any, error) {
+			var obj map[string]string
+			err := json.Unmarshal(data, &obj)
+			return obj, err
+		},
+	}
+	cache.UnmarshalFallback = func(key string, data []byte, err error) (any, error) {
+		_ = cache.Delete(context.Background(), key)
+		return nil, gouache.ErrCacheMiss
+	}
+
+	ctx := context.Background()
+	if err := bigCache.Set("old", []byte("not json")); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// The old-format value reads as a miss and is gone afterwards
+	if _, err := cache.Get(ctx, "old"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+	if _, err := bigCache.Get("old"); !errors.Is(err, bigcache.ErrEntryNotFound) {
+		t.Errorf("Expected the bad entry to be deleted, got %v", err)
+	}
+}
+
+// TestCache_CacheMissContract tests that misses are reported as gouache.ErrCacheMiss
+func TestCache_CacheMissContract(t *testing.T) {
+	bigCache, err := bigcache.NewBigCache(bigcache.DefaultConfig(5 * time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+	cachetest.AssertCacheMissContract(t, &Cache{Cache: bigCache}, cachetest.WithValueFunc(func(i int) any { return []byte(fmt.Sprint("value-", i)) }))
+}
+
+// TestNew tests creating a cache with options and validating its configuration
+func TestNew(t *testing.T) {
+	bigCache, err := bigcache.NewBigCache(bigcache.DefaultConfig(5 * time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create bigcache: %v", err)
+	}
+	clock := clocktest.NewClock(time.Now())
+	cache := New(bigCache,
+		WithMarshal(func(key string, obj any) ([]byte, error) { return json.Marshal(obj) }),
+		WithUnmarshal(func(key string, data []byte) (any, error) {
+			var obj map[string]string
+			err := json.Unmarshal(data, &obj)
+			return obj, err
+		}),
+		WithTTL(func(ctx context.Context, key string, val any) (time.Duration, error) { return time.Minute, nil }),
+		WithClock(clock),
+	)
+	if err := cache.validate(); err != nil {
+		t.Errorf("Expected a consistent configuration, got %v", err)
+	}
+
+	// Values round-trip through the codec and expire with the clock
+	ctx := context.Background()
+	if err := cache.Set(ctx, "key", map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if got, err := cache.Get(ctx, "key"); err != nil || got.(map[string]string)["a"] != "b" {
+		t.Errorf("Expected the stored value, got %v, %v", got, err)
+	}
+	clock.Advance(time.Minute)
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss after the TTL, got %v", err)
+	}
+
+	// A codec missing one direction is reported
+	marshal := func(key string, obj any) ([]byte, error) { return json.Marshal(obj) }
+	if err := New(bigCache, WithMarshal(marshal)).validate(); err == nil || !strings.Contains(err.Error(), "without Unmarshal") {
+		t.Errorf("Expected Marshal without Unmarshal to be reported, got %v", err)
+	}
+}
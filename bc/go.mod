@@ -6,4 +6,6 @@ require github.com/allegro/bigcache/v3 v3.1.0
 
 require github.com/soyacen/gouache v0.0.0-00010101000000-000000000000
 
+require golang.org/x/sync v0.11.0 // indirect
+
 replace github.com/soyacen/gouache => ../
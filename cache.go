@@ -7,12 +7,57 @@ package gouache
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // ErrCacheMiss represents a cache miss error, returned when a requested key
 // does not exist in the cache.
 var ErrCacheMiss = errors.New("gouache: key not found")
 
+// ErrContext is returned, wrapped together with the underlying
+// context.Canceled or context.DeadlineExceeded, when an operation fails
+// because its context was done rather than because the backend failed.
+// Both errors.Is(err, ErrContext) and errors.Is(err, context.Canceled)
+// hold for such errors.
+var ErrContext = errors.New("gouache: context done")
+
+// ErrMarshal is returned, wrapped together with the underlying error, when a
+// backend fails to serialize a value, so that serialization failures can be
+// told apart from storage failures.
+var ErrMarshal = errors.New("gouache: marshal failed")
+
+// ErrStorage is returned, wrapped together with the underlying error, when a
+// backend fails to store a value it has serialized.
+var ErrStorage = errors.New("gouache: storage failed")
+
+// ErrUnsupported is returned by decorators that forward an optional interface
+// when the cache they wrap doesn't support it. Use Capabilities to find out
+// beforehand which optional interfaces actually work.
+var ErrUnsupported = errors.New("gouache: operation not supported")
+
+// ErrUnsupportedValue is returned, wrapped with the offending type, when a
+// backend is given a value it can't store without a Marshal function.
+var ErrUnsupportedValue = errors.New("gouache: unsupported value type")
+
+// ErrPartialResult is returned, wrapped together with the reason, by GetMulti
+// implementations that return the values read so far alongside the error,
+// for example when the context is done before every key was read. Keys
+// missing from such a result were not read and should be treated as misses.
+var ErrPartialResult = errors.New("gouache: partial result")
+
+// ErrEmptyKey is returned when an operation is given an empty key, which is
+// almost always a bug such as an uninitialized ID. Backends accept empty keys;
+// the nonempty decorator rejects them with this error.
+var ErrEmptyKey = errors.New("gouache: empty key")
+
+// ErrorHandler is a function type that handles errors produced by background
+// cache operations, such as delayed deletions, which have no caller to return
+// the error to.
+type ErrorHandler func(err error)
+
 // Cache defines the basic operations for a cache implementation.
 type Cache interface {
 	// Get retrieves a value from the cache by its key.
@@ -83,3 +128,362 @@ type Database interface {
 	//   - An error if the operation fails
 	Delete(ctx context.Context, key string) error
 }
+
+// BatchGetter is an optional interface implemented by caches that can
+// retrieve multiple keys in a single operation.
+type BatchGetter interface {
+	// GetMulti retrieves the values for the given keys from the cache.
+	// Keys that do not exist are omitted from the returned map rather than
+	// reported as ErrCacheMiss. An implementation may return the values read
+	// so far together with an error wrapping ErrPartialResult.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - keys: The keys to retrieve the values for
+	//
+	// Returns:
+	//   - A map from each found key to its cached value
+	//   - An error if the operation fails
+	GetMulti(ctx context.Context, keys []string) (map[string]any, error)
+}
+
+// GetMulti retrieves the values for the given keys from the cache.
+//
+// If the cache implements BatchGetter, its native GetMulti is used. Otherwise
+// it falls back to calling Get sequentially for each key. Keys that do not
+// exist are omitted from the returned map.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to retrieve the values from
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - An error if any Get fails with an error other than ErrCacheMiss
+func GetMulti(ctx context.Context, cache Cache, keys []string) (map[string]any, error) {
+	// Prefer the native batch implementation when available
+	if getter, ok := cache.(BatchGetter); ok {
+		return getter.GetMulti(ctx, keys)
+	}
+
+	// Fall back to sequential Get calls
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		val, err := cache.Get(ctx, key)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		vals[key] = val
+	}
+	return vals, nil
+}
+
+// GetMultiConcurrent retrieves the values for the given keys from the cache,
+// like GetMulti, but falls back to concurrent Get calls when the cache doesn't
+// implement BatchGetter, or Capabilities reports that it doesn't support it.
+// At most maxConcurrency Gets run at the same time, which speeds up large
+// reads from remote backends without batch support.
+//
+// Keys that do not exist are omitted from the returned map. The first Get to
+// fail with an error other than ErrCacheMiss cancels the context of the
+// remaining Gets, and its error is returned.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to retrieve the values from
+//   - keys: The keys to retrieve the values for
+//   - maxConcurrency: The maximum number of concurrent Gets; non-positive values mean one
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - An error if any Get fails with an error other than ErrCacheMiss
+func GetMultiConcurrent(ctx context.Context, cache Cache, keys []string, maxConcurrency int) (map[string]any, error) {
+	// Prefer the native batch implementation when available
+	if getter, ok := cache.(BatchGetter); ok && Capabilities(cache).BatchGet {
+		return getter.GetMulti(ctx, keys)
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+
+	// Fan out Get calls over a bounded pool, stopping at the first error
+	var mu sync.Mutex
+	vals := make(map[string]any, len(keys))
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(maxConcurrency)
+	for _, key := range keys {
+		key := key
+		group.Go(func() error {
+			// Skip the remaining keys once a Get has failed
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			val, err := cache.Get(ctx, key)
+			if errors.Is(err, ErrCacheMiss) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			vals[key] = val
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// BatchSetter is an optional interface implemented by caches that can store
+// several values in a single operation.
+type BatchSetter interface {
+	// SetMulti stores each value in vals under its key.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - vals: The values to store, keyed by the key they are stored under
+	//
+	// Returns:
+	//   - An error if the operation fails
+	SetMulti(ctx context.Context, vals map[string]any) error
+}
+
+// SetMulti stores each value in vals under its key.
+//
+// If the cache implements BatchSetter, its native SetMulti is used. Otherwise
+// it falls back to calling Set sequentially for each value, stopping at the
+// first failure.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to store the values in
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - An error if any Set fails
+func SetMulti(ctx context.Context, cache Cache, vals map[string]any) error {
+	// Prefer the native batch implementation when available
+	if setter, ok := cache.(BatchSetter); ok {
+		return setter.SetMulti(ctx, vals)
+	}
+
+	// Fall back to sequential Set calls
+	for key, val := range vals {
+		if err := cache.Set(ctx, key, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchDeleter is an optional interface implemented by caches that can remove
+// several keys in a single operation.
+type BatchDeleter interface {
+	// DeleteMulti removes the values of the given keys from the cache. Keys
+	// that do not exist are ignored.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - keys: The keys of the values to delete
+	//
+	// Returns:
+	//   - An error if the operation fails
+	DeleteMulti(ctx context.Context, keys []string) error
+}
+
+// DeleteMulti removes the values of the given keys from the cache.
+//
+// If the cache implements BatchDeleter, its native DeleteMulti is used.
+// Otherwise it falls back to calling Delete sequentially for each key,
+// stopping at the first failure.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to remove the values from
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if any Delete fails
+func DeleteMulti(ctx context.Context, cache Cache, keys []string) error {
+	// Prefer the native batch implementation when available
+	if deleter, ok := cache.(BatchDeleter); ok {
+		return deleter.DeleteMulti(ctx, keys)
+	}
+
+	// Fall back to sequential Delete calls
+	for _, key := range keys {
+		if err := cache.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResultDeleter is an optional interface implemented by caches that can
+// report which keys a batch delete actually removed.
+type ResultDeleter interface {
+	// DeleteMultiResult removes the values of the given keys from the cache
+	// and reports which of them existed.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - keys: The keys of the values to delete
+	//
+	// Returns:
+	//   - The keys that existed and were deleted, in the order of keys
+	//   - An error if the operation fails
+	DeleteMultiResult(ctx context.Context, keys []string) ([]string, error)
+}
+
+// DeleteMultiResult removes the values of the given keys from the cache and
+// returns the keys that existed, for example to audit an invalidation.
+//
+// If the cache implements ResultDeleter and Capabilities reports it, its
+// native DeleteMultiResult is used. Otherwise each key is read with Get and,
+// if present, deleted with Delete, stopping at the first failure. The
+// fallback is not atomic: a key written between the two calls is deleted
+// without being reported, and one deleted concurrently may be reported twice.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - cache: The cache to remove the values from
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - An error if any Get or Delete fails, along with the keys deleted before it
+func DeleteMultiResult(ctx context.Context, cache Cache, keys []string) ([]string, error) {
+	// Prefer the native implementation when available
+	if deleter, ok := cache.(ResultDeleter); ok && Capabilities(cache).ResultDeleter {
+		return deleter.DeleteMultiResult(ctx, keys)
+	}
+
+	// Fall back to checking presence before each Delete
+	var deleted []string
+	for _, key := range keys {
+		if _, err := cache.Get(ctx, key); errors.Is(err, ErrCacheMiss) {
+			continue
+		} else if err != nil {
+			return deleted, err
+		}
+		if err := cache.Delete(ctx, key); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, key)
+	}
+	return deleted, nil
+}
+
+// Iterable is an optional interface implemented by caches that can enumerate
+// the keys they hold.
+type Iterable interface {
+	// Iterate calls fn for every key matching the glob-style pattern match.
+	// An empty match enumerates all keys. Iteration stops at the first error
+	// returned by fn, which is then returned by Iterate.
+	//
+	// The iteration order is unspecified, and depending on the implementation
+	// a key may be passed to fn more than once.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - match: The pattern keys must match, or empty to match all keys
+	//   - fn: The function called for each key
+	//
+	// Returns:
+	//   - An error if the operation fails, the context is done, or fn fails
+	Iterate(ctx context.Context, match string, fn func(key string) error) error
+}
+
+// Adder is an optional interface implemented by caches that can store a value
+// only if its key is not already present.
+type Adder interface {
+	// Add stores a value in the cache under the specified key if the key does
+	// not already exist.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key under which the value will be stored
+	//   - val: The value to store
+	//
+	// Returns:
+	//   - true if the value was stored, false if the key already existed
+	//   - An error if the operation fails
+	Add(ctx context.Context, key string, val any) (bool, error)
+}
+
+// TTLSetter is an optional interface implemented by caches that can store a
+// value with an explicit time-to-live.
+type TTLSetter interface {
+	// SetWithTTL stores a value in the cache under the specified key with the
+	// given TTL, overriding any TTL the cache would otherwise resolve.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key under which the value will be stored
+	//   - val: The value to store
+	//   - ttl: The time-to-live of the entry, zero meaning no expiration
+	//
+	// Returns:
+	//   - An error if the operation fails
+	SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error
+}
+
+// TTLReader is an optional interface implemented by caches that can report
+// the remaining time-to-live of an entry.
+type TTLReader interface {
+	// ReadTTL returns the remaining time-to-live of the entry stored under key.
+	// It returns ErrCacheMiss if the key does not exist.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key of the entry
+	//
+	// Returns:
+	//   - The remaining time-to-live, or zero if the entry never expires
+	//   - An error if the operation fails, or ErrCacheMiss if key doesn't exist
+	ReadTTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// Toucher is an optional interface implemented by caches that can extend the
+// time-to-live of an entry without rewriting its value.
+type Toucher interface {
+	// Touch sets a new time-to-live for the entry stored under key.
+	// It returns ErrCacheMiss if the key does not exist.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key of the entry
+	//   - ttl: The new time-to-live of the entry
+	//
+	// Returns:
+	//   - An error if the operation fails, or ErrCacheMiss if key doesn't exist
+	Touch(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// CompareDeleter is an optional interface implemented by caches that can
+// atomically delete a key only while it still holds an expected value.
+//
+// Invalidating with CompareAndDelete instead of Delete avoids removing a newer
+// value written concurrently between reading a value and deleting it.
+type CompareDeleter interface {
+	// CompareAndDelete deletes the entry stored under key if its value equals
+	// expected. Whether values are equal is defined by the cache, for example
+	// by comparing their serialized forms.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key of the entry
+	//   - expected: The value the entry must hold to be deleted
+	//
+	// Returns:
+	//   - true if the entry was deleted, false if it was missing or held another value
+	//   - An error if the operation fails
+	CompareAndDelete(ctx context.Context, key string, expected any) (bool, error)
+}
@@ -0,0 +1,178 @@
+package gouache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/nonempty"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestGetMulti tests that gouache.GetMulti uses the native batch implementation.
+func TestGetMulti(t *testing.T) {
+	// Create a new cache instance
+	cache := &sample.Cache{}
+
+	// Setup: store a value
+	err := cache.Set(context.Background(), "key1", "value1")
+	if err != nil {
+		t.Fatalf("Failed to set up test value: %v", err)
+	}
+
+	// Test getting through the package-level helper
+	result, err := gouache.GetMulti(context.Background(), cache, []string{"key1", "missing"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != 1 || result["key1"] != "value1" {
+		t.Errorf("Expected map[key1:value1], but got %v", result)
+	}
+}
+
+// slowCache is a cache without batch support whose Gets take a while, as
+// those of a remote backend do.
+type slowCache struct {
+	sample.Cache
+	delay time.Duration
+	fail  string
+}
+
+func (c *slowCache) Get(ctx context.Context, key string) (any, error) {
+	if key == c.fail {
+		return nil, errors.New("get failed")
+	}
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+// TestGetMultiConcurrent tests the concurrent fallback for caches without batch support.
+func TestGetMultiConcurrent(t *testing.T) {
+	backend := &slowCache{delay: time.Millisecond}
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		keys = append(keys, key)
+		if i%2 == 0 {
+			_ = backend.Set(context.Background(), key, i)
+		}
+	}
+	var cache struct{ gouache.Cache }
+	cache.Cache = backend
+
+	// Test that found keys are returned and missing ones omitted
+	vals, err := gouache.GetMultiConcurrent(context.Background(), cache, keys, 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vals) != 10 || vals["key4"] != 4 {
+		t.Errorf("Expected the 10 even keys, but got %v", vals)
+	}
+
+	// Test that the first real error is returned
+	backend.fail = "key7"
+	if _, err := gouache.GetMultiConcurrent(context.Background(), cache, keys, 4); err == nil || err.Error() != "get failed" {
+		t.Errorf("Expected get failed, but got: %v", err)
+	}
+}
+
+// concurrencyCache is a cache without batch support recording the highest
+// number of Gets in progress at once.
+type concurrencyCache struct {
+	plainCache
+	active atomic.Int32
+	peak   atomic.Int32
+}
+
+func (c *concurrencyCache) Get(ctx context.Context, key string) (any, error) {
+	n := c.active.Add(1)
+	defer c.active.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return c.plainCache.Get(ctx, key)
+}
+
+// TestGetMultiConcurrent_Decorated tests that the concurrent fallback is used
+// through a decorator forwarding BatchGetter to a cache without batch support
+func TestGetMultiConcurrent_Decorated(t *testing.T) {
+	backend := &concurrencyCache{}
+	keys := make([]string, 16)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	if _, err := gouache.GetMultiConcurrent(context.Background(), nonempty.New(backend), keys, 8); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if peak := backend.peak.Load(); peak < 2 {
+		t.Errorf("Expected concurrent Gets, but at most %d ran at once", peak)
+	}
+}
+
+// benchmarkGetMulti measures reading 100 keys from a cache without batch support.
+func benchmarkGetMulti(b *testing.B, get func(ctx context.Context, c gouache.Cache, keys []string) (map[string]any, error)) {
+	backend := &slowCache{delay: 50 * time.Microsecond}
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		_ = backend.Set(context.Background(), keys[i], i)
+	}
+	var cache struct{ gouache.Cache }
+	cache.Cache = backend
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := get(context.Background(), cache, keys); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetMulti_Sequential measures the sequential GetMulti fallback.
+func BenchmarkGetMulti_Sequential(b *testing.B) {
+	benchmarkGetMulti(b, gouache.GetMulti)
+}
+
+// BenchmarkGetMulti_Concurrent measures the GetMultiConcurrent fallback with 16 workers.
+func BenchmarkGetMulti_Concurrent(b *testing.B) {
+	benchmarkGetMulti(b, func(ctx context.Context, c gouache.Cache, keys []string) (map[string]any, error) {
+		return gouache.GetMultiConcurrent(ctx, c, keys, 16)
+	})
+}
+
+// TestDeleteMultiResult tests the native implementation and the Get-then-Delete fallback
+func TestDeleteMultiResult(t *testing.T) {
+	ctx := context.Background()
+	native := &sample.Cache{}
+	var plain struct{ gouache.Cache }
+	plain.Cache = &sample.Cache{}
+
+	for _, cache := range []gouache.Cache{native, plain} {
+		_ = cache.Set(ctx, "a", 1)
+		_ = cache.Set(ctx, "c", 3)
+
+		deleted, err := gouache.DeleteMultiResult(ctx, cache, []string{"a", "b", "c", "d"})
+		if err != nil {
+			t.Fatalf("Failed to delete keys: %v", err)
+		}
+		if fmt.Sprint(deleted) != "[a c]" {
+			t.Errorf("Expected [a c] deleted, got %v", deleted)
+		}
+		if _, err := cache.Get(ctx, "c"); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected c to be deleted, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package cachehttp exposes a gouache.Cache over HTTP for debugging.
+//
+// The handler lets operators inspect and modify cache entries with plain HTTP
+// requests during development:
+//
+//	GET    /cache/{key}   returns the value stored under key
+//	PUT    /cache/{key}   stores the request body under key as a string
+//	DELETE /cache/{key}   deletes key
+//
+// It performs no authentication and is meant for development use only. It is
+// never registered automatically; it must be constructed with Handler and
+// mounted explicitly, and should not be exposed in production.
+package cachehttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/soyacen/gouache"
+)
+
+// prefix is the path prefix under which keys are addressed.
+const prefix = "/cache/"
+
+// maxBodySize is the maximum size of a PUT request body.
+const maxBodySize = 1 << 20
+
+// handler serves cache entries over HTTP.
+type handler struct {
+	// Cache is the cache exposed by the handler
+	Cache gouache.Cache
+}
+
+// Handler returns an http.Handler exposing c under /cache/{key}.
+//
+// GET writes string and []byte values as they are and other values formatted
+// with fmt, answering 404 on a cache miss. PUT stores the request body, up to
+// 1 MiB, as a string value and answers 204. DELETE answers 204. Other methods
+// are answered with 405, and backend errors with 500.
+//
+// Parameters:
+//   - c: The cache to expose
+//
+// Returns:
+//   - An http.Handler for development use only
+func Handler(c gouache.Cache) http.Handler {
+	return &handler{Cache: c}
+}
+
+// ServeHTTP dispatches a request to the cache operation matching its method.
+//
+// Parameters:
+//   - w: The response writer
+//   - r: The request
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Extract the key from the path
+	key, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r, key)
+	case http.MethodPut:
+		h.put(w, r, key)
+	case http.MethodDelete:
+		h.delete(w, r, key)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// get writes the value stored under key.
+//
+// Parameters:
+//   - w: The response writer
+//   - r: The request
+//   - key: The key to read
+func (h *handler) get(w http.ResponseWriter, r *http.Request, key string) {
+	val, err := h.Cache.Get(r.Context(), key)
+	if errors.Is(err, gouache.ErrCacheMiss) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	switch v := val.(type) {
+	case string:
+		_, _ = io.WriteString(w, v)
+	case []byte:
+		_, _ = w.Write(v)
+	default:
+		_, _ = fmt.Fprint(w, v)
+	}
+}
+
+// put stores the request body under key as a string.
+//
+// Parameters:
+//   - w: The response writer
+//   - r: The request
+//   - key: The key to write
+func (h *handler) put(w http.ResponseWriter, r *http.Request, key string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.Cache.Set(r.Context(), key, string(body)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// delete removes key from the cache.
+//
+// Parameters:
+//   - w: The response writer
+//   - r: The request
+//   - key: The key to delete
+func (h *handler) delete(w http.ResponseWriter, r *http.Request, key string) {
+	if err := h.Cache.Delete(r.Context(), key); err != nil && !errors.Is(err, gouache.ErrCacheMiss) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
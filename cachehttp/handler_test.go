@@ -0,0 +1,83 @@
+package cachehttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/soyacen/gouache/sample"
+)
+
+// do sends a request to h and returns the response status and body.
+func do(t *testing.T, h http.Handler, method, path, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	data, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	return rec.Code, string(data)
+}
+
+// TestHandler tests the GET, PUT and DELETE routes
+func TestHandler(t *testing.T) {
+	cache := &sample.Cache{}
+	h := Handler(cache)
+
+	// A missing key is not found
+	if code, _ := do(t, h, http.MethodGet, "/cache/test-key", ""); code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", code)
+	}
+
+	// PUT stores the body as a string
+	if code, _ := do(t, h, http.MethodPut, "/cache/test-key", "test-value"); code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", code)
+	}
+	val, err := cache.Get(context.Background(), "test-key")
+	if err != nil || val != "test-value" {
+		t.Errorf("Expected test-value in the cache, got %v, %v", val, err)
+	}
+
+	// GET returns the value, formatting non-string values
+	if code, body := do(t, h, http.MethodGet, "/cache/test-key", ""); code != http.StatusOK || body != "test-value" {
+		t.Errorf("Expected 200 test-value, got %d %q", code, body)
+	}
+	_ = cache.Set(context.Background(), "number", 42)
+	if code, body := do(t, h, http.MethodGet, "/cache/number", ""); code != http.StatusOK || body != "42" {
+		t.Errorf("Expected 200 42, got %d %q", code, body)
+	}
+
+	// DELETE removes the key
+	if code, _ := do(t, h, http.MethodDelete, "/cache/test-key", ""); code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", code)
+	}
+	if code, _ := do(t, h, http.MethodGet, "/cache/test-key", ""); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after deletion, got %d", code)
+	}
+}
+
+// TestHandler_BadRequests tests paths and methods the handler rejects
+func TestHandler_BadRequests(t *testing.T) {
+	h := Handler(&sample.Cache{})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{method: http.MethodGet, path: "/other/key", code: http.StatusNotFound},
+		{method: http.MethodGet, path: "/cache/", code: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/cache/key", code: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		if code, _ := do(t, h, tt.method, tt.path, ""); code != tt.code {
+			t.Errorf("%s %s: Expected %d, got %d", tt.method, tt.path, tt.code, code)
+		}
+	}
+}
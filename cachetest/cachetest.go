@@ -0,0 +1,350 @@
+// Package cachetest provides a conformance suite and a benchmark harness for
+// gouache.Cache implementations.
+//
+// A backend's tests can call RunConformance to check that it honours the
+// contract of the interface, and its benchmarks can call BenchmarkCache, so
+// that all backends are verified and compared the same way:
+//
+//	func TestConformance(t *testing.T) {
+//		cachetest.RunConformance(t, &sample.Cache{})
+//	}
+//
+//	func BenchmarkCache(b *testing.B) {
+//		cachetest.BenchmarkCache(b, &sample.Cache{})
+//	}
+//
+// Both store string values by default. Backends that only accept other types,
+// such as byte slices, choose the values with WithValueFunc.
+package cachetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/soyacen/gouache"
+)
+
+// options holds configuration options for the conformance suite and benchmarks.
+type options struct {
+	// ValueFunc returns the i-th value stored by the tests.
+	ValueFunc func(i int) any
+}
+
+// Option is a function that modifies the test options.
+type Option func(*options)
+
+// WithValueFunc returns an Option that sets the values the tests store. The
+// function must return distinct values for distinct i, and Get must return
+// values deeply equal to them. By default the values are strings.
+//
+// Parameters:
+//   - f: A function returning the i-th value to store
+//
+// Returns:
+//   - An Option function that sets the ValueFunc
+func WithValueFunc(f func(i int) any) Option {
+	return func(o *options) {
+		o.ValueFunc = f
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Store strings by default
+	if o.ValueFunc == nil {
+		o.ValueFunc = func(i int) any { return "value-" + strconv.Itoa(i) }
+	}
+	return o
+}
+
+// concurrency is the number of goroutines used by the concurrency test.
+const concurrency = 16
+
+// RunConformance runs the conformance suite against c as subtests of t. It
+// checks the miss contract of AssertCacheMissContract, that a stored value
+// reads back unchanged and can be overwritten, that Delete removes a value and
+// succeeds for missing keys, and that concurrent access returns only stored
+// values or misses. Run it with -race to check for data
+// races as well.
+//
+// The suite uses keys prefixed with "cachetest:" and deletes them afterwards,
+// so c may be shared with other tests.
+//
+// Parameters:
+//   - t: The test to run the suite in
+//   - c: The cache under test
+//   - opts: Variable number of Option functions to configure the suite
+func RunConformance(t *testing.T, c gouache.Cache, opts ...Option) {
+	o := newOptions(opts...)
+	ctx := context.Background()
+
+	t.Run("Miss", func(t *testing.T) {
+		AssertCacheMissContract(t, c, opts...)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		key := "cachetest:round-trip"
+		defer c.Delete(ctx, key)
+
+		want := o.ValueFunc(0)
+		if err := c.Set(ctx, key, want); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		expectValue(t, c, key, want)
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		key := "cachetest:overwrite"
+		defer c.Delete(ctx, key)
+
+		if err := c.Set(ctx, key, o.ValueFunc(0)); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		want := o.ValueFunc(1)
+		if err := c.Set(ctx, key, want); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		expectValue(t, c, key, want)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		key := "cachetest:delete"
+		if err := c.Set(ctx, key, o.ValueFunc(0)); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		if err := c.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		expectMiss(t, c, key)
+
+		// Deleting a missing key is not an error
+		if err := c.Delete(ctx, key); err != nil {
+			t.Errorf("Delete of a missing key failed: %v", err)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		// Every goroutine reads, writes and deletes the same few keys
+		keys := []string{"cachetest:concurrent:0", "cachetest:concurrent:1", "cachetest:concurrent:2"}
+		defer func() {
+			for _, key := range keys {
+				_ = c.Delete(ctx, key)
+			}
+		}()
+		values := make([]any, concurrency)
+		for i := range values {
+			values[i] = o.ValueFunc(i)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					key := keys[(i+j)%len(keys)]
+					switch j % 3 {
+					case 0:
+						if err := c.Set(ctx, key, values[i]); err != nil {
+							t.Errorf("Concurrent Set failed: %v", err)
+						}
+					case 1:
+						val, err := c.Get(ctx, key)
+						if err != nil && !errors.Is(err, gouache.ErrCacheMiss) {
+							t.Errorf("Concurrent Get failed: %v", err)
+						} else if err == nil && !isOneOf(val, values) {
+							t.Errorf("Concurrent Get returned a value that was never stored: %v", val)
+						}
+					case 2:
+						if err := c.Delete(ctx, key); err != nil {
+							t.Errorf("Concurrent Delete failed: %v", err)
+						}
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+}
+
+// AssertCacheMissContract checks that c reports misses the way callers
+// expect: Get of a key that was never set, and of a key that was set and then
+// deleted, returns a nil value and an error matching gouache.ErrCacheMiss with
+// errors.Is. It catches backends that leak their own not-found error, such as
+// redis.Nil, or that return a nil error for missing keys.
+//
+// Parameters:
+//   - t: The test to report failures to
+//   - c: The cache under test
+//   - opts: Variable number of Option functions to configure the check
+func AssertCacheMissContract(t *testing.T, c gouache.Cache, opts ...Option) {
+	t.Helper()
+	o := newOptions(opts...)
+	ctx := context.Background()
+
+	// A key that was never set
+	expectMiss(t, c, "cachetest:never-set")
+
+	// A key that was set and then deleted
+	key := "cachetest:deleted"
+	if err := c.Set(ctx, key, o.ValueFunc(0)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	expectMiss(t, c, key)
+}
+
+// BenchmarkCache runs a standard set of benchmarks against c as
+// sub-benchmarks of b: hits, misses, writes, and a parallel mix of nine reads
+// to one write. Report allocations with -benchmem to compare backends.
+//
+// Parameters:
+//   - b: The benchmark to run the sub-benchmarks in
+//   - c: The cache under test
+//   - opts: Variable number of Option functions to configure the benchmarks
+func BenchmarkCache(b *testing.B, c gouache.Cache, opts ...Option) {
+	o := newOptions(opts...)
+	ctx := context.Background()
+
+	// Populate a working set of keys
+	const size = 1024
+	keys := make([]string, size)
+	values := make([]any, size)
+	for i := range keys {
+		keys[i] = "cachetest:bench:" + strconv.Itoa(i)
+		values[i] = o.ValueFunc(i)
+		if err := c.Set(ctx, keys[i], values[i]); err != nil {
+			b.Fatalf("Set failed: %v", err)
+		}
+	}
+	defer func() {
+		for _, key := range keys {
+			_ = c.Delete(ctx, key)
+		}
+	}()
+
+	b.Run("GetHit", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := c.Get(ctx, keys[i%size]); err != nil {
+				b.Fatalf("Get failed: %v", err)
+			}
+		}
+	})
+
+	b.Run("GetMiss", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := c.Get(ctx, "cachetest:bench:missing"); !errors.Is(err, gouache.ErrCacheMiss) {
+				b.Fatalf("Expected gouache.ErrCacheMiss, got %v", err)
+			}
+		}
+	})
+
+	b.Run("Set", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := c.Set(ctx, keys[i%size], values[i%size]); err != nil {
+				b.Fatalf("Set failed: %v", err)
+			}
+		}
+	})
+
+	b.Run("Parallel", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				if i%10 == 0 {
+					_ = c.Set(ctx, keys[i%size], values[i%size])
+				} else {
+					_, _ = c.Get(ctx, keys[i%size])
+				}
+			}
+		})
+	})
+}
+
+// expectValue fails t unless key holds want.
+//
+// Parameters:
+//   - t: The test to report failures to
+//   - c: The cache under test
+//   - key: The key to read
+//   - want: The expected value
+func expectValue(t *testing.T, c gouache.Cache, key string, want any) {
+	t.Helper()
+	got, err := c.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v (%T), got %v (%T)", want, want, got, got)
+	}
+}
+
+// expectMiss fails t unless Get of key returns a nil value and an error
+// matching gouache.ErrCacheMiss.
+//
+// Parameters:
+//   - t: The test to report failures to
+//   - c: The cache under test
+//   - key: The key expected to be missing
+func expectMiss(t *testing.T, c gouache.Cache, key string) {
+	t.Helper()
+	val, err := c.Get(context.Background(), key)
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Get of missing key %q: expected gouache.ErrCacheMiss, got %v", key, err)
+	}
+	if val != nil {
+		t.Errorf("Get of missing key %q: expected a nil value, got %v (%T)", key, val, val)
+	}
+}
+
+// isOneOf reports whether val is deeply equal to one of values.
+//
+// Parameters:
+//   - val: The value to look for
+//   - values: The candidate values
+//
+// Returns:
+//   - true if val equals one of values
+func isOneOf(val any, values []any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(val, v) {
+			return true
+		}
+	}
+	return false
+}
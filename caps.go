@@ -0,0 +1,82 @@
+package gouache
+
+// Caps reports which optional interfaces a cache supports.
+type Caps struct {
+	// BatchGet reports support for BatchGetter.
+	BatchGet bool
+
+	// BatchSet reports support for BatchSetter.
+	BatchSet bool
+
+	// BatchDelete reports support for BatchDeleter.
+	BatchDelete bool
+
+	// Iterate reports support for Iterable.
+	Iterate bool
+
+	// Add reports support for Adder.
+	Add bool
+
+	// SetWithTTL reports support for TTLSetter.
+	SetWithTTL bool
+
+	// ReadTTL reports support for TTLReader.
+	ReadTTL bool
+
+	// Touch reports support for Toucher.
+	Touch bool
+
+	// CompareAndDelete reports support for CompareDeleter.
+	CompareAndDelete bool
+
+	// ResultDeleter reports support for ResultDeleter.
+	ResultDeleter bool
+}
+
+// CapabilityReporter is an optional interface implemented by decorators whose
+// support for optional interfaces depends on the cache they wrap.
+//
+// Such a decorator typically implements the optional methods unconditionally
+// and delegates them to the wrapped cache, usually by embedding Forwarder, so
+// type assertions on it always succeed. Capabilities lets it report what
+// actually works instead, usually by returning the Capabilities of the
+// wrapped cache restricted to the interfaces it forwards.
+type CapabilityReporter interface {
+	// Capabilities reports which optional interfaces the cache supports.
+	//
+	// Returns:
+	//   - The supported optional interfaces
+	Capabilities() Caps
+}
+
+// Capabilities reports which optional interfaces c supports.
+//
+// If c implements CapabilityReporter, its own report is returned, which lets
+// decorators expose the capabilities of the caches they wrap, however deeply.
+// Otherwise the capabilities are discovered with type assertions on c.
+//
+// Parameters:
+//   - c: The cache to probe
+//
+// Returns:
+//   - The optional interfaces supported by c
+func Capabilities(c Cache) Caps {
+	// Prefer the cache's own report
+	if reporter, ok := c.(CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+
+	// Fall back to type assertions
+	var caps Caps
+	_, caps.BatchGet = c.(BatchGetter)
+	_, caps.BatchSet = c.(BatchSetter)
+	_, caps.BatchDelete = c.(BatchDeleter)
+	_, caps.Iterate = c.(Iterable)
+	_, caps.Add = c.(Adder)
+	_, caps.SetWithTTL = c.(TTLSetter)
+	_, caps.ReadTTL = c.(TTLReader)
+	_, caps.Touch = c.(Toucher)
+	_, caps.CompareAndDelete = c.(CompareDeleter)
+	_, caps.ResultDeleter = c.(ResultDeleter)
+	return caps
+}
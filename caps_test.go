@@ -0,0 +1,40 @@
+package gouache_test
+
+import (
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// reportingWrapper is a decorator that forwards capability probing to the cache it wraps.
+type reportingWrapper struct {
+	gouache.Cache
+}
+
+func (w reportingWrapper) Capabilities() gouache.Caps {
+	return gouache.Capabilities(w.Cache)
+}
+
+// TestCapabilities tests capability discovery on plain and wrapped caches.
+func TestCapabilities(t *testing.T) {
+	want := gouache.Caps{BatchGet: true, BatchSet: true, BatchDelete: true, Iterate: true, Add: true, CompareAndDelete: true, ResultDeleter: true}
+
+	// Test discovery by type assertions
+	if caps := gouache.Capabilities(&sample.Cache{}); caps != want {
+		t.Errorf("Expected %+v, but got %+v", want, caps)
+	}
+
+	// Test that a plain wrapper hides the capabilities
+	var plain struct{ gouache.Cache }
+	plain.Cache = &sample.Cache{}
+	if caps := gouache.Capabilities(plain); caps != (gouache.Caps{}) {
+		t.Errorf("Expected no capabilities, but got %+v", caps)
+	}
+
+	// Test that reporting wrappers expose them, however deeply nested
+	wrapped := reportingWrapper{reportingWrapper{&sample.Cache{}}}
+	if caps := gouache.Capabilities(wrapped); caps != want {
+		t.Errorf("Expected %+v, but got %+v", want, caps)
+	}
+}
@@ -0,0 +1,47 @@
+package gouache
+
+import "time"
+
+// Clock abstracts the passage of time so that time-dependent code, such as
+// delayed deletions and expiry checks, can be tested with a fake clock that
+// is advanced manually instead of waiting in real time. See the clocktest
+// package for such a fake.
+type Clock interface {
+	// Now returns the current time.
+	//
+	// Returns:
+	//   - The current time
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	//
+	// Parameters:
+	//   - d: The duration to wait
+	//
+	// Returns:
+	//   - A channel receiving the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+
+	// Sleep pauses the current goroutine for at least the duration d.
+	//
+	// Parameters:
+	//   - d: The duration to sleep
+	Sleep(d time.Duration)
+}
+
+// RealClock is the Clock backed by the time package, used by default
+// wherever a Clock can be configured.
+var RealClock Clock = realClock{}
+
+// realClock implements Clock with the functions of the time package.
+type realClock struct{}
+
+// Now returns time.Now().
+func (realClock) Now() time.Time { return time.Now() }
+
+// After returns time.After(d).
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Sleep calls time.Sleep(d).
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
@@ -0,0 +1,136 @@
+// Package clocktest provides a fake gouache.Clock for tests.
+//
+// The fake clock only moves when Advance is called, so tests of delays and
+// expiry run instantly and deterministically. Use BlockUntil to wait until
+// the code under test is sleeping before advancing the clock.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Clock implements the gouache.Clock interface at compile time.
+var _ gouache.Clock = (*Clock)(nil)
+
+// waiter is a pending After or Sleep call.
+type waiter struct {
+	// until is the time at which the waiter fires.
+	until time.Time
+
+	// ch receives the time when the waiter fires.
+	ch chan time.Time
+}
+
+// Clock is a fake gouache.Clock whose time only changes when advanced.
+// It is safe for concurrent use.
+type Clock struct {
+	// mu guards now, waiters and changed.
+	mu sync.Mutex
+
+	// now is the current fake time.
+	now time.Time
+
+	// waiters are the pending After and Sleep calls.
+	waiters []waiter
+
+	// changed is closed and replaced whenever a waiter is added.
+	changed chan struct{}
+}
+
+// NewClock creates a fake clock set to start.
+//
+// Parameters:
+//   - start: The initial time of the clock
+//
+// Returns:
+//   - A fake clock
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start, changed: make(chan struct{})}
+}
+
+// Now returns the current fake time.
+//
+// Returns:
+//   - The current fake time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the fake time once the clock has
+// been advanced by at least d.
+//
+// Parameters:
+//   - d: The duration to wait
+//
+// Returns:
+//   - A channel receiving the time once d has elapsed
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+
+	// Fire immediately for non-positive durations
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, waiter{until: c.now.Add(d), ch: ch})
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return ch
+}
+
+// Sleep blocks until the clock has been advanced by at least d.
+//
+// Parameters:
+//   - d: The duration to sleep
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d, firing every After and Sleep whose
+// duration has elapsed.
+//
+// Parameters:
+//   - d: The duration to advance the clock by
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+
+	// Fire the waiters that are due and keep the others
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.until.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// BlockUntil blocks until at least n After or Sleep calls are pending, which
+// lets a test wait for a background goroutine to start sleeping before
+// advancing the clock.
+//
+// Parameters:
+//   - n: The number of pending waiters to wait for
+func (c *Clock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		if len(c.waiters) >= n {
+			c.mu.Unlock()
+			return
+		}
+		changed := c.changed
+		c.mu.Unlock()
+		<-changed
+	}
+}
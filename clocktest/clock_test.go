@@ -0,0 +1,62 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+)
+
+// TestClock_Advance tests that waiters fire only once the clock has advanced far enough.
+func TestClock_Advance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	short := clock.After(time.Second)
+	long := clock.After(time.Minute)
+
+	clock.Advance(time.Second)
+	select {
+	case now := <-short:
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("Expected %v, got %v", start.Add(time.Second), now)
+		}
+	default:
+		t.Error("Expected the short waiter to fire")
+	}
+	select {
+	case <-long:
+		t.Error("Expected the long waiter not to fire yet")
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case <-long:
+	default:
+		t.Error("Expected the long waiter to fire")
+	}
+	if !clock.Now().Equal(start.Add(61 * time.Second)) {
+		t.Errorf("Expected %v, got %v", start.Add(61*time.Second), clock.Now())
+	}
+}
+
+// TestClock_Sleep tests that Sleep blocks until the clock is advanced.
+func TestClock_Sleep(t *testing.T) {
+	clock := NewClock(time.Now())
+	done := make(chan struct{})
+	go func() {
+		clock.Sleep(time.Hour)
+		close(done)
+	}()
+
+	// Wait for the goroutine to sleep, then wake it
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Sleep to return after advancing the clock")
+	}
+
+	// Non-positive durations return immediately
+	clock.Sleep(0)
+}
@@ -0,0 +1,199 @@
+// Package protocodec provides Marshal and Unmarshal functions that store
+// protobuf messages in the byte and string based backends.
+//
+// A Codec encodes proto.Message values in the protobuf wire format and
+// decodes them back into messages of the right type, so a single Codec can
+// be plugged into the Marshal and Unmarshal fields of fc, bc and redis instead
+// of writing a pair of functions per message type:
+//
+//	codec := protocodec.New()
+//	cache := &fc.Cache{Cache: freecache.NewCache(size), Marshal: codec.Marshal, Unmarshal: codec.Unmarshal}
+//	cache := &redis.Cache{Cache: client, Marshal: codec.MarshalString, Unmarshal: codec.UnmarshalString}
+//
+// By default each message is stored wrapped in a google.protobuf.Any, which
+// names its type, and is decoded into that type, looked up in the global
+// registry of linked-in message types. A cache holding a single message type
+// can use WithType or WithFactory instead to store the bare message.
+package protocodec
+
+import (
+	"fmt"
+
+	"github.com/soyacen/gouache"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Resolver looks up message types by the URL stored in a google.protobuf.Any.
+// protoregistry.GlobalTypes and *protoregistry.Types implement it.
+type Resolver interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+}
+
+// Codec marshals protobuf messages for storage in a cache and unmarshals
+// them back into messages of the right type.
+type Codec struct {
+	// Factory returns an empty message to decode the data stored under key
+	// into. If nil, messages are stored wrapped in a google.protobuf.Any and
+	// decoded into the type it names.
+	Factory func(key string) (proto.Message, error)
+
+	// Resolver looks up the types named by stored google.protobuf.Any
+	// messages when Factory is nil. If nil, protoregistry.GlobalTypes is used.
+	Resolver Resolver
+}
+
+// Option is a function that configures a Codec.
+type Option func(*Codec)
+
+// WithFactory returns an Option that sets the function returning the empty
+// message the data stored under a key is decoded into. Messages are then
+// stored bare, without the type name, so the factory must know the type of
+// every key, for example from its prefix.
+//
+// Parameters:
+//   - f: The function returning an empty message for a key
+//
+// Returns:
+//   - An Option function that sets Factory
+func WithFactory(f func(key string) (proto.Message, error)) Option {
+	return func(codec *Codec) {
+		codec.Factory = f
+	}
+}
+
+// WithType returns an Option that decodes every value into a message of type
+// M, for caches that hold a single message type. Messages are stored bare.
+//
+// Returns:
+//   - An Option function that sets Factory to create messages of type M
+func WithType[M proto.Message]() Option {
+	return WithFactory(func(key string) (proto.Message, error) {
+		var m M
+		return m.ProtoReflect().Type().New().Interface(), nil
+	})
+}
+
+// WithResolver returns an Option that sets the registry the types named by
+// stored google.protobuf.Any messages are looked up in.
+//
+// Parameters:
+//   - r: The registry of message types
+//
+// Returns:
+//   - An Option function that sets Resolver
+func WithResolver(r Resolver) Option {
+	return func(codec *Codec) {
+		codec.Resolver = r
+	}
+}
+
+// New creates a new Codec.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to configure the codec
+//
+// Returns:
+//   - A Codec storing messages wrapped in google.protobuf.Any unless configured otherwise
+func New(opts ...Option) *Codec {
+	codec := &Codec{}
+	for _, opt := range opts {
+		opt(codec)
+	}
+	return codec
+}
+
+// Marshal encodes a protobuf message. It has the signature of the Marshal
+// field of fc and bc.
+//
+// Parameters:
+//   - key: The key the value is stored under
+//   - obj: The value to encode, which must be a proto.Message
+//
+// Returns:
+//   - The encoded message
+//   - An error wrapping gouache.ErrUnsupportedValue if obj isn't a proto.Message, or an error if encoding fails
+func (codec *Codec) Marshal(key string, obj any) ([]byte, error) {
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a proto.Message", gouache.ErrUnsupportedValue, obj)
+	}
+
+	// Store the bare message when the factory knows its type
+	if codec.Factory != nil {
+		return proto.Marshal(msg)
+	}
+
+	// Otherwise wrap it in an Any naming its type
+	wrapped, err := anypb.New(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(wrapped)
+}
+
+// Unmarshal decodes a protobuf message encoded by Marshal. It has the
+// signature of the Unmarshal field of fc and bc.
+//
+// Parameters:
+//   - key: The key the data was stored under
+//   - data: The encoded message
+//
+// Returns:
+//   - The decoded proto.Message
+//   - An error if the type of the message can't be determined or decoding fails
+func (codec *Codec) Unmarshal(key string, data []byte) (any, error) {
+	// Decode into the message returned by the factory
+	if codec.Factory != nil {
+		msg, err := codec.Factory(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	// Otherwise decode the Any and the message type it names
+	var wrapped anypb.Any
+	if err := proto.Unmarshal(data, &wrapped); err != nil {
+		return nil, err
+	}
+	resolver := codec.Resolver
+	if resolver == nil {
+		resolver = protoregistry.GlobalTypes
+	}
+	return anypb.UnmarshalNew(&wrapped, proto.UnmarshalOptions{Resolver: resolver})
+}
+
+// MarshalString encodes a protobuf message as a string. It has the signature
+// of the Marshal field of redis.
+//
+// Parameters:
+//   - key: The key the value is stored under
+//   - obj: The value to encode, which must be a proto.Message
+//
+// Returns:
+//   - The encoded message
+//   - An error wrapping gouache.ErrUnsupportedValue if obj isn't a proto.Message, or an error if encoding fails
+func (codec *Codec) MarshalString(key string, obj any) (string, error) {
+	data, err := codec.Marshal(key, obj)
+	return string(data), err
+}
+
+// UnmarshalString decodes a protobuf message encoded by MarshalString. It has
+// the signature of the Unmarshal field of redis.
+//
+// Parameters:
+//   - key: The key the data was stored under
+//   - data: The encoded message
+//
+// Returns:
+//   - The decoded proto.Message
+//   - An error if the type of the message can't be determined or decoding fails
+func (codec *Codec) UnmarshalString(key string, data string) (any, error) {
+	return codec.Unmarshal(key, []byte(data))
+}
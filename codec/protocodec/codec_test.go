@@ -0,0 +1,86 @@
+package protocodec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TestCodec_RoundTrip tests that messages of any registered type round-trip through Any
+func TestCodec_RoundTrip(t *testing.T) {
+	codec := New()
+	user, err := structpb.NewStruct(map[string]any{"name": "gopher", "age": 13})
+	if err != nil {
+		t.Fatalf("Failed to build message: %v", err)
+	}
+
+	for _, msg := range []proto.Message{user, timestamppb.New(timestamppb.Now().AsTime()), durationpb.New(90)} {
+		data, err := codec.Marshal("key", msg)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		got, err := codec.Unmarshal("key", data)
+		if err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if !proto.Equal(got.(proto.Message), msg) {
+			t.Errorf("Expected %v, got %v", msg, got)
+		}
+	}
+
+	// The string variants used by redis round-trip too
+	str, err := codec.MarshalString("key", user)
+	if err != nil {
+		t.Fatalf("MarshalString failed: %v", err)
+	}
+	got, err := codec.UnmarshalString("key", str)
+	if err != nil || !proto.Equal(got.(proto.Message), user) {
+		t.Errorf("Expected %v, got %v, %v", user, got, err)
+	}
+}
+
+// TestCodec_WithType tests that a single-type codec stores bare messages
+func TestCodec_WithType(t *testing.T) {
+	codec := New(WithType[*durationpb.Duration]())
+	msg := durationpb.New(1500)
+
+	data, err := codec.Marshal("key", msg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	bare, _ := proto.Marshal(msg)
+	if string(data) != string(bare) {
+		t.Errorf("Expected the bare message encoding")
+	}
+	got, err := codec.Unmarshal("key", data)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if d, ok := got.(*durationpb.Duration); !ok || !proto.Equal(d, msg) {
+		t.Errorf("Expected %v, got %v", msg, got)
+	}
+}
+
+// TestCodec_Errors tests that non-proto values and unknown types are rejected
+func TestCodec_Errors(t *testing.T) {
+	codec := New()
+	if _, err := codec.Marshal("key", "not a message"); !errors.Is(err, gouache.ErrUnsupportedValue) {
+		t.Errorf("Expected gouache.ErrUnsupportedValue, got %v", err)
+	}
+
+	// A type missing from the resolver can't be decoded
+	data, err := codec.Marshal("key", durationpb.New(1))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	empty := New(WithResolver(new(protoregistry.Types)))
+	if _, err := empty.Unmarshal("key", data); err == nil {
+		t.Error("Expected an error for an unregistered message type")
+	}
+}
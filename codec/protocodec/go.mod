@@ -0,0 +1,12 @@
+module github.com/soyacen/gouache/codec/protocodec
+
+go 1.20
+
+require (
+	github.com/soyacen/gouache v0.0.0-00010101000000-000000000000
+	google.golang.org/protobuf v1.34.2
+)
+
+require golang.org/x/sync v0.11.0 // indirect
+
+replace github.com/soyacen/gouache => ../..
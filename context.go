@@ -0,0 +1,44 @@
+package gouache
+
+import "context"
+
+// DetachedContext returns a context for a background operation started on
+// behalf of the request ctx. It carries the values ctx holds under keys, such
+// as a request or trace ID, so that the operation's log lines can be
+// correlated with the request, but none of ctx's other values, and neither
+// its cancellation nor its deadline, so it outlives the request.
+//
+// Unlike context.WithoutCancel, which keeps every value, it doesn't pin
+// request-scoped state such as a tracing span that ends with the request.
+//
+// Parameters:
+//   - ctx: The request context
+//   - keys: The keys of the values to copy; keys ctx holds no value for are skipped
+//
+// Returns:
+//   - A context derived from context.Background carrying the copied values
+func DetachedContext(ctx context.Context, keys ...any) context.Context {
+	detached := context.Background()
+	for _, key := range keys {
+		if val := ctx.Value(key); val != nil {
+			detached = context.WithValue(detached, key, val)
+		}
+	}
+	return detached
+}
+
+// ContextValues returns a function deriving the context of a background
+// operation with DetachedContext, copying the values held under keys. It
+// suits the WithContextFunc options of decorators that run operations in the
+// background, such as ddd.
+//
+// Parameters:
+//   - keys: The keys of the values to copy
+//
+// Returns:
+//   - A function deriving the background context from the request context
+func ContextValues(keys ...any) func(ctx context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return DetachedContext(ctx, keys...)
+	}
+}
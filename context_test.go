@@ -0,0 +1,33 @@
+package gouache_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/soyacen/gouache"
+)
+
+// requestIDKey and spanKey are context keys used by TestDetachedContext.
+type (
+	requestIDKey struct{}
+	spanKey      struct{}
+)
+
+// TestDetachedContext tests that only the selected values are copied and cancellation is dropped
+func TestDetachedContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	ctx = context.WithValue(ctx, spanKey{}, "span")
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	detached := gouache.ContextValues(requestIDKey{}, "missing")(ctx)
+	if got := detached.Value(requestIDKey{}); got != "req-1" {
+		t.Errorf("Expected the request ID to be copied, got %v", got)
+	}
+	if got := detached.Value(spanKey{}); got != nil {
+		t.Errorf("Expected other values to be dropped, got %v", got)
+	}
+	if err := detached.Err(); err != nil {
+		t.Errorf("Expected the detached context not to be canceled, got %v", err)
+	}
+}
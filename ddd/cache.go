@@ -10,6 +10,7 @@ package ddd
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
@@ -19,8 +20,14 @@ import (
 // Ensure that cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*cache)(nil)
 
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
 // Gopher is a function type that executes a given function asynchronously.
 // It's used to run delayed operations in the background.
+//
+// A panic in a background goroutine crashes the process, so custom Gopher
+// implementations should recover panics raised by f, as the default one does.
 type Gopher func(f func()) error
 
 // options holds configuration options for the delay double delete cache.
@@ -32,10 +39,25 @@ type options struct {
 	DeleteTimeout time.Duration
 
 	// ErrorHandler is called when an error occurs during the delayed delete operation.
-	ErrorHandler func(error)
+	// It is never called with gouache.ErrCacheMiss.
+	ErrorHandler gouache.ErrorHandler
 
 	// Gopher is responsible for executing functions asynchronously.
 	Gopher Gopher
+
+	// ContextFunc derives the context of the delayed delete from the request context.
+	ContextFunc func(ctx context.Context) context.Context
+
+	// Clock is used to wait for the delay before the second deletion.
+	Clock gouache.Clock
+
+	// SynchronousSecondDelete makes Set and Delete wait for the delay and run
+	// the second deletion before returning.
+	SynchronousSecondDelete bool
+
+	// FirstStepReplace makes Set store the new value in the cache instead of
+	// deleting the cache entry before the database write.
+	FirstStepReplace bool
 }
 
 // Option is a function that modifies the cache options.
@@ -77,7 +99,7 @@ func WithDeleteTimeout(dur time.Duration) Option {
 //
 // Returns:
 //   - An Option function that sets the ErrorHandler
-func WithErrorHandler(f func(error)) Option {
+func WithErrorHandler(f gouache.ErrorHandler) Option {
 	return func(o *options) {
 		o.ErrorHandler = f
 	}
@@ -97,6 +119,103 @@ func WithGopher(gopher Gopher) Option {
 	}
 }
 
+// WithContextFunc returns an Option that sets how the context of the delayed
+// delete is derived from the request context. The default,
+// context.WithoutCancel, keeps the request's values but drops its
+// cancellation and deadline, so the delayed delete survives the end of the
+// request. The DeleteTimeout is applied on top of the derived context.
+//
+// A tracing span carried by the request context usually ends with the
+// request, before the delayed delete runs. Spans started from the derived
+// context then outlive their parent; a ContextFunc that starts a new span
+// linked to the request span keeps traces well-formed.
+//
+// Parameters:
+//   - f: A function deriving the background context from the request context
+//
+// Returns:
+//   - An Option function that sets the ContextFunc
+func WithContextFunc(f func(ctx context.Context) context.Context) Option {
+	return func(o *options) {
+		o.ContextFunc = f
+	}
+}
+
+// WithContextValues returns an Option that runs the delayed delete with a
+// context carrying only the request context's values under keys, such as a
+// request ID, so that its log lines can be correlated with the request. It is
+// a shorthand for WithContextFunc(gouache.ContextValues(keys...)).
+//
+// Parameters:
+//   - keys: The keys of the context values to copy into the background context
+//
+// Returns:
+//   - An Option function that sets the ContextFunc
+func WithContextValues(keys ...any) Option {
+	return WithContextFunc(gouache.ContextValues(keys...))
+}
+
+// WithClock returns an Option that sets the clock used to wait for the delay before the second
+// deletion.
+// It defaults to gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// WithSynchronousSecondDelete returns an Option that runs the second deletion
+// inside Set and Delete instead of in the background: they wait for the
+// DelayDuration, delete the cache entry and only then return, reporting a
+// failed second deletion as their own error rather than to the ErrorHandler.
+// The Gopher and ContextFunc are not used in this mode; the DeleteTimeout is
+// applied to the request context.
+//
+// This makes the cache empty for the key once Set or Delete returns, which
+// keeps tests and short-lived processes such as CLI tools deterministic. Every
+// write is delayed by the DelayDuration, so the mode is unsuitable for request
+// paths.
+//
+// Parameters:
+//   - enabled: Whether the second deletion runs synchronously
+//
+// Returns:
+//   - An Option function that sets SynchronousSecondDelete
+func WithSynchronousSecondDelete(enabled bool) Option {
+	return func(o *options) {
+		o.SynchronousSecondDelete = enabled
+	}
+}
+
+// WithFirstStepReplace returns an Option that makes Set replace the cache
+// entry with the new value as its first step, instead of deleting it. Readers
+// then keep hitting the cache while the database is written, rather than all
+// missing and reading the database at once, which reduces the load updates
+// put on the database. The delayed second deletion is still scheduled, so a
+// stale value written back by a racing reader is cleared as before.
+//
+// The tradeoff is that readers may see the new value before the database
+// write commits. If the write fails, Set deletes the entry again, but a
+// reader may already have seen a value that was never stored. Delete is not
+// affected.
+//
+// Parameters:
+//   - enabled: Whether Set replaces rather than deletes the cache entry first
+//
+// Returns:
+//   - An Option function that sets FirstStepReplace
+func WithFirstStepReplace(enabled bool) Option {
+	return func(o *options) {
+		o.FirstStepReplace = enabled
+	}
+}
+
 // newOptions creates a new options instance with default values and applies
 // the provided options.
 //
@@ -141,21 +260,52 @@ func (o *options) Correct() *options {
 
 	// Set default error handler if not specified
 	if o.ErrorHandler == nil {
-		o.ErrorHandler = func(err error) {
-			slog.Error("ddd.Cache.Get", slog.String("err", err.Error()))
-		}
+		o.ErrorHandler = gouache.DefaultErrorHandler("ddd.Cache.secondDelete")
+	}
+
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+
+	// Keep request values but drop cancellation by default
+	if o.ContextFunc == nil {
+		o.ContextFunc = context.WithoutCancel
 	}
 
-	// Set default Gopher if not specified
+	// Set default Gopher if not specified, recovering panics in the goroutine
 	if o.Gopher == nil {
 		o.Gopher = func(f func()) error {
-			go f()
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						o.handlePanic(r)
+					}
+				}()
+				f()
+			}()
 			return nil
 		}
 	}
 	return o
 }
 
+// handlePanic reports a panic recovered from a background operation to the
+// ErrorHandler. If the ErrorHandler panics in turn, that panic is recovered
+// and logged instead.
+//
+// Parameters:
+//   - r: The value recovered from the panic
+func (o *options) handlePanic(r any) {
+	err := fmt.Errorf("ddd: panic in background operation: %v", r)
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("ddd.Cache: panic in ErrorHandler", slog.String("err", err.Error()), slog.Any("panic", r))
+		}
+	}()
+	o.ErrorHandler(err)
+}
+
 // cache is a cache implementation that uses the delay double delete pattern
 // to maintain consistency between cache and database.
 type cache struct {
@@ -218,6 +368,13 @@ func (cache *cache) Get(ctx context.Context, key string) (any, error) {
 // existing cache entry, then upserts the value in the database, and finally
 // schedules a delayed deletion of the cache entry to handle race conditions.
 //
+// If the upsert fails, the error is returned and no delayed deletion is
+// scheduled, since the database still holds the value the cache may be
+// repopulated with.
+//
+// With WithFirstStepReplace, the cache entry is replaced with val instead of
+// deleted first, and deleted again if the upsert fails.
+//
 // Parameters:
 //   - ctx: Context for the operation
 //   - key: The key under which the value will be stored
@@ -226,39 +383,36 @@ func (cache *cache) Get(ctx context.Context, key string) (any, error) {
 // Returns:
 //   - An error if the operation fails
 func (cache *cache) Set(ctx context.Context, key string, val any) error {
-	// Delete existing cache entry
-	if err := cache.Cache.Delete(ctx, key); err != nil {
+	// Replace or delete existing cache entry
+	if cache.Options.FirstStepReplace {
+		if err := cache.Cache.Set(ctx, key, val); err != nil {
+			return err
+		}
+	} else if err := cache.Cache.Delete(ctx, key); err != nil {
 		return err
 	}
 
-	// Upsert value in database
+	// Upsert value in database, withdrawing a replaced value if that fails
 	if err := cache.Database.Upsert(ctx, key, val); err != nil {
+		if cache.Options.FirstStepReplace {
+			if delErr := cache.Cache.Delete(ctx, key); delErr != nil && !errors.Is(delErr, gouache.ErrCacheMiss) {
+				return errors.Join(err, delErr)
+			}
+		}
 		return err
 	}
 
 	// Schedule delayed cache deletion to handle race conditions
-	return cache.Options.Gopher(func() {
-		// Wait for the specified delay duration
-		time.Sleep(cache.Options.DelayDuration)
-
-		// Create a new context without the original cancellation
-		ctx := context.WithoutCancel(ctx)
-
-		// Add timeout to the context
-		ctx, cancel := context.WithTimeout(ctx, cache.Options.DeleteTimeout)
-		defer cancel()
-
-		// Perform the second cache deletion
-		if err := cache.Cache.Delete(ctx, key); err != nil {
-			cache.Options.ErrorHandler(err)
-		}
-	})
+	return cache.secondDelete(ctx, key)
 }
 
 // Delete removes a value from both the cache and database. It first deletes
 // the value from the cache, then deletes it from the database, and finally
 // schedules a delayed deletion of the cache entry to handle race conditions.
 //
+// If the database deletion fails, the error is returned and no delayed
+// deletion is scheduled.
+//
 // Parameters:
 //   - ctx: Context for the operation
 //   - key: The key of the value to delete
@@ -277,19 +431,57 @@ func (cache *cache) Delete(ctx context.Context, key string) error {
 	}
 
 	// Schedule delayed cache deletion to handle race conditions
+	return cache.secondDelete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// secondDelete deletes the cache entry of key again after the DelayDuration,
+// in the background by default, or before returning with
+// WithSynchronousSecondDelete.
+//
+// Parameters:
+//   - ctx: Context of the request
+//   - key: The key of the entry to delete
+//
+// Returns:
+//   - An error if the deletion can't be scheduled, or, in synchronous mode, if
+//     the context is done during the delay or the deletion fails
+func (cache *cache) secondDelete(ctx context.Context, key string) error {
+	// Wait and delete before returning in synchronous mode
+	if cache.Options.SynchronousSecondDelete {
+		select {
+		case <-cache.Options.Clock.After(cache.Options.DelayDuration):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		ctx, cancel := context.WithTimeout(ctx, cache.Options.DeleteTimeout)
+		defer cancel()
+		if err := cache.Cache.Delete(ctx, key); err != nil && !errors.Is(err, gouache.ErrCacheMiss) {
+			return err
+		}
+		return nil
+	}
+
 	return cache.Options.Gopher(func() {
 		// Wait for the specified delay duration
-		time.Sleep(cache.Options.DelayDuration)
+		cache.Options.Clock.Sleep(cache.Options.DelayDuration)
 
-		// Create a new context without the original cancellation
-		ctx := context.WithoutCancel(ctx)
+		// Derive the background context from the request context
+		ctx := cache.Options.ContextFunc(ctx)
 
 		// Add timeout to the context
 		ctx, cancel := context.WithTimeout(ctx, cache.Options.DeleteTimeout)
 		defer cancel()
 
-		// Perform the second cache deletion
-		if err := cache.Cache.Delete(ctx, key); err != nil {
+		// Perform the second cache deletion, ignoring keys that are already gone
+		if err := cache.Cache.Delete(ctx, key); err != nil && !errors.Is(err, gouache.ErrCacheMiss) {
 			cache.Options.ErrorHandler(err)
 		}
 	})
@@ -0,0 +1,344 @@
+package ddd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/clocktest"
+	"github.com/soyacen/gouache/sample"
+)
+
+// failingDeleteCache is a cache whose Delete fails after the first call.
+type failingDeleteCache struct {
+	sample.Cache
+	deletes int32
+}
+
+func (c *failingDeleteCache) Delete(ctx context.Context, key string) error {
+	if atomic.AddInt32(&c.deletes, 1) > 1 {
+		return errors.New("delete failed")
+	}
+	return c.Cache.Delete(ctx, key)
+}
+
+// testDatabase is a gouache.Database backed by a sample.Cache.
+type testDatabase struct {
+	sample.Cache
+}
+
+func (d *testDatabase) Select(ctx context.Context, key string) (any, error) {
+	return d.Cache.Get(ctx, key)
+}
+
+func (d *testDatabase) Upsert(ctx context.Context, key string, val any) error {
+	return d.Cache.Set(ctx, key, val)
+}
+
+// TestCache_PanickingErrorHandler tests that a panic in the background path doesn't crash the process.
+func TestCache_PanickingErrorHandler(t *testing.T) {
+	errs := make(chan error, 2)
+	cache := New(&failingDeleteCache{}, &testDatabase{},
+		WithDelayDuration(time.Millisecond),
+		WithErrorHandler(func(err error) {
+			errs <- err
+			panic("error handler panicked")
+		}),
+	)
+
+	// The delayed delete fails, and the handler panics
+	err := cache.Delete(context.Background(), "test-key")
+	if err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+
+	// The handler first sees the delete error, then the recovered panic
+	select {
+	case err := <-errs:
+		if err.Error() != "delete failed" {
+			t.Errorf("Expected delete failed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the ErrorHandler to be called")
+	}
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "error handler panicked") {
+			t.Errorf("Expected the recovered panic, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the panic to be reported to the ErrorHandler")
+	}
+
+	// The second panic is logged, and the process keeps running
+	time.Sleep(10 * time.Millisecond)
+}
+
+// ctxKey is the context key used by the tests.
+type ctxKey struct{}
+
+// deleteCall records the context a Delete was called with.
+type deleteCall struct {
+	value any
+	err   error
+}
+
+// ctxCache is a cache that reports the contexts its Delete is called with.
+type ctxCache struct {
+	sample.Cache
+	calls chan deleteCall
+}
+
+func (c *ctxCache) Delete(ctx context.Context, key string) error {
+	c.calls <- deleteCall{value: ctx.Value(ctxKey{}), err: ctx.Err()}
+	return c.Cache.Delete(ctx, key)
+}
+
+// TestCache_ContextFunc tests that the delayed delete context is derived with the ContextFunc.
+func TestCache_ContextFunc(t *testing.T) {
+	backend := &ctxCache{calls: make(chan deleteCall, 2)}
+	cache := New(backend, &testDatabase{},
+		WithDelayDuration(time.Millisecond),
+		WithContextFunc(func(ctx context.Context) context.Context {
+			return context.WithValue(context.WithoutCancel(ctx), ctxKey{}, "background")
+		}),
+	)
+
+	// Delete with a request context that is canceled right away
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "request"))
+	err := cache.Delete(ctx, "test-key")
+	cancel()
+	if err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+
+	// The first delete uses the request context
+	if call := <-backend.calls; call.value != "request" {
+		t.Errorf("Expected the request context, got %v", call.value)
+	}
+
+	// The delayed delete uses the derived context, which outlives the request
+	select {
+	case call := <-backend.calls:
+		if call.value != "background" {
+			t.Errorf("Expected the derived context, got %v", call.value)
+		}
+		if call.err != nil {
+			t.Errorf("Expected the derived context to survive cancellation, got %v", call.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the delayed delete to run")
+	}
+}
+
+// TestCache_ContextValues tests that the selected request values survive into the delayed delete.
+func TestCache_ContextValues(t *testing.T) {
+	backend := &ctxCache{calls: make(chan deleteCall, 2)}
+	cache := New(backend, &testDatabase{},
+		WithDelayDuration(time.Millisecond),
+		WithContextValues(ctxKey{}),
+	)
+
+	// Delete with a request context that is canceled right away
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "request-id"))
+	err := cache.Delete(ctx, "test-key")
+	cancel()
+	if err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	<-backend.calls
+
+	// The delayed delete still carries the request ID
+	select {
+	case call := <-backend.calls:
+		if call.value != "request-id" {
+			t.Errorf("Expected the request ID, got %v", call.value)
+		}
+		if call.err != nil {
+			t.Errorf("Expected the background context to survive cancellation, got %v", call.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the delayed delete to run")
+	}
+}
+
+// failingDatabase is a gouache.Database whose writes always fail.
+type failingDatabase struct {
+	testDatabase
+}
+
+func (d *failingDatabase) Upsert(ctx context.Context, key string, val any) error {
+	return errors.New("upsert failed")
+}
+
+func (d *failingDatabase) Delete(ctx context.Context, key string) error {
+	return errors.New("delete failed")
+}
+
+// TestCache_FailedDatabaseWrite tests that no delayed delete is scheduled after a failed database write.
+func TestCache_FailedDatabaseWrite(t *testing.T) {
+	scheduled := 0
+	cache := New(&sample.Cache{}, &failingDatabase{},
+		WithGopher(func(f func()) error {
+			scheduled++
+			return nil
+		}),
+	)
+
+	// A failed Upsert is returned and schedules nothing
+	err := cache.Set(context.Background(), "test-key", "test-value")
+	if err == nil || err.Error() != "upsert failed" {
+		t.Errorf("Expected upsert failed, got %v", err)
+	}
+
+	// A failed database Delete is returned and schedules nothing
+	err = cache.Delete(context.Background(), "test-key")
+	if err == nil || err.Error() != "delete failed" {
+		t.Errorf("Expected delete failed, got %v", err)
+	}
+
+	if scheduled != 0 {
+		t.Errorf("Expected no delayed delete to be scheduled, got %d", scheduled)
+	}
+}
+
+// TestCache_Clock tests that the delayed delete waits for the configured clock.
+func TestCache_Clock(t *testing.T) {
+	clock := clocktest.NewClock(time.Now())
+	backend := &ctxCache{calls: make(chan deleteCall, 2)}
+	cache := New(backend, &testDatabase{}, WithDelayDuration(time.Minute), WithClock(clock))
+
+	if err := cache.Delete(context.Background(), "test-key"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	<-backend.calls
+
+	// The delayed delete doesn't run before the delay has elapsed
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute - time.Second)
+	select {
+	case <-backend.calls:
+		t.Fatal("Expected the delayed delete to wait for the delay")
+	default:
+	}
+
+	// Advancing past the delay runs it without waiting in real time
+	clock.Advance(time.Second)
+	select {
+	case <-backend.calls:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the delayed delete to run")
+	}
+}
+
+// refillingCache is a cache whose first Delete is followed by a stale refill,
+// as done by a concurrent reader that loaded the old value from the database.
+type refillingCache struct {
+	sample.Cache
+	deletes int32
+}
+
+func (c *refillingCache) Delete(ctx context.Context, key string) error {
+	if err := c.Cache.Delete(ctx, key); err != nil {
+		return err
+	}
+	if atomic.AddInt32(&c.deletes, 1) == 1 {
+		return c.Cache.Set(ctx, key, "stale")
+	}
+	return nil
+}
+
+// TestCache_SynchronousSecondDelete tests that the cache is empty once Set returns in synchronous mode.
+func TestCache_SynchronousSecondDelete(t *testing.T) {
+	backend := &refillingCache{}
+	cache := New(backend, &testDatabase{},
+		WithDelayDuration(time.Millisecond),
+		WithSynchronousSecondDelete(true),
+		WithGopher(func(f func()) error {
+			t.Error("Expected no background deletion in synchronous mode")
+			return nil
+		}),
+	)
+
+	if err := cache.Set(context.Background(), "test-key", "test-value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if _, err := backend.Get(context.Background(), "test-key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the cache to be empty after Set, got %v", err)
+	}
+	if n := atomic.LoadInt32(&backend.deletes); n != 2 {
+		t.Errorf("Expected 2 deletions, got %d", n)
+	}
+
+	// A failed second deletion is returned to the caller
+	failing := New(&failingDeleteCache{}, &testDatabase{},
+		WithDelayDuration(time.Millisecond),
+		WithSynchronousSecondDelete(true),
+	)
+	if err := failing.Delete(context.Background(), "test-key"); err == nil || err.Error() != "delete failed" {
+		t.Errorf("Expected delete failed, got %v", err)
+	}
+}
+
+// probingDatabase is a gouache.Database that reads the cache while each
+// Upsert is in progress, as a concurrent reader would.
+type probingDatabase struct {
+	testDatabase
+	cache gouache.Cache
+	seen  []any
+}
+
+func (d *probingDatabase) Upsert(ctx context.Context, key string, val any) error {
+	seen, err := d.cache.Get(ctx, key)
+	if errors.Is(err, gouache.ErrCacheMiss) {
+		seen = err
+	}
+	d.seen = append(d.seen, seen)
+	return d.testDatabase.Upsert(ctx, key, val)
+}
+
+// TestCache_FirstStepReplace tests that replacing the entry first leaves no miss window during the database write
+func TestCache_FirstStepReplace(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		replace bool
+		want    any
+	}{
+		{name: "Delete", replace: false, want: gouache.ErrCacheMiss},
+		{name: "Replace", replace: true, want: "new"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &sample.Cache{}
+			database := &probingDatabase{cache: backend}
+			cache := New(backend, database,
+				WithFirstStepReplace(tt.replace),
+				WithGopher(func(f func()) error { return nil }),
+			)
+			ctx := context.Background()
+			_ = backend.Set(ctx, "key", "old")
+
+			if err := cache.Set(ctx, "key", "new"); err != nil {
+				t.Fatalf("Failed to set value: %v", err)
+			}
+
+			// Readers during the write miss by default, but hit the new value when replacing
+			if len(database.seen) != 1 || database.seen[0] != tt.want {
+				t.Errorf("Expected readers during the write to see %v, got %v", tt.want, database.seen)
+			}
+		})
+	}
+
+	// A failed write withdraws the replaced value
+	backend := &sample.Cache{}
+	cache := New(backend, &failingDatabase{}, WithFirstStepReplace(true))
+	if err := cache.Set(context.Background(), "key", "new"); err == nil {
+		t.Fatal("Expected the upsert error")
+	}
+	if _, err := backend.Get(context.Background(), "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the replaced value to be withdrawn, got %v", err)
+	}
+}
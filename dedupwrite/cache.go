@@ -0,0 +1,361 @@
+// Package dedupwrite provides a cache implementation that skips writes of
+// values that haven't changed.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// remembering, in process memory, a hash of the value last written for each
+// key. A Set whose value hashes the same as the last write is not sent to the
+// underlying cache, saving bandwidth on write-heavy paths that keep storing
+// the same values.
+//
+// This assumes that writing a value equal to the stored one is a no-op. It
+// isn't when the write would refresh a TTL, or when the entry may have been
+// evicted, expired or changed by another process since the last write; such
+// differences are bounded by WithAlwaysWriteInterval, after which a Set
+// always reaches the underlying cache again.
+//
+// Writes of a key that overlap other Sets or Deletes of the same key are
+// never remembered, since the order in which they reached the underlying
+// cache is unknown; the next Set of the key is written through.
+package dedupwrite
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// Hasher is a function type that hashes a value. Equal values must have equal
+// hashes; an error makes the Set write through.
+type Hasher func(val any) (uint64, error)
+
+// options holds configuration options for the deduplicating cache.
+type options struct {
+	// AlwaysWriteInterval is how long a remembered hash suppresses writes.
+	AlwaysWriteInterval time.Duration
+
+	// Hasher hashes the values being written.
+	Hasher Hasher
+
+	// Clock is used to expire remembered hashes.
+	Clock gouache.Clock
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithAlwaysWriteInterval returns an Option that sets how long after a write
+// identical values are skipped. Once the interval has passed, the next Set
+// reaches the underlying cache even if the value is unchanged, refreshing its
+// TTL, and remembered hashes are dropped. The interval should be shorter than
+// the TTL of the underlying entries.
+//
+// Parameters:
+//   - d: The interval after which a Set is always written
+//
+// Returns:
+//   - An Option function that sets the AlwaysWriteInterval
+func WithAlwaysWriteInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.AlwaysWriteInterval = d
+	}
+}
+
+// WithHasher returns an Option that sets the function hashing the values
+// being written.
+//
+// The default hashes strings and byte slices directly and other values by
+// their type and %#v representation. That representation shows the contents
+// of a top-level pointer to a struct but only the address of nested pointers,
+// so values whose nested pointees change in place need a custom Hasher.
+//
+// Parameters:
+//   - h: The function hashing values
+//
+// Returns:
+//   - An Option function that sets the Hasher
+func WithHasher(h Hasher) Option {
+	return func(o *options) {
+		o.Hasher = h
+	}
+}
+
+// WithClock returns an Option that sets the clock used to expire remembered hashes.
+// It defaults to gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default interval to 1 minute if not specified or invalid
+	if o.AlwaysWriteInterval <= 0 {
+		o.AlwaysWriteInterval = time.Minute
+	}
+
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+
+	// Set default hasher if not specified
+	if o.Hasher == nil {
+		o.Hasher = defaultHasher
+	}
+	return o
+}
+
+// defaultHasher hashes strings and byte slices directly and other values by
+// their type and %#v representation, using FNV-1a.
+//
+// Parameters:
+//   - val: The value to hash
+//
+// Returns:
+//   - The hash of the value
+//   - Always returns nil error
+func defaultHasher(val any) (uint64, error) {
+	h := fnv.New64a()
+	switch v := val.(type) {
+	case string:
+		_, _ = h.Write([]byte("s"))
+		_, _ = h.Write([]byte(v))
+	case []byte:
+		_, _ = h.Write([]byte("b"))
+		_, _ = h.Write(v)
+	default:
+		_, _ = fmt.Fprintf(h, "%T:%#v", val, val)
+	}
+	return h.Sum64(), nil
+}
+
+// written records the last value written for a key.
+type written struct {
+	// hash is the hash of the value.
+	hash uint64
+
+	// expiresAt is when the record stops suppressing writes.
+	expiresAt time.Time
+}
+
+// flight tracks the Sets and Deletes of a key in progress.
+type flight struct {
+	// n is the number of writes in progress.
+	n int
+
+	// overlapped reports whether writes of the key overlapped since the
+	// first of them started.
+	overlapped bool
+}
+
+// cache is a cache implementation that skips writing values equal to the last
+// value written for their key.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+
+	// mu guards hashes, flights and lastSweep.
+	mu sync.Mutex
+
+	// hashes maps keys to the last value written for them.
+	hashes map[string]written
+
+	// flights maps keys to the writes of them in progress.
+	flights map[string]*flight
+
+	// lastSweep is the last time expired records were removed.
+	lastSweep time.Time
+}
+
+// New creates a new deduplicating cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation that skips redundant writes
+func New(c gouache.Cache, opts ...Option) gouache.Cache {
+	return &cache{
+		Options: newOptions(opts...),
+		Cache:   c,
+		hashes:  make(map[string]written),
+		flights: make(map[string]*flight),
+	}
+}
+
+// Get retrieves a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set stores a value in the underlying cache unless the same value was
+// written for the key within the always-write interval.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	// Hash the value, writing through without remembering it if it can't be hashed
+	hash, hashErr := cache.Options.Hasher(val)
+
+	// Skip the write if the value is unchanged, or else start it
+	now := cache.Options.Clock.Now()
+	cache.mu.Lock()
+	if last, ok := cache.hashes[key]; ok && hashErr == nil && last.hash == hash && now.Before(last.expiresAt) {
+		cache.mu.Unlock()
+		return nil
+	}
+	f := cache.begin(key)
+	cache.mu.Unlock()
+
+	// Write the value, remembering it only if the write succeeds
+	err := cache.Cache.Set(ctx, key, val)
+	cache.end(key, f, err == nil && hashErr == nil, hash, now)
+	return err
+}
+
+// Delete removes a value from the underlying cache and forgets the last value
+// written for the key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	cache.mu.Lock()
+	f := cache.begin(key)
+	cache.mu.Unlock()
+
+	err := cache.Cache.Delete(ctx, key)
+	cache.end(key, f, false, 0, time.Time{})
+	return err
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// begin forgets the last value written for a key and registers a write of
+// it in progress. The lock must be held.
+//
+// Parameters:
+//   - key: The key being written
+//
+// Returns:
+//   - The flight of the key, to pass to end
+func (cache *cache) begin(key string) *flight {
+	delete(cache.hashes, key)
+	if f, ok := cache.flights[key]; ok {
+		f.n++
+		f.overlapped = true
+		return f
+	}
+	f := &flight{n: 1}
+	cache.flights[key] = f
+	return f
+}
+
+// end finishes a write of a key and, if remember is set and no other write of
+// the key overlapped it, records the hash of the value written. Expired
+// records are swept at most once per interval, bounding the number of records
+// held to the keys written within roughly the last two intervals.
+//
+// Parameters:
+//   - key: The key written
+//   - f: The flight returned by begin
+//   - remember: Whether to record the hash
+//   - hash: The hash of the value written
+//   - now: The time of the write
+func (cache *cache) end(key string, f *flight, remember bool, hash uint64, now time.Time) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	f.n--
+	if f.n == 0 {
+		delete(cache.flights, key)
+	}
+	if !remember || f.overlapped {
+		return
+	}
+	cache.hashes[key] = written{hash: hash, expiresAt: now.Add(cache.Options.AlwaysWriteInterval)}
+
+	// Sweep expired records periodically
+	if now.Sub(cache.lastSweep) < cache.Options.AlwaysWriteInterval {
+		return
+	}
+	for k, w := range cache.hashes {
+		if now.After(w.expiresAt) {
+			delete(cache.hashes, k)
+		}
+	}
+	cache.lastSweep = now
+}
@@ -0,0 +1,216 @@
+package dedupwrite
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache/clocktest"
+	"github.com/soyacen/gouache/sample"
+)
+
+// countingCache is a cache that counts the Set calls reaching it.
+type countingCache struct {
+	sample.Cache
+	sets int32
+	err  error
+}
+
+func (c *countingCache) Set(ctx context.Context, key string, val any) error {
+	atomic.AddInt32(&c.sets, 1)
+	if c.err != nil {
+		return c.err
+	}
+	return c.Cache.Set(ctx, key, val)
+}
+
+type testStruct struct {
+	ID   int
+	Name string
+}
+
+// TestCache_SkipsUnchanged tests that identical values are written once
+func TestCache_SkipsUnchanged(t *testing.T) {
+	backend := &countingCache{}
+	cache := New(backend)
+	ctx := context.Background()
+
+	// Writing the same value repeatedly reaches the backend once
+	for i := 0; i < 3; i++ {
+		if err := cache.Set(ctx, "key", &testStruct{ID: 1, Name: "a"}); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+	if backend.sets != 1 {
+		t.Errorf("Expected 1 write, got %d", backend.sets)
+	}
+
+	// A changed value is written
+	if err := cache.Set(ctx, "key", &testStruct{ID: 1, Name: "b"}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if backend.sets != 2 {
+		t.Errorf("Expected 2 writes, got %d", backend.sets)
+	}
+
+	// Same value under another key is written
+	if err := cache.Set(ctx, "other", &testStruct{ID: 1, Name: "b"}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if backend.sets != 3 {
+		t.Errorf("Expected 3 writes, got %d", backend.sets)
+	}
+
+	// Delete forgets the key, so the next Set is written
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if err := cache.Set(ctx, "key", &testStruct{ID: 1, Name: "b"}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if backend.sets != 4 {
+		t.Errorf("Expected 4 writes, got %d", backend.sets)
+	}
+	val, err := cache.Get(ctx, "key")
+	if err != nil || val.(*testStruct).Name != "b" {
+		t.Errorf("Expected the value to be stored, got %v, %v", val, err)
+	}
+}
+
+// TestCache_AlwaysWriteInterval tests that unchanged values are written again after the interval
+func TestCache_AlwaysWriteInterval(t *testing.T) {
+	backend := &countingCache{}
+	clock := clocktest.NewClock(time.Now())
+	cache := New(backend, WithAlwaysWriteInterval(20*time.Millisecond), WithClock(clock))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "value")
+	_ = cache.Set(ctx, "key", "value")
+	if backend.sets != 1 {
+		t.Errorf("Expected 1 write, got %d", backend.sets)
+	}
+
+	// After the interval the value is written again
+	clock.Advance(30 * time.Millisecond)
+	_ = cache.Set(ctx, "key", "value")
+	if backend.sets != 2 {
+		t.Errorf("Expected 2 writes, got %d", backend.sets)
+	}
+}
+
+// TestCache_FailedWrite tests that a failed write isn't remembered
+func TestCache_FailedWrite(t *testing.T) {
+	backend := &countingCache{err: errors.New("boom")}
+	cache := New(backend)
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err == nil {
+		t.Error("Expected an error")
+	}
+
+	// The retry reaches the backend
+	backend.err = nil
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if backend.sets != 2 {
+		t.Errorf("Expected 2 writes, got %d", backend.sets)
+	}
+}
+
+// TestCache_Hasher tests that a failing hasher writes through
+func TestCache_Hasher(t *testing.T) {
+	backend := &countingCache{}
+	cache := New(backend, WithHasher(func(val any) (uint64, error) {
+		return 0, errors.New("unhashable")
+	}))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "value")
+	_ = cache.Set(ctx, "key", "value")
+	if backend.sets != 2 {
+		t.Errorf("Expected 2 writes, got %d", backend.sets)
+	}
+}
+
+// holdingCache is a cache whose first Set stores the value and then waits
+// until release is closed, reporting on stored once the value is stored.
+type holdingCache struct {
+	sample.Cache
+	held    atomic.Bool
+	stored  chan struct{}
+	release chan struct{}
+}
+
+func newHoldingCache() *holdingCache {
+	return &holdingCache{stored: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (c *holdingCache) Set(ctx context.Context, key string, val any) error {
+	if err := c.Cache.Set(ctx, key, val); err != nil {
+		return err
+	}
+	if c.held.CompareAndSwap(false, true) {
+		close(c.stored)
+		<-c.release
+	}
+	return nil
+}
+
+// TestCache_ConcurrentSets tests that a Set finishing after a later Set of the
+// same key doesn't leave its value remembered while the backend holds the
+// other one
+func TestCache_ConcurrentSets(t *testing.T) {
+	backend := newHoldingCache()
+	cache := New(backend)
+	ctx := context.Background()
+
+	// A is stored first but finishes last
+	done := make(chan error)
+	go func() { done <- cache.Set(ctx, "key", "A") }()
+	<-backend.stored
+	if err := cache.Set(ctx, "key", "B"); err != nil {
+		t.Fatalf("Failed to set B: %v", err)
+	}
+	close(backend.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Failed to set A: %v", err)
+	}
+
+	// Setting A again reaches the backend, which holds B
+	if err := cache.Set(ctx, "key", "A"); err != nil {
+		t.Fatalf("Failed to set A: %v", err)
+	}
+	if val, err := backend.Get(ctx, "key"); err != nil || val != "A" {
+		t.Errorf("Expected A in the backend, got %v, %v", val, err)
+	}
+}
+
+// TestCache_SetRacingDelete tests that a Set finishing after a Delete of the
+// same key doesn't leave a record of the deleted value
+func TestCache_SetRacingDelete(t *testing.T) {
+	backend := newHoldingCache()
+	cache := New(backend)
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() { done <- cache.Set(ctx, "key", "value") }()
+	<-backend.stored
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	close(backend.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// Setting the value again reaches the backend, which no longer holds it
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if val, err := backend.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected the value in the backend, got %v, %v", val, err)
+	}
+}
@@ -0,0 +1,220 @@
+package gouache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DumpEntry is one cache entry in a snapshot written by Dump, encoded as a
+// line of JSON.
+type DumpEntry struct {
+	// Key is the key of the entry.
+	Key string `json:"key"`
+
+	// Value is the encoding of the value, as produced by the value codec.
+	Value json.RawMessage `json:"value"`
+}
+
+// dumpOptions holds configuration options for Dump and Restore.
+type dumpOptions struct {
+	// Match is the pattern keys must match to be dumped.
+	Match string
+
+	// Marshal encodes a value for the snapshot.
+	Marshal func(key string, val any) (json.RawMessage, error)
+
+	// Unmarshal decodes a value read from the snapshot.
+	Unmarshal func(key string, data json.RawMessage) (any, error)
+
+	// BatchSize is the number of entries restored at a time.
+	BatchSize int
+}
+
+// DumpOption is a function that modifies the Dump and Restore options.
+type DumpOption func(*dumpOptions)
+
+// WithDumpMatch returns a DumpOption that restricts Dump to keys matching the
+// glob-style pattern match, which is passed to the cache's Iterate.
+//
+// Parameters:
+//   - match: The pattern keys must match
+//
+// Returns:
+//   - A DumpOption function that sets the Match
+func WithDumpMatch(match string) DumpOption {
+	return func(o *dumpOptions) {
+		o.Match = match
+	}
+}
+
+// WithDumpCodec returns a DumpOption that sets how values are encoded in the
+// snapshot. Both functions receive the key of the entry. By default values are
+// encoded with encoding/json and decoded into their generic JSON form, so a
+// struct is restored as a map[string]any; supply a codec that knows the
+// concrete types to round-trip them exactly.
+//
+// Parameters:
+//   - marshal: The function encoding a value, or nil to keep the default
+//   - unmarshal: The function decoding a value, or nil to keep the default
+//
+// Returns:
+//   - A DumpOption function that sets the Marshal and Unmarshal functions
+func WithDumpCodec(marshal func(key string, val any) (json.RawMessage, error), unmarshal func(key string, data json.RawMessage) (any, error)) DumpOption {
+	return func(o *dumpOptions) {
+		o.Marshal = marshal
+		o.Unmarshal = unmarshal
+	}
+}
+
+// WithDumpBatchSize returns a DumpOption that sets the number of entries
+// Restore writes at a time.
+//
+// Parameters:
+//   - n: The batch size; non-positive values use the default of 100
+//
+// Returns:
+//   - A DumpOption function that sets the BatchSize
+func WithDumpBatchSize(n int) DumpOption {
+	return func(o *dumpOptions) {
+		o.BatchSize = n
+	}
+}
+
+// newDumpOptions creates the Dump and Restore options with default values and
+// applies the provided options.
+//
+// Parameters:
+//   - opts: Variable number of DumpOption functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newDumpOptions(opts ...DumpOption) *dumpOptions {
+	o := &dumpOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Marshal == nil {
+		o.Marshal = func(key string, val any) (json.RawMessage, error) {
+			return json.Marshal(val)
+		}
+	}
+	if o.Unmarshal == nil {
+		o.Unmarshal = func(key string, data json.RawMessage) (any, error) {
+			var val any
+			err := json.Unmarshal(data, &val)
+			return val, err
+		}
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	return o
+}
+
+// Dump writes a snapshot of every entry of c to w, one DumpEntry per line of
+// JSON. Keys are enumerated with c's Iterate and each value is read with Get;
+// keys that disappear during the dump are skipped.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - c: The cache to dump, which must implement Iterable
+//   - w: The writer the snapshot is written to
+//   - opts: Variable number of DumpOption functions to configure the dump
+//
+// Returns:
+//   - ErrNotIterable if c doesn't support Iterable, an error wrapping
+//     ErrMarshal and naming the key if a value can't be encoded, or an error
+//     if reading or writing fails
+func Dump(ctx context.Context, c Cache, w io.Writer, opts ...DumpOption) error {
+	// Ensure the cache can enumerate its keys
+	iterable, ok := c.(Iterable)
+	if !ok || !Capabilities(c).Iterate {
+		return ErrNotIterable
+	}
+	o := newDumpOptions(opts...)
+
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	err := iterable.Iterate(ctx, o.Match, func(key string) error {
+		// Read the value, skipping keys that are gone
+		val, err := c.Get(ctx, key)
+		if errors.Is(err, ErrCacheMiss) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Encode the value and write the entry
+		data, err := o.Marshal(key, val)
+		if err != nil {
+			return fmt.Errorf("%w: key %q: %w", ErrMarshal, key, err)
+		}
+		return encoder.Encode(DumpEntry{Key: key, Value: data})
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Restore reads a snapshot written by Dump from r and stores every entry in
+// c, in batches written with SetMulti, so c's BatchSetter is used when
+// available. Blank lines are skipped. Entries restored before a failure stay
+// in c.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - c: The cache to restore into
+//   - r: The reader the snapshot is read from
+//   - opts: Variable number of DumpOption functions to configure the restore
+//
+// Returns:
+//   - An error naming the line if an entry can't be decoded, or an error if
+//     reading or writing fails
+func Restore(ctx context.Context, c Cache, r io.Reader, opts ...DumpOption) error {
+	o := newDumpOptions(opts...)
+
+	vals := make(map[string]any, o.BatchSize)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		// Skip blank lines
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		// Decode the entry and its value
+		var entry DumpEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("gouache: restore line %d: %w", line, err)
+		}
+		val, err := o.Unmarshal(entry.Key, entry.Value)
+		if err != nil {
+			return fmt.Errorf("gouache: restore line %d: key %q: %w", line, entry.Key, err)
+		}
+
+		// Write the batch when it is full
+		vals[entry.Key] = val
+		if len(vals) < o.BatchSize {
+			continue
+		}
+		if err := SetMulti(ctx, c, vals); err != nil {
+			return err
+		}
+		vals = make(map[string]any, o.BatchSize)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Write the last partial batch
+	if len(vals) > 0 {
+		return SetMulti(ctx, c, vals)
+	}
+	return nil
+}
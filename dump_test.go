@@ -0,0 +1,87 @@
+package gouache_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestDumpRestore tests that a snapshot written by Dump restores the same entries.
+func TestDumpRestore(t *testing.T) {
+	// Setup: store some values in the source
+	src := &sample.Cache{}
+	vals := map[string]any{"a": "x", "b": 2.5, "c": map[string]any{"n": true}}
+	if err := src.SetMulti(context.Background(), vals); err != nil {
+		t.Fatalf("Failed to set up test values: %v", err)
+	}
+
+	// Test dumping the source
+	var buf bytes.Buffer
+	if err := gouache.Dump(context.Background(), src, &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(vals) {
+		t.Errorf("Expected %d lines, but got %d: %s", len(vals), lines, buf.String())
+	}
+
+	// Test restoring into an empty cache in batches of one
+	dst := &sample.Cache{}
+	if err := gouache.Restore(context.Background(), dst, &buf, gouache.WithDumpBatchSize(1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for key, want := range vals {
+		got, err := dst.Get(context.Background(), key)
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Expected %s to be %v, but got %v, %v", key, want, got, err)
+		}
+	}
+}
+
+// TestDump_Unserializable tests that Dump names the key of a value it can't encode.
+func TestDump_Unserializable(t *testing.T) {
+	src := &sample.Cache{}
+	if err := src.Set(context.Background(), "ch", make(chan int)); err != nil {
+		t.Fatalf("Failed to set up test value: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err := gouache.Dump(context.Background(), src, &buf)
+	if !errors.Is(err, gouache.ErrMarshal) || !strings.Contains(err.Error(), `"ch"`) {
+		t.Errorf("Expected an ErrMarshal naming the key, but got: %v", err)
+	}
+}
+
+// TestRestore_Codec tests that Restore decodes values with a custom codec.
+func TestRestore_Codec(t *testing.T) {
+	snapshot := `{"key":"a","value":"1"}` + "\n\n" + `{"key":"b","value":"2"}` + "\n"
+	unmarshal := func(key string, data json.RawMessage) (any, error) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return strconv.Atoi(s)
+	}
+
+	dst := &sample.Cache{}
+	err := gouache.Restore(context.Background(), dst, strings.NewReader(snapshot), gouache.WithDumpCodec(nil, unmarshal))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, err := dst.Get(context.Background(), "b"); err != nil || got != 2 {
+		t.Errorf("Expected 2, but got %v, %v", got, err)
+	}
+
+	// Test that a malformed line is reported
+	err = gouache.Restore(context.Background(), dst, strings.NewReader("{"))
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected an error naming line 1, but got: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package gouache
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// backgroundErrors counts the errors passed to the handlers returned by
+// DefaultErrorHandler.
+var backgroundErrors atomic.Int64
+
+// BackgroundErrors returns the number of errors passed to the handlers
+// returned by DefaultErrorHandler, which every decorator uses unless
+// WithErrorHandler is given, so operators can alert on failed background
+// operations, such as delayed deletions, without wiring up a handler of their
+// own. metrics.PublishBackgroundErrors publishes it as an expvar variable.
+//
+// Returns:
+//   - The number of background errors counted since the program started
+func BackgroundErrors() int64 {
+	return backgroundErrors.Load()
+}
+
+// DefaultErrorHandler returns the ErrorHandler decorators use when none is
+// configured. It increments the BackgroundErrors count and logs the error
+// with slog.Error under op. It never panics: nil errors are ignored, and a
+// panic in the slog handler is recovered, so it is safe to call from
+// background goroutines.
+//
+// Parameters:
+//   - op: The operation the errors come from, used as the log message, such as "ddd.Cache.secondDelete"
+//
+// Returns:
+//   - An ErrorHandler that counts and logs errors
+func DefaultErrorHandler(op string) ErrorHandler {
+	return func(err error) {
+		if err == nil {
+			return
+		}
+		backgroundErrors.Add(1)
+
+		// A failing log handler must not take down the background operation
+		defer func() { _ = recover() }()
+		slog.Error(op, slog.String("err", err.Error()))
+	}
+}
@@ -0,0 +1,40 @@
+package gouache_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/soyacen/gouache"
+)
+
+// TestDefaultErrorHandler tests that the default error handler counts errors
+// in gouache.BackgroundErrors, ignores nil errors, and survives a panicking
+// log handler
+func TestDefaultErrorHandler(t *testing.T) {
+	handler := gouache.DefaultErrorHandler("gouache.TestDefaultErrorHandler")
+	before := gouache.BackgroundErrors()
+
+	handler(errors.New("background failure"))
+	handler(nil)
+	if got := gouache.BackgroundErrors() - before; got != 1 {
+		t.Errorf("Expected the counter to increment by 1, got %d", got)
+	}
+
+	// A panicking log handler is recovered
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(panicHandler{}))
+	handler(errors.New("another failure"))
+	if got := gouache.BackgroundErrors() - before; got != 2 {
+		t.Errorf("Expected the counter to increment by 2, got %d", got)
+	}
+}
+
+// panicHandler is a slog.Handler that panics on every record.
+type panicHandler struct{}
+
+func (panicHandler) Enabled(context.Context, slog.Level) bool  { return true }
+func (panicHandler) Handle(context.Context, slog.Record) error { panic("log handler failed") }
+func (h panicHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h panicHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,212 @@
+// Package fallback provides a read-through cache implementation that keeps
+// reads working when the cache fails, by reading from a database instead.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// a database. Get reads the cache first; on a miss it selects the value from
+// the database and back-fills the cache, as ddd does, and on any other cache
+// error, such as a lost connection, it selects the value from the database as
+// well, so a cache outage degrades latency rather than availability. Writes
+// go to the cache only.
+//
+// While the cache is failing, back-filling it would only add load and
+// latency to every read, so values read after a cache error are not written
+// back unless WithBackfillOnError is enabled.
+package fallback
+
+import (
+	"context"
+	"errors"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// options holds configuration options for the fallback cache.
+type options struct {
+	// BackfillOnError makes Get back-fill the cache after a cache error too.
+	BackfillOnError bool
+
+	// ErrorHandler is called with the cache errors Get recovers from by
+	// falling back, and with failed back-fills.
+	ErrorHandler gouache.ErrorHandler
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithBackfillOnError returns an Option that makes Get store values read from
+// the database after a cache error, not only after a miss. Leave it disabled
+// when cache errors usually mean an outage, since every back-fill would fail
+// too.
+//
+// Parameters:
+//   - enabled: Whether to back-fill after cache errors
+//
+// Returns:
+//   - An Option function that sets BackfillOnError
+func WithBackfillOnError(enabled bool) Option {
+	return func(o *options) {
+		o.BackfillOnError = enabled
+	}
+}
+
+// WithErrorHandler returns an Option that sets the handler called with the
+// cache errors Get recovers from and with failed back-fills, which callers
+// never see. By default they are logged and counted by
+// gouache.DefaultErrorHandler.
+//
+// Parameters:
+//   - f: The error handler function
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f gouache.ErrorHandler) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Log and count recovered errors by default
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = gouache.DefaultErrorHandler("fallback.Cache.Get")
+	}
+	return o
+}
+
+// cache is a read-through cache implementation that falls back to a database.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+
+	// Database is the source of truth read on misses and cache errors
+	Database gouache.Database
+}
+
+// New creates a new fallback cache instance with the specified cache,
+// database, and options.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - d: The database read on misses and cache errors
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation that falls back to d
+func New(c gouache.Cache, d gouache.Database, opts ...Option) gouache.Cache {
+	return &cache{Options: newOptions(opts...), Cache: c, Database: d}
+}
+
+// Get retrieves a value from the cache by its key. On a miss, or on any other
+// cache error, the value is selected from the database instead. It is
+// back-filled after a miss, and after a cache error only if BackfillOnError
+// is enabled. Errors matching gouache.ErrContext are returned as is, since
+// the database call would fail as well.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached or database value or nil if not found
+//   - An error if the database fails too, or a context error
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	// Try to get the value from cache first
+	val, err := cache.Cache.Get(ctx, key)
+	if err == nil || errors.Is(err, gouache.ErrContext) {
+		return val, err
+	}
+
+	// Recover from cache failures, and decide whether to back-fill
+	missed := errors.Is(err, gouache.ErrCacheMiss)
+	if !missed {
+		cache.Options.ErrorHandler(err)
+	}
+	backfill := missed || cache.Options.BackfillOnError
+
+	// Get value from database
+	val, err = cache.Database.Select(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Populate cache with database value, which callers get even if that fails
+	if backfill {
+		if err := cache.Cache.Set(ctx, key, val); err != nil {
+			cache.Options.ErrorHandler(err)
+		}
+	}
+	return val, nil
+}
+
+// Set stores a value in the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
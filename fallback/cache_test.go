@@ -0,0 +1,148 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// errConnection simulates a cache that can't reach its server.
+var errConnection = errors.New("dial tcp 127.0.0.1:6379: connect: connection refused")
+
+// brokenCache is a cache whose operations fail while down is set.
+type brokenCache struct {
+	sample.Cache
+	down atomic.Bool
+	sets atomic.Int32
+}
+
+func (c *brokenCache) Get(ctx context.Context, key string) (any, error) {
+	if c.down.Load() {
+		return nil, errConnection
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+func (c *brokenCache) Set(ctx context.Context, key string, val any) error {
+	c.sets.Add(1)
+	if c.down.Load() {
+		return errConnection
+	}
+	return c.Cache.Set(ctx, key, val)
+}
+
+// testDatabase is a gouache.Database backed by a sample.Cache.
+type testDatabase struct {
+	sample.Cache
+	selects atomic.Int32
+}
+
+func (d *testDatabase) Select(ctx context.Context, key string) (any, error) {
+	d.selects.Add(1)
+	return d.Cache.Get(ctx, key)
+}
+
+func (d *testDatabase) Upsert(ctx context.Context, key string, val any) error {
+	return d.Cache.Set(ctx, key, val)
+}
+
+// TestCache_Miss tests that a miss reads the database and back-fills the cache
+func TestCache_Miss(t *testing.T) {
+	backend, database := &brokenCache{}, &testDatabase{}
+	_ = database.Upsert(context.Background(), "key", "value")
+	cache := New(backend, database)
+	ctx := context.Background()
+
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected value, got %v, %v", val, err)
+	}
+	if val, err := backend.Cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected the cache to be back-filled, got %v, %v", val, err)
+	}
+
+	// The next read is served by the cache
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected value, got %v, %v", val, err)
+	}
+	if n := database.selects.Load(); n != 1 {
+		t.Errorf("Expected 1 select, got %d", n)
+	}
+}
+
+// TestCache_ConnectionError tests that reads keep working while the cache is down
+func TestCache_ConnectionError(t *testing.T) {
+	backend, database := &brokenCache{}, &testDatabase{}
+	_ = database.Upsert(context.Background(), "key", "value")
+	var handled []error
+	cache := New(backend, database, WithErrorHandler(func(err error) { handled = append(handled, err) }))
+	ctx := context.Background()
+
+	backend.down.Store(true)
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected the database value, got %v, %v", val, err)
+	}
+
+	// The cache error is reported, and no back-fill is attempted
+	if len(handled) != 1 || !errors.Is(handled[0], errConnection) {
+		t.Errorf("Expected the connection error to be reported, got %v", handled)
+	}
+	if n := backend.sets.Load(); n != 0 {
+		t.Errorf("Expected no back-fill while the cache is down, got %d", n)
+	}
+
+	// A key missing from the database too returns the database error
+	if _, err := cache.Get(ctx, "missing"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the database miss, got %v", err)
+	}
+}
+
+// TestCache_BackfillOnError tests that back-filling after errors can be enabled
+func TestCache_BackfillOnError(t *testing.T) {
+	backend, database := &brokenCache{}, &testDatabase{}
+	_ = database.Upsert(context.Background(), "key", "value")
+	var handled []error
+	cache := New(backend, database,
+		WithBackfillOnError(true),
+		WithErrorHandler(func(err error) { handled = append(handled, err) }),
+	)
+	ctx := context.Background()
+
+	// The failed back-fill is reported but the read still succeeds
+	backend.down.Store(true)
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected the database value, got %v, %v", val, err)
+	}
+	if n := backend.sets.Load(); n != 1 {
+		t.Errorf("Expected a back-fill, got %d", n)
+	}
+	if len(handled) != 2 {
+		t.Errorf("Expected the read and back-fill errors to be reported, got %v", handled)
+	}
+}
+
+// TestCache_ContextError tests that context errors are returned without reading the database
+func TestCache_ContextError(t *testing.T) {
+	database := &testDatabase{}
+	cache := New(&ctxErrCache{}, database)
+
+	if _, err := cache.Get(context.Background(), "key"); !errors.Is(err, gouache.ErrContext) {
+		t.Errorf("Expected gouache.ErrContext, got %v", err)
+	}
+	if n := database.selects.Load(); n != 0 {
+		t.Errorf("Expected no select, got %d", n)
+	}
+}
+
+// ctxErrCache is a cache whose Get fails as if the context were canceled.
+type ctxErrCache struct {
+	sample.Cache
+}
+
+func (c *ctxErrCache) Get(ctx context.Context, key string) (any, error) {
+	return nil, fmt.Errorf("%w: %w", gouache.ErrContext, context.Canceled)
+}
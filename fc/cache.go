@@ -8,6 +8,8 @@ package fc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/coocood/freecache"
@@ -17,6 +19,15 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*Cache)(nil)
+
 // Cache is an implementation of gouache.Cache using freecache as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // support for custom serialization/deserialization and configurable TTL.
@@ -25,7 +36,9 @@ type Cache struct {
 	Cache *freecache.Cache
 
 	// TTL is an optional function to determine the time-to-live duration for a cache entry.
-	// If not provided, entries will not expire by default.
+	// If not provided, entries will not expire by default. A positive result is
+	// scaled by the context's gouache.TTLMultiplier, and any result is clamped to
+	// the context's gouache.TTLCeiling, before it is applied.
 	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
 
 	// Marshal is an optional function to serialize objects into byte slices.
@@ -35,6 +48,167 @@ type Cache struct {
 	// Unmarshal is an optional function to deserialize byte slices into objects.
 	// If not provided, raw byte slices are returned.
 	Unmarshal func(key string, data []byte) (any, error)
+
+	// UnmarshalFallback is an optional function called when decoding a stored
+	// value fails, for example after its format changed, with the key, the
+	// raw data and the decoding error. Its result is returned instead, so it
+	// can delete the entry and return gouache.ErrCacheMiss to have the value
+	// recomputed, or return a default. If nil, the decoding error is returned.
+	UnmarshalFallback func(key string, data []byte, err error) (any, error)
+
+	// CacheSize is an optional hint of the size, in bytes, the freecache
+	// instance was created with. It is only used to report the entry size
+	// limit in a LargeEntryError.
+	CacheSize int
+}
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithTTL returns an Option that sets the function determining the TTL of
+// each entry. See the TTL field.
+//
+// Parameters:
+//   - f: The function returning the TTL of an entry
+//
+// Returns:
+//   - An Option function that sets TTL
+func WithTTL(f func(ctx context.Context, key string, val any) (time.Duration, error)) Option {
+	return func(cache *Cache) {
+		cache.TTL = f
+	}
+}
+
+// WithMarshal returns an Option that sets the function serializing values
+// that are not byte slices.
+//
+// Parameters:
+//   - f: The function encoding a value
+//
+// Returns:
+//   - An Option function that sets Marshal
+func WithMarshal(f func(key string, obj any) ([]byte, error)) Option {
+	return func(cache *Cache) {
+		cache.Marshal = f
+	}
+}
+
+// WithUnmarshal returns an Option that sets the function deserializing stored data.
+//
+// Parameters:
+//   - f: The function decoding a value
+//
+// Returns:
+//   - An Option function that sets Unmarshal
+func WithUnmarshal(f func(key string, data []byte) (any, error)) Option {
+	return func(cache *Cache) {
+		cache.Unmarshal = f
+	}
+}
+
+// WithUnmarshalFallback returns an Option that sets the function handling
+// values that fail to decode. See the UnmarshalFallback field.
+//
+// Parameters:
+//   - f: The function called with the key, raw data and decoding error
+//
+// Returns:
+//   - An Option function that sets UnmarshalFallback
+func WithUnmarshalFallback(f func(key string, data []byte, err error) (any, error)) Option {
+	return func(cache *Cache) {
+		cache.UnmarshalFallback = f
+	}
+}
+
+// WithCacheSize returns an Option that sets the size hint used to report the
+// entry size limit. See the CacheSize field.
+//
+// Parameters:
+//   - size: The size in bytes the freecache instance was created with
+//
+// Returns:
+//   - An Option function that sets CacheSize
+func WithCacheSize(size int) Option {
+	return func(cache *Cache) {
+		cache.CacheSize = size
+	}
+}
+
+// New creates a new Cache backed by the specified freecache instance. The
+// fields set by the options can still be set directly. New logs a warning
+// for each inconsistent setting reported by validate, such as Unmarshal
+// without Marshal.
+//
+// Parameters:
+//   - c: The freecache instance used for storage operations
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache using c as its storage
+func New(c *freecache.Cache, opts ...Option) *Cache {
+	cache := &Cache{Cache: c}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	if err := cache.validate(); err != nil {
+		slog.Warn("fc.New: inconsistent configuration", slog.String("err", err.Error()))
+	}
+	return cache
+}
+
+// validate reports settings that are accepted but almost certainly a mistake:
+// a codec missing one direction, so values don't round-trip, and an
+// UnmarshalFallback that is never called because nothing is decoded.
+//
+// Returns:
+//   - An error listing the inconsistent settings, or nil
+func (cache *Cache) validate() error {
+	var errs []error
+	if cache.Unmarshal != nil && cache.Marshal == nil {
+		errs = append(errs, errors.New("Unmarshal is set without Marshal, so only raw values can be stored"))
+	}
+	if cache.Marshal != nil && cache.Unmarshal == nil {
+		errs = append(errs, errors.New("Marshal is set without Unmarshal, so Get returns serialized data"))
+	}
+	if cache.UnmarshalFallback != nil && cache.Unmarshal == nil {
+		errs = append(errs, errors.New("UnmarshalFallback is set without Unmarshal, so it is never called"))
+	}
+	return errors.Join(errs...)
+}
+
+// LargeEntryError is returned by Set and SetWithTTL when freecache refuses an
+// entry with freecache.ErrLargeEntry because the key and value together exceed
+// 1/1024 of the cache size. Both errors.Is(err, gouache.ErrStorage) and
+// errors.Is(err, freecache.ErrLargeEntry) hold for it.
+type LargeEntryError struct {
+	// Key is the key of the refused entry.
+	Key string
+
+	// Size is the size of the key and the serialized value in bytes.
+	Size int
+
+	// Limit is the largest size freecache accepts, or zero if Cache.CacheSize is not set.
+	Limit int
+}
+
+// Error returns a message describing the refused entry and the size limit.
+//
+// Returns:
+//   - The error message
+func (e *LargeEntryError) Error() string {
+	if e.Limit <= 0 {
+		return fmt.Sprintf("fc: entry for key %q is %d bytes, larger than 1/1024 of the cache size", e.Key, e.Size)
+	}
+	return fmt.Sprintf("fc: entry for key %q is %d bytes, larger than the %d byte limit (1/1024 of the cache size)", e.Key, e.Size, e.Limit)
+}
+
+// Unwrap returns gouache.ErrStorage and freecache.ErrLargeEntry so that
+// errors.Is matches both.
+//
+// Returns:
+//   - The wrapped errors
+func (e *LargeEntryError) Unwrap() []error {
+	return []error{gouache.ErrStorage, freecache.ErrLargeEntry}
 }
 
 // Get retrieves a value from the cache by its key.
@@ -66,9 +240,12 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 		return data, nil
 	}
 
-	// Use custom unmarshal function to decode the data
+	// Use custom unmarshal function to decode the data, falling back on failure
 	obj, err := cache.Unmarshal(key, data)
 	if err != nil {
+		if cache.UnmarshalFallback != nil {
+			return cache.UnmarshalFallback(key, data, err)
+		}
 		return nil, err
 	}
 
@@ -85,7 +262,8 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 //   - val: The value to store, either as byte slice or any other type requiring marshaling
 //
 // Returns:
-//   - An error if the operation fails, including when Marshal is nil for non-byte values
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-byte values
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 	// Initialize TTL to zero (no expiration)
 	ttl := time.Duration(0)
@@ -100,25 +278,119 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 		}
 	}
 
+	// Apply the per-request TTL multiplier and ceiling, if any
+	ttl = gouache.AdjustTTL(ctx, ttl)
+
+	// Store the value with the resolved TTL
+	return cache.SetWithTTL(ctx, key, val, ttl)
+}
+
+// SetWithTTL stores a value in the cache under the specified key with an
+// explicit TTL, bypassing the TTL function and the TTL multiplier.
+// freecache expires entries with one-second granularity, so positive TTLs
+// are rounded up to whole seconds rather than down to no expiration.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as byte slice or any other type requiring marshaling
+//   - ttl: The time-to-live of the entry, zero meaning no expiration
+//
+// Returns:
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     a *LargeEntryError if the entry is too large, or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-byte values
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
 	// Check if the value is already a byte slice
-	if data, ok := val.([]byte); ok {
-		// Directly store byte slices without marshaling
-		return cache.Cache.Set([]byte(key), data, int(ttl/time.Second))
+	data, ok := val.([]byte)
+	if !ok {
+		// For non-byte values, ensure a marshal function is available
+		if cache.Marshal == nil {
+			return fmt.Errorf("%w: %T requires Marshal", gouache.ErrUnsupportedValue, val)
+		}
+
+		// Marshal the value into bytes using the custom marshal function
+		var err error
+		data, err = cache.Marshal(key, val)
+		if err != nil {
+			return fmt.Errorf("%w: %w", gouache.ErrMarshal, err)
+		}
+	}
+
+	// Store the data in freecache
+	err := cache.Cache.Set([]byte(key), data, ttlSeconds(ttl))
+
+	// Explain entries refused for their size, which would otherwise just be missing
+	if errors.Is(err, freecache.ErrLargeEntry) {
+		return &LargeEntryError{Key: key, Size: len(key) + len(data), Limit: cache.maxEntrySize()}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", gouache.ErrStorage, err)
 	}
+	return nil
+}
 
-	// For non-byte values, ensure a marshal function is available
-	if cache.Marshal == nil {
-		return errors.New("gouache: Marshal is nil")
+// maxEntrySize returns the largest combined key and value size freecache
+// accepts, derived from CacheSize the same way freecache derives it from the
+// size of its segments.
+//
+// Returns:
+//   - The limit in bytes, or zero if CacheSize is not set
+func (cache *Cache) maxEntrySize() int {
+	if cache.CacheSize <= 0 {
+		return 0
 	}
+	// freecache splits its memory into 256 segments and accepts entries, with
+	// their 24 byte header, of up to a quarter of a segment
+	return cache.CacheSize/256/4 - 24
+}
 
-	// Marshal the value into bytes using the custom marshal function
-	data, err := cache.Marshal(key, val)
+// ReadTTL returns the remaining time-to-live of the entry stored under key.
+// freecache tracks expiry with one-second granularity.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//
+// Returns:
+//   - The remaining time-to-live, or zero if the entry never expires
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	// Ask freecache for the remaining seconds
+	seconds, err := cache.Cache.TTL([]byte(key))
+
+	// Handle case where entry is not found
+	if errors.Is(err, freecache.ErrNotFound) {
+		return 0, gouache.ErrCacheMiss
+	}
+
+	// Return other errors as-is
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	// Store the marshaled data in freecache
-	return cache.Cache.Set([]byte(key), data, int(ttl/time.Second))
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// Touch sets a new time-to-live for the entry stored under key using
+// freecache's Touch, without rewriting the value. Positive TTLs are rounded
+// up to whole seconds.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live of the entry, zero meaning no expiration
+//
+// Returns:
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	// Update the expiry in freecache
+	err := cache.Cache.Touch([]byte(key), ttlSeconds(ttl))
+
+	// Handle case where entry is not found
+	if errors.Is(err, freecache.ErrNotFound) {
+		return gouache.ErrCacheMiss
+	}
+	return err
 }
 
 // Delete removes a value from the cache by its key.
@@ -134,3 +406,20 @@ func (cache *Cache) Delete(ctx context.Context, key string) error {
 	cache.Cache.Del([]byte(key))
 	return nil
 }
+
+// ttlSeconds converts a TTL to the whole seconds freecache expects, rounding
+// positive TTLs up so that a sub-second TTL, such as one capped by
+// gouache.TTLCeiling, still expires instead of becoming 0, which freecache
+// treats as no expiration.
+//
+// Parameters:
+//   - ttl: The time-to-live, zero or negative meaning no expiration
+//
+// Returns:
+//   - The TTL in seconds, at least 1 for positive TTLs
+func ttlSeconds(ttl time.Duration) int {
+	if ttl <= 0 {
+		return 0
+	}
+	return int((ttl + time.Second - 1) / time.Second)
+}
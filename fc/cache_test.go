@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/coocood/freecache"
 	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
 )
 
 // TestStruct 是用于测试的自定义结构体
@@ -185,8 +188,11 @@ func TestCache_SetWithoutMarshal(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
-	if err.Error() != "gouache: Marshal is nil" {
-		t.Errorf("expected 'gouache: Marshal is nil', got %v", err)
+	if !errors.Is(err, gouache.ErrUnsupportedValue) {
+		t.Errorf("expected ErrUnsupportedValue, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "*fc.TestStruct") {
+		t.Errorf("expected the value type in the message, got %v", err)
 	}
 }
 
@@ -247,7 +253,331 @@ func TestCache_GetWithUnmarshalError(t *testing.T) {
 	}
 }
 
+// 测试解码失败时UnmarshalFallback删除坏条目并返回未命中
+func TestCache_UnmarshalFallback(t *testing.T) {
+	cache := &Cache{
+		Cache: freecache.NewCache(1024 * 1024),
+		Unmarshal: func(key string, data []byte) (any, error) {
+			var obj map[string]string
+			err := json.Unmarshal(data, &obj)
+			return obj, err
+		},
+	}
+	var fallbackData []byte
+	cache.UnmarshalFallback = func(key string, data []byte, err error) (any, error) {
+		fallbackData = data
+		_ = cache.Delete(context.Background(), key)
+		return nil, gouache.ErrCacheMiss
+	}
+
+	ctx := context.Background()
+	key := "old_format"
+	if err := cache.Cache.Set([]byte(key), []byte("not json"), 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// 旧格式的值被视为未命中，并且已被删除
+	if _, err := cache.Get(ctx, key); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("expected gouache.ErrCacheMiss, got %v", err)
+	}
+	if string(fallbackData) != "not json" {
+		t.Errorf("expected raw data passed to fallback, got %q", fallbackData)
+	}
+	if _, err := cache.Cache.Get([]byte(key)); !errors.Is(err, freecache.ErrNotFound) {
+		t.Errorf("expected bad entry deleted, got %v", err)
+	}
+}
+
 // 测试Cache实现gouache.Cache接口
 func TestCache_InterfaceImplementation(t *testing.T) {
 	var _ gouache.Cache = (*Cache)(nil)
 }
+
+// 测试上下文中的TTL倍数
+func TestCache_TTLMultiplier(t *testing.T) {
+	cache := &Cache{
+		Cache: freecache.NewCache(1024 * 1024),
+		TTL: func(ctx context.Context, key string, val any) (time.Duration, error) {
+			return 10 * time.Second, nil
+		},
+	}
+
+	// 使用3倍TTL设置值
+	ctx := gouache.WithTTLMultiplier(context.Background(), 3)
+	err := cache.Set(ctx, "multiplied_key", []byte("value"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// 剩余TTL应该接近30秒
+	ttl, err := cache.Cache.TTL([]byte("multiplied_key"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ttl < 29 || ttl > 30 {
+		t.Errorf("expected TTL close to 30s, got %ds", ttl)
+	}
+}
+
+// 测试上下文中的TTL上限
+func TestCache_TTLCeiling(t *testing.T) {
+	cache := &Cache{
+		Cache: freecache.NewCache(1024 * 1024),
+		TTL: func(ctx context.Context, key string, val any) (time.Duration, error) {
+			if key == "forever_key" {
+				return 0, nil
+			}
+			return time.Hour, nil
+		},
+	}
+
+	// 超过上限的TTL和永不过期的条目都应该被限制为10秒
+	ctx := gouache.WithTTLCeiling(context.Background(), 10*time.Second)
+	for _, key := range []string{"long_key", "forever_key"} {
+		if err := cache.Set(ctx, key, []byte("value")); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		ttl, err := cache.ReadTTL(ctx, key)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if ttl < 9*time.Second || ttl > 10*time.Second {
+			t.Errorf("expected TTL close to 10s for %s, got %v", key, ttl)
+		}
+	}
+}
+
+// 测试读取剩余TTL
+func TestCache_ReadTTL(t *testing.T) {
+	cache := &Cache{
+		Cache: freecache.NewCache(1024 * 1024),
+		TTL: func(ctx context.Context, key string, val any) (time.Duration, error) {
+			return 10 * time.Second, nil
+		},
+	}
+
+	ctx := context.Background()
+
+	// 设置带10秒TTL的值
+	err := cache.Set(ctx, "ttl_key", []byte("value"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// 剩余TTL应该接近10秒
+	ttl, err := cache.ReadTTL(ctx, "ttl_key")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ttl < 9*time.Second || ttl > 10*time.Second {
+		t.Errorf("expected TTL close to 10s, got %v", ttl)
+	}
+
+	// 不存在的键应该返回ErrCacheMiss
+	_, err = cache.ReadTTL(ctx, "non_existent_key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("expected ErrCacheMiss, got %v", err)
+	}
+}
+
+// 测试Touch更新TTL
+func TestCache_Touch(t *testing.T) {
+	cache := &Cache{
+		Cache: freecache.NewCache(1024 * 1024),
+		TTL: func(ctx context.Context, key string, val any) (time.Duration, error) {
+			return 10 * time.Second, nil
+		},
+	}
+
+	ctx := context.Background()
+	err := cache.Set(ctx, "touch_key", []byte("value"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// 延长TTL到100秒
+	err = cache.Touch(ctx, "touch_key", 100*time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	ttl, err := cache.ReadTTL(ctx, "touch_key")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ttl < 99*time.Second {
+		t.Errorf("expected TTL close to 100s, got %v", ttl)
+	}
+
+	// 不存在的键应该返回ErrCacheMiss
+	err = cache.Touch(ctx, "non_existent_key", time.Second)
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("expected ErrCacheMiss, got %v", err)
+	}
+}
+
+// 测试序列化错误与存储错误的分类
+func TestCache_ErrorClassification(t *testing.T) {
+	marshalErr := errors.New("marshal error")
+	cache := &Cache{
+		Cache: freecache.NewCache(1024 * 1024),
+		Marshal: func(key string, obj any) ([]byte, error) {
+			return nil, marshalErr
+		},
+	}
+
+	ctx := context.Background()
+
+	// Marshal失败应该返回ErrMarshal并包装原始错误
+	err := cache.Set(ctx, "struct_key", &TestStruct{ID: 1})
+	if !errors.Is(err, gouache.ErrMarshal) || !errors.Is(err, marshalErr) {
+		t.Errorf("expected ErrMarshal wrapping %v, got %v", marshalErr, err)
+	}
+
+	// 超大条目存储失败应该返回ErrStorage并包装freecache的错误
+	err = cache.Set(ctx, "large_key", make([]byte, 1024*1024))
+	if !errors.Is(err, gouache.ErrStorage) || !errors.Is(err, freecache.ErrLargeEntry) {
+		t.Errorf("expected ErrStorage wrapping %v, got %v", freecache.ErrLargeEntry, err)
+	}
+}
+
+// 测试超大条目返回带有大小信息的LargeEntryError
+func TestCache_LargeEntry(t *testing.T) {
+	cache := &Cache{
+		Cache:     freecache.NewCache(1024 * 1024),
+		CacheSize: 1024 * 1024,
+	}
+
+	ctx := context.Background()
+
+	// 1MB缓存最多接受1000字节的条目
+	err := cache.Set(ctx, "large_key", make([]byte, 2000))
+	var largeErr *LargeEntryError
+	if !errors.As(err, &largeErr) {
+		t.Fatalf("expected *LargeEntryError, got %v", err)
+	}
+	if largeErr.Key != "large_key" || largeErr.Size != 2009 || largeErr.Limit != 1000 {
+		t.Errorf("expected key large_key, size 2009 and limit 1000, got %+v", largeErr)
+	}
+	if !errors.Is(err, gouache.ErrStorage) || !errors.Is(err, freecache.ErrLargeEntry) {
+		t.Errorf("expected error to match ErrStorage and ErrLargeEntry, got %v", err)
+	}
+
+	// 不超过限制的条目可以存储
+	err = cache.Set(ctx, "fit_key", make([]byte, 1000-len("fit_key")))
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// 测试未命中时返回 gouache.ErrCacheMiss
+func TestCache_CacheMissContract(t *testing.T) {
+	cache := &Cache{Cache: freecache.NewCache(1024 * 1024)}
+	cachetest.AssertCacheMissContract(t, cache, cachetest.WithValueFunc(func(i int) any { return []byte(fmt.Sprint("value-", i)) }))
+}
+
+// 测试使用选项创建缓存并校验配置
+func TestNew(t *testing.T) {
+	// 通过选项设置编解码函数
+	cache := New(freecache.NewCache(1024*1024),
+		WithMarshal(func(key string, obj any) ([]byte, error) { return json.Marshal(obj) }),
+		WithUnmarshal(func(key string, data []byte) (any, error) {
+			var obj TestStruct
+			err := json.Unmarshal(data, &obj)
+			return obj, err
+		}),
+		WithTTL(func(ctx context.Context, key string, val any) (time.Duration, error) { return time.Minute, nil }),
+		WithCacheSize(1024*1024),
+	)
+	if err := cache.validate(); err != nil {
+		t.Errorf("expected a consistent configuration, got %v", err)
+	}
+
+	ctx := context.Background()
+	want := TestStruct{ID: 1, Name: "test"}
+	if err := cache.Set(ctx, "key", want); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got, err := cache.Get(ctx, "key"); err != nil || got != want {
+		t.Errorf("expected %v, got %v, %v", want, got, err)
+	}
+	if ttl, err := cache.ReadTTL(ctx, "key"); err != nil || ttl <= 0 {
+		t.Errorf("expected a positive TTL, got %v, %v", ttl, err)
+	}
+
+	// 只设置单向编解码时报告不一致
+	unmarshal := func(key string, data []byte) (any, error) { return data, nil }
+	if err := New(freecache.NewCache(1024*1024), WithUnmarshal(unmarshal)).validate(); err == nil || !strings.Contains(err.Error(), "without Marshal") {
+		t.Errorf("expected Unmarshal without Marshal to be reported, got %v", err)
+	}
+	fallback := func(key string, data []byte, err error) (any, error) { return nil, err }
+	if err := New(freecache.NewCache(1024*1024), WithUnmarshalFallback(fallback)).validate(); err == nil || !strings.Contains(err.Error(), "never called") {
+		t.Errorf("expected an unused UnmarshalFallback to be reported, got %v", err)
+	}
+}
+
+// fakeTimer 是可手动推进的 freecache.Timer
+type fakeTimer struct {
+	now uint32
+}
+
+func (t *fakeTimer) Now() uint32 { return t.now }
+
+// 测试不足一秒的 TTL 向上取整为一秒，而不是变成永不过期
+func TestCache_SubSecondTTL(t *testing.T) {
+	timer := &fakeTimer{now: 1000}
+	cache := &Cache{Cache: freecache.NewCacheCustomTimer(1024*1024, timer)}
+	ctx := context.Background()
+
+	// SetWithTTL 使用不足一秒的 TTL
+	if err := cache.SetWithTTL(ctx, "explicit", []byte("value"), 500*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+
+	// Set 在 TTL 上限下写入，上限不足一秒
+	capped := gouache.WithTTLCeiling(ctx, 500*time.Millisecond)
+	if err := cache.Set(capped, "capped", []byte("value")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Touch 使用不足一秒的 TTL
+	if err := cache.SetWithTTL(ctx, "touched", []byte("value"), 0); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+	if err := cache.Touch(ctx, "touched", 500*time.Millisecond); err != nil {
+		t.Fatalf("Touch failed: %v", err)
+	}
+
+	for _, key := range []string{"explicit", "capped", "touched"} {
+		if ttl, err := cache.ReadTTL(ctx, key); err != nil || ttl != time.Second {
+			t.Errorf("%s: expected a TTL of 1s, got %v, %v", key, ttl, err)
+		}
+	}
+
+	// 推进时间后条目应已过期
+	timer.now += 2
+	for _, key := range []string{"explicit", "capped", "touched"} {
+		if _, err := cache.Get(ctx, key); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("%s: expected gouache.ErrCacheMiss after expiry, got %v", key, err)
+		}
+	}
+}
+
+// 测试 TTL 换算为秒时向上取整
+func TestTTLSeconds(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want int
+	}{
+		{0, 0},
+		{-time.Second, 0},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	}
+	for _, tt := range tests {
+		if got := ttlSeconds(tt.ttl); got != tt.want {
+			t.Errorf("ttlSeconds(%v) = %d, want %d", tt.ttl, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,9 @@ require (
 	github.com/soyacen/gouache v0.0.0-00010101000000-000000000000
 )
 
-require github.com/cespare/xxhash/v2 v2.1.2 // indirect
+require (
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+)
 
 replace github.com/soyacen/gouache => ../
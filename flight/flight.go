@@ -0,0 +1,170 @@
+// Package flight provides a registry of in-flight calls, shared by the
+// decorators that deduplicate concurrent work for the same key, such as sf
+// and loading.
+//
+// Like golang.org/x/sync/singleflight, a Group runs a function once for all
+// concurrent callers of Do with the same key. Unlike it, the shared call runs
+// with a merged context rather than the context of the caller that started
+// it. The merged context carries the values of that first caller but none of
+// its cancellation or deadline; it is canceled only once every caller waiting
+// for the result has given up. A waiter with a 5s deadline therefore still
+// gets its result when the first caller had a 1s deadline, while a call
+// nobody waits for anymore is abandoned.
+package flight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/soyacen/gouache"
+)
+
+// call is a function call in flight, shared by every caller waiting for it.
+type call struct {
+	// ctx is the merged context the call runs with.
+	ctx context.Context
+
+	// cancel cancels ctx.
+	cancel context.CancelFunc
+
+	// waiters is the number of callers still waiting for the result.
+	waiters int
+
+	// done is closed once val and err are set.
+	done chan struct{}
+
+	// val and err are the result of the call.
+	val any
+	err error
+}
+
+// Group deduplicates concurrent calls by key. The zero value is ready to use,
+// and a Group must not be copied after first use.
+type Group struct {
+	// mu guards calls.
+	mu sync.Mutex
+
+	// calls maps keys to the calls in flight for them.
+	calls map[string]*call
+}
+
+// Do runs fn once for all concurrent callers with the same key and returns
+// its result to each of them. fn receives the merged context of the call.
+// Each caller waits until the result is ready or its own ctx is done, in
+// which case it returns an error wrapping gouache.ErrContext. A panic in fn
+// is returned to every waiter as an error.
+//
+// Parameters:
+//   - ctx: Context of the caller, whose values the call runs with if it starts it
+//   - key: The key concurrent calls are shared under
+//   - fn: The function to run
+//
+// Returns:
+//   - The value returned by fn, or nil if ctx is done first
+//   - The error returned by fn, or an error wrapping gouache.ErrContext if ctx is done first
+//   - Whether the caller joined a call already in flight rather than starting one
+func (g *Group) Do(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (any, error, bool) {
+	// Join the call in flight for this key, or start one
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c, shared := g.calls[key]
+	if !shared {
+		mctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call{ctx: mctx, cancel: cancel, done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(key, c, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	// Wait for the result, or leave if the caller gives up first
+	select {
+	case <-c.done:
+		return c.val, c.err, shared
+	case <-ctx.Done():
+		g.leave(key, c)
+		return nil, fmt.Errorf("%w: %w", gouache.ErrContext, ctx.Err()), shared
+	}
+}
+
+// Waiters returns the number of callers waiting for the call in flight for
+// key, for monitoring and tests.
+//
+// Parameters:
+//   - key: The key the call is shared under
+//
+// Returns:
+//   - The number of waiting callers, or 0 if no call is in flight for key
+func (g *Group) Waiters(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		return c.waiters
+	}
+	return 0
+}
+
+// Forget stops sharing the call in flight for key, so that the next Do with
+// the key starts a new call, for example after the value was changed. Callers
+// already waiting still get the result of the forgotten call.
+//
+// Parameters:
+//   - key: The key to forget
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
+
+// run performs a shared call and publishes its result.
+//
+// Parameters:
+//   - key: The key the call is shared under
+//   - c: The shared call
+//   - fn: The function to run
+func (g *Group) run(key string, c *call, fn func(ctx context.Context) (any, error)) {
+	defer c.cancel()
+	defer close(c.done)
+
+	// Report a panic in fn as an error to every waiter
+	defer func() {
+		if r := recover(); r != nil {
+			c.val, c.err = nil, fmt.Errorf("flight: panic in call: %v", r)
+		}
+	}()
+
+	// Stop sharing the call before publishing its result
+	defer func() {
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+	}()
+
+	c.val, c.err = fn(c.ctx)
+}
+
+// leave removes a waiter from a shared call, canceling the call if nobody is
+// waiting for it anymore.
+//
+// Parameters:
+//   - key: The key the call is shared under
+//   - c: The shared call
+func (g *Group) leave(key string, c *call) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+	// Abandon the call so a later Do starts a fresh one
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	c.cancel()
+}
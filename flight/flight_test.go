@@ -0,0 +1,169 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// ctxKey is the context key used by the tests.
+type ctxKey struct{}
+
+// TestGroup_Do tests that concurrent calls with the same key share one call
+func TestGroup_Do(t *testing.T) {
+	var g Group
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	var shared atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err, s := g.Do(context.Background(), "key", func(ctx context.Context) (any, error) {
+				calls.Add(1)
+				<-release
+				return "value", nil
+			})
+			if err != nil || val != "value" {
+				t.Errorf("Expected value, got %v, %v", val, err)
+			}
+			if s {
+				shared.Add(1)
+			}
+		}()
+	}
+
+	// Let every caller join before the call returns
+	deadline := time.Now().Add(5 * time.Second)
+	for g.Waiters("key") < 10 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the callers to join, %d waiting", g.Waiters("key"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if n := g.Waiters("key"); n != 0 {
+		t.Errorf("Expected no waiters once the call returned, got %d", n)
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected 1 call, got %d", n)
+	}
+	if n := shared.Load(); n != 9 {
+		t.Errorf("Expected 9 shared callers, got %d", n)
+	}
+}
+
+// TestGroup_MergedContext tests that the call outlives its first caller as long as someone waits
+func TestGroup_MergedContext(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (any, error) {
+		close(started)
+		select {
+		case <-release:
+			return ctx.Value(ctxKey{}), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// The first caller gives up quickly
+	first, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "first"))
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do(first, "key", fn)
+		firstDone <- err
+	}()
+	<-started
+
+	// A second caller joins and keeps waiting
+	secondDone := make(chan any, 1)
+	go func() {
+		val, _, _ := g.Do(context.Background(), "key", fn)
+		secondDone <- val
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-firstDone; !errors.Is(err, gouache.ErrContext) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the first caller to get a context error, got %v", err)
+	}
+
+	// The call keeps running for the second caller, with the first caller's values
+	close(release)
+	if val := <-secondDone; val != "first" {
+		t.Errorf("Expected the first caller's context values, got %v", val)
+	}
+}
+
+// TestGroup_Abandon tests that the call is canceled once every caller has given up
+func TestGroup_Abandon(t *testing.T) {
+	var g Group
+	canceled := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err, _ := g.Do(ctx, "key", func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the abandoned call to be canceled")
+	}
+}
+
+// TestGroup_Forget tests that a forgotten key starts a new call while old waiters get the old result
+func TestGroup_Forget(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	oldDone := make(chan any, 1)
+	go func() {
+		val, _, _ := g.Do(context.Background(), "key", func(ctx context.Context) (any, error) {
+			<-release
+			return "old", nil
+		})
+		oldDone <- val
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	g.Forget("key")
+	val, err, shared := g.Do(context.Background(), "key", func(ctx context.Context) (any, error) {
+		return "new", nil
+	})
+	if err != nil || val != "new" || shared {
+		t.Errorf("Expected a new call returning new, got %v, %v, shared %v", val, err, shared)
+	}
+
+	close(release)
+	if val := <-oldDone; val != "old" {
+		t.Errorf("Expected the old waiter to get old, got %v", val)
+	}
+}
+
+// TestGroup_Panic tests that a panic in the call is returned as an error
+func TestGroup_Panic(t *testing.T) {
+	var g Group
+	_, err, _ := g.Do(context.Background(), "key", func(ctx context.Context) (any, error) {
+		panic("boom")
+	})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the panic as an error, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package gouache
+
+import (
+	"context"
+	"time"
+)
+
+// Ensure that Forwarder implements the Cache interface at compile time.
+var _ Cache = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the BatchGetter interface at compile time.
+var _ BatchGetter = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the BatchSetter interface at compile time.
+var _ BatchSetter = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the BatchDeleter interface at compile time.
+var _ BatchDeleter = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the ResultDeleter interface at compile time.
+var _ ResultDeleter = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the Iterable interface at compile time.
+var _ Iterable = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the Adder interface at compile time.
+var _ Adder = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the TTLSetter interface at compile time.
+var _ TTLSetter = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the TTLReader interface at compile time.
+var _ TTLReader = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the Toucher interface at compile time.
+var _ Toucher = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the CompareDeleter interface at compile time.
+var _ CompareDeleter = (*Forwarder)(nil)
+
+// Ensure that Forwarder forwards capability probing at compile time.
+var _ CapabilityReporter = (*Forwarder)(nil)
+
+// Ensure that Forwarder implements the Unwrapper interface at compile time.
+var _ Unwrapper = (*Forwarder)(nil)
+
+// Forwarder passes every operation, including those of the optional
+// interfaces, straight through to the cache it wraps. Optional operations the
+// wrapped cache doesn't support fail with ErrUnsupported, and Capabilities
+// reports the capabilities of the wrapped cache.
+//
+// Forwarder is meant to be embedded in decorators, so that wrapping a cache
+// doesn't hide its optional interfaces. A decorator embedding it overrides
+// only the methods it intercepts, but must override every method that would
+// otherwise bypass its behavior: a decorator intercepting Get, for example,
+// must also override GetMulti, which Forwarder sends directly to the wrapped
+// cache. A decorator that stops forwarding an interface overrides the method
+// to return ErrUnsupported and clears it in Capabilities.
+type Forwarder struct {
+	// Cache is the underlying cache implementation
+	Cache Cache
+}
+
+// Get retrieves a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails
+func (f *Forwarder) Get(ctx context.Context, key string) (any, error) {
+	return f.Cache.Get(ctx, key)
+}
+
+// Set stores a value in the underlying cache under the specified key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (f *Forwarder) Set(ctx context.Context, key string, val any) error {
+	return f.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (f *Forwarder) Delete(ctx context.Context, key string) error {
+	return f.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (f *Forwarder) Unwrap() Cache {
+	return f.Cache
+}
+
+// GetMulti retrieves the values for the given keys from the underlying cache,
+// using its native GetMulti when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - An error if the operation fails
+func (f *Forwarder) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	return GetMulti(ctx, f.Cache, keys)
+}
+
+// SetMulti stores each value in vals in the underlying cache, using its
+// native SetMulti when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - An error if the operation fails
+func (f *Forwarder) SetMulti(ctx context.Context, vals map[string]any) error {
+	return SetMulti(ctx, f.Cache, vals)
+}
+
+// DeleteMulti removes the values of the given keys from the underlying cache,
+// using its native DeleteMulti when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (f *Forwarder) DeleteMulti(ctx context.Context, keys []string) error {
+	return DeleteMulti(ctx, f.Cache, keys)
+}
+
+// DeleteMultiResult removes the values of the given keys from the underlying
+// cache and reports which of them existed, using its native DeleteMultiResult
+// when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - An error if the operation fails
+func (f *Forwarder) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	return DeleteMultiResult(ctx, f.Cache, keys)
+}
+
+// Iterate calls fn for every key of the underlying cache matching match.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - match: The pattern keys must match, or empty to match all keys
+//   - fn: The function called for each key
+//
+// Returns:
+//   - ErrUnsupported if the underlying cache isn't iterable, or an error if the operation fails
+func (f *Forwarder) Iterate(ctx context.Context, match string, fn func(key string) error) error {
+	iterable, ok := f.Cache.(Iterable)
+	if !ok {
+		return ErrUnsupported
+	}
+	return iterable.Iterate(ctx, match, fn)
+}
+
+// Add stores a value in the underlying cache only if the key is absent.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - ErrUnsupported if the underlying cache isn't an Adder, or an error if the operation fails
+func (f *Forwarder) Add(ctx context.Context, key string, val any) (bool, error) {
+	adder, ok := f.Cache.(Adder)
+	if !ok {
+		return false, ErrUnsupported
+	}
+	return adder.Add(ctx, key, val)
+}
+
+// SetWithTTL stores a value in the underlying cache with an explicit TTL.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry
+//
+// Returns:
+//   - ErrUnsupported if the underlying cache isn't a TTLSetter, or an error if the operation fails
+func (f *Forwarder) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	setter, ok := f.Cache.(TTLSetter)
+	if !ok {
+		return ErrUnsupported
+	}
+	return setter.SetWithTTL(ctx, key, val, ttl)
+}
+
+// ReadTTL returns the remaining time-to-live of an entry of the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//
+// Returns:
+//   - The remaining time-to-live
+//   - ErrUnsupported if the underlying cache isn't a TTLReader, or an error if the operation fails
+func (f *Forwarder) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	reader, ok := f.Cache.(TTLReader)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return reader.ReadTTL(ctx, key)
+}
+
+// Touch sets a new time-to-live for an entry of the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live of the entry
+//
+// Returns:
+//   - ErrUnsupported if the underlying cache isn't a Toucher, or an error if the operation fails
+func (f *Forwarder) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	toucher, ok := f.Cache.(Toucher)
+	if !ok {
+		return ErrUnsupported
+	}
+	return toucher.Touch(ctx, key, ttl)
+}
+
+// CompareAndDelete deletes the entry stored under key if it still holds
+// expected in the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - expected: The value the entry must hold to be deleted
+//
+// Returns:
+//   - true if the entry was deleted, false if it was missing or held another value
+//   - ErrUnsupported if the underlying cache isn't a CompareDeleter, or an error if the operation fails
+func (f *Forwarder) CompareAndDelete(ctx context.Context, key string, expected any) (bool, error) {
+	deleter, ok := f.Cache.(CompareDeleter)
+	if !ok {
+		return false, ErrUnsupported
+	}
+	return deleter.CompareAndDelete(ctx, key, expected)
+}
+
+// Capabilities reports the optional interfaces of the underlying cache, all
+// of which are forwarded.
+//
+// Returns:
+//   - The capabilities of the underlying cache
+func (f *Forwarder) Capabilities() Caps {
+	return Capabilities(f.Cache)
+}
@@ -0,0 +1,184 @@
+package gouache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/batcher"
+	"github.com/soyacen/gouache/generation"
+	"github.com/soyacen/gouache/keymutex"
+	"github.com/soyacen/gouache/loading"
+	"github.com/soyacen/gouache/metrics"
+	"github.com/soyacen/gouache/missreason"
+	"github.com/soyacen/gouache/nonempty"
+	"github.com/soyacen/gouache/sample"
+	"github.com/soyacen/gouache/sliding"
+	"github.com/soyacen/gouache/synclock"
+	"github.com/soyacen/gouache/ttlcap"
+)
+
+// plainCache is a cache implementing none of the optional interfaces.
+type plainCache struct {
+	Cache sample.Cache
+}
+
+func (c *plainCache) Get(ctx context.Context, key string) (any, error) {
+	return c.Cache.Get(ctx, key)
+}
+
+func (c *plainCache) Set(ctx context.Context, key string, val any) error {
+	return c.Cache.Set(ctx, key, val)
+}
+
+func (c *plainCache) Delete(ctx context.Context, key string) error {
+	return c.Cache.Delete(ctx, key)
+}
+
+// nopRecorder is a metrics.Recorder that discards events.
+type nopRecorder struct{}
+
+func (nopRecorder) Record(ctx context.Context, event metrics.Event) {}
+
+// TestForwarder tests that Forwarder passes optional operations through to
+// the wrapped cache, and reports ErrUnsupported for those it lacks
+func TestForwarder(t *testing.T) {
+	ctx := context.Background()
+
+	// A backend with the optional interfaces is used directly
+	native := &gouache.Forwarder{Cache: &sample.Cache{}}
+	if ok, err := native.Add(ctx, "key", "value"); err != nil || !ok {
+		t.Fatalf("Expected Add to store the value, got %v, %v", ok, err)
+	}
+	if val, err := native.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected 'value', got %v, %v", val, err)
+	}
+	if caps := gouache.Capabilities(native); caps != gouache.Capabilities(&sample.Cache{}) {
+		t.Errorf("Expected the capabilities of the backend, got %+v", caps)
+	}
+
+	// A backend without them fails with ErrUnsupported and reports nothing
+	plain := &gouache.Forwarder{Cache: &plainCache{}}
+	if _, err := plain.Add(ctx, "key", "value"); !errors.Is(err, gouache.ErrUnsupported) {
+		t.Errorf("Expected gouache.ErrUnsupported from Add, got %v", err)
+	}
+	if err := plain.Iterate(ctx, "", func(string) error { return nil }); !errors.Is(err, gouache.ErrUnsupported) {
+		t.Errorf("Expected gouache.ErrUnsupported from Iterate, got %v", err)
+	}
+	if caps := gouache.Capabilities(plain); caps != (gouache.Caps{}) {
+		t.Errorf("Expected no capabilities, got %+v", caps)
+	}
+
+	// Batch operations fall back to the basic ones
+	if err := plain.SetMulti(ctx, map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("SetMulti failed: %v", err)
+	}
+	if vals, err := plain.GetMulti(ctx, []string{"a", "b", "c"}); err != nil || len(vals) != 2 {
+		t.Errorf("Expected 2 values, got %v, %v", vals, err)
+	}
+	if got := gouache.Unwrap(plain); got != plain.Cache {
+		t.Errorf("Expected Unwrap to return the wrapped cache, got %T", got)
+	}
+}
+
+// TestForwardingDecorators_Fallback tests that the consumers of optional
+// interfaces fall back to the basic operations when a decorator forwarding
+// them wraps a cache that lacks them, rather than failing with
+// gouache.ErrUnsupported
+func TestForwardingDecorators_Fallback(t *testing.T) {
+	decorators := map[string]func(c gouache.Cache) gouache.Cache{
+		"synclock":   func(c gouache.Cache) gouache.Cache { return synclock.New(c) },
+		"keymutex":   func(c gouache.Cache) gouache.Cache { return keymutex.New(c) },
+		"nonempty":   nonempty.New,
+		"metrics":    func(c gouache.Cache) gouache.Cache { return metrics.New(c, nopRecorder{}) },
+		"ttlcap":     func(c gouache.Cache) gouache.Cache { return ttlcap.New(c, time.Hour) },
+		"generation": func(c gouache.Cache) gouache.Cache { return generation.New(c) },
+		"missreason": func(c gouache.Cache) gouache.Cache { return missreason.New(c) },
+	}
+	ctx := context.Background()
+	loader := func(ctx context.Context, key string) (any, error) { return "loaded", nil }
+
+	for name, decorate := range decorators {
+		t.Run(name, func(t *testing.T) {
+			t.Run("loading", func(t *testing.T) {
+				cache := loading.New(decorate(&plainCache{}), loader, loading.WithTTL(time.Minute))
+				if val, err := cache.Get(ctx, "key"); err != nil || val != "loaded" {
+					t.Errorf("Expected 'loaded', got %v, %v", val, err)
+				}
+			})
+
+			t.Run("sliding", func(t *testing.T) {
+				var handled []error
+				cache := sliding.New(decorate(&plainCache{}),
+					sliding.WithGopher(func(f func()) error { f(); return nil }),
+					sliding.WithErrorHandler(func(err error) { handled = append(handled, err) }),
+				)
+				if err := cache.Set(ctx, "key", "value"); err != nil {
+					t.Fatalf("Set failed: %v", err)
+				}
+				if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+					t.Errorf("Expected 'value', got %v, %v", val, err)
+				}
+				if len(handled) > 0 {
+					t.Errorf("Expected no background errors, got %v", handled)
+				}
+			})
+
+			t.Run("batcher", func(t *testing.T) {
+				backend := decorate(&plainCache{})
+				if err := backend.Set(ctx, "key", "value"); err != nil {
+					t.Fatalf("Set failed: %v", err)
+				}
+				if val, err := batcher.New(backend).Get(ctx, "key"); err != nil || val != "value" {
+					t.Errorf("Expected 'value', got %v, %v", val, err)
+				}
+			})
+
+			t.Run("Migrate", func(t *testing.T) {
+				if _, err := gouache.Migrate(ctx, decorate(&plainCache{}), &sample.Cache{}); !errors.Is(err, gouache.ErrNotIterable) {
+					t.Errorf("Expected gouache.ErrNotIterable from the source, got %v", err)
+				}
+
+				src := &sample.Cache{}
+				_ = src.Set(ctx, "key", "value")
+				dst := decorate(&plainCache{})
+				if n, err := gouache.Migrate(ctx, src, dst); err != nil || n != 1 {
+					t.Fatalf("Expected 1 value copied, got %d, %v", n, err)
+				}
+				if val, err := dst.Get(ctx, "key"); err != nil || val != "value" {
+					t.Errorf("Expected 'value', got %v, %v", val, err)
+				}
+			})
+
+			t.Run("Dump", func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := gouache.Dump(ctx, decorate(&plainCache{}), &buf); !errors.Is(err, gouache.ErrNotIterable) {
+					t.Errorf("Expected gouache.ErrNotIterable, got %v", err)
+				}
+			})
+
+			t.Run("DeleteMultiResult", func(t *testing.T) {
+				for _, backend := range []gouache.Cache{&plainCache{}, &sample.Cache{}} {
+					cache := decorate(backend)
+					_, native := backend.(*sample.Cache)
+					if got := gouache.Capabilities(cache).ResultDeleter; got != (native && name != "missreason") {
+						t.Errorf("Expected ResultDeleter reported as %v for %T, got %v", !got, backend, got)
+					}
+					_ = cache.Set(ctx, "a", 1)
+					_ = cache.Set(ctx, "c", 3)
+					deleted, err := gouache.DeleteMultiResult(ctx, cache, []string{"a", "b", "c"})
+					if err != nil || fmt.Sprint(deleted) != "[a c]" {
+						t.Errorf("Expected [a c] deleted from %T, got %v, %v", backend, deleted, err)
+					}
+					if _, err := cache.Get(ctx, "a"); !errors.Is(err, gouache.ErrCacheMiss) {
+						t.Errorf("Expected a to be deleted from %T, got %v", backend, err)
+					}
+				}
+			})
+		})
+	}
+}
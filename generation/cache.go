@@ -0,0 +1,702 @@
+// Package generation provides a cache implementation that invalidates whole
+// namespaces of keys at once.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// tagging every key with the current generation of its namespace. Bumping a
+// namespace's generation with Invalidate makes all keys written under the
+// previous generation unreachable without deleting them; they are left to
+// expire through the backend's TTL or eviction.
+//
+// Every operation first resolves the current generation of the key's
+// namespace, which costs an extra read from the generation store. That read
+// can be cached in-process for a short time with WithGenerationTTL.
+//
+// The batch and TTL interfaces of the wrapped cache are forwarded with the
+// keys resolved, so wrapping doesn't hide a backend's batch support. Iterate
+// is not forwarded.
+package generation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchSetter interface at compile time.
+var _ gouache.BatchSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.CompareDeleter interface at compile time.
+var _ gouache.CompareDeleter = (*Cache)(nil)
+
+// Ensure that Cache forwards capability probing at compile time.
+var _ gouache.CapabilityReporter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*Cache)(nil)
+
+// NamespaceFunc is a function type that extracts the namespace and the rest of
+// a key. A key for which ok is false has no namespace and is passed through
+// to the underlying cache unchanged.
+type NamespaceFunc func(key string) (ns string, rest string, ok bool)
+
+// options holds configuration options for the generation cache.
+type options struct {
+	// NamespaceFunc extracts the namespace of a key.
+	NamespaceFunc NamespaceFunc
+
+	// Store holds the generation of every namespace.
+	Store gouache.Cache
+
+	// KeyPrefix is prepended to a namespace to form the key of its generation in Store.
+	KeyPrefix string
+
+	// GenerationTTL is how long a generation read from Store is cached in-process.
+	GenerationTTL time.Duration
+
+	// Clock provides the current time.
+	Clock gouache.Clock
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithNamespaceFunc returns an Option that sets the function extracting the
+// namespace of a key. By default the namespace is the part of the key before
+// the first ':', and keys without ':' have no namespace.
+//
+// Parameters:
+//   - f: A function that splits a key into its namespace and the rest
+//
+// Returns:
+//   - An Option function that sets the NamespaceFunc
+func WithNamespaceFunc(f NamespaceFunc) Option {
+	return func(o *options) {
+		o.NamespaceFunc = f
+	}
+}
+
+// WithStore returns an Option that sets the cache holding the generation of
+// every namespace. By default generations are stored in the wrapped cache,
+// which must then be able to store and return string values.
+//
+// Parameters:
+//   - store: The cache holding the generations
+//
+// Returns:
+//   - An Option function that sets the Store
+func WithStore(store gouache.Cache) Option {
+	return func(o *options) {
+		o.Store = store
+	}
+}
+
+// WithKeyPrefix returns an Option that sets the prefix of the keys under
+// which generations are stored.
+//
+// Parameters:
+//   - prefix: The prefix prepended to a namespace
+//
+// Returns:
+//   - An Option function that sets the KeyPrefix
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.KeyPrefix = prefix
+	}
+}
+
+// WithGenerationTTL returns an Option that caches the generations read from
+// the store in-process for d, saving the extra read on most operations.
+//
+// An Invalidate made by this Cache is seen immediately, but one made by
+// another process may take up to d to be seen here.
+//
+// Parameters:
+//   - d: How long a generation is cached; zero disables caching
+//
+// Returns:
+//   - An Option function that sets the GenerationTTL
+func WithGenerationTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.GenerationTTL = d
+	}
+}
+
+// WithClock returns an Option that sets the clock providing the current time,
+// for generation values and in-process expiry. It defaults to
+// gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Split keys on the first ':' by default
+	if o.NamespaceFunc == nil {
+		o.NamespaceFunc = func(key string) (string, string, bool) {
+			return strings.Cut(key, ":")
+		}
+	}
+
+	// Set default key prefix if not specified
+	if o.KeyPrefix == "" {
+		o.KeyPrefix = "gouache:generation:"
+	}
+
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+	return o
+}
+
+// cachedGeneration is a generation cached in-process.
+type cachedGeneration struct {
+	// gen is the generation value.
+	gen string
+
+	// expiresAt is when the cached generation must be read again.
+	expiresAt time.Time
+}
+
+// Cache is a cache implementation that tags keys with the generation of their
+// namespace.
+type Cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Forwarder holds the underlying cache and forwards the operations the
+	// cache doesn't intercept to it
+	gouache.Forwarder
+
+	// mu guards generations.
+	mu sync.Mutex
+
+	// generations caches generations read from the store.
+	generations map[string]cachedGeneration
+}
+
+// New creates a new generation cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache that tags keys with the generation of their namespace
+func New(c gouache.Cache, opts ...Option) *Cache {
+	options := newOptions(opts...)
+	if options.Store == nil {
+		options.Store = c
+	}
+	return &Cache{Options: options, Forwarder: gouache.Forwarder{Cache: c}, generations: make(map[string]cachedGeneration)}
+}
+
+// Get retrieves a value written under the current generation of the key's namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	key, err := cache.resolve(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set stores a value under the current generation of the key's namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	key, err := cache.resolve(ctx, key)
+	if err != nil {
+		return err
+	}
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value stored under the current generation of the key's namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	key, err := cache.resolve(ctx, key)
+	if err != nil {
+		return err
+	}
+	return cache.Cache.Delete(ctx, key)
+}
+
+// GetMulti retrieves the values written under the current generations of the
+// keys' namespaces, using the native GetMulti of the underlying cache when
+// available. The generation of each namespace is resolved once per call.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key, as given, to its cached value
+//   - An error if the operation fails
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	resolved, err := cache.resolveAll(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look up the tagged keys, remembering which key each one came from
+	original := make(map[string]string, len(keys))
+	tagged := make([]string, 0, len(keys))
+	for _, key := range keys {
+		original[resolved[key]] = key
+		tagged = append(tagged, resolved[key])
+	}
+	vals, err := gouache.GetMulti(ctx, cache.Cache, tagged)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report the values under the keys the caller asked for
+	result := make(map[string]any, len(vals))
+	for key, val := range vals {
+		result[original[key]] = val
+	}
+	return result, nil
+}
+
+// SetMulti stores each value in vals under the current generation of its
+// key's namespace, using the native SetMulti of the underlying cache when
+// available. The generation of each namespace is resolved once per call.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	keys := make([]string, 0, len(vals))
+	for key := range vals {
+		keys = append(keys, key)
+	}
+	resolved, err := cache.resolveAll(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	tagged := make(map[string]any, len(vals))
+	for key, val := range vals {
+		tagged[resolved[key]] = val
+	}
+	return gouache.SetMulti(ctx, cache.Cache, tagged)
+}
+
+// DeleteMulti removes the values of the given keys stored under the current
+// generations of their namespaces, using the native DeleteMulti of the
+// underlying cache when available. The generation of each namespace is
+// resolved once per call.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	resolved, err := cache.resolveAll(ctx, keys)
+	if err != nil {
+		return err
+	}
+	tagged := make([]string, 0, len(keys))
+	for _, key := range keys {
+		tagged = append(tagged, resolved[key])
+	}
+	return gouache.DeleteMulti(ctx, cache.Cache, tagged)
+}
+
+// DeleteMultiResult removes the values of the given keys stored under the
+// current generations of their namespaces and reports which of them existed,
+// using the native DeleteMultiResult of the underlying cache when available.
+// The generation of each namespace is resolved once per call.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - An error if the operation fails
+func (cache *Cache) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	resolved, err := cache.resolveAll(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	tagged := make([]string, 0, len(keys))
+	untagged := make(map[string]string, len(keys))
+	for _, key := range keys {
+		tagged = append(tagged, resolved[key])
+		untagged[resolved[key]] = key
+	}
+	deleted, err := gouache.DeleteMultiResult(ctx, cache.Cache, tagged)
+	for i, key := range deleted {
+		deleted[i] = untagged[key]
+	}
+	return deleted, err
+}
+
+// Add stores a value under the current generation of the key's namespace
+// only if it is absent there.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - gouache.ErrUnsupported if the underlying cache isn't an Adder, or an error if the operation fails
+func (cache *Cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	adder, ok := cache.Cache.(gouache.Adder)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	key, err := cache.resolve(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return adder.Add(ctx, key, val)
+}
+
+// SetWithTTL stores a value with an explicit TTL under the current generation
+// of the key's namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLSetter, or an error if the operation fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	setter, ok := cache.Cache.(gouache.TTLSetter)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	key, err := cache.resolve(ctx, key)
+	if err != nil {
+		return err
+	}
+	return setter.SetWithTTL(ctx, key, val, ttl)
+}
+
+// ReadTTL returns the remaining time-to-live of an entry stored under the
+// current generation of the key's namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//
+// Returns:
+//   - The remaining time-to-live
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLReader, or an error if the operation fails
+func (cache *Cache) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	reader, ok := cache.Cache.(gouache.TTLReader)
+	if !ok {
+		return 0, gouache.ErrUnsupported
+	}
+	key, err := cache.resolve(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return reader.ReadTTL(ctx, key)
+}
+
+// Touch sets a new time-to-live for an entry stored under the current
+// generation of the key's namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a Toucher, or an error if the operation fails
+func (cache *Cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	toucher, ok := cache.Cache.(gouache.Toucher)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	key, err := cache.resolve(ctx, key)
+	if err != nil {
+		return err
+	}
+	return toucher.Touch(ctx, key, ttl)
+}
+
+// CompareAndDelete deletes the entry stored under key if it still holds
+// expected under the current generation of the key's namespace.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - expected: The value the entry must hold to be deleted
+//
+// Returns:
+//   - true if the entry was deleted, false if it was missing or held another value
+//   - gouache.ErrUnsupported if the underlying cache isn't a CompareDeleter, or an error if the operation fails
+func (cache *Cache) CompareAndDelete(ctx context.Context, key string, expected any) (bool, error) {
+	deleter, ok := cache.Cache.(gouache.CompareDeleter)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	key, err := cache.resolve(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return deleter.CompareAndDelete(ctx, key, expected)
+}
+
+// Iterate is not forwarded, since the underlying keys carry generation tags
+// and stale generations would be enumerated too.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - match: The pattern keys must match, or empty to match all keys
+//   - fn: The function called for each key
+//
+// Returns:
+//   - gouache.ErrUnsupported
+func (cache *Cache) Iterate(ctx context.Context, match string, fn func(key string) error) error {
+	return gouache.ErrUnsupported
+}
+
+// Capabilities reports the optional interfaces of the underlying cache that
+// are forwarded. Iterate is never forwarded, since the underlying keys carry
+// generation tags and stale generations would be enumerated too.
+//
+// Returns:
+//   - The capabilities of the underlying cache, without Iterate
+func (cache *Cache) Capabilities() gouache.Caps {
+	caps := gouache.Capabilities(cache.Cache)
+	caps.Iterate = false
+	return caps
+}
+
+// Invalidate starts a new generation for the namespace ns, making every key
+// written under the previous generation unreachable.
+//
+// The new generation is derived from the current time and a random suffix
+// rather than incremented, so concurrent invalidations never need a
+// read-modify-write, and invalidations within the resolution of the clock, or
+// from other processes, never reuse a generation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - ns: The namespace to invalidate
+//
+// Returns:
+//   - An error if the new generation can't be stored
+func (cache *Cache) Invalidate(ctx context.Context, ns string) error {
+	// Store a new generation
+	gen := strconv.FormatInt(cache.Options.Clock.Now().UnixNano(), 36) + "." + strconv.FormatUint(rand.Uint64(), 36)
+	if err := cache.Options.Store.Set(ctx, cache.Options.KeyPrefix+ns, gen); err != nil {
+		return err
+	}
+
+	// Make the new generation visible to this Cache immediately
+	cache.mu.Lock()
+	if cache.Options.GenerationTTL > 0 {
+		cache.generations[ns] = cachedGeneration{gen: gen, expiresAt: cache.Options.Clock.Now().Add(cache.Options.GenerationTTL)}
+	} else {
+		delete(cache.generations, ns)
+	}
+	cache.mu.Unlock()
+	return nil
+}
+
+// resolve returns the key under which key is stored in the current generation.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to resolve
+//
+// Returns:
+//   - The key tagged with its namespace's generation, or key itself if it has no namespace
+//   - An error if the generation can't be read
+func (cache *Cache) resolve(ctx context.Context, key string) (string, error) {
+	ns, rest, ok := cache.Options.NamespaceFunc(key)
+	if !ok {
+		return key, nil
+	}
+	gen, err := cache.generation(ctx, ns)
+	if err != nil {
+		return "", err
+	}
+	return ns + ":@" + gen + ":" + rest, nil
+}
+
+// resolveAll resolves several keys at once, reading the generation of each
+// namespace only once.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to resolve
+//
+// Returns:
+//   - A map from each key to the key it is stored under in the current generation
+//   - An error if a generation can't be read
+func (cache *Cache) resolveAll(ctx context.Context, keys []string) (map[string]string, error) {
+	resolved := make(map[string]string, len(keys))
+	gens := make(map[string]string)
+	for _, key := range keys {
+		ns, rest, ok := cache.Options.NamespaceFunc(key)
+		if !ok {
+			resolved[key] = key
+			continue
+		}
+		gen, ok := gens[ns]
+		if !ok {
+			var err error
+			gen, err = cache.generation(ctx, ns)
+			if err != nil {
+				return nil, err
+			}
+			gens[ns] = gen
+		}
+		resolved[key] = ns + ":@" + gen + ":" + rest
+	}
+	return resolved, nil
+}
+
+// generation returns the current generation of the namespace ns, from the
+// in-process cache if it is still fresh.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - ns: The namespace
+//
+// Returns:
+//   - The generation, "0" if the namespace was never invalidated
+//   - An error if the generation can't be read
+func (cache *Cache) generation(ctx context.Context, ns string) (string, error) {
+	// Use the cached generation if it is still fresh
+	if cache.Options.GenerationTTL > 0 {
+		cache.mu.Lock()
+		cached, ok := cache.generations[ns]
+		cache.mu.Unlock()
+		if ok && cache.Options.Clock.Now().Before(cached.expiresAt) {
+			return cached.gen, nil
+		}
+	}
+
+	// Read the generation from the store
+	val, err := cache.Options.Store.Get(ctx, cache.Options.KeyPrefix+ns)
+	var gen string
+	switch {
+	case errors.Is(err, gouache.ErrCacheMiss):
+		gen = "0"
+	case err != nil:
+		return "", err
+	default:
+		switch v := val.(type) {
+		case string:
+			gen = v
+		case []byte:
+			gen = string(v)
+		default:
+			return "", fmt.Errorf("generation: unexpected generation type %T for namespace %q", val, ns)
+		}
+	}
+
+	// Cache the generation in-process
+	if cache.Options.GenerationTTL > 0 {
+		cache.mu.Lock()
+		cache.generations[ns] = cachedGeneration{gen: gen, expiresAt: cache.Options.Clock.Now().Add(cache.Options.GenerationTTL)}
+		cache.mu.Unlock()
+	}
+	return gen, nil
+}
@@ -0,0 +1,139 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/clocktest"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestCache_Invalidate tests that Invalidate hides every key of a namespace
+func TestCache_Invalidate(t *testing.T) {
+	cache := New(&sample.Cache{})
+	ctx := context.Background()
+
+	for _, key := range []string{"user:1", "user:2", "order:1", "plain"} {
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	// Invalidate the user namespace
+	if err := cache.Invalidate(ctx, "user"); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+
+	// Keys of the invalidated namespace are gone
+	for _, key := range []string{"user:1", "user:2"} {
+		_, err := cache.Get(ctx, key)
+		if !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected gouache.ErrCacheMiss for %s, got %v", key, err)
+		}
+	}
+
+	// Other keys are untouched
+	for _, key := range []string{"order:1", "plain"} {
+		val, err := cache.Get(ctx, key)
+		if err != nil || val != key {
+			t.Errorf("Expected %s, got %v, %v", key, val, err)
+		}
+	}
+
+	// New writes to the namespace are visible
+	if err := cache.Set(ctx, "user:1", "new"); err != nil {
+		t.Fatalf("Failed to set user:1: %v", err)
+	}
+	val, err := cache.Get(ctx, "user:1")
+	if err != nil || val != "new" {
+		t.Errorf("Expected new, got %v, %v", val, err)
+	}
+}
+
+// TestCache_InvalidateFrozenClock tests that invalidations at the same
+// instant each start a new generation
+func TestCache_InvalidateFrozenClock(t *testing.T) {
+	cache := New(&sample.Cache{}, WithClock(clocktest.NewClock(time.Unix(1000, 0))))
+	ctx := context.Background()
+
+	if err := cache.Invalidate(ctx, "user"); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	if err := cache.Set(ctx, "user:1", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// The second invalidation hides the key written after the first
+	if err := cache.Invalidate(ctx, "user"); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	if _, err := cache.Get(ctx, "user:1"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+}
+
+// TestCache_GenerationTTL tests that generations are cached in-process
+func TestCache_GenerationTTL(t *testing.T) {
+	store := &sample.Cache{}
+	clock := clocktest.NewClock(time.Unix(1000, 0))
+	backend := &sample.Cache{}
+	cache := New(backend, WithStore(store), WithGenerationTTL(time.Minute), WithClock(clock))
+	other := New(backend, WithStore(store), WithClock(clock))
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "user:1", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// Another instance invalidates the namespace
+	clock.Advance(time.Second)
+	if err := other.Invalidate(ctx, "user"); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+
+	// The cached generation is still used
+	val, err := cache.Get(ctx, "user:1")
+	if err != nil || val != "value" {
+		t.Errorf("Expected value from the cached generation, got %v, %v", val, err)
+	}
+
+	// Once it expires, the new generation is read
+	clock.Advance(time.Minute)
+	_, err = cache.Get(ctx, "user:1")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+}
+
+// TestCache_Capabilities tests that batch operations are forwarded with the keys resolved
+func TestCache_Capabilities(t *testing.T) {
+	backend := &sample.Cache{}
+	cache := New(backend)
+	ctx := context.Background()
+
+	want := gouache.Capabilities(backend)
+	want.Iterate = false
+	if caps := gouache.Capabilities(cache); caps != want {
+		t.Errorf("Expected %+v, got %+v", want, caps)
+	}
+
+	if err := gouache.SetMulti(ctx, cache, map[string]any{"user:1": 1, "user:2": 2, "plain": 3}); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+	vals, err := gouache.GetMulti(ctx, cache, []string{"user:1", "user:2", "plain", "user:3"})
+	if err != nil || len(vals) != 3 || vals["user:1"] != 1 || vals["user:2"] != 2 || vals["plain"] != 3 {
+		t.Errorf("Expected user:1, user:2 and plain, got %v, %v", vals, err)
+	}
+
+	// Batch reads honor invalidation
+	if err := cache.Invalidate(ctx, "user"); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	vals, err = gouache.GetMulti(ctx, cache, []string{"user:1", "plain"})
+	if err != nil || len(vals) != 1 || vals["plain"] != 3 {
+		t.Errorf("Expected only plain, got %v, %v", vals, err)
+	}
+}
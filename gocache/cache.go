@@ -16,6 +16,15 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*Cache)(nil)
+
 // Cache is an implementation of gouache.Cache using go-cache as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // support for configurable time-to-live (TTL) settings.
@@ -24,8 +33,59 @@ type Cache struct {
 	Cache *gocache.Cache
 
 	// TTL is an optional function to determine the time-to-live duration for a cache entry.
-	// If not provided, the default expiration behavior of go-cache is used.
+	// If not provided, the default expiration behavior of go-cache is used and
+	// gouache.TTLMultiplier has no effect.
+	//
+	// A positive result is the TTL of the entry. Zero selects go-cache's
+	// default expiration, unless ZeroTTLMeansNoExpiry is set. NoExpiration, or
+	// any other negative result, stores the entry without expiration. A
+	// positive result is scaled by the context's gouache.TTLMultiplier, and
+	// any result but the default expiration is clamped to the context's
+	// gouache.TTLCeiling.
 	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+
+	// ZeroTTLMeansNoExpiry makes a zero result of the TTL function store the
+	// entry without expiration, as the other backends do, instead of with
+	// go-cache's default expiration.
+	ZeroTTLMeansNoExpiry bool
+}
+
+// NoExpiration is the TTL the TTL function returns for entries that must
+// never expire. It is the same value as go-cache's NoExpiration.
+const NoExpiration = gocache.NoExpiration
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithZeroTTLMeansNoExpiry returns an Option that sets whether a zero result
+// of the TTL function means no expiration rather than go-cache's default
+// expiration.
+//
+// Parameters:
+//   - enabled: Whether zero means no expiration
+//
+// Returns:
+//   - An Option function that sets ZeroTTLMeansNoExpiry
+func WithZeroTTLMeansNoExpiry(enabled bool) Option {
+	return func(cache *Cache) {
+		cache.ZeroTTLMeansNoExpiry = enabled
+	}
+}
+
+// New creates a new Cache backed by the specified go-cache instance.
+//
+// Parameters:
+//   - c: The go-cache instance used for storage
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache using c as its storage
+func New(c *gocache.Cache, opts ...Option) *Cache {
+	cache := &Cache{Cache: c}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	return cache
 }
 
 // Get retrieves a value from the cache by its key.
@@ -63,23 +123,110 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 // Returns:
 //   - An error if the TTL function (if configured) returns an error, otherwise nil
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
-	// Initialize TTL to default expiration value
-	ttl := gocache.DefaultExpiration
-
-	// Check if a custom TTL function is configured
-	if cache.TTL != nil {
-		// Use the TTL function to determine expiration duration
-		var err error
-		ttl, err = cache.TTL(ctx, key, val)
-		if err != nil {
-			return err
-		}
-		// Store the value with the computed TTL
-		cache.Cache.Set(key, val, ttl)
-		return nil
+	// Determine the expiration of the entry
+	ttl, err := cache.resolveTTL(ctx, key, val)
+	if err != nil {
+		return err
+	}
+
+	// Store the value with the computed TTL
+	cache.Cache.Set(key, val, ttl)
+	return nil
+}
+
+// Add stores a value under key only if the key is not already present or has
+// expired, using go-cache's atomic Add. The TTL is resolved as in Set.
+//
+// Parameters:
+//   - ctx: Context for the operation, passed to the TTL function if configured
+//   - key: The key under which the value will be stored
+//   - val: The value to store in the cache
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - An error if the TTL function (if configured) returns an error
+func (cache *Cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	// Determine the expiration of the entry
+	ttl, err := cache.resolveTTL(ctx, key, val)
+	if err != nil {
+		return false, err
+	}
+
+	// go-cache's Add only fails if the key already exists
+	return cache.Cache.Add(key, val, ttl) == nil, nil
+}
+
+// resolveTTL determines the go-cache expiration of an entry being stored.
+//
+// Parameters:
+//   - ctx: Context for the operation, passed to the TTL function if configured
+//   - key: The key under which the value will be stored
+//   - val: The value to store in the cache
+//
+// Returns:
+//   - The expiration to store the entry with, go-cache's default if TTL is nil
+//   - An error if the TTL function returns an error
+func (cache *Cache) resolveTTL(ctx context.Context, key string, val any) (time.Duration, error) {
+	// Without a TTL function, use go-cache's default expiration
+	if cache.TTL == nil {
+		return gocache.DefaultExpiration, nil
+	}
+
+	// Use the TTL function to determine expiration duration
+	ttl, err := cache.TTL(ctx, key, val)
+	if err != nil {
+		return 0, err
+	}
+	// Map zero to no expiration if configured
+	if ttl == gocache.DefaultExpiration && cache.ZeroTTLMeansNoExpiry {
+		ttl = NoExpiration
+	}
+	// Apply the per-request TTL multiplier and ceiling, if any. go-cache's
+	// default expiration is unknown here, so it is left unchanged.
+	if ttl != gocache.DefaultExpiration {
+		ttl = gouache.AdjustTTL(ctx, ttl)
+	}
+	return ttl, nil
+}
+
+// SetWithTTL stores a value in the cache under the specified key with an
+// explicit TTL, bypassing the TTL function and the TTL multiplier.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store in the cache
+//   - ttl: The time-to-live of the entry, following go-cache's expiration semantics
+//
+// Returns:
+//   - Always returns nil as go-cache.Set doesn't return errors
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	// Store the value with the given TTL
+	cache.Cache.Set(key, val, ttl)
+	return nil
+}
+
+// Touch sets a new time-to-live for the entry stored under key.
+//
+// go-cache cannot update an expiry in place, so the value is read and stored
+// again with the new TTL. The two steps are not atomic: a concurrent Set
+// between them may be overwritten with the previously read value.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live, following go-cache's expiration semantics
+//
+// Returns:
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	// Read the current value
+	val, ok := cache.Cache.Get(key)
+	if !ok {
+		return gouache.ErrCacheMiss
 	}
 
-	// Store the value with default expiration
+	// Store it again with the new TTL
 	cache.Cache.Set(key, val, ttl)
 	return nil
 }
@@ -3,10 +3,12 @@ package gc
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
 	"github.com/patrickmn/go-cache"
 )
 
@@ -183,3 +185,156 @@ func TestCache_TTLWithError(t *testing.T) {
 		t.Errorf("Expected TTL error, got %v", err)
 	}
 }
+
+// TestCache_TTLMultiplier tests that the context TTL multiplier scales the resolved TTL
+func TestCache_TTLMultiplier(t *testing.T) {
+	goCache := cache.New(5*time.Minute, 10*time.Minute)
+
+	cacheImpl := &Cache{
+		Cache: goCache,
+		TTL: func(ctx context.Context, key string, val any) (time.Duration, error) {
+			return 10 * time.Second, nil
+		},
+	}
+
+	// Set a value with a doubled TTL
+	ctx := gouache.WithTTLMultiplier(context.Background(), 2)
+	start := time.Now()
+	err := cacheImpl.Set(ctx, "test-key", "test-value")
+	if err != nil {
+		t.Errorf("Failed to set value: %v", err)
+	}
+
+	// The expiration should be about 20 seconds from now
+	_, expiration, ok := goCache.GetWithExpiration("test-key")
+	if !ok {
+		t.Fatal("Expected key to be present")
+	}
+	remaining := expiration.Sub(start)
+	if remaining < 19*time.Second || remaining > 21*time.Second {
+		t.Errorf("Expected expiration about 20s away, got %v", remaining)
+	}
+}
+
+// TestCache_Touch tests extending the TTL of an entry
+func TestCache_Touch(t *testing.T) {
+	goCache := cache.New(5*time.Minute, 10*time.Minute)
+
+	cacheImpl := &Cache{
+		Cache: goCache,
+	}
+
+	ctx := context.Background()
+	err := cacheImpl.SetWithTTL(ctx, "test-key", "test-value", time.Second)
+	if err != nil {
+		t.Errorf("Failed to set value: %v", err)
+	}
+
+	// Test Touch extends the expiration
+	err = cacheImpl.Touch(ctx, "test-key", time.Hour)
+	if err != nil {
+		t.Errorf("Failed to touch value: %v", err)
+	}
+	val, expiration, ok := goCache.GetWithExpiration("test-key")
+	if !ok || val != "test-value" {
+		t.Fatalf("Expected test-value to be present, got %v", val)
+	}
+	if time.Until(expiration) < 59*time.Minute {
+		t.Errorf("Expected expiration about 1h away, got %v", time.Until(expiration))
+	}
+
+	// Test Touch on a missing key
+	err = cacheImpl.Touch(ctx, "non-existent-key", time.Hour)
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+}
+
+// TestCache_TTLResults tests how zero, negative and positive TTL function results are stored
+func TestCache_TTLResults(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		zeroNoExp  bool
+		expiration time.Duration // zero meaning no expiration
+	}{
+		{name: "Positive", ttl: time.Minute, expiration: time.Minute},
+		{name: "Zero uses the default expiration", ttl: 0, expiration: 5 * time.Minute},
+		{name: "Zero means no expiration", ttl: 0, zeroNoExp: true},
+		{name: "NoExpiration", ttl: NoExpiration},
+		{name: "Negative", ttl: -time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goCache := cache.New(5*time.Minute, 10*time.Minute)
+			cacheImpl := New(goCache, WithZeroTTLMeansNoExpiry(tt.zeroNoExp))
+			cacheImpl.TTL = func(ctx context.Context, key string, val any) (time.Duration, error) {
+				return tt.ttl, nil
+			}
+
+			start := time.Now()
+			if err := cacheImpl.Set(context.Background(), "test-key", "test-value"); err != nil {
+				t.Fatalf("Failed to set value: %v", err)
+			}
+			_, expiration, ok := goCache.GetWithExpiration("test-key")
+			if !ok {
+				t.Fatal("Expected key to be present")
+			}
+			if tt.expiration == 0 {
+				if !expiration.IsZero() {
+					t.Errorf("Expected no expiration, got %v", expiration)
+				}
+				return
+			}
+			remaining := expiration.Sub(start)
+			if remaining < tt.expiration-time.Second || remaining > tt.expiration+time.Second {
+				t.Errorf("Expected expiration about %v away, got %v", tt.expiration, remaining)
+			}
+		})
+	}
+}
+
+// TestCache_AddConcurrent tests that exactly one of many concurrent Adds of a key succeeds
+func TestCache_AddConcurrent(t *testing.T) {
+	cache := New(cache.New(time.Minute, time.Minute))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []int
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := cache.Add(ctx, "singleton", i)
+			if err != nil {
+				t.Errorf("Failed to add value: %v", err)
+			}
+			if ok {
+				mu.Lock()
+				winners = append(winners, i)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(winners) != 1 {
+		t.Fatalf("Expected exactly one Add to succeed, got %v", winners)
+	}
+	if val, err := cache.Get(ctx, "singleton"); err != nil || val != winners[0] {
+		t.Errorf("Expected the winner's value %d, got %v, %v", winners[0], val, err)
+	}
+
+	// A later Add leaves the value alone
+	if ok, _ := cache.Add(ctx, "singleton", -1); ok {
+		t.Error("Expected Add of an existing key to fail")
+	}
+}
+
+// TestCache_CacheMissContract tests that misses are reported as gouache.ErrCacheMiss
+func TestCache_CacheMissContract(t *testing.T) {
+	c := &Cache{Cache: cache.New(5*time.Minute, 10*time.Minute)}
+	cachetest.AssertCacheMissContract(t, c)
+}
@@ -0,0 +1,108 @@
+// Package key provides helpers to build cache keys from multiple parts.
+//
+// Keys built by hand with string concatenation are easy to get wrong: a part
+// containing the separator makes two different sets of parts produce the same
+// key. Builder escapes separators in parts and bounds the key length, so its
+// keys can be used safely with any gouache.Cache.
+package key
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+)
+
+// escape is the character used to escape separators and itself in parts.
+const escape = `\`
+
+// hashLength is the number of hex characters of the hash appended to
+// truncated keys.
+const hashLength = 32
+
+// Builder joins key parts with a separator, escaping the separator in parts
+// and shortening keys longer than MaxLength.
+//
+// The zero value joins parts with ':' and never truncates.
+type Builder struct {
+	// Separator is placed between parts. If empty, ":" is used.
+	Separator string
+
+	// MaxLength is the maximum length of a key in bytes. Longer keys are
+	// truncated and their tail replaced by a hash of the whole key, so they
+	// stay distinct. If not positive, keys are never truncated.
+	MaxLength int
+}
+
+// Build joins parts into a key.
+//
+// Occurrences of the separator and of the escape character '\' in a part are
+// prefixed with '\', so distinct parts always produce distinct keys. If the
+// result is longer than MaxLength, it is cut at a character boundary and
+// completed with '#' and a hash of the full key, so it is at most MaxLength
+// bytes long. When MaxLength leaves no room for a prefix, the key is the hash
+// alone, cut to MaxLength.
+//
+// Parameters:
+//   - parts: The parts of the key
+//
+// Returns:
+//   - The key
+func (b Builder) Build(parts ...string) string {
+	sep := b.Separator
+	if sep == "" {
+		sep = ":"
+	}
+
+	// Escape the escape character first, then the separator
+	replacer := strings.NewReplacer(escape, escape+escape, sep, escape+sep)
+	var builder strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			builder.WriteString(sep)
+		}
+		builder.WriteString(replacer.Replace(part))
+	}
+	key := builder.String()
+
+	// Return short enough keys unchanged
+	if b.MaxLength <= 0 || len(key) <= b.MaxLength {
+		return key
+	}
+	return truncate(key, b.MaxLength)
+}
+
+// truncate shortens key to at most max bytes, replacing its tail with a hash
+// of the full key.
+//
+// Parameters:
+//   - key: The key to shorten
+//   - max: The maximum length in bytes
+//
+// Returns:
+//   - The shortened key
+func truncate(key string, max int) string {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])[:hashLength]
+
+	// Without room for a prefix, the hash alone stands for the key
+	suffix := "#" + hash
+	if max <= len(suffix) {
+		return hash[:min(max, len(hash))]
+	}
+
+	// Cut the prefix at a character boundary
+	cut := max - len(suffix)
+	for cut > 0 && !utf8.RuneStart(key[cut]) {
+		cut--
+	}
+	return key[:cut] + suffix
+}
+
+// min returns the smaller of a and b.
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
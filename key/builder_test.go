@@ -0,0 +1,89 @@
+package key
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestBuilder_Build tests joining and escaping parts
+func TestBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder Builder
+		parts   []string
+		want    string
+	}{
+		{name: "Default separator", parts: []string{"user", "42"}, want: "user:42"},
+		{name: "Custom separator", builder: Builder{Separator: "/"}, parts: []string{"user", "42"}, want: "user/42"},
+		{name: "Escaped separator", parts: []string{"a:b", "c"}, want: `a\:b:c`},
+		{name: "Escaped escape", parts: []string{`a\`, "b"}, want: `a\\:b`},
+		{name: "Empty parts", parts: []string{"", ""}, want: ":"},
+		{name: "No parts", parts: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.builder.Build(tt.parts...)
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestBuilder_NoCollisions tests that parts containing separators don't collide
+func TestBuilder_NoCollisions(t *testing.T) {
+	var b Builder
+	sets := [][]string{
+		{"a:b", "c"},
+		{"a", "b:c"},
+		{"a", "b", "c"},
+		{`a\`, "b"},
+		{`a\:b`},
+	}
+
+	seen := make(map[string][]string)
+	for _, parts := range sets {
+		key := b.Build(parts...)
+		if other, ok := seen[key]; ok {
+			t.Errorf("Expected distinct keys, got %q for both %q and %q", key, parts, other)
+		}
+		seen[key] = parts
+	}
+}
+
+// TestBuilder_MaxLength tests truncating long keys
+func TestBuilder_MaxLength(t *testing.T) {
+	b := Builder{MaxLength: 50}
+
+	// Short keys are unchanged
+	if got := b.Build("user", "42"); got != "user:42" {
+		t.Errorf("Expected user:42, got %q", got)
+	}
+
+	// Long keys are truncated, keep their prefix and stay distinct
+	long1 := b.Build("user", strings.Repeat("x", 100))
+	long2 := b.Build("user", strings.Repeat("x", 99)+"y")
+	if len(long1) != 50 || len(long2) != 50 {
+		t.Errorf("Expected 50 bytes, got %d and %d", len(long1), len(long2))
+	}
+	if !strings.HasPrefix(long1, "user:xxx") {
+		t.Errorf("Expected the key prefix to be kept, got %q", long1)
+	}
+	if long1 == long2 {
+		t.Errorf("Expected distinct truncated keys, got %q", long1)
+	}
+
+	// Truncation doesn't split multi-byte characters
+	got := Builder{MaxLength: 40}.Build(strings.Repeat("é", 30))
+	if len(got) > 40 || !utf8.ValidString(got) {
+		t.Errorf("Expected a valid key of at most 40 bytes, got %q", got)
+	}
+
+	// A tiny limit yields the hash alone
+	got = Builder{MaxLength: 8}.Build(strings.Repeat("x", 100))
+	if len(got) != 8 {
+		t.Errorf("Expected 8 bytes, got %q", got)
+	}
+}
@@ -0,0 +1,446 @@
+// Package keymutex provides a cache implementation that serializes operations
+// on the same key while letting operations on different keys run concurrently.
+//
+// This package implements the gouache.Cache interface by guarding every
+// operation with a per-key read/write lock taken from a fixed set of striped
+// locks. Reads of a key share its read lock, while Set, Delete and the other
+// writes take its write lock, so a Set and a Delete of the same key can never
+// interleave inside the wrapped cache. Keys that hash to the same stripe share
+// a lock, which bounds memory at the cost of occasional false contention.
+//
+// Do runs a read-modify-write sequence on the wrapped cache under a key's
+// write lock, which makes it a building block for compare-and-set style
+// updates and for delayed double delete flows that must not race a Set.
+package keymutex
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchSetter interface at compile time.
+var _ gouache.BatchSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Iterable interface at compile time.
+var _ gouache.Iterable = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.CompareDeleter interface at compile time.
+var _ gouache.CompareDeleter = (*Cache)(nil)
+
+// Ensure that Cache forwards capability probing at compile time.
+var _ gouache.CapabilityReporter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*Cache)(nil)
+
+// options holds configuration options for the per-key locking cache.
+type options struct {
+	// Stripes is the number of locks keys are distributed over.
+	Stripes int
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithStripes returns an Option that sets the number of locks keys are
+// distributed over. More stripes reduce false contention between unrelated
+// keys at the cost of memory.
+//
+// Parameters:
+//   - n: The number of stripes; non-positive values use the default of 256
+//
+// Returns:
+//   - An Option function that sets the Stripes
+func WithStripes(n int) Option {
+	return func(o *options) {
+		o.Stripes = n
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default stripe count to 256 if not specified or invalid
+	if o.Stripes <= 0 {
+		o.Stripes = 256
+	}
+	return o
+}
+
+// Cache is a cache implementation that guards the operations on each key of
+// the underlying cache with a striped read/write lock.
+type Cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Forwarder holds the underlying cache and forwards the operations the
+	// cache doesn't intercept to it
+	gouache.Forwarder
+
+	// stripes holds the locks keys are distributed over
+	stripes []sync.RWMutex
+}
+
+// New creates a new per-key locking cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache that serializes operations on the same key
+func New(c gouache.Cache, opts ...Option) *Cache {
+	o := newOptions(opts...)
+	return &Cache{Options: o, Forwarder: gouache.Forwarder{Cache: c}, stripes: make([]sync.RWMutex, o.Stripes)}
+}
+
+// stripe returns the index of the lock guarding a key.
+//
+// Parameters:
+//   - key: The key
+//
+// Returns:
+//   - The index of the key's stripe
+func (cache *Cache) stripe(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(cache.stripes)))
+}
+
+// lockKeys takes the write locks, or the read locks if read is true, of the
+// stripes of all given keys. Each stripe is locked once, in ascending order,
+// so that concurrent multi-key operations can't deadlock.
+//
+// Parameters:
+//   - keys: The keys to lock
+//   - read: Whether to take the read locks instead of the write locks
+//
+// Returns:
+//   - A function that releases the locks
+func (cache *Cache) lockKeys(keys []string, read bool) func() {
+	// Collect the distinct stripes in ascending order
+	seen := make(map[int]struct{}, len(keys))
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		i := cache.stripe(key)
+		if _, ok := seen[i]; ok {
+			continue
+		}
+		seen[i] = struct{}{}
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	// Lock the stripes in order
+	for _, i := range indexes {
+		if read {
+			cache.stripes[i].RLock()
+		} else {
+			cache.stripes[i].Lock()
+		}
+	}
+	return func() {
+		for _, i := range indexes {
+			if read {
+				cache.stripes[i].RUnlock()
+			} else {
+				cache.stripes[i].Unlock()
+			}
+		}
+	}
+}
+
+// Get retrieves a value from the cache by its key while holding the key's
+// read lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.RLock()
+	defer mu.RUnlock()
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set stores a value in the cache under the specified key while holding the
+// key's write lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.Lock()
+	defer mu.Unlock()
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the cache by its key while holding the key's
+// write lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.Lock()
+	defer mu.Unlock()
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Do calls fn while holding the write lock of key, so that no other operation
+// on the key through this cache runs until fn returns. fn receives the
+// underlying cache and must use it rather than this cache, whose locks are not
+// reentrant.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to lock
+//   - fn: The function to call with the underlying cache
+//
+// Returns:
+//   - The error returned by fn
+func (cache *Cache) Do(ctx context.Context, key string, fn func(ctx context.Context, c gouache.Cache) error) error {
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.Lock()
+	defer mu.Unlock()
+	return fn(ctx, cache.Cache)
+}
+
+// GetMulti retrieves the values for the given keys while holding their read
+// locks, using the native GetMulti of the underlying cache when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - An error if the operation fails
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	defer cache.lockKeys(keys, true)()
+	return gouache.GetMulti(ctx, cache.Cache, keys)
+}
+
+// SetMulti stores each value in vals while holding the write locks of their
+// keys, using the native SetMulti of the underlying cache when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	keys := make([]string, 0, len(vals))
+	for key := range vals {
+		keys = append(keys, key)
+	}
+	defer cache.lockKeys(keys, false)()
+	return gouache.SetMulti(ctx, cache.Cache, vals)
+}
+
+// DeleteMulti removes the values of the given keys while holding their write
+// locks, using the native DeleteMulti of the underlying cache when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	defer cache.lockKeys(keys, false)()
+	return gouache.DeleteMulti(ctx, cache.Cache, keys)
+}
+
+// DeleteMultiResult removes the values of the given keys while holding their
+// write locks and reports which of them existed, using the native
+// DeleteMultiResult of the underlying cache when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - An error if the operation fails
+func (cache *Cache) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	defer cache.lockKeys(keys, false)()
+	return gouache.DeleteMultiResult(ctx, cache.Cache, keys)
+}
+
+// Add stores a value only if the key is absent, while holding the key's write lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - gouache.ErrUnsupported if the underlying cache isn't an Adder, or an error if the operation fails
+func (cache *Cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	adder, ok := cache.Cache.(gouache.Adder)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.Lock()
+	defer mu.Unlock()
+	return adder.Add(ctx, key, val)
+}
+
+// SetWithTTL stores a value with an explicit TTL while holding the key's write lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLSetter, or an error if the operation fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	setter, ok := cache.Cache.(gouache.TTLSetter)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.Lock()
+	defer mu.Unlock()
+	return setter.SetWithTTL(ctx, key, val, ttl)
+}
+
+// ReadTTL returns the remaining time-to-live of an entry while holding the
+// key's read lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//
+// Returns:
+//   - The remaining time-to-live
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLReader, or an error if the operation fails
+func (cache *Cache) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	reader, ok := cache.Cache.(gouache.TTLReader)
+	if !ok {
+		return 0, gouache.ErrUnsupported
+	}
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.RLock()
+	defer mu.RUnlock()
+	return reader.ReadTTL(ctx, key)
+}
+
+// Touch sets a new time-to-live for an entry while holding the key's write lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a Toucher, or an error if the operation fails
+func (cache *Cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	toucher, ok := cache.Cache.(gouache.Toucher)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.Lock()
+	defer mu.Unlock()
+	return toucher.Touch(ctx, key, ttl)
+}
+
+// CompareAndDelete deletes the entry stored under key if it still holds
+// expected, while holding the key's write lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - expected: The value the entry must hold to be deleted
+//
+// Returns:
+//   - true if the entry was deleted, false if it was missing or held another value
+//   - gouache.ErrUnsupported if the underlying cache isn't a CompareDeleter, or an error if the operation fails
+func (cache *Cache) CompareAndDelete(ctx context.Context, key string, expected any) (bool, error) {
+	deleter, ok := cache.Cache.(gouache.CompareDeleter)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	mu := &cache.stripes[cache.stripe(key)]
+	mu.Lock()
+	defer mu.Unlock()
+	return deleter.CompareAndDelete(ctx, key, expected)
+}
@@ -0,0 +1,212 @@
+package keymutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// trackingCache wraps a sample.Cache and records overlapping operations on the
+// same key: a write that overlaps any other operation on its key is counted as
+// an interleaving.
+type trackingCache struct {
+	sample.Cache
+	mu           sync.Mutex
+	readers      map[string]int
+	writers      map[string]int
+	interleaved  int32
+	writeWait    time.Duration
+	activeWrites int32
+	peakWrites   int32
+}
+
+// newTrackingCache creates a new trackingCache instance.
+func newTrackingCache() *trackingCache {
+	return &trackingCache{readers: make(map[string]int), writers: make(map[string]int)}
+}
+
+// enter records an operation on key entering the cache and returns a function to leave it.
+func (t *trackingCache) enter(key string, write bool) func() {
+	t.mu.Lock()
+	if t.writers[key] > 0 || (write && t.readers[key] > 0) {
+		atomic.AddInt32(&t.interleaved, 1)
+	}
+	if write {
+		t.writers[key]++
+	} else {
+		t.readers[key]++
+	}
+	t.mu.Unlock()
+
+	if write {
+		active := atomic.AddInt32(&t.activeWrites, 1)
+		for {
+			peak := atomic.LoadInt32(&t.peakWrites)
+			if active <= peak || atomic.CompareAndSwapInt32(&t.peakWrites, peak, active) {
+				break
+			}
+		}
+		time.Sleep(t.writeWait)
+	}
+	return func() {
+		if write {
+			atomic.AddInt32(&t.activeWrites, -1)
+		}
+		t.mu.Lock()
+		if write {
+			t.writers[key]--
+		} else {
+			t.readers[key]--
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *trackingCache) Get(ctx context.Context, key string) (any, error) {
+	defer t.enter(key, false)()
+	return t.Cache.Get(ctx, key)
+}
+
+func (t *trackingCache) Set(ctx context.Context, key string, val any) error {
+	defer t.enter(key, true)()
+	return t.Cache.Set(ctx, key, val)
+}
+
+func (t *trackingCache) Delete(ctx context.Context, key string) error {
+	defer t.enter(key, true)()
+	return t.Cache.Delete(ctx, key)
+}
+
+// TestCache_NoInterleaving tests that concurrent Set, Delete and Get on one key never interleave
+func TestCache_NoInterleaving(t *testing.T) {
+	ctx := context.Background()
+	backend := newTrackingCache()
+	backend.writeWait = 100 * time.Microsecond
+	cache := New(backend)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				switch (i + j) % 3 {
+				case 0:
+					_ = cache.Set(ctx, "key", i)
+				case 1:
+					_ = cache.Delete(ctx, "key")
+				default:
+					_, _ = cache.Get(ctx, "key")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&backend.interleaved); n != 0 {
+		t.Fatalf("expected no interleaving on a single key, got %d", n)
+	}
+}
+
+// TestCache_DifferentKeysConcurrent tests that keys on different stripes are written concurrently
+func TestCache_DifferentKeysConcurrent(t *testing.T) {
+	ctx := context.Background()
+	backend := newTrackingCache()
+	backend.writeWait = 20 * time.Millisecond
+	cache := New(backend)
+
+	// Pick two keys that land on different stripes
+	a := "a"
+	b := ""
+	for i := 0; ; i++ {
+		b = fmt.Sprintf("b%d", i)
+		if cache.stripe(a) != cache.stripe(b) {
+			break
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range []string{a, b} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_ = cache.Set(ctx, key, key)
+		}(key)
+	}
+	wg.Wait()
+
+	if peak := atomic.LoadInt32(&backend.peakWrites); peak != 2 {
+		t.Fatalf("expected writes to different keys to overlap, peak %d", peak)
+	}
+}
+
+// TestCache_Do tests that Do runs a read-modify-write under the write lock
+func TestCache_Do(t *testing.T) {
+	ctx := context.Background()
+	cache := New(&sample.Cache{}, WithStripes(1))
+	if err := cache.Set(ctx, "counter", 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := cache.Do(ctx, "counter", func(ctx context.Context, c gouache.Cache) error {
+				val, err := c.Get(ctx, "counter")
+				if err != nil {
+					return err
+				}
+				return c.Set(ctx, "counter", val.(int)+1)
+			})
+			if err != nil {
+				t.Errorf("Do failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	val, err := cache.Get(ctx, "counter")
+	if err != nil || val != 50 {
+		t.Fatalf("expected 50, got %v, %v", val, err)
+	}
+}
+
+// TestCache_Multi tests that multi-key operations lock every key without deadlocking
+func TestCache_Multi(t *testing.T) {
+	ctx := context.Background()
+	cache := New(&sample.Cache{}, WithStripes(4))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				vals := map[string]any{"a": i, "b": i, "c": i, "d": i, "e": i}
+				if err := cache.SetMulti(ctx, vals); err != nil {
+					t.Errorf("SetMulti failed: %v", err)
+				}
+				if _, err := cache.GetMulti(ctx, []string{"e", "d", "c", "b", "a"}); err != nil {
+					t.Errorf("GetMulti failed: %v", err)
+				}
+				if err := cache.DeleteMulti(ctx, []string{"c", "a"}); err != nil {
+					t.Errorf("DeleteMulti failed: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if _, err := cache.Get(ctx, "a"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Fatalf("expected a to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,785 @@
+// Package loading provides a read-through cache implementation that fills
+// missing entries from a loader function, GroupCache style.
+//
+// This package implements the gouache.Cache interface by wrapping a backend
+// cache. Get serves values from the backend, and on a miss calls the loader
+// exactly once per key no matter how many goroutines are waiting for it, stores
+// the result in the backend and returns it to every waiter. Keys the loader
+// reports as missing can be remembered for a short time to shield the source
+// from repeated lookups of keys that do not exist.
+//
+// Concurrent loads are shared with a flight.Group, so the loader runs with a
+// merged context that keeps the values of the first caller but is canceled
+// only once every caller waiting for the load has given up.
+package loading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/flight"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// ErrTooManyLoads is returned by Get when the maximum number of concurrent
+// loads is reached and WithLoadFailFast is enabled.
+var ErrTooManyLoads = errors.New("gouache: too many concurrent loads")
+
+// Loader is a function type that loads the value for a key from the source of
+// truth, such as a database. It returns gouache.ErrCacheMiss if the key does
+// not exist.
+type Loader func(ctx context.Context, key string) (any, error)
+
+// BatchLoader is a function type that loads the values for several keys from
+// the source of truth in one call. Keys that do not exist are omitted from
+// the returned map.
+type BatchLoader func(ctx context.Context, keys []string) (map[string]any, error)
+
+// options holds configuration options for the loading cache.
+type options struct {
+	// TTL is the time-to-live of loaded values stored in the backend.
+	TTL time.Duration
+
+	// NegativeTTL is how long a key reported missing by the loader is
+	// remembered as missing.
+	NegativeTTL time.Duration
+
+	// MaxConcurrentLoads is the maximum number of loader calls running at
+	// the same time, or zero for no limit.
+	MaxConcurrentLoads int
+
+	// LoadFailFast makes Get fail with ErrTooManyLoads instead of waiting
+	// when MaxConcurrentLoads is reached.
+	LoadFailFast bool
+
+	// Clock is used to expire negative entries and to age loaded values.
+	Clock gouache.Clock
+
+	// RefreshAfter is how long a loaded value stays fresh before Get reloads it.
+	RefreshAfter time.Duration
+
+	// ServeStaleOnError makes Get return the stale value when reloading it fails.
+	ServeStaleOnError bool
+
+	// BatchLoader loads the keys GetMulti misses in one call.
+	BatchLoader BatchLoader
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithTTL returns an Option that sets the time-to-live of loaded values.
+//
+// The TTL is only applied when the backend implements gouache.TTLSetter;
+// otherwise loaded values are stored with the backend's own TTL behavior.
+//
+// Parameters:
+//   - ttl: The time-to-live of loaded values
+//
+// Returns:
+//   - An Option function that sets the TTL
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.TTL = ttl
+	}
+}
+
+// WithNegativeCache returns an Option that remembers keys the loader reported
+// as missing for the given duration, during which Get returns
+// gouache.ErrCacheMiss without calling the loader again.
+//
+// Negative entries are kept in process memory, not in the backend.
+//
+// Parameters:
+//   - ttl: How long a missing key is remembered, or zero to disable
+//
+// Returns:
+//   - An Option function that sets the NegativeTTL
+func WithNegativeCache(ttl time.Duration) Option {
+	return func(o *options) {
+		o.NegativeTTL = ttl
+	}
+}
+
+// WithMaxConcurrentLoads returns an Option that limits the number of loader
+// calls running at the same time across all keys. Gets that miss while the
+// limit is reached wait for a free slot, or for their context to be done.
+// Cache hits and remembered misses never wait.
+//
+// Parameters:
+//   - n: The maximum number of concurrent loader calls, or zero for no limit
+//
+// Returns:
+//   - An Option function that sets MaxConcurrentLoads
+func WithMaxConcurrentLoads(n int) Option {
+	return func(o *options) {
+		o.MaxConcurrentLoads = n
+	}
+}
+
+// WithLoadFailFast returns an Option that makes Get return ErrTooManyLoads
+// immediately, instead of waiting, when the WithMaxConcurrentLoads limit is
+// reached.
+//
+// Parameters:
+//   - failFast: Whether to fail instead of waiting for a free load slot
+//
+// Returns:
+//   - An Option function that sets LoadFailFast
+func WithLoadFailFast(failFast bool) Option {
+	return func(o *options) {
+		o.LoadFailFast = failFast
+	}
+}
+
+// WithClock returns an Option that sets the clock used to expire negative
+// entries and to age loaded values.
+// It defaults to gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// WithBatchLoader returns an Option that sets the function GetMulti uses to
+// load all the keys missing from the backend in a single call. Without it,
+// GetMulti loads missing keys one at a time with the Loader.
+//
+// Parameters:
+//   - loader: The function loading several keys at once
+//
+// Returns:
+//   - An Option function that sets the BatchLoader
+func WithBatchLoader(loader BatchLoader) Option {
+	return func(o *options) {
+		o.BatchLoader = loader
+	}
+}
+
+// WithRefreshAfter returns an Option that sets how long a value stays fresh
+// after it was loaded or Set through the cache. A Get that finds an older
+// value in the backend reloads it, once for all concurrent callers, and stores
+// and returns the new value. This separates the logical freshness of a value
+// from the hard TTL of the backend, which bounds how long a stale value can
+// still be found; see WithServeStaleOnError.
+//
+// Load times are kept in process memory, so values found in the backend that
+// weren't loaded or Set by this cache are treated as fresh. Without a TTL, a
+// load time is kept until the key is deleted through the cache.
+//
+// Parameters:
+//   - d: How long a value stays fresh, or zero to never reload values found in the backend
+//
+// Returns:
+//   - An Option function that sets RefreshAfter
+func WithRefreshAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.RefreshAfter = d
+	}
+}
+
+// WithServeStaleOnError returns an Option that makes Get return the stale
+// value instead of the error when reloading a value older than RefreshAfter
+// fails, so that an outage of the source degrades to stale reads rather than
+// failed ones. The reload is retried by the next Get.
+//
+// A stale value is served for as long as the backend keeps it, that is until
+// its TTL expires or it is evicted, so with WithTTL values are at most TTL old
+// when served. It has no effect without WithRefreshAfter, and a key the loader
+// reports as missing is deleted rather than served stale.
+//
+// Parameters:
+//   - enabled: Whether stale values are served when reloading fails
+//
+// Returns:
+//   - An Option function that sets ServeStaleOnError
+func WithServeStaleOnError(enabled bool) Option {
+	return func(o *options) {
+		o.ServeStaleOnError = enabled
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+	return o
+}
+
+// cache is a read-through cache implementation that fills misses from a
+// Loader, deduplicating concurrent loads of the same key.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the backend cache implementation
+	Cache gouache.Cache
+
+	// Loader loads values missing from the backend
+	Loader Loader
+
+	// group deduplicates concurrent loads.
+	group flight.Group
+
+	// mu guards negatives, lastSweep, loadedAt and lastLoadedSweep.
+	mu sync.Mutex
+
+	// negatives maps keys reported missing by the loader to the time the
+	// negative entry expires.
+	negatives map[string]time.Time
+
+	// lastSweep is the last time expired negative entries were removed.
+	lastSweep time.Time
+
+	// loadedAt maps keys loaded or Set through the cache to the time their
+	// value was stored, when RefreshAfter is set.
+	loadedAt map[string]time.Time
+
+	// lastLoadedSweep is the last time outdated load times were removed.
+	lastLoadedSweep time.Time
+
+	// slots is a semaphore limiting concurrent loader calls, or nil for no limit.
+	slots chan struct{}
+}
+
+// New creates a new loading cache instance with the specified backend,
+// loader, and options.
+//
+// Parameters:
+//   - backend: The cache implementation that stores loaded values
+//   - loader: The function that loads values missing from the backend
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation that fills misses from the loader
+func New(backend gouache.Cache, loader Loader, opts ...Option) gouache.Cache {
+	options := newOptions(opts...)
+	cache := &cache{
+		Options:   options,
+		Cache:     backend,
+		Loader:    loader,
+		negatives: make(map[string]time.Time),
+		loadedAt:  make(map[string]time.Time),
+	}
+	// Create the load semaphore if concurrency is limited
+	if options.MaxConcurrentLoads > 0 {
+		cache.slots = make(chan struct{}, options.MaxConcurrentLoads)
+	}
+	return cache
+}
+
+// Get retrieves a value from the backend by its key. On a miss, the loader is
+// called once per key across all concurrent callers, and its result is stored
+// in the backend and returned.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached or loaded value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if the loader reports the key missing
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	// Try to get the value from the backend first
+	val, err := cache.Cache.Get(ctx, key)
+	if err == nil && cache.isStale(key) {
+		return cache.refresh(ctx, key, val)
+	}
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		return val, err
+	}
+
+	// Skip the loader for keys recently reported missing
+	if cache.isNegative(key) {
+		return nil, gouache.ErrCacheMiss
+	}
+
+	// Load the value once for all concurrent callers
+	val, err, _ = cache.group.Do(ctx, key, func(ctx context.Context) (any, error) {
+		return cache.load(ctx, key)
+	})
+	return val, err
+}
+
+// GetMulti retrieves the values for the given keys from the backend with
+// gouache.GetMulti, then loads the keys it misses. With a BatchLoader, all
+// misses are loaded in one call, shared by concurrent GetMultis missing the
+// same set of keys; without one, each miss is loaded as by Get. Loaded values
+// are stored in the backend. Stale values found in the backend are reloaded
+// one by one as by Get, honouring RefreshAfter and ServeStaleOnError.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found or loaded key to its value
+//   - An error if reading, loading or storing fails
+func (cache *cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	// Read what the backend has
+	vals, err := gouache.GetMulti(ctx, cache.Cache, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the distinct misses not remembered as missing, refreshing stale hits
+	seen := make(map[string]struct{}, len(keys))
+	var missing []string
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		if stale, ok := vals[key]; ok {
+			if !cache.isStale(key) {
+				continue
+			}
+			val, err := cache.refresh(ctx, key, stale)
+			if errors.Is(err, gouache.ErrCacheMiss) {
+				delete(vals, key)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			vals[key] = val
+			continue
+		}
+		if !cache.isNegative(key) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return vals, nil
+	}
+
+	// Without a batch loader, load each miss as Get does
+	if cache.Options.BatchLoader == nil {
+		for _, key := range missing {
+			val, err, _ := cache.group.Do(ctx, key, func(ctx context.Context) (any, error) {
+				return cache.load(ctx, key)
+			})
+			if errors.Is(err, gouache.ErrCacheMiss) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			vals[key] = val
+		}
+		return vals, nil
+	}
+
+	// Load all misses once for every caller missing the same set
+	sort.Strings(missing)
+	loaded, err, _ := cache.group.Do(ctx, "\x00batch\x00"+strings.Join(missing, "\x00"), func(ctx context.Context) (any, error) {
+		return cache.loadMulti(ctx, missing)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range loaded.(map[string]any) {
+		vals[key] = val
+	}
+	return vals, nil
+}
+
+// Set stores a value in the backend under the specified key and forgets any
+// negative entry for the key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	cache.forgetNegative(key)
+	if err := cache.Cache.Set(ctx, key, val); err != nil {
+		return err
+	}
+	cache.markLoaded(key)
+	return nil
+}
+
+// Delete removes a value from the backend by its key and forgets any negative
+// entry for the key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	cache.forgetNegative(key)
+	cache.forgetLoaded(key)
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// load calls the loader for a key and stores the result in the backend.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to load the value for
+//
+// Returns:
+//   - The loaded value
+//   - An error if loading or storing fails, or gouache.ErrCacheMiss if the key doesn't exist
+func (cache *cache) load(ctx context.Context, key string) (any, error) {
+	// Wait for a free load slot, if concurrency is limited
+	release, err := cache.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Load the value from the source
+	val, err := cache.Loader(ctx, key)
+	if errors.Is(err, gouache.ErrCacheMiss) {
+		cache.storeNegative(key)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the loaded value, with an explicit TTL if the backend supports it
+	if setter, ok := cache.ttlSetter(); ok && cache.Options.TTL > 0 {
+		err = setter.SetWithTTL(ctx, key, val, cache.Options.TTL)
+	} else {
+		err = cache.Cache.Set(ctx, key, val)
+	}
+	if err == nil {
+		cache.markLoaded(key)
+	}
+	return val, err
+}
+
+// refresh reloads a stale value found in the backend, once for all
+// concurrent callers. If reloading fails, the stale value is returned when
+// ServeStaleOnError is enabled. If the loader reports the key missing, the
+// stale value is deleted from the backend.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the stale value
+//   - stale: The stale value found in the backend
+//
+// Returns:
+//   - The reloaded value, or the stale value if reloading fails and ServeStaleOnError is enabled
+//   - An error if reloading fails, or gouache.ErrCacheMiss if the loader reports the key missing
+func (cache *cache) refresh(ctx context.Context, key string, stale any) (any, error) {
+	val, err, _ := cache.group.Do(ctx, key, func(ctx context.Context) (any, error) {
+		return cache.load(ctx, key)
+	})
+	switch {
+	case err == nil:
+		return val, nil
+	case errors.Is(err, gouache.ErrCacheMiss):
+		// The source no longer has the key, so drop the stale value
+		cache.forgetLoaded(key)
+		if err := cache.Cache.Delete(ctx, key); err != nil {
+			return nil, err
+		}
+		return nil, gouache.ErrCacheMiss
+	case cache.Options.ServeStaleOnError:
+		return stale, nil
+	default:
+		return nil, err
+	}
+}
+
+// loadMulti calls the batch loader for several keys and stores the results
+// in the backend. Keys the loader omits are remembered as missing.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to load the values for
+//
+// Returns:
+//   - The loaded values
+//   - An error if loading or storing fails
+func (cache *cache) loadMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	// Wait for a free load slot, if concurrency is limited
+	release, err := cache.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Load the values from the source
+	loaded, err := cache.Options.BatchLoader(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if _, ok := loaded[key]; !ok {
+			cache.storeNegative(key)
+		}
+	}
+	if len(loaded) == 0 {
+		return loaded, nil
+	}
+
+	// Store the loaded values, with an explicit TTL if the backend supports it
+	if setter, ok := cache.ttlSetter(); ok && cache.Options.TTL > 0 {
+		for key, val := range loaded {
+			if err := setter.SetWithTTL(ctx, key, val, cache.Options.TTL); err != nil {
+				return loaded, err
+			}
+			cache.markLoaded(key)
+		}
+		return loaded, nil
+	}
+	if err := gouache.SetMulti(ctx, cache.Cache, loaded); err != nil {
+		return loaded, err
+	}
+	for key := range loaded {
+		cache.markLoaded(key)
+	}
+	return loaded, nil
+}
+
+// acquire takes a load slot from the semaphore, waiting if none is free
+// unless LoadFailFast is enabled.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//
+// Returns:
+//   - A function that returns the slot to the semaphore
+//   - ErrTooManyLoads when failing fast, or an error wrapping gouache.ErrContext if ctx is done while waiting
+func (cache *cache) acquire(ctx context.Context) (func(), error) {
+	// No limit configured
+	if cache.slots == nil {
+		return func() {}, nil
+	}
+
+	release := func() { <-cache.slots }
+
+	// Fail immediately if no slot is free and failing fast is enabled
+	if cache.Options.LoadFailFast {
+		select {
+		case cache.slots <- struct{}{}:
+			return release, nil
+		default:
+			return nil, ErrTooManyLoads
+		}
+	}
+
+	// Otherwise wait for a free slot
+	select {
+	case cache.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", gouache.ErrContext, ctx.Err())
+	}
+}
+
+// isNegative reports whether a key is remembered as missing.
+//
+// Parameters:
+//   - key: The key to check
+//
+// Returns:
+//   - true if the key has an unexpired negative entry
+func (cache *cache) isNegative(key string) bool {
+	if cache.Options.NegativeTTL <= 0 {
+		return false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	expiresAt, ok := cache.negatives[key]
+	if !ok {
+		return false
+	}
+	// Drop the entry once it has expired
+	if cache.Options.Clock.Now().After(expiresAt) {
+		delete(cache.negatives, key)
+		return false
+	}
+	return true
+}
+
+// storeNegative remembers a key as missing for NegativeTTL. Expired entries
+// are swept at most once per NegativeTTL, bounding the number of entries
+// held to those reported missing within roughly the last two windows.
+//
+// Parameters:
+//   - key: The key to remember as missing
+func (cache *cache) storeNegative(key string) {
+	if cache.Options.NegativeTTL <= 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := cache.Options.Clock.Now()
+	cache.negatives[key] = now.Add(cache.Options.NegativeTTL)
+
+	// Sweep expired entries periodically
+	if now.Sub(cache.lastSweep) < cache.Options.NegativeTTL {
+		return
+	}
+	for k, expiresAt := range cache.negatives {
+		if now.After(expiresAt) {
+			delete(cache.negatives, k)
+		}
+	}
+	cache.lastSweep = now
+}
+
+// forgetNegative removes any negative entry for a key.
+//
+// Parameters:
+//   - key: The key to forget
+func (cache *cache) forgetNegative(key string) {
+	if cache.Options.NegativeTTL <= 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.negatives, key)
+}
+
+// isStale reports whether the value of a key was stored through the cache
+// more than RefreshAfter ago.
+//
+// Parameters:
+//   - key: The key to check
+//
+// Returns:
+//   - true if the value should be reloaded
+func (cache *cache) isStale(key string) bool {
+	if cache.Options.RefreshAfter <= 0 {
+		return false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	loadedAt, ok := cache.loadedAt[key]
+	return ok && cache.Options.Clock.Now().Sub(loadedAt) >= cache.Options.RefreshAfter
+}
+
+// ttlSetter returns the underlying cache as a TTLSetter if it actually
+// supports explicit TTLs. Decorators that forward TTLSetter implement it
+// whatever they wrap, so the type assertion alone isn't enough.
+//
+// Returns:
+//   - The underlying cache as a TTLSetter
+//   - true if SetWithTTL is supported
+func (cache *cache) ttlSetter() (gouache.TTLSetter, bool) {
+	setter, ok := cache.Cache.(gouache.TTLSetter)
+	return setter, ok && gouache.Capabilities(cache.Cache).SetWithTTL
+}
+
+// markLoaded records that the value of a key was just stored. With a TTL,
+// load times older than the TTL, whose values the backend has dropped, are
+// swept at most once per TTL.
+//
+// Parameters:
+//   - key: The key whose value was stored
+func (cache *cache) markLoaded(key string) {
+	if cache.Options.RefreshAfter <= 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := cache.Options.Clock.Now()
+	cache.loadedAt[key] = now
+
+	// Sweep load times of values past their TTL periodically
+	ttl := cache.Options.TTL
+	if ttl <= 0 || now.Sub(cache.lastLoadedSweep) < ttl {
+		return
+	}
+	for k, loadedAt := range cache.loadedAt {
+		if now.Sub(loadedAt) > ttl {
+			delete(cache.loadedAt, k)
+		}
+	}
+	cache.lastLoadedSweep = now
+}
+
+// forgetLoaded removes the load time of a key.
+//
+// Parameters:
+//   - key: The key to forget
+func (cache *cache) forgetLoaded(key string) {
+	if cache.Options.RefreshAfter <= 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.loadedAt, key)
+}
@@ -0,0 +1,416 @@
+package loading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/clocktest"
+	"github.com/soyacen/gouache/sample"
+)
+
+// ttlCache is a sample cache that records the TTL passed to SetWithTTL.
+type ttlCache struct {
+	sample.Cache
+	ttl time.Duration
+}
+
+func (c *ttlCache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	c.ttl = ttl
+	return c.Set(ctx, key, val)
+}
+
+// countingLoader returns a Loader that counts its calls and returns "value-" + key.
+func countingLoader(calls *int32, delay time.Duration) Loader {
+	return func(ctx context.Context, key string) (any, error) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		return "value-" + key, nil
+	}
+}
+
+// TestCache_Get tests that a miss is loaded, stored and then served from the backend.
+func TestCache_Get(t *testing.T) {
+	var calls int32
+	backend := &sample.Cache{}
+	cache := New(backend, countingLoader(&calls, 0))
+
+	// First Get loads the value
+	result, err := cache.Get(context.Background(), "key")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if result != "value-key" {
+		t.Errorf("Expected value-key, but got %v", result)
+	}
+
+	// The loaded value is stored in the backend
+	stored, err := backend.Get(context.Background(), "key")
+	if err != nil || stored != "value-key" {
+		t.Errorf("Expected backend to hold value-key, but got %v, %v", stored, err)
+	}
+
+	// Second Get is served from the backend
+	_, _ = cache.Get(context.Background(), "key")
+	if calls != 1 {
+		t.Errorf("Expected 1 loader call, but got %d", calls)
+	}
+}
+
+// TestCache_GetDeduplicates tests that concurrent misses call the loader once.
+func TestCache_GetDeduplicates(t *testing.T) {
+	var calls int32
+	cache := New(&sample.Cache{}, countingLoader(&calls, 50*time.Millisecond))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := cache.Get(context.Background(), "key")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if result != "value-key" {
+				t.Errorf("Expected value-key, but got %v", result)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected 1 loader call, but got %d", calls)
+	}
+}
+
+// TestCache_WithTTL tests that loaded values are stored with the configured TTL.
+func TestCache_WithTTL(t *testing.T) {
+	var calls int32
+	backend := &ttlCache{}
+	cache := New(backend, countingLoader(&calls, 0), WithTTL(time.Minute))
+
+	_, err := cache.Get(context.Background(), "key")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if backend.ttl != time.Minute {
+		t.Errorf("Expected TTL %v, but got %v", time.Minute, backend.ttl)
+	}
+}
+
+// TestCache_WithNegativeCache tests that missing keys are remembered briefly.
+func TestCache_WithNegativeCache(t *testing.T) {
+	var calls int32
+	loader := func(ctx context.Context, key string) (any, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, gouache.ErrCacheMiss
+	}
+	clock := clocktest.NewClock(time.Now())
+	cache := New(&sample.Cache{}, loader, WithNegativeCache(50*time.Millisecond), WithClock(clock))
+
+	// Repeated misses within the window call the loader once
+	for i := 0; i < 3; i++ {
+		_, err := cache.Get(context.Background(), "missing")
+		if !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 loader call, but got %d", calls)
+	}
+
+	// After the window the loader is consulted again
+	clock.Advance(60 * time.Millisecond)
+	_, _ = cache.Get(context.Background(), "missing")
+	if calls != 2 {
+		t.Errorf("Expected 2 loader calls, but got %d", calls)
+	}
+
+	// Set clears the negative entry
+	err := cache.Set(context.Background(), "missing", "now-present")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	result, err := cache.Get(context.Background(), "missing")
+	if err != nil || result != "now-present" {
+		t.Errorf("Expected now-present, but got %v, %v", result, err)
+	}
+}
+
+// TestCache_LoaderError tests that loader errors are returned and not cached.
+func TestCache_LoaderError(t *testing.T) {
+	loadErr := errors.New("database down")
+	backend := &sample.Cache{}
+	cache := New(backend, func(ctx context.Context, key string) (any, error) {
+		return nil, loadErr
+	})
+
+	_, err := cache.Get(context.Background(), "key")
+	if !errors.Is(err, loadErr) {
+		t.Errorf("Expected loader error, but got: %v", err)
+	}
+	_, err = backend.Get(context.Background(), "key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected nothing stored after loader error, but got: %v", err)
+	}
+}
+
+// TestCache_WithMaxConcurrentLoads tests that no more than n loaders run at once.
+func TestCache_WithMaxConcurrentLoads(t *testing.T) {
+	var active, peak int32
+	loader := func(ctx context.Context, key string) (any, error) {
+		current := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return "value-" + key, nil
+	}
+	cache := New(&sample.Cache{}, loader, WithMaxConcurrentLoads(2))
+
+	// Miss 10 distinct keys concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			key := string(rune('a' + index))
+			result, err := cache.Get(context.Background(), key)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if result != "value-"+key {
+				t.Errorf("Expected value-%s, but got %v", key, result)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent loaders, but got %d", peak)
+	}
+}
+
+// TestCache_WithLoadFailFast tests that misses fail fast when all load slots are busy.
+func TestCache_WithLoadFailFast(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	loader := func(ctx context.Context, key string) (any, error) {
+		close(started)
+		<-unblock
+		return "value", nil
+	}
+	backend := &sample.Cache{}
+	cache := New(backend, loader, WithMaxConcurrentLoads(1), WithLoadFailFast(true))
+
+	// Occupy the only load slot
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cache.Get(context.Background(), "slow")
+	}()
+	<-started
+
+	// A miss on another key fails fast
+	_, err := cache.Get(context.Background(), "other")
+	if !errors.Is(err, ErrTooManyLoads) {
+		t.Errorf("Expected ErrTooManyLoads, but got: %v", err)
+	}
+
+	// A hit is never blocked by the limit
+	_ = backend.Set(context.Background(), "hit", "value")
+	result, err := cache.Get(context.Background(), "hit")
+	if err != nil || result != "value" {
+		t.Errorf("Expected hit to succeed, but got %v, %v", result, err)
+	}
+
+	close(unblock)
+	<-done
+}
+
+// TestCache_WaitForLoadSlot tests that a Get giving up while waiting for a
+// load slot returns an error wrapping gouache.ErrContext
+func TestCache_WaitForLoadSlot(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	loader := func(ctx context.Context, key string) (any, error) {
+		close(started)
+		<-unblock
+		return "value", nil
+	}
+	cache := New(&sample.Cache{}, loader, WithMaxConcurrentLoads(1))
+
+	// Occupy the only load slot
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cache.Get(context.Background(), "slow")
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cache.Get(ctx, "other")
+	if !errors.Is(err, gouache.ErrContext) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected an error wrapping gouache.ErrContext and context.DeadlineExceeded, got %v", err)
+	}
+
+	close(unblock)
+	<-done
+}
+
+// TestCache_GetMulti tests that the misses of a GetMulti are loaded with a single batch loader call.
+func TestCache_GetMulti(t *testing.T) {
+	backend := &sample.Cache{}
+	var calls [][]string
+	batchLoader := func(ctx context.Context, keys []string) (map[string]any, error) {
+		calls = append(calls, append([]string(nil), keys...))
+		vals := make(map[string]any)
+		for _, key := range keys {
+			if key != "key-9" {
+				vals[key] = "loaded-" + key
+			}
+		}
+		return vals, nil
+	}
+	cache := New(backend, countingLoader(new(int32), 0),
+		WithBatchLoader(batchLoader), WithNegativeCache(time.Minute)).(*cache)
+	ctx := context.Background()
+
+	// Store the even keys, so the odd half misses
+	keys := make([]string, 10)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		if i%2 == 0 {
+			_ = backend.Set(ctx, keys[i], "cached-"+keys[i])
+		}
+	}
+
+	vals, err := cache.GetMulti(ctx, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []string{"key-1", "key-3", "key-5", "key-7", "key-9"}
+	if len(calls) != 1 || fmt.Sprint(calls[0]) != fmt.Sprint(want) {
+		t.Errorf("Expected one batch loader call with %v, but got %v", want, calls)
+	}
+	if len(vals) != 9 || vals["key-0"] != "cached-key-0" || vals["key-1"] != "loaded-key-1" {
+		t.Errorf("Expected 9 cached and loaded values, but got %v", vals)
+	}
+
+	// Loaded values are stored and the missing key is remembered
+	if stored, err := backend.Get(ctx, "key-3"); err != nil || stored != "loaded-key-3" {
+		t.Errorf("Expected backend to hold loaded-key-3, but got %v, %v", stored, err)
+	}
+	if _, err := cache.GetMulti(ctx, keys); err != nil || len(calls) != 1 {
+		t.Errorf("Expected no further loader calls, but got %v, %v", calls, err)
+	}
+}
+
+// TestCache_ServeStaleOnError tests that stale values are reloaded, and served when reloading fails.
+func TestCache_ServeStaleOnError(t *testing.T) {
+	var calls int32
+	var down atomic.Bool
+	loader := func(ctx context.Context, key string) (any, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if down.Load() {
+			return nil, errors.New("database down")
+		}
+		return fmt.Sprintf("value-%d", n), nil
+	}
+	clock := clocktest.NewClock(time.Now())
+	cache := New(&sample.Cache{}, loader, WithRefreshAfter(time.Minute), WithServeStaleOnError(true), WithClock(clock))
+	ctx := context.Background()
+
+	// A fresh value is served from the backend
+	for i := 0; i < 2; i++ {
+		if val, err := cache.Get(ctx, "key"); err != nil || val != "value-1" {
+			t.Fatalf("Expected value-1, but got %v, %v", val, err)
+		}
+	}
+
+	// A stale value is reloaded
+	clock.Advance(time.Minute)
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value-2" {
+		t.Fatalf("Expected value-2, but got %v, %v", val, err)
+	}
+
+	// A stale value is served when reloading fails, and the reload is retried
+	clock.Advance(time.Minute)
+	down.Store(true)
+	for i := 0; i < 2; i++ {
+		if val, err := cache.Get(ctx, "key"); err != nil || val != "value-2" {
+			t.Errorf("Expected stale value-2, but got %v, %v", val, err)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("Expected 4 loader calls, but got %d", calls)
+	}
+
+	// Without ServeStaleOnError the error is returned
+	strict := New(&sample.Cache{}, loader, WithRefreshAfter(time.Minute), WithClock(clock))
+	down.Store(false)
+	if _, err := strict.Get(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	down.Store(true)
+	if _, err := strict.Get(ctx, "key"); err == nil || err.Error() != "database down" {
+		t.Errorf("Expected database down, but got %v", err)
+	}
+}
+
+// TestCache_GetMultiRefresh tests that GetMulti reloads stale values as Get does
+func TestCache_GetMultiRefresh(t *testing.T) {
+	var calls int32
+	var down atomic.Bool
+	loader := func(ctx context.Context, key string) (any, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if down.Load() {
+			return nil, errors.New("database down")
+		}
+		return fmt.Sprintf("%s-%d", key, n), nil
+	}
+	clock := clocktest.NewClock(time.Now())
+	cache := New(&sample.Cache{}, loader, WithRefreshAfter(time.Minute), WithServeStaleOnError(true), WithClock(clock))
+	getter := cache.(gouache.BatchGetter)
+	ctx := context.Background()
+
+	// The first read loads the value, the second serves it from the backend
+	for i := 0; i < 2; i++ {
+		vals, err := getter.GetMulti(ctx, []string{"key", "key"})
+		if err != nil || vals["key"] != "key-1" {
+			t.Fatalf("Expected key-1, but got %v, %v", vals, err)
+		}
+	}
+
+	// A stale value is reloaded once, even for a repeated key
+	clock.Advance(time.Minute)
+	vals, err := getter.GetMulti(ctx, []string{"key", "key"})
+	if err != nil || vals["key"] != "key-2" {
+		t.Fatalf("Expected key-2, but got %v, %v", vals, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 loader calls, but got %d", calls)
+	}
+
+	// A stale value is served when reloading fails
+	clock.Advance(time.Minute)
+	down.Store(true)
+	vals, err = getter.GetMulti(ctx, []string{"key"})
+	if err != nil || vals["key"] != "key-2" {
+		t.Errorf("Expected stale key-2, but got %v, %v", vals, err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 loader calls, but got %d", calls)
+	}
+}
@@ -0,0 +1,407 @@
+// Package lrttl provides an in-memory implementation of the gouache.Cache
+// interface with both a fixed capacity and per-entry expiry.
+//
+// The lru package bounds the number of entries but never expires them, and
+// the gc package expires entries but never bounds their number. This cache
+// does both: each entry expires after the TTL resolved by the TTL function,
+// and when the cache is full, storing a new key drops an expired entry if
+// there is one, or else evicts the least recently used entry. Expiry is
+// checked lazily: an expired entry is removed when it is read or when room is
+// needed, so no background goroutine is needed.
+package lrttl
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*Cache)(nil)
+
+// entry is a key, its value and its expiry, stored in the recency list.
+type entry struct {
+	key string
+	val any
+
+	// expiresAt is when the entry expires, or the zero time if it never does.
+	expiresAt time.Time
+
+	// index is the position of the entry in the expiry heap, or -1 if it
+	// never expires.
+	index int
+}
+
+// expired reports whether the entry has expired at now.
+//
+// Parameters:
+//   - now: The current time
+//
+// Returns:
+//   - true if the entry has an expiry that is not after now
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// Cache is an implementation of gouache.Cache that holds at most a fixed
+// number of entries, evicting the least recently used one on overflow, and
+// expires each entry after its TTL.
+type Cache struct {
+	// TTL is an optional function to determine the time-to-live duration for
+	// a cache entry. A zero result means the entry never expires and is only
+	// evicted for capacity. A positive result is scaled by the context's
+	// gouache.TTLMultiplier, and any result is clamped to the context's
+	// gouache.TTLCeiling, before it is applied.
+	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+
+	// Clock is an optional clock used to compute and check the expiry of
+	// entries. If not provided, gouache.RealClock is used.
+	Clock gouache.Clock
+
+	// OnEvict is an optional function called with each live entry evicted to
+	// make room, after the cache lock is released.
+	OnEvict func(key string, val any)
+
+	// mu guards capacity, order, elems and expiries
+	mu sync.Mutex
+
+	// capacity is the maximum number of entries
+	capacity int
+
+	// order holds the entries, most recently used at the front
+	order *list.List
+
+	// elems maps each key to its element in order
+	elems map[string]*list.Element
+
+	// expiries holds the entries that expire, soonest first
+	expiries expiryHeap
+}
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithCapacity returns an Option that sets the maximum number of entries.
+// It is required.
+//
+// Parameters:
+//   - capacity: The maximum number of entries, which must be positive
+//
+// Returns:
+//   - An Option function that sets the capacity
+func WithCapacity(capacity int) Option {
+	return func(cache *Cache) {
+		cache.capacity = capacity
+	}
+}
+
+// WithTTL returns an Option that sets the function determining the TTL of
+// each entry. See the TTL field.
+//
+// Parameters:
+//   - f: The function returning the TTL of an entry
+//
+// Returns:
+//   - An Option function that sets TTL
+func WithTTL(f func(ctx context.Context, key string, val any) (time.Duration, error)) Option {
+	return func(cache *Cache) {
+		cache.TTL = f
+	}
+}
+
+// WithClock returns an Option that sets the clock entries expire by. See the
+// Clock field.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(cache *Cache) {
+		cache.Clock = clock
+	}
+}
+
+// WithOnEvict returns an Option that sets the function called with each live
+// entry evicted to make room. It isn't called for deleted entries, nor for
+// expired entries. It is called after the cache lock is released, so it may
+// use the cache.
+//
+// Parameters:
+//   - f: The function called with the evicted key and value
+//
+// Returns:
+//   - An Option function that sets OnEvict
+func WithOnEvict(f func(key string, val any)) Option {
+	return func(cache *Cache) {
+		cache.OnEvict = f
+	}
+}
+
+// New creates a new Cache configured by opts, which must include WithCapacity.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - An empty Cache
+//   - An error if the capacity is not positive
+func New(opts ...Option) (*Cache, error) {
+	cache := &Cache{order: list.New(), elems: make(map[string]*list.Element)}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	if cache.capacity <= 0 {
+		return nil, errors.New("lrttl: capacity must be positive")
+	}
+	return cache, nil
+}
+
+// Get retrieves a value from the cache by its key, making it the most
+// recently used entry. An expired entry is removed and reported as a miss.
+// It returns gouache.ErrCacheMiss if the key does not exist.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist or has expired
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Handle case where entry is not found
+	elem, ok := cache.elems[key]
+	if !ok {
+		return nil, gouache.ErrCacheMiss
+	}
+
+	// Remove the entry if it has expired
+	e := elem.Value.(*entry)
+	if e.expired(cache.clock().Now()) {
+		cache.remove(elem)
+		return nil, gouache.ErrCacheMiss
+	}
+
+	// Promote the entry and return its value
+	cache.order.MoveToFront(elem)
+	return e.val, nil
+}
+
+// Set stores a value in the cache with the given key, making it the most
+// recently used entry. The TTL is determined by the TTL function if
+// provided. If the key is new and the cache is full, the least recently used
+// entry is evicted first.
+//
+// Parameters:
+//   - ctx: Context for the operation, passed to the TTL function if configured
+//   - key: The key to store the value under
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the TTL function (if configured) returns an error, otherwise nil
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	// Resolve the TTL for the value
+	var ttl time.Duration
+	if cache.TTL != nil {
+		var err error
+		if ttl, err = cache.TTL(ctx, key, val); err != nil {
+			return err
+		}
+		ttl = gouache.AdjustTTL(ctx, ttl)
+	}
+	cache.store(key, val, ttl)
+	return nil
+}
+
+// SetWithTTL stores a value in the cache under the specified key with an
+// explicit TTL, bypassing the TTL function and the TTL multiplier.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to store the value under
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero meaning no expiration
+//
+// Returns:
+//   - Always returns nil as storing in memory can't fail
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	cache.store(key, val, ttl)
+	return nil
+}
+
+// ReadTTL returns the remaining time-to-live of the entry stored under key.
+// It returns gouache.ErrCacheMiss if the key does not exist or has expired.
+// It doesn't change the recency of the entry.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//
+// Returns:
+//   - The remaining time-to-live, or zero if the entry never expires
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist or has expired
+func (cache *Cache) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	elem, ok := cache.elems[key]
+	if !ok {
+		return 0, gouache.ErrCacheMiss
+	}
+	e := elem.Value.(*entry)
+	now := cache.clock().Now()
+	if e.expired(now) {
+		return 0, gouache.ErrCacheMiss
+	}
+	if e.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return e.expiresAt.Sub(now), nil
+}
+
+// Delete removes a value from the cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - Always returns nil as removing from memory can't fail
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if elem, ok := cache.elems[key]; ok {
+		cache.remove(elem)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the cache, including expired entries
+// that haven't been removed yet.
+//
+// Returns:
+//   - The number of entries
+func (cache *Cache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
+
+// store stores a value with a resolved TTL. If the key is new and the cache
+// is full, an expired entry is dropped to make room, or if there is none, the
+// least recently used entry is evicted and handed to OnEvict once the lock is
+// released.
+//
+// Parameters:
+//   - key: The key to store the value under
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or negative meaning no expiration
+func (cache *Cache) store(key string, val any, ttl time.Duration) {
+	evicted := cache.storeLocked(key, val, ttl)
+	if evicted != nil && cache.OnEvict != nil {
+		cache.OnEvict(evicted.key, evicted.val)
+	}
+}
+
+// storeLocked stores a value with a resolved TTL while holding the lock.
+//
+// Parameters:
+//   - key: The key to store the value under
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry, zero or negative meaning no expiration
+//
+// Returns:
+//   - The live entry evicted to make room, or nil if none was
+func (cache *Cache) storeLocked(key string, val any, ttl time.Duration) *entry {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := cache.clock().Now()
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+
+	// Update an existing entry in place
+	if elem, ok := cache.elems[key]; ok {
+		e := elem.Value.(*entry)
+		e.val = val
+		cache.setExpiry(e, expiresAt)
+		cache.order.MoveToFront(elem)
+		return nil
+	}
+
+	// Make room, preferring an expired entry over the least recently used one
+	var evicted *entry
+	if cache.order.Len() >= cache.capacity {
+		if len(cache.expiries) > 0 && cache.expiries[0].expired(now) {
+			cache.remove(cache.elems[cache.expiries[0].key])
+		} else {
+			back := cache.order.Back()
+			evicted = back.Value.(*entry)
+			cache.remove(back)
+		}
+	}
+
+	e := &entry{key: key, val: val, index: -1}
+	cache.setExpiry(e, expiresAt)
+	cache.elems[key] = cache.order.PushFront(e)
+	return evicted
+}
+
+// setExpiry sets the expiry of an entry and updates its place in the expiry
+// heap. The lock must be held.
+//
+// Parameters:
+//   - e: The entry
+//   - expiresAt: When the entry expires, or the zero time if it never does
+func (cache *Cache) setExpiry(e *entry, expiresAt time.Time) {
+	e.expiresAt = expiresAt
+	switch {
+	case e.index >= 0 && expiresAt.IsZero():
+		heap.Remove(&cache.expiries, e.index)
+	case e.index >= 0:
+		heap.Fix(&cache.expiries, e.index)
+	case !expiresAt.IsZero():
+		heap.Push(&cache.expiries, e)
+	}
+}
+
+// remove removes an element from the cache. The lock must be held.
+//
+// Parameters:
+//   - elem: The element of the entry to remove
+func (cache *Cache) remove(elem *list.Element) {
+	e := cache.order.Remove(elem).(*entry)
+	delete(cache.elems, e.key)
+	if e.index >= 0 {
+		heap.Remove(&cache.expiries, e.index)
+	}
+}
+
+// clock returns the configured Clock, or gouache.RealClock if none is set.
+//
+// Returns:
+//   - The clock used for expiry
+func (cache *Cache) clock() gouache.Clock {
+	if cache.Clock == nil {
+		return gouache.RealClock
+	}
+	return cache.Clock
+}
@@ -0,0 +1,232 @@
+package lrttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
+	"github.com/soyacen/gouache/clocktest"
+)
+
+// fixedTTL returns a TTL function giving every entry the same TTL.
+func fixedTTL(ttl time.Duration) func(ctx context.Context, key string, val any) (time.Duration, error) {
+	return func(ctx context.Context, key string, val any) (time.Duration, error) {
+		return ttl, nil
+	}
+}
+
+// TestNew tests that a missing or non-positive capacity is rejected
+func TestNew(t *testing.T) {
+	if _, err := New(); err == nil {
+		t.Error("Expected an error without a capacity")
+	}
+	if _, err := New(WithCapacity(-1)); err == nil {
+		t.Error("Expected an error for a negative capacity")
+	}
+}
+
+// TestCache_CapacityEviction tests that the least recently used entry is
+// evicted when the cache is full, whatever the TTLs
+func TestCache_CapacityEviction(t *testing.T) {
+	var evicted []string
+	cache, err := New(
+		WithCapacity(3),
+		WithTTL(fixedTTL(time.Hour)),
+		WithOnEvict(func(key string, val any) { evicted = append(evicted, key) }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		_ = cache.Set(ctx, key, key)
+	}
+
+	// Reading a makes b the least recently used
+	if _, err := cache.Get(ctx, "a"); err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	_ = cache.Set(ctx, "d", "d")
+
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("Expected [b] evicted, got %v", evicted)
+	}
+	if _, err := cache.Get(ctx, "b"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss for evicted key, got %v", err)
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, err := cache.Get(ctx, key); err != nil {
+			t.Errorf("Expected %s to remain, got %v", key, err)
+		}
+	}
+
+	// Overwriting an existing key evicts nothing
+	_ = cache.Set(ctx, "a", "updated")
+	if len(evicted) != 1 {
+		t.Errorf("Expected no eviction on overwrite, got %v", evicted)
+	}
+	if cache.Len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", cache.Len())
+	}
+}
+
+// TestCache_TTLExpiry tests that entries expire after their TTL, while
+// entries stored with a zero TTL never do
+func TestCache_TTLExpiry(t *testing.T) {
+	clock := clocktest.NewClock(time.Now())
+	cache, err := New(
+		WithCapacity(8),
+		WithClock(clock),
+		WithTTL(func(ctx context.Context, key string, val any) (time.Duration, error) {
+			if key == "forever" {
+				return 0, nil
+			}
+			return time.Minute, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "value")
+	_ = cache.Set(ctx, "forever", "value")
+
+	clock.Advance(30 * time.Second)
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected value before expiry, got %v, %v", val, err)
+	}
+	if ttl, err := cache.ReadTTL(ctx, "key"); err != nil || ttl != 30*time.Second {
+		t.Errorf("Expected 30s remaining, got %v, %v", ttl, err)
+	}
+
+	clock.Advance(30 * time.Second)
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss after expiry, got %v", err)
+	}
+	if val, err := cache.Get(ctx, "forever"); err != nil || val != "value" {
+		t.Errorf("Expected entry without TTL to remain, got %v, %v", val, err)
+	}
+	if ttl, err := cache.ReadTTL(ctx, "forever"); err != nil || ttl != 0 {
+		t.Errorf("Expected zero TTL, got %v, %v", ttl, err)
+	}
+
+	// SetWithTTL bypasses the TTL function
+	_ = cache.SetWithTTL(ctx, "explicit", "value", time.Second)
+	clock.Advance(time.Second)
+	if _, err := cache.Get(ctx, "explicit"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss after explicit TTL, got %v", err)
+	}
+}
+
+// TestCache_ExpiredNotEvicted tests that an expired entry still held by the
+// cache is reported as a miss, and removed when read
+func TestCache_ExpiredNotEvicted(t *testing.T) {
+	clock := clocktest.NewClock(time.Now())
+	cache, err := New(WithCapacity(8), WithClock(clock), WithTTL(fixedTTL(time.Minute)))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "value")
+	clock.Advance(time.Minute)
+
+	// Expiry is lazy, so the entry is still held
+	if cache.Len() != 1 {
+		t.Fatalf("Expected the expired entry to be held, got %d entries", cache.Len())
+	}
+	if _, err := cache.ReadTTL(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss from ReadTTL, got %v", err)
+	}
+	val, err := cache.Get(ctx, "key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+	if val != nil {
+		t.Errorf("Expected a nil value, got %v", val)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected the expired entry to be removed, got %d entries", cache.Len())
+	}
+}
+
+// TestCache_ExpiredEvictedFirst tests that a full cache drops an expired
+// entry to make room before evicting a live one
+func TestCache_ExpiredEvictedFirst(t *testing.T) {
+	clock := clocktest.NewClock(time.Now())
+	var evicted []string
+	cache, err := New(
+		WithCapacity(3),
+		WithClock(clock),
+		WithTTL(fixedTTL(time.Hour)),
+		WithOnEvict(func(key string, val any) { evicted = append(evicted, key) }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	// a is the least recently used, b expires first
+	_ = cache.Set(ctx, "a", "a")
+	_ = cache.SetWithTTL(ctx, "b", "b", time.Minute)
+	_ = cache.Set(ctx, "c", "c")
+	clock.Advance(time.Minute)
+
+	_ = cache.Set(ctx, "d", "d")
+	if len(evicted) != 0 {
+		t.Errorf("Expected no live entry evicted, got %v", evicted)
+	}
+	if cache.Len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", cache.Len())
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, err := cache.Get(ctx, key); err != nil {
+			t.Errorf("Expected %s to remain, got %v", key, err)
+		}
+	}
+
+	// With nothing expired, the least recently used entry is evicted
+	_ = cache.Set(ctx, "e", "e")
+	if len(evicted) != 1 || evicted[0] != "a" {
+		t.Errorf("Expected [a] evicted, got %v", evicted)
+	}
+}
+
+// TestCache_OnEvictUsesCache tests that OnEvict is called without the cache
+// lock held, so it can use the cache
+func TestCache_OnEvictUsesCache(t *testing.T) {
+	var cache *Cache
+	var lens []int
+	cache, err := New(
+		WithCapacity(1),
+		WithTTL(fixedTTL(time.Hour)),
+		WithOnEvict(func(key string, val any) {
+			_, _ = cache.Get(context.Background(), key)
+			lens = append(lens, cache.Len())
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a", "a")
+	_ = cache.Set(ctx, "b", "b")
+	if len(lens) != 1 || lens[0] != 1 {
+		t.Errorf("Expected OnEvict to see 1 entry, got %v", lens)
+	}
+}
+
+// TestCache_Conformance runs the conformance suite
+func TestCache_Conformance(t *testing.T) {
+	cache, err := New(WithCapacity(64), WithTTL(fixedTTL(time.Hour)))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	cachetest.RunConformance(t, cache)
+}
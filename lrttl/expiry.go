@@ -0,0 +1,36 @@
+package lrttl
+
+// expiryHeap is a min-heap of the entries that expire, soonest first, so that
+// a full cache can find an expired entry to drop without scanning. It
+// implements heap.Interface and keeps the index of each entry up to date.
+type expiryHeap []*entry
+
+// Len returns the number of entries in the heap.
+func (h expiryHeap) Len() int { return len(h) }
+
+// Less orders entries by expiry.
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+
+// Swap swaps two entries and their indexes.
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+// Push adds an entry; use heap.Push instead.
+func (h *expiryHeap) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+// Pop removes the last entry; use heap.Pop instead.
+func (h *expiryHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	e.index = -1
+	return e
+}
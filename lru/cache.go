@@ -7,6 +7,7 @@ package lru
 
 import (
 	"context"
+	"sync"
 
 	"github.com/soyacen/gouache"
 	lrucache "github.com/hashicorp/golang-lru"
@@ -15,16 +16,107 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*Cache)(nil)
+
 // Cache is an implementation of gouache.Cache using LRU cache as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // LRU eviction policy when the cache reaches its capacity.
 type Cache struct {
 	// Cache is the underlying LRU cache instance used for storage.
 	Cache *lrucache.Cache
+
+	// SetNoPromote makes Set overwrite existing keys without promoting them to
+	// most recently used. See WithSetNoPromote for its limitations.
+	SetNoPromote bool
+}
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithSetNoPromote returns an Option that makes Set overwrite existing keys
+// without changing their recency, so that rewriting a key doesn't protect it
+// from eviction. This suits write-heavy workloads where only reads should
+// count as use.
+//
+// golang-lru has no way to replace a value without promoting its key, so
+// with this option values are stored boxed in a mutable entry that Set
+// updates in place. This has some limitations:
+//   - New keys are still inserted as most recently used.
+//   - Keys stored before the option was enabled are promoted once on their
+//     first rewrite, which boxes them.
+//   - Code using the underlying lrucache.Cache directly, including eviction
+//     callbacks, sees the boxed entries rather than the values.
+//
+// Parameters:
+//   - enabled: Whether Set leaves the recency of existing keys unchanged
+//
+// Returns:
+//   - An Option function that sets SetNoPromote
+func WithSetNoPromote(enabled bool) Option {
+	return func(cache *Cache) {
+		cache.SetNoPromote = enabled
+	}
+}
+
+// New creates a new Cache backed by the specified LRU cache.
+//
+// Parameters:
+//   - c: The underlying LRU cache instance
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache using c as its storage
+func New(c *lrucache.Cache, opts ...Option) *Cache {
+	cache := &Cache{Cache: c}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	return cache
+}
+
+// entry is a mutable box holding a value, stored in place of the value when
+// SetNoPromote is enabled so that it can be replaced without promoting its key.
+type entry struct {
+	// mu guards val
+	mu sync.Mutex
+
+	// val is the boxed value
+	val any
+}
+
+// load returns the boxed value.
+func (e *entry) load() any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.val
+}
+
+// store replaces the boxed value.
+func (e *entry) store(val any) {
+	e.mu.Lock()
+	e.val = val
+	e.mu.Unlock()
+}
+
+// unbox returns the value held in val if it is an entry, or val itself otherwise.
+func unbox(val any) any {
+	if e, ok := val.(*entry); ok {
+		return e.load()
+	}
+	return val
 }
 
 // Get retrieves a value from the cache by its key.
-// It returns gouache.ErrCacheMiss if the key does not exist.
+// It returns gouache.ErrCacheMiss if the key does not exist. A nil value that
+// was stored is returned as (nil, nil), so callers must check the error, not
+// the value, to tell it from a miss.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -43,10 +135,37 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 	}
 
 	// Return the found value
-	return val, nil
+	return unbox(val), nil
 }
 
-// Set stores a value in the cache with the given key.
+// GetMulti retrieves the values for the given keys from the cache.
+// Keys that do not exist are omitted from the returned map, while keys
+// holding a stored nil map to nil.
+//
+// golang-lru has no batch lookup, so this performs one Get per key. Each Get
+// acquires the LRU lock separately and promotes the key to most recently used,
+// exactly as an individual Get would.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - Always returns nil error as LRU cache Get operation doesn't return errors
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		// Only record keys that are present in the LRU cache
+		if val, ok := cache.Cache.Get(key); ok {
+			vals[key] = unbox(val)
+		}
+	}
+	return vals, nil
+}
+
+// Set stores a value in the cache with the given key. The key is promoted to
+// most recently used unless SetNoPromote is enabled and the key already exists.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -57,10 +176,50 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 //   - Always returns nil as LRU cache Add operation is always successful
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 	// Add the value to the LRU cache
-	_ = cache.Cache.Add(key, val)
+	if !cache.SetNoPromote {
+		_ = cache.Cache.Add(key, val)
+		return nil
+	}
+
+	// Insert a new box, or update the existing one without promoting its key
+	box := &entry{val: val}
+	prev, ok, _ := cache.Cache.PeekOrAdd(key, box)
+	if !ok {
+		return nil
+	}
+	if prevBox, isBox := prev.(*entry); isBox {
+		prevBox.store(val)
+		return nil
+	}
+
+	// The key was stored unboxed, so it has to be replaced and promoted once
+	_ = cache.Cache.Add(key, box)
 	return nil
 }
 
+// Add stores a value under key only if the key is not already present, using
+// golang-lru's atomic ContainsOrAdd. An existing key is left unchanged and not
+// promoted. A stored key becomes the most recently used and may evict the
+// least recently used one.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to store the value under
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - Always returns nil error as LRU cache ContainsOrAdd doesn't return errors
+func (cache *Cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	// Box the value so that later Sets can update it without promoting the key
+	var stored any = val
+	if cache.SetNoPromote {
+		stored = &entry{val: val}
+	}
+	found, _ := cache.Cache.ContainsOrAdd(key, stored)
+	return !found, nil
+}
+
 // Delete removes a value from the cache by its key.
 //
 // Parameters:
@@ -74,3 +233,23 @@ func (cache *Cache) Delete(ctx context.Context, key string) error {
 	_ = cache.Cache.Remove(key)
 	return nil
 }
+
+// DeleteMultiResult removes the values of the given keys from the cache and
+// reports which of them existed, as told by golang-lru's Remove.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - Always returns nil error as LRU cache Remove operation doesn't return errors
+func (cache *Cache) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	var deleted []string
+	for _, key := range keys {
+		if cache.Cache.Remove(key) {
+			deleted = append(deleted, key)
+		}
+	}
+	return deleted, nil
+}
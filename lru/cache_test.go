@@ -2,9 +2,12 @@ package lru
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 
 	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
 	lru "github.com/hashicorp/golang-lru"
 )
 
@@ -214,3 +217,207 @@ func TestCache_LRU_AccessOrder(t *testing.T) {
 		t.Errorf("Failed to get value3: %v", err)
 	}
 }
+
+// TestCache_GetMulti tests that GetMulti returns found keys and omits missing ones
+func TestCache_GetMulti(t *testing.T) {
+	lruCache, err := lru.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+
+	cache := &Cache{
+		Cache: lruCache,
+	}
+
+	ctx := context.Background()
+
+	// Set up some values
+	err = cache.Set(ctx, "key1", "value1")
+	if err != nil {
+		t.Errorf("Failed to set value1: %v", err)
+	}
+
+	err = cache.Set(ctx, "key2", "value2")
+	if err != nil {
+		t.Errorf("Failed to set value2: %v", err)
+	}
+
+	// Test GetMulti with a mix of existing and missing keys
+	result, err := cache.GetMulti(ctx, []string{"key1", "key2", "missing"})
+	if err != nil {
+		t.Errorf("Failed to get multiple values: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Errorf("Expected 2 values, got %d", len(result))
+	}
+
+	if result["key1"] != "value1" {
+		t.Errorf("Expected value1, got %v", result["key1"])
+	}
+
+	if result["key2"] != "value2" {
+		t.Errorf("Expected value2, got %v", result["key2"])
+	}
+
+	// Missing keys should be absent from the result
+	if _, ok := result["missing"]; ok {
+		t.Error("Expected missing key to be absent from result")
+	}
+}
+
+// TestCache_SetNoPromote tests that rewriting a key leaves its recency unchanged
+func TestCache_SetNoPromote(t *testing.T) {
+	for _, noPromote := range []bool{false, true} {
+		lruCache, err := lru.New(2)
+		if err != nil {
+			t.Fatalf("Failed to create LRU cache: %v", err)
+		}
+		cache := New(lruCache, WithSetNoPromote(noPromote))
+		ctx := context.Background()
+
+		_ = cache.Set(ctx, "key1", "value1")
+		_ = cache.Set(ctx, "key2", "value2")
+
+		// Rewrite key1, which promotes it only without SetNoPromote
+		_ = cache.Set(ctx, "key1", "value1-new")
+
+		// Add a third item, evicting the least recently used key
+		_ = cache.Set(ctx, "key3", "value3")
+		evicted, kept := "key2", "key1"
+		if noPromote {
+			evicted, kept = "key1", "key2"
+		}
+		if _, err := cache.Get(ctx, evicted); err != gouache.ErrCacheMiss {
+			t.Errorf("noPromote=%v: Expected %s to be evicted, got %v", noPromote, evicted, err)
+		}
+		if _, err := cache.Get(ctx, kept); err != nil {
+			t.Errorf("noPromote=%v: Expected %s to be kept, got %v", noPromote, kept, err)
+		}
+
+		// Rewritten values are visible through Get and GetMulti
+		_ = cache.Set(ctx, "key3", "value3-new")
+		val, err := cache.Get(ctx, "key3")
+		if err != nil || val != "value3-new" {
+			t.Errorf("noPromote=%v: Expected value3-new, got %v, %v", noPromote, val, err)
+		}
+		vals, _ := cache.GetMulti(ctx, []string{"key3"})
+		if vals["key3"] != "value3-new" {
+			t.Errorf("noPromote=%v: Expected value3-new from GetMulti, got %v", noPromote, vals["key3"])
+		}
+	}
+}
+
+// TestCache_AddConcurrent tests that exactly one of many concurrent Adds of a key succeeds
+func TestCache_AddConcurrent(t *testing.T) {
+	lruCache, err := lru.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+	cache := New(lruCache)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []int
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := cache.Add(ctx, "singleton", i)
+			if err != nil {
+				t.Errorf("Failed to add value: %v", err)
+			}
+			if ok {
+				mu.Lock()
+				winners = append(winners, i)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(winners) != 1 {
+		t.Fatalf("Expected exactly one Add to succeed, got %v", winners)
+	}
+	if val, err := cache.Get(ctx, "singleton"); err != nil || val != winners[0] {
+		t.Errorf("Expected the winner's value %d, got %v, %v", winners[0], val, err)
+	}
+
+	// A later Add leaves the value alone
+	if ok, _ := cache.Add(ctx, "singleton", -1); ok {
+		t.Error("Expected Add of an existing key to fail")
+	}
+}
+
+// TestCache_DeleteMultiResult tests that only keys that existed are reported
+func TestCache_DeleteMultiResult(t *testing.T) {
+	lruCache, err := lru.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+	cache := New(lruCache)
+	ctx := context.Background()
+	_ = cache.Set(ctx, "a", 1)
+	_ = cache.Set(ctx, "b", 2)
+
+	deleted, err := cache.DeleteMultiResult(ctx, []string{"a", "missing", "b", "a"})
+	if err != nil {
+		t.Fatalf("Failed to delete keys: %v", err)
+	}
+	if len(deleted) != 2 || deleted[0] != "a" || deleted[1] != "b" {
+		t.Errorf("Expected [a b] deleted, got %v", deleted)
+	}
+	if lruCache.Len() != 0 {
+		t.Errorf("Expected an empty cache, got %d entries", lruCache.Len())
+	}
+}
+
+// TestCache_StoredNil tests that a stored nil value is distinguishable from an absent key
+func TestCache_StoredNil(t *testing.T) {
+	for _, noPromote := range []bool{false, true} {
+		lruCache, err := lru.New(100)
+		if err != nil {
+			t.Fatalf("Failed to create LRU cache: %v", err)
+		}
+		cache := New(lruCache, WithSetNoPromote(noPromote))
+		ctx := context.Background()
+
+		if err := cache.Set(ctx, "nil-key", nil); err != nil {
+			t.Fatalf("Failed to set nil value: %v", err)
+		}
+
+		// A stored nil is a hit
+		val, err := cache.Get(ctx, "nil-key")
+		if err != nil || val != nil {
+			t.Errorf("Expected (nil, nil) for a stored nil, got (%v, %v)", val, err)
+		}
+
+		// An absent key is a miss
+		val, err = cache.Get(ctx, "absent")
+		if !errors.Is(err, gouache.ErrCacheMiss) || val != nil {
+			t.Errorf("Expected (nil, ErrCacheMiss) for an absent key, got (%v, %v)", val, err)
+		}
+
+		// GetMulti reports the stored nil and omits the absent key
+		vals, err := cache.GetMulti(ctx, []string{"nil-key", "absent"})
+		if err != nil {
+			t.Fatalf("Failed to get values: %v", err)
+		}
+		if v, ok := vals["nil-key"]; !ok || v != nil {
+			t.Errorf("Expected nil-key to map to nil, got %v", vals)
+		}
+		if _, ok := vals["absent"]; ok {
+			t.Errorf("Expected absent to be omitted, got %v", vals)
+		}
+	}
+}
+
+// TestCache_CacheMissContract tests that misses are reported as gouache.ErrCacheMiss
+func TestCache_CacheMissContract(t *testing.T) {
+	lruCache, err := lru.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create LRU cache: %v", err)
+	}
+	cachetest.AssertCacheMissContract(t, New(lruCache))
+}
@@ -6,4 +6,6 @@ require github.com/hashicorp/golang-lru v1.0.2
 
 require github.com/soyacen/gouache v0.0.0-00010101000000-000000000000
 
+require golang.org/x/sync v0.11.0 // indirect
+
 replace github.com/soyacen/gouache => ../
@@ -0,0 +1,64 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Memoize returns a function that caches the results of loader in c, using
+// the cache-aside pattern with typed values.
+//
+// The returned function looks up keyFn(arg) in c and returns the cached value
+// on a hit. On a miss it calls loader, stores the result in c and returns it.
+// Concurrent calls missing the same key share a single loader call. Errors
+// returned by loader are not cached.
+//
+// The cached values must have type V when read back, so c should either keep
+// values in memory or decode them to V, for example with a redis Unmarshal
+// function.
+//
+// Parameters:
+//   - c: The cache the results are stored in
+//   - keyFn: The function deriving the cache key from an argument
+//   - loader: The function computing the result for an argument
+//
+// Returns:
+//   - A function returning the cached or computed result for an argument, and
+//     an error if reading, loading or storing fails or a cached value isn't a V
+func Memoize[K comparable, V any](c Cache, keyFn func(K) string, loader func(ctx context.Context, arg K) (V, error)) func(ctx context.Context, arg K) (V, error) {
+	var group singleflight.Group
+	return func(ctx context.Context, arg K) (V, error) {
+		var zero V
+		key := keyFn(arg)
+
+		// Serve the cached value on a hit
+		val, err := c.Get(ctx, key)
+		if err == nil {
+			v, ok := val.(V)
+			if !ok {
+				return zero, fmt.Errorf("gouache: memoized value of key %q is %T, not %T", key, val, zero)
+			}
+			return v, nil
+		}
+		if !errors.Is(err, ErrCacheMiss) {
+			return zero, err
+		}
+
+		// Load and store the value once for all concurrent callers
+		val, err, _ = group.Do(key, func() (any, error) {
+			v, err := loader(ctx, arg)
+			if err != nil {
+				return nil, err
+			}
+			return v, c.Set(ctx, key, v)
+		})
+		if err != nil {
+			return zero, err
+		}
+		v, _ := val.(V)
+		return v, nil
+	}
+}
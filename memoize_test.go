@@ -0,0 +1,58 @@
+package gouache_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestMemoize tests that a memoized function runs its loader once per distinct key.
+func TestMemoize(t *testing.T) {
+	// Setup: an expensive computation counting its calls per argument
+	var mu sync.Mutex
+	calls := make(map[int]int)
+	square := func(ctx context.Context, n int) (int, error) {
+		mu.Lock()
+		calls[n]++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		return n * n, nil
+	}
+	cache := &sample.Cache{}
+	memo := gouache.Memoize(cache, func(n int) string { return "square:" + strconv.Itoa(n) }, square)
+
+	// Test concurrent and repeated calls for a few distinct arguments
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			got, err := memo(context.Background(), n)
+			if err != nil || got != n*n {
+				t.Errorf("Expected %d, but got %d, %v", n*n, got, err)
+			}
+		}(i % 3)
+	}
+	wg.Wait()
+	if got, err := memo(context.Background(), 2); err != nil || got != 4 {
+		t.Errorf("Expected 4, but got %d, %v", got, err)
+	}
+	for n := 0; n < 3; n++ {
+		if calls[n] != 1 {
+			t.Errorf("Expected the loader to run once for %d, but it ran %d times", n, calls[n])
+		}
+	}
+
+	// Test that a cached value of the wrong type is reported
+	if err := cache.Set(context.Background(), "square:9", "81"); err != nil {
+		t.Fatalf("Failed to set up test value: %v", err)
+	}
+	if _, err := memo(context.Background(), 9); err == nil {
+		t.Error("Expected an error for a cached value of the wrong type")
+	}
+}
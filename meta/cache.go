@@ -0,0 +1,238 @@
+// Package meta provides a cache implementation that records metadata, such as
+// the time a value was cached, alongside every stored value.
+//
+// This package implements the gouache.Cache interface by wrapping stored values
+// in an Envelope. Get transparently unwraps the envelope, while GetWithMeta
+// additionally returns the recorded metadata. This works regardless of whether
+// the underlying backend supports TTL inspection.
+package meta
+
+import (
+	"context"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*Cache)(nil)
+
+// Meta holds the metadata recorded for a cached value.
+type Meta struct {
+	// CachedAt is the time the value was stored in the cache.
+	CachedAt time.Time `json:"cached_at"`
+
+	// Extra holds optional custom metadata supplied by the MetaFunc option.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// Envelope is the value actually stored in the underlying cache.
+//
+// Its fields are exported and tagged so it can be serialized by the codecs of
+// byte or string based backends such as redis, fc and bc. To decode Value into
+// a concrete type with encoding/json, unmarshal into an Envelope whose Value is
+// a pointer to that type.
+type Envelope struct {
+	// Value is the original value passed to Set.
+	Value any `json:"value"`
+
+	// Meta is the metadata recorded when the value was stored.
+	Meta Meta `json:"meta"`
+}
+
+// MetaFunc is a function type that produces custom metadata for a value
+// being stored in the cache.
+type MetaFunc func(ctx context.Context, key string, val any) map[string]string
+
+// options holds configuration options for the metadata cache.
+type options struct {
+	// MetaFunc produces optional custom metadata for each stored value.
+	MetaFunc MetaFunc
+
+	// Clock provides the current time used as the CachedAt timestamp.
+	Clock gouache.Clock
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithMetaFunc returns an Option that sets a function producing custom
+// metadata for each stored value.
+//
+// Parameters:
+//   - f: A function that returns custom metadata for a key and value
+//
+// Returns:
+//   - An Option function that sets the MetaFunc
+func WithMetaFunc(f MetaFunc) Option {
+	return func(o *options) {
+		o.MetaFunc = f
+	}
+}
+
+// WithClock returns an Option that sets the clock providing the CachedAt
+// timestamp. It defaults to gouache.RealClock; tests can pass a
+// clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+	return o
+}
+
+// Cache is a cache implementation that stores each value together with its
+// metadata in an Envelope.
+type Cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+}
+
+// New creates a new metadata cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache that records metadata alongside stored values
+func New(c gouache.Cache, opts ...Option) *Cache {
+	return &Cache{Options: newOptions(opts...), Cache: c}
+}
+
+// Get retrieves a value from the cache by its key, discarding its metadata.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	val, _, err := cache.GetWithMeta(ctx, key)
+	return val, err
+}
+
+// GetWithMeta retrieves a value and its metadata from the cache by its key.
+//
+// Values that were not stored through this cache are returned as-is with a
+// zero Meta.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The metadata recorded when the value was stored
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithMeta(ctx context.Context, key string) (any, Meta, error) {
+	// Get the stored envelope from the underlying cache
+	val, err := cache.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, Meta{}, err
+	}
+
+	// Unwrap the envelope, which may come back by value or by pointer
+	// depending on the backend and its codec
+	switch envelope := val.(type) {
+	case *Envelope:
+		return envelope.Value, envelope.Meta, nil
+	case Envelope:
+		return envelope.Value, envelope.Meta, nil
+	default:
+		return val, Meta{}, nil
+	}
+}
+
+// Set wraps the value in an Envelope recording the current time and any custom
+// metadata, and stores it in the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	// Record the store time
+	envelope := &Envelope{Value: val, Meta: Meta{CachedAt: cache.Options.Clock.Now()}}
+
+	// Attach custom metadata if configured
+	if cache.Options.MetaFunc != nil {
+		envelope.Meta.Extra = cache.Options.MetaFunc(ctx, key, val)
+	}
+
+	return cache.Cache.Set(ctx, key, envelope)
+}
+
+// Delete removes a value from the cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *Cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
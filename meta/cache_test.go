@@ -0,0 +1,128 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/clocktest"
+	"github.com/soyacen/gouache/sample"
+)
+
+// jsonCache is a cache that stores values as JSON bytes, mimicking a
+// byte-oriented backend with a codec.
+type jsonCache struct {
+	data map[string][]byte
+}
+
+func (j *jsonCache) Get(ctx context.Context, key string) (any, error) {
+	data, ok := j.data[key]
+	if !ok {
+		return nil, gouache.ErrCacheMiss
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	return &envelope, nil
+}
+
+func (j *jsonCache) Set(ctx context.Context, key string, val any) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	j.data[key] = data
+	return nil
+}
+
+func (j *jsonCache) Delete(ctx context.Context, key string) error {
+	delete(j.data, key)
+	return nil
+}
+
+// TestCache_GetWithMeta tests that the store time and custom metadata are recorded.
+func TestCache_GetWithMeta(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cache := New(
+		&sample.Cache{},
+		WithClock(clocktest.NewClock(now)),
+		WithMetaFunc(func(ctx context.Context, key string, val any) map[string]string {
+			return map[string]string{"source": "test"}
+		}),
+	)
+
+	err := cache.Set(context.Background(), "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// Test GetWithMeta
+	val, meta, err := cache.GetWithMeta(context.Background(), "test-key")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if val != "test-value" {
+		t.Errorf("Expected test-value, but got %v", val)
+	}
+	if !meta.CachedAt.Equal(now) {
+		t.Errorf("Expected CachedAt %v, but got %v", now, meta.CachedAt)
+	}
+	if meta.Extra["source"] != "test" {
+		t.Errorf("Expected extra metadata source=test, but got %v", meta.Extra)
+	}
+
+	// Test plain Get unwraps the value
+	val, err = cache.Get(context.Background(), "test-key")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if val != "test-value" {
+		t.Errorf("Expected test-value, but got %v", val)
+	}
+}
+
+// TestCache_Serialized tests that the envelope survives a round-trip through a codec.
+func TestCache_Serialized(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cache := New(&jsonCache{data: map[string][]byte{}}, WithClock(clocktest.NewClock(now)))
+
+	err := cache.Set(context.Background(), "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	val, meta, err := cache.GetWithMeta(context.Background(), "test-key")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if val != "test-value" {
+		t.Errorf("Expected test-value, but got %v", val)
+	}
+	if !meta.CachedAt.Equal(now) {
+		t.Errorf("Expected CachedAt %v, but got %v", now, meta.CachedAt)
+	}
+}
+
+// TestCache_Miss tests that a cache miss is passed through.
+func TestCache_Miss(t *testing.T) {
+	cache := New(&sample.Cache{})
+
+	_, _, err := cache.GetWithMeta(context.Background(), "non-existent-key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+	}
+
+	// Test Delete removes the entry
+	_ = cache.Set(context.Background(), "test-key", "test-value")
+	if err := cache.Delete(context.Background(), "test-key"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	_, err = cache.Get(context.Background(), "test-key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss after deletion, but got: %v", err)
+	}
+}
@@ -0,0 +1,510 @@
+// Package metrics provides a cache implementation that records metrics about
+// the operations on a wrapped cache.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// reporting every Get, Set and Delete as an Event to a Recorder, which can
+// forward it to any metrics system. An expvar-backed Recorder is provided.
+//
+// The optional interfaces of the wrapped cache are forwarded, so wrapping
+// doesn't hide a backend's batch or TTL support. Batch reads and writes are
+// recorded per key; Iterate, ReadTTL and Touch are passed through unrecorded.
+//
+// WithKeyLabelFunc labels each event with a key family derived from its key,
+// giving per-family hit rates.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchSetter interface at compile time.
+var _ gouache.BatchSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Iterable interface at compile time.
+var _ gouache.Iterable = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*cache)(nil)
+
+// Ensure that cache implements the gouache.CompareDeleter interface at compile time.
+var _ gouache.CompareDeleter = (*cache)(nil)
+
+// Ensure that cache forwards capability probing at compile time.
+var _ gouache.CapabilityReporter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// Op identifies a cache operation.
+type Op string
+
+const (
+	// OpGet is a Get operation.
+	OpGet Op = "get"
+
+	// OpSet is a Set operation.
+	OpSet Op = "set"
+
+	// OpDelete is a Delete operation.
+	OpDelete Op = "delete"
+)
+
+// Result is the outcome of a cache operation.
+type Result string
+
+const (
+	// ResultHit is a Get that found its key.
+	ResultHit Result = "hit"
+
+	// ResultMiss is a Get that didn't find its key.
+	ResultMiss Result = "miss"
+
+	// ResultOK is a successful Set or Delete.
+	ResultOK Result = "ok"
+
+	// ResultError is a failed operation.
+	ResultError Result = "error"
+)
+
+// ErrorKind categorizes the error of a failed operation.
+type ErrorKind string
+
+const (
+	// ErrorKindMarshal is a failure to serialize a value, see gouache.ErrMarshal.
+	ErrorKindMarshal ErrorKind = "marshal"
+
+	// ErrorKindStorage is a failure to store a value, see gouache.ErrStorage.
+	ErrorKindStorage ErrorKind = "storage"
+
+	// ErrorKindContext is an operation aborted because its context was done.
+	ErrorKindContext ErrorKind = "context"
+
+	// ErrorKindOther is any other failure.
+	ErrorKindOther ErrorKind = "other"
+)
+
+// Event describes one cache operation.
+type Event struct {
+	// Op is the operation performed.
+	Op Op
+
+	// Result is the outcome of the operation.
+	Result Result
+
+	// ErrorKind categorizes the error when Result is ResultError, and is empty otherwise.
+	ErrorKind ErrorKind
+
+	// Duration is how long the operation took.
+	Duration time.Duration
+
+	// KeyLabel is the key family of the operation's key as derived by the
+	// KeyLabelFunc, or empty if none is set or the event covers a failed batch.
+	KeyLabel string
+}
+
+// Recorder receives the events of a metrics cache. Implementations must be
+// safe for concurrent use.
+type Recorder interface {
+	// Record records one cache operation.
+	//
+	// Parameters:
+	//   - ctx: Context of the operation
+	//   - event: The operation to record
+	Record(ctx context.Context, event Event)
+}
+
+// options holds configuration options for the metrics cache.
+type options struct {
+	// KeyLabelFunc derives the key family an event is labeled with.
+	KeyLabelFunc func(key string) string
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithKeyLabelFunc returns an Option that labels every event with the key
+// family of its key, such as "user" or "session", so that hit rates can be
+// broken down per family.
+//
+// Recorders typically turn each label into its own series, so f must return
+// one of a small, bounded set of labels: take the family from a known list,
+// for example by matching the first key segment, and map anything unexpected
+// to a catch-all such as "other". Returning key segments that contain IDs, or
+// the key itself, creates a series per key and can exhaust the memory of the
+// metrics system.
+//
+// Parameters:
+//   - f: A function returning the bounded key family of a key
+//
+// Returns:
+//   - An Option function that sets the KeyLabelFunc
+func WithKeyLabelFunc(f func(key string) string) Option {
+	return func(o *options) {
+		o.KeyLabelFunc = f
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Leave events unlabeled by default
+	if o.KeyLabelFunc == nil {
+		o.KeyLabelFunc = func(key string) string { return "" }
+	}
+	return o
+}
+
+// cache is a cache implementation that records an Event for every operation.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Recorder receives the events
+	Recorder Recorder
+
+	// Forwarder holds the underlying cache and forwards the operations the
+	// cache doesn't intercept to it
+	gouache.Forwarder
+}
+
+// New creates a new metrics cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - r: The Recorder receiving an Event for every operation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation that records metrics
+func New(c gouache.Cache, r Recorder, opts ...Option) gouache.Cache {
+	return &cache{Options: newOptions(opts...), Recorder: r, Forwarder: gouache.Forwarder{Cache: c}}
+}
+
+// Get retrieves a value from the underlying cache by its key, recording a
+// hit, a miss or an error.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	start := time.Now()
+	val, err := cache.Cache.Get(ctx, key)
+	cache.record(ctx, OpGet, cache.Options.KeyLabelFunc(key), err, time.Since(start))
+	return val, err
+}
+
+// Set stores a value in the underlying cache, recording its outcome.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	start := time.Now()
+	err := cache.Cache.Set(ctx, key, val)
+	cache.record(ctx, OpSet, cache.Options.KeyLabelFunc(key), err, time.Since(start))
+	return err
+}
+
+// Delete removes a value from the underlying cache, recording its outcome.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := cache.Cache.Delete(ctx, key)
+	cache.record(ctx, OpDelete, cache.Options.KeyLabelFunc(key), err, time.Since(start))
+	return err
+}
+
+// record builds the Event for an operation and hands it to the Recorder.
+//
+// Parameters:
+//   - ctx: Context of the operation
+//   - op: The operation performed
+//   - label: The key family of the operation's key, or empty
+//   - err: The error returned by the operation
+//   - d: How long the operation took
+func (cache *cache) record(ctx context.Context, op Op, label string, err error, d time.Duration) {
+	event := Event{Op: op, Duration: d, KeyLabel: label}
+	switch {
+	case err == nil && op == OpGet:
+		event.Result = ResultHit
+	case err == nil:
+		event.Result = ResultOK
+	case op == OpGet && errors.Is(err, gouache.ErrCacheMiss):
+		event.Result = ResultMiss
+	default:
+		event.Result = ResultError
+		event.ErrorKind = Classify(err)
+	}
+	cache.Recorder.Record(ctx, event)
+}
+
+// GetMulti retrieves the values for the given keys from the underlying cache,
+// using its native GetMulti when available, and records a hit or a miss for
+// each key. Each of those events carries the average per-key duration of the
+// batch. A failed batch is recorded as a single error.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - An error if the operation fails
+func (cache *cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	start := time.Now()
+	vals, err := gouache.GetMulti(ctx, cache.Cache, keys)
+	d := time.Since(start)
+	if err != nil {
+		cache.record(ctx, OpGet, "", err, d)
+		return nil, err
+	}
+	if len(keys) > 0 {
+		d /= time.Duration(len(keys))
+	}
+	for _, key := range keys {
+		if _, ok := vals[key]; ok {
+			cache.record(ctx, OpGet, cache.Options.KeyLabelFunc(key), nil, d)
+		} else {
+			cache.record(ctx, OpGet, cache.Options.KeyLabelFunc(key), gouache.ErrCacheMiss, d)
+		}
+	}
+	return vals, nil
+}
+
+// SetMulti stores each value in vals in the underlying cache, using its native
+// SetMulti when available, and records a Set for each value. Each of those
+// events carries the average per-key duration of the batch. A failed batch is
+// recorded as a single error.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	start := time.Now()
+	err := gouache.SetMulti(ctx, cache.Cache, vals)
+	d := time.Since(start)
+	if err != nil {
+		cache.record(ctx, OpSet, "", err, d)
+		return err
+	}
+	if len(vals) > 0 {
+		d /= time.Duration(len(vals))
+	}
+	for key := range vals {
+		cache.record(ctx, OpSet, cache.Options.KeyLabelFunc(key), nil, d)
+	}
+	return nil
+}
+
+// DeleteMulti removes the values of the given keys from the underlying cache,
+// using its native DeleteMulti when available, and records a Delete for each
+// key. Each of those events carries the average per-key duration of the
+// batch. A failed batch is recorded as a single error.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) DeleteMulti(ctx context.Context, keys []string) error {
+	start := time.Now()
+	err := gouache.DeleteMulti(ctx, cache.Cache, keys)
+	d := time.Since(start)
+	if err != nil {
+		cache.record(ctx, OpDelete, "", err, d)
+		return err
+	}
+	if len(keys) > 0 {
+		d /= time.Duration(len(keys))
+	}
+	for _, key := range keys {
+		cache.record(ctx, OpDelete, cache.Options.KeyLabelFunc(key), nil, d)
+	}
+	return nil
+}
+
+// DeleteMultiResult removes the values of the given keys from the underlying
+// cache and reports which of them existed, using its native DeleteMultiResult
+// when available, and records events like DeleteMulti.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - An error if the operation fails
+func (cache *cache) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	start := time.Now()
+	deleted, err := gouache.DeleteMultiResult(ctx, cache.Cache, keys)
+	d := time.Since(start)
+	if err != nil {
+		cache.record(ctx, OpDelete, "", err, d)
+		return deleted, err
+	}
+	if len(keys) > 0 {
+		d /= time.Duration(len(keys))
+	}
+	for _, key := range keys {
+		cache.record(ctx, OpDelete, cache.Options.KeyLabelFunc(key), nil, d)
+	}
+	return deleted, nil
+}
+
+// Add stores a value in the underlying cache only if the key is absent,
+// recording it as a Set whether or not the value was stored.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - gouache.ErrUnsupported if the underlying cache isn't an Adder, or an error if the operation fails
+func (cache *cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	adder, ok := cache.Cache.(gouache.Adder)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	start := time.Now()
+	added, err := adder.Add(ctx, key, val)
+	cache.record(ctx, OpSet, cache.Options.KeyLabelFunc(key), err, time.Since(start))
+	return added, err
+}
+
+// SetWithTTL stores a value in the underlying cache with an explicit TTL,
+// recording it as a Set.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLSetter, or an error if the operation fails
+func (cache *cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	setter, ok := cache.Cache.(gouache.TTLSetter)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	start := time.Now()
+	err := setter.SetWithTTL(ctx, key, val, ttl)
+	cache.record(ctx, OpSet, cache.Options.KeyLabelFunc(key), err, time.Since(start))
+	return err
+}
+
+// CompareAndDelete deletes the entry stored under key if it still holds
+// expected in the underlying cache, recording it as a Delete.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - expected: The value the entry must hold to be deleted
+//
+// Returns:
+//   - true if the entry was deleted, false if it was missing or held another value
+//   - gouache.ErrUnsupported if the underlying cache isn't a CompareDeleter, or an error if the operation fails
+func (cache *cache) CompareAndDelete(ctx context.Context, key string, expected any) (bool, error) {
+	deleter, ok := cache.Cache.(gouache.CompareDeleter)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	start := time.Now()
+	deleted, err := deleter.CompareAndDelete(ctx, key, expected)
+	cache.record(ctx, OpDelete, cache.Options.KeyLabelFunc(key), err, time.Since(start))
+	return deleted, err
+}
+
+// Classify returns the ErrorKind of an error returned by a cache operation.
+//
+// Parameters:
+//   - err: The error to classify
+//
+// Returns:
+//   - The kind of the error
+func Classify(err error) ErrorKind {
+	switch {
+	case errors.Is(err, gouache.ErrMarshal):
+		return ErrorKindMarshal
+	case errors.Is(err, gouache.ErrStorage):
+		return ErrorKindStorage
+	case errors.Is(err, gouache.ErrContext), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorKindContext
+	default:
+		return ErrorKindOther
+	}
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// eventRecorder is a Recorder that keeps every event.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) Record(ctx context.Context, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// failingCache is a cache whose Set always fails with err.
+type failingCache struct {
+	sample.Cache
+	err error
+}
+
+func (f *failingCache) Set(ctx context.Context, key string, val any) error {
+	return f.err
+}
+
+// TestCache_Record tests that every operation is recorded with its outcome
+func TestCache_Record(t *testing.T) {
+	recorder := &eventRecorder{}
+	cache := New(&sample.Cache{}, recorder)
+	ctx := context.Background()
+
+	_, _ = cache.Get(ctx, "test-key")
+	_ = cache.Set(ctx, "test-key", "test-value")
+	_, _ = cache.Get(ctx, "test-key")
+	_ = cache.Delete(ctx, "test-key")
+
+	want := []struct {
+		op     Op
+		result Result
+	}{
+		{OpGet, ResultMiss},
+		{OpSet, ResultOK},
+		{OpGet, ResultHit},
+		{OpDelete, ResultOK},
+	}
+	if len(recorder.events) != len(want) {
+		t.Fatalf("Expected %d events, got %v", len(want), recorder.events)
+	}
+	for i, w := range want {
+		got := recorder.events[i]
+		if got.Op != w.op || got.Result != w.result || got.ErrorKind != "" {
+			t.Errorf("Event %d: Expected %s %s, got %+v", i, w.op, w.result, got)
+		}
+	}
+}
+
+// TestCache_ErrorKind tests that failures are categorized
+func TestCache_ErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind ErrorKind
+	}{
+		{err: fmt.Errorf("%w: %w", gouache.ErrMarshal, errors.New("bad value")), kind: ErrorKindMarshal},
+		{err: fmt.Errorf("%w: %w", gouache.ErrStorage, errors.New("full")), kind: ErrorKindStorage},
+		{err: fmt.Errorf("%w: %w", gouache.ErrContext, context.Canceled), kind: ErrorKindContext},
+		{err: errors.New("boom"), kind: ErrorKindOther},
+	}
+
+	for _, tt := range tests {
+		recorder := &eventRecorder{}
+		cache := New(&failingCache{err: tt.err}, recorder)
+		err := cache.Set(context.Background(), "test-key", "test-value")
+		if err != tt.err {
+			t.Errorf("Expected %v, got %v", tt.err, err)
+		}
+		got := recorder.events[0]
+		if got.Result != ResultError || got.ErrorKind != tt.kind {
+			t.Errorf("Expected error of kind %s, got %+v", tt.kind, got)
+		}
+	}
+}
+
+// TestCache_Capabilities tests that the optional interfaces of the backend
+// survive wrapping and that batch operations are recorded per key
+func TestCache_Capabilities(t *testing.T) {
+	recorder := &eventRecorder{}
+	backend := &sample.Cache{}
+	cache := New(backend, recorder)
+	ctx := context.Background()
+
+	if caps := gouache.Capabilities(cache); caps != gouache.Capabilities(backend) {
+		t.Errorf("Expected %+v, got %+v", gouache.Capabilities(backend), caps)
+	}
+
+	if err := gouache.SetMulti(ctx, cache, map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+	vals, err := gouache.GetMulti(ctx, cache, []string{"a", "b", "c"})
+	if err != nil || len(vals) != 2 {
+		t.Errorf("Expected a and b, got %v, %v", vals, err)
+	}
+
+	want := []Result{ResultOK, ResultOK, ResultHit, ResultHit, ResultMiss}
+	if len(recorder.events) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(recorder.events))
+	}
+	for i, event := range recorder.events {
+		if event.Result != want[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i, want[i], event.Result)
+		}
+	}
+}
+
+// TestCache_KeyLabel tests that events are labeled with the key family
+func TestCache_KeyLabel(t *testing.T) {
+	recorder := &eventRecorder{}
+	cache := New(&sample.Cache{}, recorder, WithKeyLabelFunc(func(key string) string {
+		family, _, _ := strings.Cut(key, ":")
+		switch family {
+		case "user", "session":
+			return family
+		default:
+			return "other"
+		}
+	}))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "user:1", 1)
+	_, _ = cache.Get(ctx, "user:1")
+	_, _ = cache.Get(ctx, "user:2")
+	_, _ = cache.Get(ctx, "session:abc")
+	_, _ = gouache.GetMulti(ctx, cache, []string{"user:1", "tmp:9"})
+
+	// Group the results by label
+	got := map[string][]Result{}
+	for _, event := range recorder.events {
+		got[event.KeyLabel] = append(got[event.KeyLabel], event.Result)
+	}
+	want := map[string][]Result{
+		"user":    {ResultOK, ResultHit, ResultMiss, ResultHit},
+		"session": {ResultMiss},
+		"other":   {ResultMiss},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"context"
+	"expvar"
+	"sync"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that ExpvarRecorder implements the Recorder interface at compile time.
+var _ Recorder = (*ExpvarRecorder)(nil)
+
+// ExpvarRecorder is a Recorder that publishes counters as expvar variables,
+// giving a zero-dependency view of cache activity on the /debug/vars page.
+//
+// For a prefix p, it publishes the integers p.hits, p.misses, p.sets,
+// p.deletes and p.errors, the map p.errors_by_kind keyed by ErrorKind, the
+// map p.by_label counting labeled events under "<label>.<counter>", such as
+// "user.hits", and, if a size function is given, p.size.
+type ExpvarRecorder struct {
+	// Hits counts Gets that found their key.
+	Hits *expvar.Int
+
+	// Misses counts Gets that didn't find their key.
+	Misses *expvar.Int
+
+	// Sets counts successful Sets.
+	Sets *expvar.Int
+
+	// Deletes counts successful Deletes.
+	Deletes *expvar.Int
+
+	// Errors counts failed operations.
+	Errors *expvar.Int
+
+	// ErrorsByKind counts failed operations by ErrorKind.
+	ErrorsByKind *expvar.Map
+
+	// ByLabel counts events carrying a KeyLabel by label and counter.
+	ByLabel *expvar.Map
+}
+
+// NewExpvarRecorder creates an ExpvarRecorder publishing its variables under prefix.
+//
+// Like expvar.Publish, it panics if a variable with the same name is already
+// published, so it must be called once per prefix, typically at startup.
+//
+// Parameters:
+//   - prefix: The prefix of the published variable names
+//   - size: An optional function reporting the number of entries in the cache,
+//     such as the Len of an LRU, published as prefix.size; may be nil
+//
+// Returns:
+//   - A new ExpvarRecorder
+func NewExpvarRecorder(prefix string, size func() int) *ExpvarRecorder {
+	r := &ExpvarRecorder{
+		Hits:         expvar.NewInt(prefix + ".hits"),
+		Misses:       expvar.NewInt(prefix + ".misses"),
+		Sets:         expvar.NewInt(prefix + ".sets"),
+		Deletes:      expvar.NewInt(prefix + ".deletes"),
+		Errors:       expvar.NewInt(prefix + ".errors"),
+		ErrorsByKind: expvar.NewMap(prefix + ".errors_by_kind"),
+		ByLabel:      expvar.NewMap(prefix + ".by_label"),
+	}
+	if size != nil {
+		expvar.Publish(prefix+".size", expvar.Func(func() any { return size() }))
+	}
+	return r
+}
+
+// publishBackgroundErrors publishes gouache.BackgroundErrors once.
+var publishBackgroundErrors sync.Once
+
+// PublishBackgroundErrors publishes gouache.BackgroundErrors, the number of
+// errors handled by gouache.DefaultErrorHandler, as the expvar integer
+// "gouache.background_errors". The gouache package doesn't publish it itself,
+// since importing expvar registers the /debug/vars handler; calling this
+// opts in. It may be called more than once.
+func PublishBackgroundErrors() {
+	publishBackgroundErrors.Do(func() {
+		expvar.Publish("gouache.background_errors", expvar.Func(func() any { return gouache.BackgroundErrors() }))
+	})
+}
+
+// Record increments the counters matching event.
+//
+// Parameters:
+//   - ctx: Context of the operation (not used in this implementation)
+//   - event: The operation to record
+func (r *ExpvarRecorder) Record(ctx context.Context, event Event) {
+	var counter string
+	switch event.Result {
+	case ResultHit:
+		r.Hits.Add(1)
+		counter = "hits"
+	case ResultMiss:
+		r.Misses.Add(1)
+		counter = "misses"
+	case ResultError:
+		r.Errors.Add(1)
+		r.ErrorsByKind.Add(string(event.ErrorKind), 1)
+		counter = "errors"
+	case ResultOK:
+		if event.Op == OpSet {
+			r.Sets.Add(1)
+			counter = "sets"
+		} else {
+			r.Deletes.Add(1)
+			counter = "deletes"
+		}
+	}
+
+	// Count labeled events per key family as well
+	if event.KeyLabel != "" && counter != "" {
+		r.ByLabel.Add(event.KeyLabel+"."+counter, 1)
+	}
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strconv"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestExpvarRecorder tests reading back the published expvar values
+func TestExpvarRecorder(t *testing.T) {
+	backend := &sample.Cache{}
+	size := 0
+	cache := New(backend, NewExpvarRecorder("test_cache", func() int { return size }))
+	ctx := context.Background()
+
+	// Perform some operations
+	_, _ = cache.Get(ctx, "key1")
+	_ = cache.Set(ctx, "key1", "value1")
+	_ = cache.Set(ctx, "key2", "value2")
+	_, _ = cache.Get(ctx, "key1")
+	_, _ = cache.Get(ctx, "key2")
+	_ = cache.Delete(ctx, "key2")
+	size = 1
+
+	// Read back the published values
+	want := map[string]string{
+		"test_cache.hits":    "2",
+		"test_cache.misses":  "1",
+		"test_cache.sets":    "2",
+		"test_cache.deletes": "1",
+		"test_cache.errors":  "0",
+		"test_cache.size":    "1",
+	}
+	for name, value := range want {
+		v := expvar.Get(name)
+		if v == nil {
+			t.Errorf("Expected %s to be published", name)
+			continue
+		}
+		if v.String() != value {
+			t.Errorf("Expected %s = %s, got %s", name, value, v.String())
+		}
+	}
+
+	// Errors are counted by kind
+	failing := New(&failingCache{err: errors.New("boom")}, NewExpvarRecorder("test_failing", nil))
+	_ = failing.Set(ctx, "key", "value")
+	if v := expvar.Get("test_failing.errors_by_kind").String(); v != `{"other": 1}` {
+		t.Errorf("Expected errors by kind {\"other\": 1}, got %s", v)
+	}
+	if expvar.Get("test_failing.size") != nil {
+		t.Error("Expected no size without a size function")
+	}
+}
+
+// TestExpvarRecorder_ByLabel tests that labeled events are counted per label
+func TestExpvarRecorder_ByLabel(t *testing.T) {
+	cache := New(&sample.Cache{}, NewExpvarRecorder("test_labeled", nil), WithKeyLabelFunc(func(key string) string {
+		return key[:1]
+	}))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a1", 1)
+	_, _ = cache.Get(ctx, "a1")
+	_, _ = cache.Get(ctx, "a2")
+	_, _ = cache.Get(ctx, "b1")
+
+	want := `{"a.hits": 1, "a.misses": 1, "a.sets": 1, "b.misses": 1}`
+	if v := expvar.Get("test_labeled.by_label").String(); v != want {
+		t.Errorf("Expected %s, got %s", want, v)
+	}
+}
+
+// TestPublishBackgroundErrors tests publishing the background error count,
+// more than once
+func TestPublishBackgroundErrors(t *testing.T) {
+	PublishBackgroundErrors()
+	PublishBackgroundErrors()
+
+	before := gouache.BackgroundErrors()
+	gouache.DefaultErrorHandler("metrics.TestPublishBackgroundErrors")(errors.New("background failure"))
+	want := strconv.FormatInt(before+1, 10)
+	if v := expvar.Get("gouache.background_errors").String(); v != want {
+		t.Errorf("Expected %s, got %s", want, v)
+	}
+}
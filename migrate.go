@@ -0,0 +1,312 @@
+package gouache
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// ErrNotIterable is returned by Migrate when the source cache doesn't
+// support Iterable, as reported by Capabilities.
+var ErrNotIterable = errors.New("gouache: cache is not iterable")
+
+// migrateOptions holds configuration options for Migrate.
+type migrateOptions struct {
+	// Match is the pattern keys must match to be migrated.
+	Match string
+
+	// Filter decides whether a key and its value are migrated.
+	Filter func(key string, val any) bool
+
+	// BatchSize is the number of keys read and written at a time.
+	BatchSize int
+
+	// Progress is called after each batch with the number of values copied so far.
+	Progress func(copied int)
+
+	// Sorted makes the migration copy keys in ascending order.
+	Sorted bool
+
+	// DryRun makes the migration read and count values without writing them.
+	DryRun bool
+
+	// Plan is called for every value that is copied, or would be in a dry run.
+	Plan func(key string, size int)
+}
+
+// MigrateOption is a function that modifies the Migrate options.
+type MigrateOption func(*migrateOptions)
+
+// WithMigrateMatch returns a MigrateOption that restricts the migration to
+// keys matching the glob-style pattern match, which is passed to the source's
+// Iterate.
+//
+// Parameters:
+//   - match: The pattern keys must match
+//
+// Returns:
+//   - A MigrateOption function that sets the Match
+func WithMigrateMatch(match string) MigrateOption {
+	return func(o *migrateOptions) {
+		o.Match = match
+	}
+}
+
+// WithMigrateFilter returns a MigrateOption that only migrates the values for
+// which filter returns true.
+//
+// Parameters:
+//   - filter: A predicate called with each key and its value
+//
+// Returns:
+//   - A MigrateOption function that sets the Filter
+func WithMigrateFilter(filter func(key string, val any) bool) MigrateOption {
+	return func(o *migrateOptions) {
+		o.Filter = filter
+	}
+}
+
+// WithMigrateBatchSize returns a MigrateOption that sets the number of keys
+// read from the source and written to the destination at a time.
+//
+// Parameters:
+//   - n: The batch size; non-positive values use the default of 100
+//
+// Returns:
+//   - A MigrateOption function that sets the BatchSize
+func WithMigrateBatchSize(n int) MigrateOption {
+	return func(o *migrateOptions) {
+		o.BatchSize = n
+	}
+}
+
+// WithMigrateProgress returns a MigrateOption that reports progress after
+// every batch.
+//
+// Parameters:
+//   - f: A function called with the number of values copied so far
+//
+// Returns:
+//   - A MigrateOption function that sets the Progress
+func WithMigrateProgress(f func(copied int)) MigrateOption {
+	return func(o *migrateOptions) {
+		o.Progress = f
+	}
+}
+
+// WithMigrateSorted returns a MigrateOption that copies keys in ascending
+// order, one Set at a time, so that the writes reaching dst, such as a
+// snapshot written by the record decorator, are in a stable, diffable order.
+//
+// Sorting requires collecting every key of src before copying, so it holds
+// the full key set in memory and is off by default. Keys reported more than
+// once by Iterate are copied only once.
+//
+// Parameters:
+//   - sorted: Whether to copy keys in ascending order
+//
+// Returns:
+//   - A MigrateOption function that sets Sorted
+func WithMigrateSorted(sorted bool) MigrateOption {
+	return func(o *migrateOptions) {
+		o.Sorted = sorted
+	}
+}
+
+// WithMigrateDryRun returns a MigrateOption that previews a migration: keys
+// are enumerated, read and filtered as usual, and counted, but nothing is
+// written to dst. Combine it with WithMigratePlan to list what would be
+// copied, and with WithMigrateProgress to follow the scan.
+//
+// Parameters:
+//   - dryRun: Whether to skip writing to dst
+//
+// Returns:
+//   - A MigrateOption function that sets DryRun
+func WithMigrateDryRun(dryRun bool) MigrateOption {
+	return func(o *migrateOptions) {
+		o.DryRun = dryRun
+	}
+}
+
+// WithMigratePlan returns a MigrateOption that calls f for every value that
+// is copied, or would be copied in a dry run, before it is written. The size
+// is the length in bytes of string and []byte values, and -1 for values of
+// other types, whose size isn't known.
+//
+// Parameters:
+//   - f: A function called with each key and the size of its value
+//
+// Returns:
+//   - A MigrateOption function that sets the Plan
+func WithMigratePlan(f func(key string, size int)) MigrateOption {
+	return func(o *migrateOptions) {
+		o.Plan = f
+	}
+}
+
+// Migrate copies every key and value from src to dst and returns the number
+// of values copied, or that would be copied in a dry run.
+//
+// Keys are enumerated with src's Iterate and processed in batches: values are
+// read with GetMulti, so src's BatchGetter is used when available, and
+// written with dst's SetMulti if dst implements BatchSetter, or one Set per
+// value otherwise. Keys that disappear from src during the migration are
+// skipped. Since Iterate may report a key more than once, a value may be
+// copied, and counted, more than once.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - src: The cache to copy from, which must implement Iterable
+//   - dst: The cache to copy to
+//   - opts: Variable number of MigrateOption functions to configure the migration
+//
+// Returns:
+//   - The number of values copied, including those copied before a failure
+//   - ErrNotIterable if src doesn't support Iterable, or an error if reading or writing fails
+func Migrate(ctx context.Context, src, dst Cache, opts ...MigrateOption) (int, error) {
+	// Ensure the source can enumerate its keys
+	iterable, ok := src.(Iterable)
+	if !ok || !Capabilities(src).Iterate {
+		return 0, ErrNotIterable
+	}
+
+	// Apply the options
+	o := &migrateOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+
+	copied := 0
+	keys := make([]string, 0, o.BatchSize)
+	flush := func() error {
+		// Read the batch from the source, skipping keys that are gone
+		batch := keys
+		vals, err := GetMulti(ctx, src, batch)
+		keys = keys[:0]
+		if err != nil {
+			return err
+		}
+
+		// Drop the values rejected by the filter
+		if o.Filter != nil {
+			for key, val := range vals {
+				if !o.Filter(key, val) {
+					delete(vals, key)
+				}
+			}
+		}
+
+		// Report the values about to be copied
+		if o.Plan != nil {
+			for _, key := range batch {
+				if val, ok := vals[key]; ok {
+					o.Plan(key, valueSize(val))
+				}
+			}
+		}
+
+		// Write the batch to the destination, in key order if sorted
+		if o.DryRun {
+			copied += len(vals)
+		} else if o.Sorted {
+			for _, key := range batch {
+				val, ok := vals[key]
+				if !ok {
+					continue
+				}
+				if err := dst.Set(ctx, key, val); err != nil {
+					return err
+				}
+				copied++
+			}
+		} else if setter, ok := dst.(BatchSetter); ok && Capabilities(dst).BatchSet && len(vals) > 0 {
+			if err := setter.SetMulti(ctx, vals); err != nil {
+				return err
+			}
+			copied += len(vals)
+		} else {
+			for key, val := range vals {
+				if err := dst.Set(ctx, key, val); err != nil {
+					return err
+				}
+				copied++
+			}
+		}
+
+		// Report progress
+		if o.Progress != nil {
+			o.Progress(copied)
+		}
+		return nil
+	}
+
+	// Collect and sort every key first, then copy them in batches
+	if o.Sorted {
+		seen := make(map[string]struct{})
+		var all []string
+		err := iterable.Iterate(ctx, o.Match, func(key string) error {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				all = append(all, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		sort.Strings(all)
+		for start := 0; start < len(all); start += o.BatchSize {
+			end := start + o.BatchSize
+			if end > len(all) {
+				end = len(all)
+			}
+			keys = append(keys, all[start:end]...)
+			if err := flush(); err != nil {
+				return copied, err
+			}
+		}
+		return copied, nil
+	}
+
+	// Collect keys into batches, flushing each batch when it is full
+	err := iterable.Iterate(ctx, o.Match, func(key string) error {
+		keys = append(keys, key)
+		if len(keys) < o.BatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return copied, err
+	}
+
+	// Flush the last partial batch
+	if len(keys) > 0 {
+		if err := flush(); err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
+
+// valueSize returns the size in bytes of a value, if known.
+//
+// Parameters:
+//   - val: The value
+//
+// Returns:
+//   - The length of string and []byte values, or -1 for other types
+func valueSize(val any) int {
+	switch val := val.(type) {
+	case string:
+		return len(val)
+	case []byte:
+		return len(val)
+	default:
+		return -1
+	}
+}
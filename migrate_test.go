@@ -0,0 +1,133 @@
+package gouache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestMigrate tests copying values between caches with gouache.Migrate.
+func TestMigrate(t *testing.T) {
+	// Setup: store some values in the source
+	src := &sample.Cache{}
+	for i := 0; i < 25; i++ {
+		err := src.Set(context.Background(), fmt.Sprintf("key-%d", i), i)
+		if err != nil {
+			t.Fatalf("Failed to set up test value: %v", err)
+		}
+	}
+
+	// Test migrating even values in batches of 10
+	dst := &sample.Cache{}
+	var progress []int
+	n, err := gouache.Migrate(context.Background(), src, dst,
+		gouache.WithMigrateBatchSize(10),
+		gouache.WithMigrateFilter(func(key string, val any) bool { return val.(int)%2 == 0 }),
+		gouache.WithMigrateProgress(func(copied int) { progress = append(progress, copied) }),
+	)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if n != 13 {
+		t.Errorf("Expected 13 values copied, but got %d", n)
+	}
+	if len(progress) != 3 || progress[2] != 13 {
+		t.Errorf("Expected 3 progress reports ending at 13, but got %v", progress)
+	}
+
+	// Verify the destination holds exactly the filtered values
+	for i := 0; i < 25; i++ {
+		val, err := dst.Get(context.Background(), fmt.Sprintf("key-%d", i))
+		if i%2 == 0 && (err != nil || val != i) {
+			t.Errorf("Expected %v, but got %v, %v", i, val, err)
+		}
+		if i%2 == 1 && err != gouache.ErrCacheMiss {
+			t.Errorf("Expected ErrCacheMiss for key-%d, but got: %v", i, err)
+		}
+	}
+}
+
+// orderCache records the order in which keys are stored.
+type orderCache struct {
+	sample.Cache
+	order []string
+}
+
+func (c *orderCache) Set(ctx context.Context, key string, val any) error {
+	c.order = append(c.order, key)
+	return c.Cache.Set(ctx, key, val)
+}
+
+// TestMigrate_Sorted tests that WithMigrateSorted copies keys in ascending order.
+func TestMigrate_Sorted(t *testing.T) {
+	// Setup: store some values in the source
+	src := &sample.Cache{}
+	for _, key := range []string{"c", "a", "e", "b", "d"} {
+		if err := src.Set(context.Background(), key, key); err != nil {
+			t.Fatalf("Failed to set up test value: %v", err)
+		}
+	}
+
+	// Test migrating in batches of 2 to a destination recording the write order
+	dst := &orderCache{}
+	n, err := gouache.Migrate(context.Background(), src, dst,
+		gouache.WithMigrateBatchSize(2),
+		gouache.WithMigrateSorted(true),
+	)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	want := []string{"a", "b", "c", "d", "e"}
+	if n != len(want) || fmt.Sprint(dst.order) != fmt.Sprint(want) {
+		t.Errorf("Expected %v copied in order, but got %d copied in order %v", want, n, dst.order)
+	}
+}
+
+// TestMigrate_DryRun tests that a dry run reports the plan without writing to the destination.
+func TestMigrate_DryRun(t *testing.T) {
+	// Setup: store some values in the source
+	src := &sample.Cache{}
+	vals := map[string]any{"a": "xx", "b": []byte("yyy"), "c": 1}
+	if err := src.SetMulti(context.Background(), vals); err != nil {
+		t.Fatalf("Failed to set up test values: %v", err)
+	}
+
+	// Test a dry run collecting the plan
+	dst := &orderCache{}
+	sizes := make(map[string]int)
+	n, err := gouache.Migrate(context.Background(), src, dst,
+		gouache.WithMigrateDryRun(true),
+		gouache.WithMigratePlan(func(key string, size int) { sizes[key] = size }),
+	)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if n != len(vals) {
+		t.Errorf("Expected %d values to be counted, but got %d", len(vals), n)
+	}
+	want := map[string]int{"a": 2, "b": 3, "c": -1}
+	if fmt.Sprint(sizes) != fmt.Sprint(want) {
+		t.Errorf("Expected plan %v, but got %v", want, sizes)
+	}
+
+	// Verify the destination is untouched
+	if len(dst.order) != 0 {
+		t.Errorf("Expected no writes, but got %v", dst.order)
+	}
+	if _, err := dst.Get(context.Background(), "a"); err != gouache.ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+	}
+}
+
+// TestMigrate_NotIterable tests that Migrate rejects sources that can't be iterated.
+func TestMigrate_NotIterable(t *testing.T) {
+	var src struct{ gouache.Cache }
+	src.Cache = &sample.Cache{}
+	_, err := gouache.Migrate(context.Background(), src, &sample.Cache{})
+	if err != gouache.ErrNotIterable {
+		t.Errorf("Expected ErrNotIterable, but got: %v", err)
+	}
+}
@@ -0,0 +1,467 @@
+// Package missreason provides a cache implementation that attributes each
+// cache miss to a reason, such as a cold key, an expiration or an eviction,
+// and counts the misses per reason.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// remembering, for every key written through it, when the entry expires and
+// whether it was removed since. A Get that misses is then classified from
+// what is known about the key. The attribution is best effort and depends on
+// the signals the backend provides:
+//   - Deletes made through the cache are always recognized.
+//   - Expirations are recognized for backends implementing gouache.TTLReader,
+//     such as fc, whose TTL is read back after each Set, and for entries
+//     written with SetWithTTL or Touch.
+//   - Evictions are recognized when the backend's eviction callback calls
+//     Removed: golang-lru's onEvict callback for lru, bigcache's
+//     OnRemoveWithReason for bc, which also reports expirations, and
+//     go-cache's OnEvicted for gc, which fires for expirations too.
+//
+// Misses that can't be told apart are counted as ReasonUnknown. Since only
+// keys written through this cache instance are tracked, shared backends such
+// as redis report keys written by other processes as cold.
+package missreason
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*Cache)(nil)
+
+// Ensure that Cache forwards capability probing at compile time.
+var _ gouache.CapabilityReporter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*Cache)(nil)
+
+// Reason is the cause a cache miss is attributed to.
+type Reason string
+
+const (
+	// ReasonCold is a miss of a key never written through the cache.
+	ReasonCold Reason = "cold"
+
+	// ReasonExpired is a miss of a key whose time-to-live has passed.
+	ReasonExpired Reason = "expired"
+
+	// ReasonEvicted is a miss of a key the backend evicted to make room.
+	ReasonEvicted Reason = "evicted"
+
+	// ReasonDeleted is a miss of a key deleted through the cache.
+	ReasonDeleted Reason = "deleted"
+
+	// ReasonUnknown is a miss whose cause can't be determined.
+	ReasonUnknown Reason = "unknown"
+)
+
+// options holds configuration options for the miss reason cache.
+type options struct {
+	// MaxKeys is the maximum number of keys tracked.
+	MaxKeys int
+
+	// OnMiss is called with the reason of every miss.
+	OnMiss func(key string, reason Reason)
+
+	// Clock is used to tell whether an entry has expired.
+	Clock gouache.Clock
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithMaxKeys returns an Option that bounds the number of keys tracked. Once
+// the limit is reached, keys written for the first time are no longer
+// tracked, and their misses, like those of keys never written, are counted as
+// ReasonUnknown instead of ReasonCold.
+//
+// Parameters:
+//   - n: The maximum number of keys; non-positive values use the default of 100000
+//
+// Returns:
+//   - An Option function that sets MaxKeys
+func WithMaxKeys(n int) Option {
+	return func(o *options) {
+		o.MaxKeys = n
+	}
+}
+
+// WithOnMiss returns an Option that sets a function called with the key and
+// reason of every miss, for example to log misses of a particular key.
+//
+// Parameters:
+//   - f: The function called for every miss
+//
+// Returns:
+//   - An Option function that sets OnMiss
+func WithOnMiss(f func(key string, reason Reason)) Option {
+	return func(o *options) {
+		o.OnMiss = f
+	}
+}
+
+// WithClock returns an Option that sets the clock used to tell whether an
+// entry has expired. It defaults to gouache.RealClock; tests can pass a
+// clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default key limit to 100000 if not specified or invalid
+	if o.MaxKeys <= 0 {
+		o.MaxKeys = 100000
+	}
+
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+	return o
+}
+
+// state is what is known about a key written through the cache.
+type state struct {
+	// expiresAt is when the entry expires, or zero if unknown or never
+	expiresAt time.Time
+
+	// removed is the reason the entry was removed, or empty if it wasn't
+	removed Reason
+}
+
+// Cache is a cache implementation that counts misses per reason.
+type Cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+
+	// mu guards keys, overflowed and counts
+	mu sync.Mutex
+
+	// keys holds the state of the tracked keys
+	keys map[string]*state
+
+	// overflowed reports whether a key was left untracked because of MaxKeys
+	overflowed bool
+
+	// counts holds the number of misses per reason
+	counts map[Reason]uint64
+}
+
+// New creates a new miss reason cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache that attributes misses to reasons
+func New(c gouache.Cache, opts ...Option) *Cache {
+	return &Cache{
+		Options: newOptions(opts...),
+		Cache:   c,
+		keys:    make(map[string]*state),
+		counts:  make(map[Reason]uint64),
+	}
+}
+
+// Get retrieves a value from the underlying cache by its key, attributing a
+// miss to a reason.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	val, err := cache.Cache.Get(ctx, key)
+	if errors.Is(err, gouache.ErrCacheMiss) {
+		cache.miss(key)
+	}
+	return val, err
+}
+
+// Set stores a value in the underlying cache and starts tracking the key. If
+// the underlying cache implements gouache.TTLReader, the TTL of the entry is
+// read back to recognize its expiration.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	if err := cache.Cache.Set(ctx, key, val); err != nil {
+		return err
+	}
+
+	// Learn when the entry expires, if the backend can tell
+	var ttl time.Duration
+	if reader, ok := cache.Cache.(gouache.TTLReader); ok && gouache.Capabilities(cache.Cache).ReadTTL {
+		ttl, _ = reader.ReadTTL(ctx, key)
+	}
+	cache.track(key, ttl)
+	return nil
+}
+
+// Delete removes a value from the underlying cache, remembering that the key
+// was deleted.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	if err := cache.Cache.Delete(ctx, key); err != nil {
+		return err
+	}
+	cache.Removed(key, ReasonDeleted)
+	return nil
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *Cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// SetWithTTL stores a value with an explicit TTL and starts tracking the key
+// with that TTL.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLSetter, or an error if the operation fails
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	setter, ok := cache.Cache.(gouache.TTLSetter)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	if err := setter.SetWithTTL(ctx, key, val, ttl); err != nil {
+		return err
+	}
+	cache.track(key, ttl)
+	return nil
+}
+
+// ReadTTL returns the remaining time-to-live of an entry of the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//
+// Returns:
+//   - The remaining time-to-live
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLReader, or an error if the operation fails
+func (cache *Cache) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	reader, ok := cache.Cache.(gouache.TTLReader)
+	if !ok {
+		return 0, gouache.ErrUnsupported
+	}
+	return reader.ReadTTL(ctx, key)
+}
+
+// Touch sets a new time-to-live for an entry of the underlying cache and
+// updates when the tracked key expires.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a Toucher, or an error if the operation fails
+func (cache *Cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	toucher, ok := cache.Cache.(gouache.Toucher)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	if err := toucher.Touch(ctx, key, ttl); err != nil {
+		return err
+	}
+	cache.mu.Lock()
+	if st, ok := cache.keys[key]; ok {
+		st.expiresAt = cache.deadline(ttl)
+	}
+	cache.mu.Unlock()
+	return nil
+}
+
+// Capabilities reports the TTL interfaces of the underlying cache, which are
+// the only optional interfaces forwarded.
+//
+// Returns:
+//   - The capabilities of the underlying cache restricted to the forwarded interfaces
+func (cache *Cache) Capabilities() gouache.Caps {
+	caps := gouache.Capabilities(cache.Cache)
+	return gouache.Caps{SetWithTTL: caps.SetWithTTL, ReadTTL: caps.ReadTTL, Touch: caps.Touch}
+}
+
+// Removed records that the backend removed a key for the given reason. Call
+// it from the backend's eviction callback, with ReasonEvicted for capacity
+// evictions and ReasonExpired for expirations. Keys that aren't tracked are
+// ignored.
+//
+// Parameters:
+//   - key: The key of the removed entry
+//   - reason: Why the entry was removed
+func (cache *Cache) Removed(key string, reason Reason) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if st, ok := cache.keys[key]; ok {
+		st.removed = reason
+	}
+}
+
+// Counts returns the number of misses attributed to each reason so far.
+//
+// Returns:
+//   - A copy of the miss counts, keyed by reason
+func (cache *Cache) Counts() map[Reason]uint64 {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	counts := make(map[Reason]uint64, len(cache.counts))
+	for reason, n := range cache.counts {
+		counts[reason] = n
+	}
+	return counts
+}
+
+// track starts tracking a key that was just written, or resets its state.
+//
+// Parameters:
+//   - key: The key that was written
+//   - ttl: The time-to-live of the entry, or zero if unknown or never expiring
+func (cache *Cache) track(key string, ttl time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	st, ok := cache.keys[key]
+	if !ok {
+		if len(cache.keys) >= cache.Options.MaxKeys {
+			cache.overflowed = true
+			return
+		}
+		st = &state{}
+		cache.keys[key] = st
+	}
+	st.expiresAt = cache.deadline(ttl)
+	st.removed = ""
+}
+
+// deadline returns when an entry written now with ttl expires.
+//
+// Parameters:
+//   - ttl: The time-to-live of the entry
+//
+// Returns:
+//   - The expiration time, or zero if ttl isn't positive
+func (cache *Cache) deadline(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return cache.Options.Clock.Now().Add(ttl)
+}
+
+// miss attributes a miss of key to a reason and counts it.
+//
+// Parameters:
+//   - key: The key that was missed
+func (cache *Cache) miss(key string) {
+	cache.mu.Lock()
+	reason := cache.reason(key)
+	cache.counts[reason]++
+	cache.mu.Unlock()
+
+	if cache.Options.OnMiss != nil {
+		cache.Options.OnMiss(key, reason)
+	}
+}
+
+// reason determines why key was missed. The caller must hold mu.
+//
+// Parameters:
+//   - key: The key that was missed
+//
+// Returns:
+//   - The reason of the miss
+func (cache *Cache) reason(key string) Reason {
+	st, ok := cache.keys[key]
+	switch {
+	case !ok && cache.overflowed:
+		return ReasonUnknown
+	case !ok:
+		return ReasonCold
+	case st.removed != "":
+		return st.removed
+	case !st.expiresAt.IsZero() && !cache.Options.Clock.Now().Before(st.expiresAt):
+		return ReasonExpired
+	default:
+		return ReasonUnknown
+	}
+}
@@ -0,0 +1,133 @@
+package missreason
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/clocktest"
+	"github.com/soyacen/gouache/sample"
+)
+
+// expiringCache is a backend whose entries expire after a fixed TTL on a fake
+// clock, and which can evict entries on demand through an eviction callback.
+type expiringCache struct {
+	sample.Cache
+	clock   *clocktest.Clock
+	ttl     time.Duration
+	mu      sync.Mutex
+	expires map[string]time.Time
+	onEvict func(key string)
+}
+
+func (c *expiringCache) Get(ctx context.Context, key string) (any, error) {
+	c.mu.Lock()
+	expiresAt, ok := c.expires[key]
+	c.mu.Unlock()
+	if ok && !c.clock.Now().Before(expiresAt) {
+		return nil, gouache.ErrCacheMiss
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+func (c *expiringCache) Set(ctx context.Context, key string, val any) error {
+	c.mu.Lock()
+	c.expires[key] = c.clock.Now().Add(c.ttl)
+	c.mu.Unlock()
+	return c.Cache.Set(ctx, key, val)
+}
+
+func (c *expiringCache) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt, ok := c.expires[key]
+	if !ok {
+		return 0, gouache.ErrCacheMiss
+	}
+	return expiresAt.Sub(c.clock.Now()), nil
+}
+
+// evict removes a key and reports it to the eviction callback.
+func (c *expiringCache) evict(key string) {
+	_ = c.Cache.Delete(context.Background(), key)
+	c.onEvict(key)
+}
+
+// TestCache_Reasons tests that misses are attributed to their reasons
+func TestCache_Reasons(t *testing.T) {
+	ctx := context.Background()
+	clock := clocktest.NewClock(time.Unix(0, 0))
+	backend := &expiringCache{clock: clock, ttl: time.Minute, expires: make(map[string]time.Time)}
+	var seen []string
+	cache := New(backend, WithClock(clock), WithOnMiss(func(key string, reason Reason) {
+		seen = append(seen, key+"="+string(reason))
+	}))
+	backend.onEvict = func(key string) { cache.Removed(key, ReasonEvicted) }
+
+	for _, key := range []string{"expired", "evicted", "deleted"} {
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	if err := cache.Delete(ctx, "deleted"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	backend.evict("evicted")
+	clock.Advance(time.Minute)
+
+	for _, key := range []string{"cold", "expired", "evicted", "deleted"} {
+		if _, err := cache.Get(ctx, key); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Fatalf("Expected a miss for %s, got %v", key, err)
+		}
+	}
+
+	want := "[cold=cold expired=expired evicted=evicted deleted=deleted]"
+	if fmt.Sprint(seen) != want {
+		t.Errorf("Expected reasons %s, got %v", want, seen)
+	}
+	counts := cache.Counts()
+	for _, reason := range []Reason{ReasonCold, ReasonExpired, ReasonEvicted, ReasonDeleted} {
+		if counts[reason] != 1 {
+			t.Errorf("Expected 1 miss for %s, got %d", reason, counts[reason])
+		}
+	}
+
+	// Test that rewriting a key clears its removal reason
+	if err := cache.Set(ctx, "deleted", "again"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := cache.Get(ctx, "deleted"); err != nil {
+		t.Errorf("Expected a hit after rewriting, got %v", err)
+	}
+}
+
+// TestCache_Unknown tests that misses whose cause is unclear are counted as unknown
+func TestCache_Unknown(t *testing.T) {
+	ctx := context.Background()
+	backend := &sample.Cache{}
+	cache := New(backend, WithMaxKeys(1))
+
+	// A tracked key removed behind the decorator's back has no known reason
+	if err := cache.Set(ctx, "a", 1); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	_ = backend.Delete(ctx, "a")
+	_, _ = cache.Get(ctx, "a")
+
+	// Once the limit is reached, untracked keys are no longer known to be cold
+	if err := cache.Set(ctx, "b", 2); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	_, _ = cache.Get(ctx, "never")
+
+	if n := cache.Counts()[ReasonUnknown]; n != 2 {
+		t.Errorf("Expected 2 unknown misses, got %d", n)
+	}
+	if n := cache.Counts()[ReasonCold]; n != 0 {
+		t.Errorf("Expected no cold misses, got %d", n)
+	}
+}
@@ -0,0 +1,204 @@
+// Package mru provides an in-memory implementation of the gouache.Cache
+// interface with a fixed capacity and MRU (Most Recently Used) eviction.
+//
+// When the cache is full, storing a new key evicts the entry that was used
+// most recently, rather than least recently as in the lru package. This is
+// unusual, but suits one-pass scans such as analytics jobs: the keys just
+// read are the ones least likely to be read again, so evicting them keeps
+// the older, still useful entries resident. golang-lru has no MRU policy, so
+// the cache keeps its own map and recency list.
+package mru
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*Cache)(nil)
+
+// entry is a key and its value, stored in the recency list.
+type entry struct {
+	key string
+	val any
+}
+
+// Cache is an implementation of gouache.Cache that holds at most a fixed
+// number of entries and evicts the most recently used one on overflow.
+type Cache struct {
+	// OnEvict is an optional function called with each entry evicted to make
+	// room, while the cache lock is held.
+	OnEvict func(key string, val any)
+
+	// mu guards size, order and elems
+	mu sync.Mutex
+
+	// size is the maximum number of entries
+	size int
+
+	// order holds the entries, most recently used at the front
+	order *list.List
+
+	// elems maps each key to its element in order
+	elems map[string]*list.Element
+}
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithOnEvict returns an Option that sets the function called with each
+// entry evicted to make room. It isn't called for deleted entries.
+//
+// Parameters:
+//   - f: The function called with the evicted key and value
+//
+// Returns:
+//   - An Option function that sets OnEvict
+func WithOnEvict(f func(key string, val any)) Option {
+	return func(cache *Cache) {
+		cache.OnEvict = f
+	}
+}
+
+// New creates a new Cache holding at most size entries.
+//
+// Parameters:
+//   - size: The maximum number of entries, which must be positive
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - An empty Cache
+//   - An error if size is not positive
+func New(size int, opts ...Option) (*Cache, error) {
+	if size <= 0 {
+		return nil, errors.New("mru: size must be positive")
+	}
+	cache := &Cache{size: size, order: list.New(), elems: make(map[string]*list.Element)}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	return cache, nil
+}
+
+// Get retrieves a value from the cache by its key, making it the most
+// recently used entry.
+// It returns gouache.ErrCacheMiss if the key does not exist.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Handle case where entry is not found
+	elem, ok := cache.elems[key]
+	if !ok {
+		return nil, gouache.ErrCacheMiss
+	}
+
+	// Promote the entry and return its value
+	cache.order.MoveToFront(elem)
+	return elem.Value.(*entry).val, nil
+}
+
+// GetMulti retrieves the values for the given keys from the cache, making
+// each found key the most recently used in turn, exactly as individual Gets
+// would. Keys that do not exist are omitted from the returned map.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - Always returns nil error as the lookups can't fail
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		// Only record keys that are present in the cache
+		if elem, ok := cache.elems[key]; ok {
+			cache.order.MoveToFront(elem)
+			vals[key] = elem.Value.(*entry).val
+		}
+	}
+	return vals, nil
+}
+
+// Set stores a value in the cache with the given key, making it the most
+// recently used entry. If the key is new and the cache is full, the entry
+// used most recently before this Set is evicted first.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to store the value under
+//   - val: The value to store
+//
+// Returns:
+//   - Always returns nil as storing in memory can't fail
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Update an existing entry in place
+	if elem, ok := cache.elems[key]; ok {
+		elem.Value.(*entry).val = val
+		cache.order.MoveToFront(elem)
+		return nil
+	}
+
+	// Make room by evicting the most recently used entry
+	if cache.order.Len() >= cache.size {
+		evicted := cache.order.Remove(cache.order.Front()).(*entry)
+		delete(cache.elems, evicted.key)
+		if cache.OnEvict != nil {
+			cache.OnEvict(evicted.key, evicted.val)
+		}
+	}
+
+	cache.elems[key] = cache.order.PushFront(&entry{key: key, val: val})
+	return nil
+}
+
+// Delete removes a value from the cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - Always returns nil as removing from memory can't fail
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if elem, ok := cache.elems[key]; ok {
+		cache.order.Remove(elem)
+		delete(cache.elems, key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the cache.
+//
+// Returns:
+//   - The number of entries
+func (cache *Cache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
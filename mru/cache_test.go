@@ -0,0 +1,90 @@
+package mru
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
+)
+
+// TestNew tests that a non-positive size is rejected
+func TestNew(t *testing.T) {
+	if _, err := New(0); err == nil {
+		t.Error("Expected an error for a zero size")
+	}
+}
+
+// TestCache_GetSetDelete tests basic Get, Set and Delete operations
+func TestCache_GetSetDelete(t *testing.T) {
+	cache, err := New(2)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected value, got %v, %v", val, err)
+	}
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+}
+
+// TestCache_EvictionOrder tests that the most recently used entry is evicted,
+// where an LRU cache would have evicted the least recently used one
+func TestCache_EvictionOrder(t *testing.T) {
+	var evicted []string
+	cache, err := New(3, WithOnEvict(func(key string, val any) { evicted = append(evicted, key) }))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		_ = cache.Set(ctx, key, key)
+	}
+
+	// Reading a makes it the most recently used, so it goes first; LRU would evict b
+	if _, err := cache.Get(ctx, "a"); err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	_ = cache.Set(ctx, "d", "d")
+
+	// Now d was used last
+	_ = cache.Set(ctx, "e", "e")
+
+	if len(evicted) != 2 || evicted[0] != "a" || evicted[1] != "d" {
+		t.Errorf("Expected [a d] evicted, got %v", evicted)
+	}
+	for _, key := range []string{"b", "c", "e"} {
+		if _, err := cache.Get(ctx, key); err != nil {
+			t.Errorf("Expected %s to remain, got %v", key, err)
+		}
+	}
+	if cache.Len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", cache.Len())
+	}
+
+	// Overwriting an existing key evicts nothing
+	_ = cache.Set(ctx, "b", "updated")
+	if len(evicted) != 2 {
+		t.Errorf("Expected no eviction on overwrite, got %v", evicted)
+	}
+}
+
+// TestCache_CacheMissContract tests that misses are reported as gouache.ErrCacheMiss
+func TestCache_CacheMissContract(t *testing.T) {
+	cache, err := New(8)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	cachetest.AssertCacheMissContract(t, cache)
+}
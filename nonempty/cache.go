@@ -0,0 +1,302 @@
+// Package nonempty provides a cache implementation that rejects empty keys.
+//
+// This package implements the gouache.Cache interface by wrapping another
+// cache and failing fast with gouache.ErrEmptyKey whenever an operation is
+// given the empty key "", which backends would otherwise happily store and
+// which usually points to an uninitialized ID. It is opt-in so that existing
+// deliberate uses of the empty key keep working.
+package nonempty
+
+import (
+	"context"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchSetter interface at compile time.
+var _ gouache.BatchSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Iterable interface at compile time.
+var _ gouache.Iterable = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*cache)(nil)
+
+// Ensure that cache implements the gouache.CompareDeleter interface at compile time.
+var _ gouache.CompareDeleter = (*cache)(nil)
+
+// Ensure that cache forwards capability probing at compile time.
+var _ gouache.CapabilityReporter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// cache is a cache implementation that rejects empty keys before they reach
+// the underlying cache.
+type cache struct {
+	// Forwarder holds the underlying cache and forwards the operations the
+	// cache doesn't intercept to it
+	gouache.Forwarder
+}
+
+// New creates a new cache instance wrapping the specified cache and rejecting
+// empty keys.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//
+// Returns:
+//   - A gouache.Cache implementation that rejects empty keys
+func New(c gouache.Cache) gouache.Cache {
+	return &cache{Forwarder: gouache.Forwarder{Cache: c}}
+}
+
+// Get retrieves a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - gouache.ErrEmptyKey if key is empty, or an error if the operation fails
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	if key == "" {
+		return nil, gouache.ErrEmptyKey
+	}
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set stores a value in the underlying cache under the specified key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - gouache.ErrEmptyKey if key is empty, or an error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	if key == "" {
+		return gouache.ErrEmptyKey
+	}
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - gouache.ErrEmptyKey if key is empty, or an error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return gouache.ErrEmptyKey
+	}
+	return cache.Cache.Delete(ctx, key)
+}
+
+// GetMulti retrieves the values for the given keys from the underlying cache,
+// using its native GetMulti when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - gouache.ErrEmptyKey if any key is empty, or an error if the operation fails
+func (cache *cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	for _, key := range keys {
+		if key == "" {
+			return nil, gouache.ErrEmptyKey
+		}
+	}
+	return gouache.GetMulti(ctx, cache.Cache, keys)
+}
+
+// SetMulti stores each value in vals in the underlying cache, using its
+// native SetMulti when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - gouache.ErrEmptyKey if any key is empty, or an error if the operation fails
+func (cache *cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	if _, ok := vals[""]; ok {
+		return gouache.ErrEmptyKey
+	}
+	return gouache.SetMulti(ctx, cache.Cache, vals)
+}
+
+// DeleteMulti removes the values of the given keys from the underlying cache,
+// using its native DeleteMulti when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - gouache.ErrEmptyKey if any key is empty, or an error if the operation fails
+func (cache *cache) DeleteMulti(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if key == "" {
+			return gouache.ErrEmptyKey
+		}
+	}
+	return gouache.DeleteMulti(ctx, cache.Cache, keys)
+}
+
+// DeleteMultiResult removes the values of the given keys from the underlying
+// cache and reports which of them existed, using its native DeleteMultiResult
+// when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - gouache.ErrEmptyKey if any key is empty, or an error if the operation fails
+func (cache *cache) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	for _, key := range keys {
+		if key == "" {
+			return nil, gouache.ErrEmptyKey
+		}
+	}
+	return gouache.DeleteMultiResult(ctx, cache.Cache, keys)
+}
+
+// Add stores a value in the underlying cache only if the key is absent.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - gouache.ErrEmptyKey if key is empty, gouache.ErrUnsupported if the
+//     underlying cache isn't an Adder, or an error if the operation fails
+func (cache *cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	if key == "" {
+		return false, gouache.ErrEmptyKey
+	}
+	adder, ok := cache.Cache.(gouache.Adder)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	return adder.Add(ctx, key, val)
+}
+
+// SetWithTTL stores a value in the underlying cache with an explicit TTL.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrEmptyKey if key is empty, gouache.ErrUnsupported if the
+//     underlying cache isn't a TTLSetter, or an error if the operation fails
+func (cache *cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	if key == "" {
+		return gouache.ErrEmptyKey
+	}
+	setter, ok := cache.Cache.(gouache.TTLSetter)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	return setter.SetWithTTL(ctx, key, val, ttl)
+}
+
+// ReadTTL returns the remaining time-to-live of an entry of the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//
+// Returns:
+//   - The remaining time-to-live
+//   - gouache.ErrEmptyKey if key is empty, gouache.ErrUnsupported if the
+//     underlying cache isn't a TTLReader, or an error if the operation fails
+func (cache *cache) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	if key == "" {
+		return 0, gouache.ErrEmptyKey
+	}
+	reader, ok := cache.Cache.(gouache.TTLReader)
+	if !ok {
+		return 0, gouache.ErrUnsupported
+	}
+	return reader.ReadTTL(ctx, key)
+}
+
+// Touch sets a new time-to-live for an entry of the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrEmptyKey if key is empty, gouache.ErrUnsupported if the
+//     underlying cache isn't a Toucher, or an error if the operation fails
+func (cache *cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if key == "" {
+		return gouache.ErrEmptyKey
+	}
+	toucher, ok := cache.Cache.(gouache.Toucher)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	return toucher.Touch(ctx, key, ttl)
+}
+
+// CompareAndDelete deletes the entry stored under key if it still holds
+// expected in the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - expected: The value the entry must hold to be deleted
+//
+// Returns:
+//   - true if the entry was deleted, false if it was missing or held another value
+//   - gouache.ErrEmptyKey if key is empty, gouache.ErrUnsupported if the underlying cache isn't a CompareDeleter, or an error if the operation fails
+func (cache *cache) CompareAndDelete(ctx context.Context, key string, expected any) (bool, error) {
+	if key == "" {
+		return false, gouache.ErrEmptyKey
+	}
+	deleter, ok := cache.Cache.(gouache.CompareDeleter)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	return deleter.CompareAndDelete(ctx, key, expected)
+}
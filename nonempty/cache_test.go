@@ -0,0 +1,97 @@
+package nonempty
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestCache_EmptyKey tests that empty keys are rejected and never reach the backend.
+func TestCache_EmptyKey(t *testing.T) {
+	backend := &sample.Cache{}
+	cache := New(backend)
+	ctx := context.Background()
+
+	// All operations reject the empty key
+	if err := cache.Set(ctx, "", "value"); !errors.Is(err, gouache.ErrEmptyKey) {
+		t.Errorf("Expected ErrEmptyKey from Set, but got: %v", err)
+	}
+	if _, err := cache.Get(ctx, ""); !errors.Is(err, gouache.ErrEmptyKey) {
+		t.Errorf("Expected ErrEmptyKey from Get, but got: %v", err)
+	}
+	if err := cache.Delete(ctx, ""); !errors.Is(err, gouache.ErrEmptyKey) {
+		t.Errorf("Expected ErrEmptyKey from Delete, but got: %v", err)
+	}
+
+	// Nothing was stored in the backend
+	if _, err := backend.Get(ctx, ""); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss from the backend, but got: %v", err)
+	}
+}
+
+// TestCache_PassThrough tests that non-empty keys pass through untouched.
+func TestCache_PassThrough(t *testing.T) {
+	backend := &sample.Cache{}
+	cache := New(backend)
+	ctx := context.Background()
+
+	// A Set reaches the backend
+	err := cache.Set(ctx, "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	result, err := backend.Get(ctx, "test-key")
+	if err != nil || result != "test-value" {
+		t.Errorf("Expected test-value in the backend, but got %v, %v", result, err)
+	}
+
+	// A Get is answered by the backend, including misses
+	result, err = cache.Get(ctx, "test-key")
+	if err != nil || result != "test-value" {
+		t.Errorf("Expected test-value, but got %v, %v", result, err)
+	}
+	if _, err = cache.Get(ctx, "missing"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+	}
+
+	// A Delete reaches the backend
+	err = cache.Delete(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if _, err = backend.Get(ctx, "test-key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss after deletion, but got: %v", err)
+	}
+}
+
+// TestCache_Capabilities tests that the optional interfaces of the backend survive wrapping.
+func TestCache_Capabilities(t *testing.T) {
+	backend := &sample.Cache{}
+	cache := New(backend)
+	ctx := context.Background()
+
+	if caps := gouache.Capabilities(cache); caps != gouache.Capabilities(backend) {
+		t.Errorf("Expected %+v, but got %+v", gouache.Capabilities(backend), caps)
+	}
+
+	// Batch operations reach the backend and still reject the empty key
+	if err := gouache.SetMulti(ctx, cache, map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+	vals, err := gouache.GetMulti(ctx, cache, []string{"a", "b", "c"})
+	if err != nil || len(vals) != 2 || vals["a"] != 1 || vals["b"] != 2 {
+		t.Errorf("Expected a and b, but got %v, %v", vals, err)
+	}
+	if _, err := gouache.GetMulti(ctx, cache, []string{"a", ""}); !errors.Is(err, gouache.ErrEmptyKey) {
+		t.Errorf("Expected ErrEmptyKey from GetMulti, but got: %v", err)
+	}
+
+	// Interfaces the backend lacks are reported as unsupported
+	if err := cache.(gouache.Toucher).Touch(ctx, "a", time.Second); !errors.Is(err, gouache.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported from Touch, but got: %v", err)
+	}
+}
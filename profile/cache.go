@@ -0,0 +1,368 @@
+// Package profile provides a cache implementation that samples operations to
+// profile the keys and values flowing through a cache.
+//
+// This package implements the gouache.Cache interface by wrapping another cache
+// and recording, for a configurable fraction of operations, the key length and
+// the value size into power-of-two histograms. The histograms can be read at
+// any time with Snapshot, which helps with capacity planning without logging
+// every operation. Operations that are not sampled only pay for one random
+// number.
+package profile
+
+import (
+	"context"
+	"math/bits"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*Cache)(nil)
+
+// Sizer is a function type that returns the size in bytes of a value, usually
+// its marshaled size. It returns a negative size if the size is unknown.
+type Sizer func(key string, val any) int
+
+// Histogram is a snapshot of observations grouped into power-of-two buckets.
+type Histogram struct {
+	// Buckets[0] counts observations equal to 0, and Buckets[i] counts
+	// observations v with 2^(i-1) <= v < 2^i.
+	Buckets [65]uint64
+
+	// Count is the total number of observations.
+	Count uint64
+
+	// Sum is the sum of all observations.
+	Sum uint64
+
+	// Max is the largest observation.
+	Max uint64
+}
+
+// Snapshot is a point-in-time copy of the recorded profile.
+type Snapshot struct {
+	// Gets, Sets and Deletes are the number of sampled operations of each kind.
+	Gets, Sets, Deletes uint64
+
+	// KeyLength is the distribution of sampled key lengths.
+	KeyLength Histogram
+
+	// ValueSize is the distribution of sampled value sizes, from Set and from
+	// Get hits. Values whose size is unknown are not recorded.
+	ValueSize Histogram
+}
+
+// histogram is a concurrency-safe power-of-two histogram.
+type histogram struct {
+	buckets [65]atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Uint64
+	max     atomic.Uint64
+}
+
+// observe records one observation.
+//
+// Parameters:
+//   - v: The observed value
+func (h *histogram) observe(v uint64) {
+	h.buckets[bits.Len64(v)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(v)
+	for {
+		current := h.max.Load()
+		if v <= current || h.max.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+// snapshot copies the current state of the histogram.
+//
+// Returns:
+//   - A Histogram holding the recorded observations
+func (h *histogram) snapshot() Histogram {
+	var snapshot Histogram
+	for i := range h.buckets {
+		snapshot.Buckets[i] = h.buckets[i].Load()
+	}
+	snapshot.Count = h.count.Load()
+	snapshot.Sum = h.sum.Load()
+	snapshot.Max = h.max.Load()
+	return snapshot
+}
+
+// options holds configuration options for the profiling cache.
+type options struct {
+	// SampleRate is the fraction of operations that are recorded.
+	SampleRate float64
+
+	// Sizer determines the size of values.
+	Sizer Sizer
+
+	// Rand is the random source used to sample operations, or nil for the
+	// global source.
+	Rand *rand.Rand
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithSampleRate returns an Option that sets the fraction of operations that
+// are recorded.
+//
+// Parameters:
+//   - rate: The fraction of operations to record, between 0 and 1
+//
+// Returns:
+//   - An Option function that sets the SampleRate
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.SampleRate = rate
+	}
+}
+
+// WithSizer returns an Option that sets the function used to determine value
+// sizes, for example by marshaling the value with the backend's codec.
+//
+// Parameters:
+//   - sizer: A function that returns the size of a value
+//
+// Returns:
+//   - An Option function that sets the Sizer
+func WithSizer(sizer Sizer) Option {
+	return func(o *options) {
+		o.Sizer = sizer
+	}
+}
+
+// WithRand returns an Option that sets the random source used to decide which
+// operations are sampled, so tests can inject a seeded source and get
+// reproducible samples. By default the concurrency-safe global source of
+// math/rand is used.
+//
+// A *rand.Rand is not safe for concurrent use, so the Cache serializes its
+// calls to r; r must not be used elsewhere concurrently.
+//
+// Parameters:
+//   - r: The random source
+//
+// Returns:
+//   - An Option function that sets the Rand
+func WithRand(r *rand.Rand) Option {
+	return func(o *options) {
+		o.Rand = r
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{SampleRate: 0.01}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Clamp the sample rate to [0, 1]
+	if o.SampleRate < 0 {
+		o.SampleRate = 0
+	}
+	if o.SampleRate > 1 {
+		o.SampleRate = 1
+	}
+
+	// By default only the size of byte slices and strings is known
+	if o.Sizer == nil {
+		o.Sizer = func(key string, val any) int {
+			switch v := val.(type) {
+			case []byte:
+				return len(v)
+			case string:
+				return len(v)
+			default:
+				return -1
+			}
+		}
+	}
+	return o
+}
+
+// Cache is a cache implementation that samples operations on the underlying
+// cache and records key lengths and value sizes.
+type Cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+
+	// gets, sets and deletes count sampled operations.
+	gets, sets, deletes atomic.Uint64
+
+	// keyLength and valueSize hold the recorded distributions.
+	keyLength, valueSize histogram
+
+	// randMu serializes calls to Options.Rand.
+	randMu sync.Mutex
+}
+
+// New creates a new profiling cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache that profiles a sample of operations
+func New(c gouache.Cache, opts ...Option) *Cache {
+	return &Cache{Options: newOptions(opts...), Cache: c}
+}
+
+// Get retrieves a value from the cache by its key, recording the key length
+// and, on a hit, the value size if the operation is sampled.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	val, err := cache.Cache.Get(ctx, key)
+	if cache.sampled() {
+		cache.gets.Add(1)
+		cache.keyLength.observe(uint64(len(key)))
+		if err == nil {
+			cache.observeValue(key, val)
+		}
+	}
+	return val, err
+}
+
+// Set stores a value in the cache under the specified key, recording the key
+// length and value size if the operation is sampled.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	if cache.sampled() {
+		cache.sets.Add(1)
+		cache.keyLength.observe(uint64(len(key)))
+		cache.observeValue(key, val)
+	}
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the cache by its key, recording the key length
+// if the operation is sampled.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	if cache.sampled() {
+		cache.deletes.Add(1)
+		cache.keyLength.observe(uint64(len(key)))
+	}
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *Cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// Snapshot returns a copy of the profile recorded so far.
+//
+// Returns:
+//   - A Snapshot of the sampled operation counts and histograms
+func (cache *Cache) Snapshot() Snapshot {
+	return Snapshot{
+		Gets:      cache.gets.Load(),
+		Sets:      cache.sets.Load(),
+		Deletes:   cache.deletes.Load(),
+		KeyLength: cache.keyLength.snapshot(),
+		ValueSize: cache.valueSize.snapshot(),
+	}
+}
+
+// sampled reports whether the current operation should be recorded.
+//
+// Returns:
+//   - true if the operation is sampled
+func (cache *Cache) sampled() bool {
+	switch cache.Options.SampleRate {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		return cache.float64() < cache.Options.SampleRate
+	}
+}
+
+// float64 returns a pseudo-random number in [0.0, 1.0) from the configured
+// random source, or the global source if none is configured.
+//
+// Returns:
+//   - A pseudo-random number in [0.0, 1.0)
+func (cache *Cache) float64() float64 {
+	if cache.Options.Rand == nil {
+		return rand.Float64()
+	}
+	cache.randMu.Lock()
+	defer cache.randMu.Unlock()
+	return cache.Options.Rand.Float64()
+}
+
+// observeValue records the size of a value if it is known.
+//
+// Parameters:
+//   - key: The key of the value
+//   - val: The value to record the size of
+func (cache *Cache) observeValue(key string, val any) {
+	if size := cache.Options.Sizer(key, val); size >= 0 {
+		cache.valueSize.observe(uint64(size))
+	}
+}
@@ -0,0 +1,101 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestCache_Snapshot tests that sampled operations are recorded in the histograms.
+func TestCache_Snapshot(t *testing.T) {
+	cache := New(&sample.Cache{}, WithSampleRate(1))
+
+	// Record a few operations with known sizes
+	_ = cache.Set(context.Background(), "key", "12345678")    // key 3, value 8
+	_ = cache.Set(context.Background(), "longer-key", "1234") // key 10, value 4
+	_, _ = cache.Get(context.Background(), "key")             // key 3, value 8
+	_, _ = cache.Get(context.Background(), "missing")         // key 7, no value
+	_ = cache.Delete(context.Background(), "key")             // key 3
+
+	snapshot := cache.Snapshot()
+	if snapshot.Gets != 2 || snapshot.Sets != 2 || snapshot.Deletes != 1 {
+		t.Errorf("Expected 2 gets, 2 sets, 1 delete, but got %d, %d, %d", snapshot.Gets, snapshot.Sets, snapshot.Deletes)
+	}
+
+	// Key lengths: 3, 10, 3, 7, 3
+	if snapshot.KeyLength.Count != 5 {
+		t.Errorf("Expected 5 key length observations, but got %d", snapshot.KeyLength.Count)
+	}
+	if snapshot.KeyLength.Max != 10 {
+		t.Errorf("Expected max key length 10, but got %d", snapshot.KeyLength.Max)
+	}
+	if snapshot.KeyLength.Buckets[2] != 3 {
+		t.Errorf("Expected 3 key lengths in [2, 4), but got %d", snapshot.KeyLength.Buckets[2])
+	}
+
+	// Value sizes: 8, 4, 8
+	if snapshot.ValueSize.Count != 3 {
+		t.Errorf("Expected 3 value size observations, but got %d", snapshot.ValueSize.Count)
+	}
+	if snapshot.ValueSize.Sum != 20 {
+		t.Errorf("Expected value size sum 20, but got %d", snapshot.ValueSize.Sum)
+	}
+	if snapshot.ValueSize.Buckets[4] != 2 {
+		t.Errorf("Expected 2 value sizes in [8, 16), but got %d", snapshot.ValueSize.Buckets[4])
+	}
+}
+
+// TestCache_SampleRate tests that a zero sample rate records nothing.
+func TestCache_SampleRate(t *testing.T) {
+	cache := New(&sample.Cache{}, WithSampleRate(0))
+
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(context.Background(), "key", "value")
+	}
+
+	snapshot := cache.Snapshot()
+	if snapshot.Sets != 0 || snapshot.KeyLength.Count != 0 {
+		t.Errorf("Expected no samples, but got %d sets and %d key lengths", snapshot.Sets, snapshot.KeyLength.Count)
+	}
+}
+
+// TestCache_WithSizer tests that a custom sizer is used to measure values.
+func TestCache_WithSizer(t *testing.T) {
+	sizer := func(key string, val any) int {
+		data, err := json.Marshal(val)
+		if err != nil {
+			return -1
+		}
+		return len(data)
+	}
+	cache := New(&sample.Cache{}, WithSampleRate(1), WithSizer(sizer))
+
+	_ = cache.Set(context.Background(), "key", map[string]int{"a": 1}) // {"a":1}
+
+	snapshot := cache.Snapshot()
+	if snapshot.ValueSize.Sum != 7 {
+		t.Errorf("Expected value size 7, but got %d", snapshot.ValueSize.Sum)
+	}
+}
+
+// TestCache_WithRand tests that a seeded random source makes sampling reproducible.
+func TestCache_WithRand(t *testing.T) {
+	run := func() Snapshot {
+		cache := New(&sample.Cache{}, WithSampleRate(0.5), WithRand(rand.New(rand.NewSource(42))))
+		for i := 0; i < 100; i++ {
+			_ = cache.Set(context.Background(), "key", "value")
+		}
+		return cache.Snapshot()
+	}
+
+	first, second := run(), run()
+	if first.Sets != second.Sets {
+		t.Errorf("Expected the same samples with the same seed, but got %d and %d", first.Sets, second.Sets)
+	}
+	if first.Sets == 0 || first.Sets == 100 {
+		t.Errorf("Expected some but not all operations sampled, but got %d", first.Sets)
+	}
+}
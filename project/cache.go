@@ -0,0 +1,165 @@
+// Package project provides a cache implementation that stores a reduced
+// projection of each value instead of the value itself.
+//
+// Read-mostly caches often hold large records, such as database rows, of
+// which callers only ever read a few fields. This package implements the
+// gouache.Cache interface by wrapping a cache and passing every value through
+// a projection function on Set, so only the reduced form takes memory in the
+// backend.
+//
+// The projection changes what is stored, so Get returns the projected form,
+// not the value that was passed to Set, unless a reconstruction function is
+// given with WithReconstruct to turn the projection back into the type
+// callers expect.
+package project
+
+import (
+	"context"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// options holds configuration options for the projecting cache.
+type options struct {
+	// Reconstruct rebuilds a value from its stored projection on Get.
+	Reconstruct func(projected any) any
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithReconstruct returns an Option that sets the function Get passes stored
+// projections through, for example to rebuild a partially filled struct of
+// the original type from the projected fields.
+//
+// Parameters:
+//   - f: A function rebuilding a value from its projection
+//
+// Returns:
+//   - An Option function that sets Reconstruct
+func WithReconstruct(f func(projected any) any) Option {
+	return func(o *options) {
+		o.Reconstruct = f
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Return projections as stored by default
+	if o.Reconstruct == nil {
+		o.Reconstruct = func(projected any) any { return projected }
+	}
+	return o
+}
+
+// cache is a cache implementation that stores projections of values.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Projection reduces a value to the form stored
+	Projection func(val any) any
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+}
+
+// New creates a new projecting cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - projection: A function reducing each value to the form stored
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation that stores projected values
+func New(c gouache.Cache, projection func(val any) any, opts ...Option) gouache.Cache {
+	return &cache{Options: newOptions(opts...), Projection: projection, Cache: c}
+}
+
+// Get retrieves the stored projection of a value from the underlying cache,
+// passed through the reconstruction function if one is set.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The projected, or reconstructed, value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	val, err := cache.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return cache.Options.Reconstruct(val), nil
+}
+
+// Set stores the projection of a value in the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value whose projection to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	return cache.Cache.Set(ctx, key, cache.Projection(val))
+}
+
+// Delete removes a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
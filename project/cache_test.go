@@ -0,0 +1,80 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// row is a large record of which only a few fields are read.
+type row struct {
+	ID      int
+	Name    string
+	Payload []byte
+}
+
+// summary is the projection of a row that is cached.
+type summary struct {
+	ID   int
+	Name string
+}
+
+func summarize(val any) any {
+	r := val.(*row)
+	return summary{ID: r.ID, Name: r.Name}
+}
+
+// TestCache_Projection tests that only the projection is stored and returned
+func TestCache_Projection(t *testing.T) {
+	backend := &sample.Cache{}
+	cache := New(backend, summarize)
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "row:1", &row{ID: 1, Name: "gopher", Payload: make([]byte, 1<<20)}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// The backend holds the projection, not the row
+	stored, _ := backend.Get(ctx, "row:1")
+	if _, ok := stored.(summary); !ok {
+		t.Errorf("Expected a summary to be stored, got %T", stored)
+	}
+
+	// Get returns the projected form
+	val, err := cache.Get(ctx, "row:1")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if val != (summary{ID: 1, Name: "gopher"}) {
+		t.Errorf("Expected the summary, got %+v", val)
+	}
+
+	if err := cache.Delete(ctx, "row:1"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if _, err := cache.Get(ctx, "row:1"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+}
+
+// TestCache_Reconstruct tests that Get can rebuild the original type from the projection
+func TestCache_Reconstruct(t *testing.T) {
+	cache := New(&sample.Cache{}, summarize, WithReconstruct(func(projected any) any {
+		s := projected.(summary)
+		return &row{ID: s.ID, Name: s.Name}
+	}))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "row:2", &row{ID: 2, Name: "gc", Payload: []byte("large")})
+	val, err := cache.Get(ctx, "row:2")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	r, ok := val.(*row)
+	if !ok || r.ID != 2 || r.Name != "gc" || r.Payload != nil {
+		t.Errorf("Expected a row rebuilt from the projection, got %+v", val)
+	}
+}
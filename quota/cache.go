@@ -0,0 +1,424 @@
+// Package quota provides a cache implementation that limits the number of
+// keys each prefix, such as a tenant, may hold in a shared cache.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// tracking the keys written through it per prefix, derived from each key by a
+// configurable function. A Set that would take a prefix over its quota is
+// rejected with ErrQuotaExceeded, or, in eviction mode, makes room by
+// deleting the least recently used key of the same prefix, so one tenant
+// flooding the keyspace can only ever evict its own keys.
+//
+// Only keys written through the cache are counted. Keys that expire or are
+// evicted by the backend keep counting until a Get finds them missing, they
+// are deleted, or a Set finds the prefix full: before rejecting a new key,
+// Set checks whether the least recently used keys of the prefix still exist
+// in the backend and stops counting those that don't, so a tenant whose keys
+// merely expired is never locked out.
+package quota
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*Cache)(nil)
+
+// ErrQuotaExceeded is returned, wrapped with the prefix, by Set when a new key
+// would take its prefix over its quota and eviction is disabled.
+var ErrQuotaExceeded = errors.New("gouache: quota exceeded")
+
+// options holds configuration options for the quota cache.
+type options struct {
+	// PrefixFunc derives the prefix a key counts against.
+	PrefixFunc func(key string) string
+
+	// QuotaFunc returns the quota of a prefix, overriding the default quota.
+	QuotaFunc func(prefix string) int
+
+	// Evict makes Set delete the least recently used key of a full prefix
+	// instead of failing.
+	Evict bool
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithPrefixFunc returns an Option that sets how the prefix a key counts
+// against is derived from the key. By default it is the part of the key
+// before the first ':', or the whole key if it has none.
+//
+// Parameters:
+//   - f: The function deriving the prefix of a key
+//
+// Returns:
+//   - An Option function that sets the PrefixFunc
+func WithPrefixFunc(f func(key string) string) Option {
+	return func(o *options) {
+		o.PrefixFunc = f
+	}
+}
+
+// WithQuotaFunc returns an Option that sets the quota of each prefix, for
+// example to give larger tenants more room. A non-positive quota means the
+// prefix is unlimited.
+//
+// Parameters:
+//   - f: The function returning the quota of a prefix
+//
+// Returns:
+//   - An Option function that sets the QuotaFunc
+func WithQuotaFunc(f func(prefix string) int) Option {
+	return func(o *options) {
+		o.QuotaFunc = f
+	}
+}
+
+// WithEvict returns an Option that makes a Set of a new key into a full
+// prefix delete the least recently used key of that prefix, instead of
+// failing with ErrQuotaExceeded. Gets and Sets of a key count as use.
+//
+// Parameters:
+//   - enabled: Whether to evict instead of rejecting
+//
+// Returns:
+//   - An Option function that sets Evict
+func WithEvict(enabled bool) Option {
+	return func(o *options) {
+		o.Evict = enabled
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - quota: The default quota of every prefix
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(quota int, opts ...Option) *options {
+	options := &options{QuotaFunc: func(string) int { return quota }}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Use the part of the key before the first ':' by default
+	if o.PrefixFunc == nil {
+		o.PrefixFunc = func(key string) string {
+			prefix, _, _ := strings.Cut(key, ":")
+			return prefix
+		}
+	}
+	return o
+}
+
+// usage tracks the keys of one prefix, most recently used first.
+type usage struct {
+	// order holds the keys, most recently used at the front
+	order *list.List
+
+	// elems maps each key to its element in order
+	elems map[string]*list.Element
+}
+
+// Cache is a cache implementation that limits the number of keys per prefix.
+type Cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+
+	// mu guards prefixes
+	mu sync.Mutex
+
+	// prefixes holds the tracked keys of each prefix
+	prefixes map[string]*usage
+}
+
+// New creates a new quota cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - quota: The maximum number of keys of each prefix; non-positive values mean unlimited
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache that enforces the quota
+func New(c gouache.Cache, quota int, opts ...Option) *Cache {
+	return &Cache{Options: newOptions(quota, opts...), Cache: c, prefixes: make(map[string]*usage)}
+}
+
+// Get retrieves a value from the underlying cache by its key, marking the key
+// as used. A tracked key found missing no longer counts against its prefix.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	val, err := cache.Cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		cache.touch(key)
+	case errors.Is(err, gouache.ErrCacheMiss):
+		cache.forget(key)
+	}
+	return val, err
+}
+
+// Set stores a value in the underlying cache. A new key that would take its
+// prefix over its quota is rejected, or, with WithEvict, replaces the least
+// recently used key of the prefix.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error wrapping ErrQuotaExceeded if the prefix is full, or an error if
+//     evicting or storing fails
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	// Reserve room for the key, picking a victim if the prefix is full
+	victim, added, err := cache.reserve(key)
+	for errors.Is(err, ErrQuotaExceeded) {
+		// Retry while the least recently used key turns out to be gone
+		if !cache.pruneOldest(ctx, key) {
+			return err
+		}
+		victim, added, err = cache.reserve(key)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Make room by deleting the victim, putting it back if that fails
+	if victim != "" {
+		if err := cache.Cache.Delete(ctx, victim); err != nil {
+			cache.forget(key)
+			cache.restore(victim)
+			return err
+		}
+	}
+
+	// Store the value, releasing the reservation if that fails
+	if err := cache.Cache.Set(ctx, key, val); err != nil {
+		if added {
+			cache.forget(key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a value from the underlying cache, freeing its place in the
+// prefix's quota.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	if err := cache.Cache.Delete(ctx, key); err != nil {
+		return err
+	}
+	cache.forget(key)
+	return nil
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *Cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// Count returns the number of keys of a prefix currently counted against its quota.
+//
+// Parameters:
+//   - prefix: The prefix
+//
+// Returns:
+//   - The number of tracked keys of the prefix
+func (cache *Cache) Count(prefix string) int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if u, ok := cache.prefixes[prefix]; ok {
+		return u.order.Len()
+	}
+	return 0
+}
+
+// reserve records key as the most recently used key of its prefix. If the
+// key is new and the prefix is full, it either fails or, with Evict, removes
+// the least recently used key of the prefix and returns it as the victim.
+//
+// Parameters:
+//   - key: The key about to be stored
+//
+// Returns:
+//   - The key to delete to make room, or empty if none
+//   - Whether key was newly tracked
+//   - An error wrapping ErrQuotaExceeded if the prefix is full and Evict is disabled
+func (cache *Cache) reserve(key string) (string, bool, error) {
+	prefix := cache.Options.PrefixFunc(key)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	u, ok := cache.prefixes[prefix]
+	if !ok {
+		u = &usage{order: list.New(), elems: make(map[string]*list.Element)}
+		cache.prefixes[prefix] = u
+	}
+
+	// Rewriting a tracked key doesn't take more room
+	if elem, ok := u.elems[key]; ok {
+		u.order.MoveToFront(elem)
+		return "", false, nil
+	}
+
+	// Make room when the prefix is full
+	var victim string
+	if quota := cache.Options.QuotaFunc(prefix); quota > 0 && u.order.Len() >= quota {
+		if !cache.Options.Evict {
+			return "", false, fmt.Errorf("%w: prefix %q", ErrQuotaExceeded, prefix)
+		}
+		victim = u.order.Remove(u.order.Back()).(string)
+		delete(u.elems, victim)
+	}
+
+	u.elems[key] = u.order.PushFront(key)
+	return victim, true, nil
+}
+
+// touch marks a tracked key as the most recently used key of its prefix.
+//
+// Parameters:
+//   - key: The key that was used
+func (cache *Cache) touch(key string) {
+	prefix := cache.Options.PrefixFunc(key)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if u, ok := cache.prefixes[prefix]; ok {
+		if elem, ok := u.elems[key]; ok {
+			u.order.MoveToFront(elem)
+		}
+	}
+}
+
+// pruneOldest stops counting the least recently used key of the prefix of
+// key if the backend no longer has it, having expired or evicted it.
+// Existence is checked with ReadTTL if the backend supports it, or with Get.
+//
+// Parameters:
+//   - ctx: Context for the check
+//   - key: A key of the prefix to prune
+//
+// Returns:
+//   - true if a key was forgotten, false if the oldest key still exists or can't be checked
+func (cache *Cache) pruneOldest(ctx context.Context, key string) bool {
+	prefix := cache.Options.PrefixFunc(key)
+
+	// Pick the least recently used key of the prefix
+	cache.mu.Lock()
+	u, ok := cache.prefixes[prefix]
+	if !ok || u.order.Len() == 0 {
+		cache.mu.Unlock()
+		return false
+	}
+	oldest := u.order.Back().Value.(string)
+	cache.mu.Unlock()
+
+	// Check whether the backend still has it
+	var err error
+	if reader, ok := cache.Cache.(gouache.TTLReader); ok && gouache.Capabilities(cache.Cache).ReadTTL {
+		_, err = reader.ReadTTL(ctx, oldest)
+	} else {
+		_, err = cache.Cache.Get(ctx, oldest)
+	}
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		return false
+	}
+	cache.forget(oldest)
+	return true
+}
+
+// restore tracks a key again as the least recently used key of its prefix,
+// after an eviction of it failed.
+//
+// Parameters:
+//   - key: The key to track again
+func (cache *Cache) restore(key string) {
+	prefix := cache.Options.PrefixFunc(key)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	u, ok := cache.prefixes[prefix]
+	if !ok {
+		u = &usage{order: list.New(), elems: make(map[string]*list.Element)}
+		cache.prefixes[prefix] = u
+	}
+	if _, ok := u.elems[key]; !ok {
+		u.elems[key] = u.order.PushBack(key)
+	}
+}
+
+// forget stops counting a key against its prefix.
+//
+// Parameters:
+//   - key: The key to forget
+func (cache *Cache) forget(key string) {
+	prefix := cache.Options.PrefixFunc(key)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	u, ok := cache.prefixes[prefix]
+	if !ok {
+		return
+	}
+	if elem, ok := u.elems[key]; ok {
+		u.order.Remove(elem)
+		delete(u.elems, key)
+	}
+	if u.order.Len() == 0 {
+		delete(cache.prefixes, prefix)
+	}
+}
@@ -0,0 +1,134 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestCache_Reject tests that Sets of new keys beyond a prefix's quota are rejected
+func TestCache_Reject(t *testing.T) {
+	ctx := context.Background()
+	cache := New(&sample.Cache{}, 2)
+
+	for _, key := range []string{"a:1", "a:2", "b:1"} {
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Fatalf("Set %s failed: %v", key, err)
+		}
+	}
+
+	// A third key of tenant a is rejected, without touching tenant b
+	if err := cache.Set(ctx, "a:3", "a:3"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := cache.Get(ctx, "a:3"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the rejected key not to be stored, got %v", err)
+	}
+	if err := cache.Set(ctx, "b:2", "b:2"); err != nil {
+		t.Errorf("Expected another prefix to have room, got %v", err)
+	}
+
+	// Overwriting a counted key takes no extra room
+	if err := cache.Set(ctx, "a:1", "new"); err != nil {
+		t.Errorf("Expected overwriting to succeed, got %v", err)
+	}
+
+	// Deleting a key frees its place
+	if err := cache.Delete(ctx, "a:2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := cache.Set(ctx, "a:3", "a:3"); err != nil {
+		t.Errorf("Expected room after a delete, got %v", err)
+	}
+	if got := cache.Count("a"); got != 2 {
+		t.Errorf("Expected 2 keys for prefix a, got %d", got)
+	}
+}
+
+// TestCache_Evict tests that eviction mode replaces the least recently used key of the prefix
+func TestCache_Evict(t *testing.T) {
+	ctx := context.Background()
+	backend := &sample.Cache{}
+	cache := New(backend, 2, WithEvict(true))
+
+	_ = cache.Set(ctx, "a:1", 1)
+	_ = cache.Set(ctx, "a:2", 2)
+	_ = cache.Set(ctx, "b:1", 1)
+
+	// Reading a:1 makes a:2 the least recently used key of tenant a
+	if _, err := cache.Get(ctx, "a:1"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := cache.Set(ctx, "a:3", 3); err != nil {
+		t.Fatalf("Expected eviction instead of an error, got %v", err)
+	}
+	if _, err := backend.Get(ctx, "a:2"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected a:2 to be evicted, got %v", err)
+	}
+	for _, key := range []string{"a:1", "a:3", "b:1"} {
+		if _, err := backend.Get(ctx, key); err != nil {
+			t.Errorf("Expected %s to remain, got %v", key, err)
+		}
+	}
+	if got := cache.Count("a"); got != 2 {
+		t.Errorf("Expected 2 keys for prefix a, got %d", got)
+	}
+}
+
+// TestCache_Options tests custom prefix and quota functions
+func TestCache_Options(t *testing.T) {
+	ctx := context.Background()
+	cache := New(&sample.Cache{}, 1,
+		WithPrefixFunc(func(key string) string { return key[:1] }),
+		WithQuotaFunc(func(prefix string) int {
+			if prefix == "v" {
+				return 0
+			}
+			return 1
+		}))
+
+	// Prefix v is unlimited
+	for _, key := range []string{"v1", "v2", "v3"} {
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Errorf("Expected unlimited prefix, got %v", err)
+		}
+	}
+	_ = cache.Set(ctx, "x1", 1)
+	if err := cache.Set(ctx, "x2", 2); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+// TestCache_ExpiredKeys tests that keys the backend dropped on its own stop
+// counting when the prefix is full, instead of locking the tenant out
+func TestCache_ExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	backend := &sample.Cache{}
+	cache := New(backend, 2)
+
+	for _, key := range []string{"a:1", "a:2"} {
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Fatalf("Set %s failed: %v", key, err)
+		}
+	}
+
+	// Both keys expire in the backend, unseen by the quota cache
+	_ = backend.Delete(ctx, "a:1")
+	_ = backend.Delete(ctx, "a:2")
+	for _, key := range []string{"a:3", "a:4"} {
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Errorf("Expected room after expiry for %s, got %v", key, err)
+		}
+	}
+	if got := cache.Count("a"); got != 2 {
+		t.Errorf("Expected 2 keys for prefix a, got %d", got)
+	}
+
+	// Live keys still count
+	if err := cache.Set(ctx, "a:5", "a:5"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+}
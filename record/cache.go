@@ -0,0 +1,347 @@
+// Package record provides a cache implementation that records the operations
+// on a wrapped cache, and a Replay function that runs a recorded trace
+// against another cache.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// writing every operation as a line of JSON to an io.Writer. A trace captured
+// in production can then be replayed locally, for example against a fresh
+// sample.Cache, to reproduce ordering bugs deterministically.
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// Op identifies a cache operation.
+type Op string
+
+const (
+	// OpGet is a Get operation.
+	OpGet Op = "get"
+
+	// OpSet is a Set operation.
+	OpSet Op = "set"
+
+	// OpDelete is a Delete operation.
+	OpDelete Op = "delete"
+)
+
+// Result is the outcome of a cache operation.
+type Result string
+
+const (
+	// ResultHit is a Get that found its key.
+	ResultHit Result = "hit"
+
+	// ResultMiss is a Get that didn't find its key.
+	ResultMiss Result = "miss"
+
+	// ResultOK is a successful Set or Delete.
+	ResultOK Result = "ok"
+
+	// ResultError is a failed operation.
+	ResultError Result = "error"
+)
+
+// Entry is one recorded operation, written as a line of JSON.
+type Entry struct {
+	// Time is when the operation started.
+	Time time.Time `json:"time"`
+
+	// Op is the operation performed.
+	Op Op `json:"op"`
+
+	// Key is the key operated on.
+	Key string `json:"key"`
+
+	// Value is the JSON encoding of the value stored by a Set, if values are recorded.
+	Value json.RawMessage `json:"value,omitempty"`
+
+	// Result is the outcome of the operation.
+	Result Result `json:"result"`
+
+	// Error is the message of the error when Result is ResultError.
+	Error string `json:"error,omitempty"`
+}
+
+// options holds configuration options for the recording cache.
+type options struct {
+	// Ops is the set of operations recorded.
+	Ops map[Op]bool
+
+	// Values reports whether the values stored by Set are recorded.
+	Values bool
+
+	// ErrorHandler is called when an entry can't be encoded or written.
+	ErrorHandler gouache.ErrorHandler
+
+	// Clock provides the time of each entry.
+	Clock gouache.Clock
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithOps returns an Option that restricts recording to the given operations.
+// By default all operations are recorded. Operations that aren't recorded
+// cost nothing beyond a map lookup.
+//
+// Parameters:
+//   - ops: The operations to record
+//
+// Returns:
+//   - An Option function that sets the recorded Ops
+func WithOps(ops ...Op) Option {
+	return func(o *options) {
+		o.Ops = make(map[Op]bool, len(ops))
+		for _, op := range ops {
+			o.Ops[op] = true
+		}
+	}
+}
+
+// WithValues returns an Option that sets whether the values stored by Set are
+// recorded. Values are recorded by default, since Replay needs them to store
+// something; disabling them saves encoding each value and keeps sensitive
+// data out of the trace.
+//
+// Parameters:
+//   - enabled: Whether values are recorded
+//
+// Returns:
+//   - An Option function that sets Values
+func WithValues(enabled bool) Option {
+	return func(o *options) {
+		o.Values = enabled
+	}
+}
+
+// WithErrorHandler returns an Option that sets a custom error handler for
+// entries that can't be encoded or written.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f gouache.ErrorHandler) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// WithClock returns an Option that sets the clock providing the time of each
+// entry. It defaults to gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{Values: true}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Record all operations by default
+	if o.Ops == nil {
+		o.Ops = map[Op]bool{OpGet: true, OpSet: true, OpDelete: true}
+	}
+
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = gouache.DefaultErrorHandler("record.Cache")
+	}
+
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+	return o
+}
+
+// cache is a cache implementation that records every operation to a writer.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+
+	// mu serializes writes to the encoder.
+	mu sync.Mutex
+
+	// encoder writes entries to the trace.
+	encoder *json.Encoder
+}
+
+// New creates a new recording cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - w: The writer receiving one JSON entry per line
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation that records its operations
+func New(c gouache.Cache, w io.Writer, opts ...Option) gouache.Cache {
+	return &cache{Options: newOptions(opts...), Cache: c, encoder: json.NewEncoder(w)}
+}
+
+// Get retrieves a value from the underlying cache by its key, recording a
+// hit, a miss or an error.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	if !cache.Options.Ops[OpGet] {
+		return cache.Cache.Get(ctx, key)
+	}
+	start := cache.Options.Clock.Now()
+	val, err := cache.Cache.Get(ctx, key)
+	cache.record(Entry{Time: start, Op: OpGet, Key: key}, err)
+	return val, err
+}
+
+// Set stores a value in the underlying cache, recording its outcome and,
+// unless disabled, the value.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	if !cache.Options.Ops[OpSet] {
+		return cache.Cache.Set(ctx, key, val)
+	}
+	entry := Entry{Time: cache.Options.Clock.Now(), Op: OpSet, Key: key}
+	if cache.Options.Values {
+		data, err := json.Marshal(val)
+		if err != nil {
+			cache.Options.ErrorHandler(err)
+		}
+		entry.Value = data
+	}
+	err := cache.Cache.Set(ctx, key, val)
+	cache.record(entry, err)
+	return err
+}
+
+// Delete removes a value from the underlying cache, recording its outcome.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	if !cache.Options.Ops[OpDelete] {
+		return cache.Cache.Delete(ctx, key)
+	}
+	start := cache.Options.Clock.Now()
+	err := cache.Cache.Delete(ctx, key)
+	cache.record(Entry{Time: start, Op: OpDelete, Key: key}, err)
+	return err
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// record fills in the result of an entry and writes it to the trace.
+//
+// Parameters:
+//   - entry: The entry to write
+//   - err: The error returned by the operation
+func (cache *cache) record(entry Entry, err error) {
+	entry.Result = result(entry.Op, err)
+	if entry.Result == ResultError {
+		entry.Error = err.Error()
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if err := cache.encoder.Encode(entry); err != nil {
+		cache.Options.ErrorHandler(err)
+	}
+}
+
+// result returns the Result of an operation that returned err.
+//
+// Parameters:
+//   - op: The operation performed
+//   - err: The error returned by the operation
+//
+// Returns:
+//   - The outcome of the operation
+func result(op Op, err error) Result {
+	switch {
+	case err == nil && op == OpGet:
+		return ResultHit
+	case err == nil:
+		return ResultOK
+	case op == OpGet && errors.Is(err, gouache.ErrCacheMiss):
+		return ResultMiss
+	default:
+		return ResultError
+	}
+}
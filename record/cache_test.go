@@ -0,0 +1,99 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache/clocktest"
+	"github.com/soyacen/gouache/sample"
+)
+
+// decode parses a trace into its entries.
+func decode(t *testing.T, trace *bytes.Buffer) []Entry {
+	var entries []Entry
+	decoder := json.NewDecoder(bytes.NewReader(trace.Bytes()))
+	for decoder.More() {
+		var entry Entry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatalf("Failed to decode trace: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// TestCache_Record tests that every operation is written with its outcome
+func TestCache_Record(t *testing.T) {
+	var trace bytes.Buffer
+	cache := New(&sample.Cache{}, &trace)
+	ctx := context.Background()
+
+	_, _ = cache.Get(ctx, "key")
+	_ = cache.Set(ctx, "key", "value")
+	_, _ = cache.Get(ctx, "key")
+	_ = cache.Delete(ctx, "key")
+
+	entries := decode(t, &trace)
+	want := []struct {
+		op     Op
+		result Result
+	}{{OpGet, ResultMiss}, {OpSet, ResultOK}, {OpGet, ResultHit}, {OpDelete, ResultOK}}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Op != want[i].op || entry.Result != want[i].result || entry.Key != "key" {
+			t.Errorf("Expected entry %d to be %s %s, got %+v", i, want[i].op, want[i].result, entry)
+		}
+	}
+	if string(entries[1].Value) != `"value"` {
+		t.Errorf("Expected the Set value to be recorded, got %s", entries[1].Value)
+	}
+}
+
+// TestCache_Options tests restricting the recorded operations and omitting values
+func TestCache_Options(t *testing.T) {
+	var trace bytes.Buffer
+	cache := New(&sample.Cache{}, &trace, WithOps(OpSet), WithValues(false))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "value")
+	_, _ = cache.Get(ctx, "key")
+	_ = cache.Delete(ctx, "key")
+
+	entries := decode(t, &trace)
+	if len(entries) != 1 || entries[0].Op != OpSet {
+		t.Fatalf("Expected only the Set to be recorded, got %+v", entries)
+	}
+	if entries[0].Value != nil {
+		t.Errorf("Expected no value, got %s", entries[0].Value)
+	}
+}
+
+// TestCache_Clock tests that entries are timed with the configured clock
+func TestCache_Clock(t *testing.T) {
+	var trace bytes.Buffer
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := clocktest.NewClock(start)
+	cache := New(&sample.Cache{}, &trace, WithClock(clock))
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "value")
+	clock.Advance(time.Second)
+	_, _ = cache.Get(ctx, "key")
+	clock.Advance(time.Second)
+	_ = cache.Delete(ctx, "key")
+
+	entries := decode(t, &trace)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if want := start.Add(time.Duration(i) * time.Second); !entry.Time.Equal(want) {
+			t.Errorf("Expected entry %d at %v, got %v", i, want, entry.Time)
+		}
+	}
+}
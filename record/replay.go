@@ -0,0 +1,82 @@
+package record
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/soyacen/gouache"
+)
+
+// Divergence is a replayed operation whose outcome differs from the recorded one.
+type Divergence struct {
+	// Line is the 1-based line of the entry in the trace.
+	Line int
+
+	// Entry is the recorded entry.
+	Entry Entry
+
+	// Got is the outcome of the replayed operation.
+	Got Result
+}
+
+// Replay runs the operations of a trace written by a recording cache against
+// c, in order, and reports every operation whose outcome differs from the
+// recorded one.
+//
+// Set entries store their recorded value as decoded by encoding/json, so
+// numbers come back as float64, byte slices as base64 strings and structs as
+// maps. Set entries recorded without a value store nil. Timing is not
+// reproduced; operations run back to back.
+//
+// Parameters:
+//   - ctx: Context for the replayed operations
+//   - r: The trace to replay
+//   - c: The cache to replay against, usually a fresh one
+//
+// Returns:
+//   - The operations whose outcome differs from the trace
+//   - An error if the trace can't be read or parsed
+func Replay(ctx context.Context, r io.Reader, c gouache.Cache) ([]Divergence, error) {
+	var divergences []Divergence
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		// Skip blank lines
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return divergences, fmt.Errorf("record: line %d: %w", line, err)
+		}
+
+		// Run the recorded operation
+		var err error
+		switch entry.Op {
+		case OpGet:
+			_, err = c.Get(ctx, entry.Key)
+		case OpSet:
+			var val any
+			if len(entry.Value) > 0 {
+				if err := json.Unmarshal(entry.Value, &val); err != nil {
+					return divergences, fmt.Errorf("record: line %d: %w", line, err)
+				}
+			}
+			err = c.Set(ctx, entry.Key, val)
+		case OpDelete:
+			err = c.Delete(ctx, entry.Key)
+		default:
+			return divergences, fmt.Errorf("record: line %d: unknown operation %q", line, entry.Op)
+		}
+
+		// Compare the outcome with the recorded one
+		if got := result(entry.Op, err); got != entry.Result {
+			divergences = append(divergences, Divergence{Line: line, Entry: entry, Got: got})
+		}
+	}
+	return divergences, scanner.Err()
+}
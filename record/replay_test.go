@@ -0,0 +1,58 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestReplay tests that a recorded trace replays against a fresh cache
+func TestReplay(t *testing.T) {
+	var trace bytes.Buffer
+	cache := New(&sample.Cache{}, &trace)
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "a", "1")
+	_, _ = cache.Get(ctx, "a")
+	_ = cache.Delete(ctx, "a")
+	_, _ = cache.Get(ctx, "a")
+
+	// Replaying against a fresh cache reproduces every outcome
+	fresh := &sample.Cache{}
+	divergences, err := Replay(ctx, bytes.NewReader(trace.Bytes()), fresh)
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if len(divergences) != 0 {
+		t.Errorf("Expected no divergences, got %+v", divergences)
+	}
+
+	// Replaying against a cache in another state reports the differences
+	stale := &sample.Cache{}
+	trace.Reset()
+	cache = New(stale, &trace)
+	_, _ = cache.Get(ctx, "b")
+	_ = stale.Set(ctx, "b", "2")
+	divergences, err = Replay(ctx, bytes.NewReader(trace.Bytes()), stale)
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if len(divergences) != 1 || divergences[0].Line != 1 || divergences[0].Got != ResultHit {
+		t.Errorf("Expected a hit instead of the recorded miss on line 1, got %+v", divergences)
+	}
+}
+
+// TestReplay_Malformed tests that malformed traces are rejected
+func TestReplay_Malformed(t *testing.T) {
+	_, err := Replay(context.Background(), strings.NewReader("{\"op\":\"flush\"}\n"), &sample.Cache{})
+	if err == nil {
+		t.Error("Expected an error for an unknown operation")
+	}
+	_, err = Replay(context.Background(), strings.NewReader("not json\n"), &sample.Cache{})
+	if err == nil {
+		t.Error("Expected an error for malformed JSON")
+	}
+}
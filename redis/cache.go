@@ -8,6 +8,9 @@ package redis
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/soyacen/gouache"
@@ -17,6 +20,38 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Iterable interface at compile time.
+var _ gouache.Iterable = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.CompareDeleter interface at compile time.
+var _ gouache.CompareDeleter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*Cache)(nil)
+
+// compareAndDeleteScript deletes a key only if it still holds the expected data.
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
 // Cache is an implementation of gouache.Cache using Redis as the storage backend.
 // It provides methods for storing, retrieving, and deleting cached values with
 // support for custom serialization/deserialization and configurable TTL.
@@ -25,7 +60,9 @@ type Cache struct {
 	Cache redis.Cmdable
 
 	// TTL is an optional function to determine the time-to-live duration for a cache entry.
-	// If not provided, entries will not expire by default.
+	// If not provided, entries will not expire by default. A positive result is
+	// scaled by the context's gouache.TTLMultiplier, and any result is clamped to
+	// the context's gouache.TTLCeiling, before it is applied.
 	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
 
 	// Marshal is an optional function to serialize objects into strings.
@@ -35,10 +72,189 @@ type Cache struct {
 	// Unmarshal is an optional function to deserialize strings into objects.
 	// If not provided, raw strings are returned.
 	Unmarshal func(key string, data string) (any, error)
+
+	// UnmarshalFallback is an optional function called when decoding a stored
+	// value fails, for example after its format changed, with the key, the
+	// raw data and the decoding error. Its result is returned instead, so it
+	// can delete the entry and return gouache.ErrCacheMiss to have the value
+	// recomputed, or return a default. If nil, the decoding error is returned.
+	UnmarshalFallback func(key string, data []byte, err error) (any, error)
+
+	// Types is an optional registry of type tags. When set, values of
+	// registered types are stored prefixed with their tag and decoded on Get
+	// with the Unmarshal registered for that tag, bypassing Unmarshal.
+	Types *TypeRegistry
+
+	// KeyEncoder is an optional function mapping each key to the key stored
+	// in Redis, such as SafeKeyEncoder. It is applied by every operation
+	// taking keys, so Get, Set and Delete stay symmetric, while Marshal,
+	// Unmarshal and TTL still see the original key. Iterate match patterns and
+	// ClearByPrefix work on stored keys. If nil, keys are stored unchanged.
+	KeyEncoder func(key string) string
+
+	// KeyDecoder is an optional function reversing KeyEncoder, such as
+	// DecodeSafeKey, which Iterate applies to the keys it finds so that they
+	// can be passed back to Get, as Migrate and Dump do. It reports false for
+	// stored keys it can't reverse, which Iterate skips. If nil, Iterate
+	// passes stored keys unchanged.
+	KeyDecoder func(stored string) (string, bool)
+
+	// ScanCount is the COUNT hint passed to each SCAN call made by Iterate
+	// and ClearByPrefix.
+	// If not positive, a default of 100 is used.
+	ScanCount int64
+
+	// GetMultiBatchSize is the number of keys read by each MGET command
+	// issued by GetMulti. If not positive, a default of 100 is used.
+	GetMultiBatchSize int
+
+	// DefaultTimeout bounds each Redis operation whose context has no
+	// deadline, so that a hung server can't block callers forever. Contexts
+	// that already have a deadline are used unchanged. If not positive,
+	// operations are not bounded.
+	DefaultTimeout time.Duration
+
+	// Timestamp makes every stored value carry the time it was set, so that
+	// GetWithMeta reports its age without an extra TTL round-trip. See
+	// WithTimestamp for the format and its compatibility constraints.
+	Timestamp bool
+
+	// Clock is an optional clock providing the set time stamped on values
+	// when Timestamp is enabled. If not provided, gouache.RealClock is used.
+	Clock gouache.Clock
+}
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithTTL returns an Option that sets the function determining the TTL of
+// each entry. See the TTL field.
+//
+// Parameters:
+//   - f: The function returning the TTL of an entry
+//
+// Returns:
+//   - An Option function that sets TTL
+func WithTTL(f func(ctx context.Context, key string, val any) (time.Duration, error)) Option {
+	return func(cache *Cache) {
+		cache.TTL = f
+	}
+}
+
+// WithMarshal returns an Option that sets the function serializing values
+// that are not strings.
+//
+// Parameters:
+//   - f: The function encoding a value
+//
+// Returns:
+//   - An Option function that sets Marshal
+func WithMarshal(f func(key string, obj any) (string, error)) Option {
+	return func(cache *Cache) {
+		cache.Marshal = f
+	}
+}
+
+// WithUnmarshal returns an Option that sets the function deserializing stored data.
+//
+// Parameters:
+//   - f: The function decoding a value
+//
+// Returns:
+//   - An Option function that sets Unmarshal
+func WithUnmarshal(f func(key string, data string) (any, error)) Option {
+	return func(cache *Cache) {
+		cache.Unmarshal = f
+	}
+}
+
+// WithDefaultTimeout returns an Option that sets the timeout applied to Redis
+// operations whose context has no deadline.
+//
+// Parameters:
+//   - d: The default timeout of an operation
+//
+// Returns:
+//   - An Option function that sets DefaultTimeout
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(cache *Cache) {
+		cache.DefaultTimeout = d
+	}
+}
+
+// WithGetMultiBatchSize returns an Option that sets the number of keys read
+// by each MGET command issued by GetMulti.
+//
+// Parameters:
+//   - n: The number of keys per MGET
+//
+// Returns:
+//   - An Option function that sets GetMultiBatchSize
+func WithGetMultiBatchSize(n int) Option {
+	return func(cache *Cache) {
+		cache.GetMultiBatchSize = n
+	}
+}
+
+// WithUnmarshalFallback returns an Option that sets the function handling
+// values that fail to decode. See the UnmarshalFallback field.
+//
+// Parameters:
+//   - f: The function called with the key, raw data and decoding error
+//
+// Returns:
+//   - An Option function that sets UnmarshalFallback
+func WithUnmarshalFallback(f func(key string, data []byte, err error) (any, error)) Option {
+	return func(cache *Cache) {
+		cache.UnmarshalFallback = f
+	}
+}
+
+// New creates a new Cache backed by the specified Redis client. The fields
+// set by the options can still be set directly. New logs a warning for each
+// inconsistent setting reported by validate, such as Unmarshal without Marshal.
+//
+// Parameters:
+//   - client: The Redis client used for storage operations
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache using client as its storage
+func New(client redis.Cmdable, opts ...Option) *Cache {
+	cache := &Cache{Cache: client}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	if err := cache.validate(); err != nil {
+		slog.Warn("redis.New: inconsistent configuration", slog.String("err", err.Error()))
+	}
+	return cache
+}
+
+// validate reports settings that are accepted but almost certainly a mistake:
+// a codec missing one direction, so values don't round-trip, and an
+// UnmarshalFallback that is never called because nothing is decoded. A type
+// registry supplies its own decoding, so it makes both acceptable.
+//
+// Returns:
+//   - An error listing the inconsistent settings, or nil
+func (cache *Cache) validate() error {
+	var errs []error
+	if cache.Unmarshal != nil && cache.Marshal == nil {
+		errs = append(errs, errors.New("Unmarshal is set without Marshal, so only raw values can be stored"))
+	}
+	if cache.Marshal != nil && cache.Unmarshal == nil && cache.Types == nil {
+		errs = append(errs, errors.New("Marshal is set without Unmarshal, so Get returns serialized data"))
+	}
+	if cache.UnmarshalFallback != nil && cache.Unmarshal == nil && cache.Types == nil {
+		errs = append(errs, errors.New("UnmarshalFallback is set without Unmarshal, so it is never called"))
+	}
+	return errors.Join(errs...)
 }
 
 // Get retrieves a value from the Redis cache by its key.
-// It returns gouache.ErrCacheMiss if the key does not exist.
+// It returns gouache.ErrCacheMiss if the key does not exist, and an error
+// wrapping gouache.ErrContext if the context is done before Redis replies.
 //
 // Parameters:
 //   - ctx: Context for the Redis operation
@@ -48,19 +264,223 @@ type Cache struct {
 //   - The cached value or nil if not found
 //   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
 func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
 	// Attempt to get the value from Redis
-	data, err := cache.Cache.Get(ctx, key).Result()
+	data, err := cache.Cache.Get(ctx, cache.storedKey(key)).Result()
 
 	// Handle case where entry is not found
 	if errors.Is(err, redis.Nil) {
 		return nil, gouache.ErrCacheMiss
 	}
 
-	// Return other errors as-is
+	// Return other errors, distinguishing context cancellation from backend failures
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return cache.unmarshal(key, data)
+}
+
+// GetMulti retrieves the values for the given keys from Redis with MGET
+// commands of at most GetMultiBatchSize keys each, issued one after another.
+// Keys that don't exist are omitted from the returned map. In Redis Cluster
+// the keys of each batch must hash to the same slot.
+//
+// If the context is done before every batch was read, GetMulti returns the
+// values read by the completed batches together with an error wrapping
+// gouache.ErrPartialResult and gouache.ErrContext. Keys absent from such a
+// partial result were not read and should be treated as misses, which keeps
+// large batches useful under a tight deadline.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value, possibly partial
+//   - An error wrapping gouache.ErrPartialResult if the context is done midway,
+//     or an error if a read or decoding fails
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Resolve the batch size
+	size := cache.GetMultiBatchSize
+	if size <= 0 {
+		size = 100
+	}
+
+	vals := make(map[string]any, len(keys))
+	for start := 0; start < len(keys); start += size {
+		end := start + size
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+
+		// Read the batch, returning what was read so far once the context is done
+		if err := ctx.Err(); err != nil {
+			return vals, fmt.Errorf("%w: %w", gouache.ErrPartialResult, contextError(ctx, err))
+		}
+		datas, err := cache.Cache.MGet(ctx, cache.storedKeys(batch)...).Result()
+		if err != nil {
+			if err = contextError(ctx, err); errors.Is(err, gouache.ErrContext) {
+				return vals, fmt.Errorf("%w: %w", gouache.ErrPartialResult, err)
+			}
+			return nil, err
+		}
+
+		// Decode the values that were found, MGET replies nil for missing keys
+		// and UnmarshalFallback may turn values that fail to decode into misses
+		for i, data := range datas {
+			str, ok := data.(string)
+			if !ok {
+				continue
+			}
+			val, err := cache.unmarshal(batch[i], str)
+			if errors.Is(err, gouache.ErrCacheMiss) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			vals[batch[i]] = val
+		}
+	}
+	return vals, nil
+}
+
+// GetDel atomically retrieves and deletes a value using GETDEL, so that only
+// one caller can ever consume it, as needed for one-shot tokens.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the value to consume
+//
+// Returns:
+//   - The value that was stored, or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetDel(ctx context.Context, key string) (any, error) {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Read and delete the value in one command
+	data, err := cache.Cache.GetDel(ctx, cache.storedKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, gouache.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return cache.unmarshal(key, data)
+}
+
+// GetSet atomically stores a value and retrieves the one it replaces using
+// SET with the GET option. The TTL is resolved as for Set.
+//
+// The new value is stored even when there was no previous value, in which
+// case gouache.ErrCacheMiss is returned.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as string or any other type requiring marshaling
+//
+// Returns:
+//   - The value that was replaced, or nil if there was none
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or gouache.ErrCacheMiss if key didn't exist before
+func (cache *Cache) GetSet(ctx context.Context, key string, val any) (any, error) {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Resolve the TTL for the value
+	ttl, err := cache.resolveTTL(ctx, key, val)
 	if err != nil {
 		return nil, err
 	}
 
+	// Convert the value into the string stored in Redis
+	data, err := cache.encode(key, val)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the new value and read the previous one in one command
+	prev, err := cache.Cache.SetArgs(ctx, cache.storedKey(key), data, redis.SetArgs{TTL: ttl, Get: true}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, gouache.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", gouache.ErrStorage, err)
+	}
+
+	return cache.unmarshal(key, prev)
+}
+
+// unmarshal converts a string read from Redis into the cached value,
+// handing decoding failures to UnmarshalFallback if it is set.
+//
+// Parameters:
+//   - key: The key the data was stored under
+//   - data: The data read from Redis
+//
+// Returns:
+//   - The decoded value, or the raw string if Unmarshal is nil
+//   - An error if decoding fails, or the result of UnmarshalFallback
+func (cache *Cache) unmarshal(key string, data string) (any, error) {
+	obj, _, err := cache.unmarshalStamped(key, data)
+	return obj, err
+}
+
+// unmarshalStamped converts a string read from Redis into the cached value
+// and the time it was set, handing decoding failures to UnmarshalFallback if
+// it is set.
+//
+// Parameters:
+//   - key: The key the data was stored under
+//   - data: The data read from Redis
+//
+// Returns:
+//   - The decoded value, or the raw string if Unmarshal is nil
+//   - The time the value was set, or the zero time if Timestamp is disabled
+//   - An error if decoding fails, or the result of UnmarshalFallback
+func (cache *Cache) unmarshalStamped(key string, data string) (any, time.Time, error) {
+	payload, setAt, err := cache.unstamp(data)
+	var obj any
+	if err == nil {
+		obj, err = cache.decode(key, payload)
+	}
+	if err != nil && cache.UnmarshalFallback != nil {
+		obj, err = cache.UnmarshalFallback(key, []byte(data), err)
+	}
+	return obj, setAt, err
+}
+
+// decode converts a string read from Redis into the cached value.
+//
+// Parameters:
+//   - key: The key the data was stored under
+//   - data: The data read from Redis
+//
+// Returns:
+//   - The decoded value, or the raw string if Unmarshal is nil
+//   - An error if decoding fails
+func (cache *Cache) decode(key string, data string) (any, error) {
+	// Decode values stored with a registered type tag
+	if cache.Types != nil {
+		if obj, ok, err := cache.Types.decode(key, data); ok {
+			return obj, err
+		}
+	}
+
 	// If no unmarshal function is defined, return raw data
 	if cache.Unmarshal == nil {
 		return data, nil
@@ -85,8 +505,98 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 //   - val: The value to store, either as string or any other type requiring marshaling
 //
 // Returns:
-//   - An error if the operation fails, including when Marshal is nil for non-string values
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-string values
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	// Resolve the TTL for the value
+	ttl, err := cache.resolveTTL(ctx, key, val)
+	if err != nil {
+		return err
+	}
+
+	// Store the value with the resolved TTL
+	return cache.SetWithTTL(ctx, key, val, ttl)
+}
+
+// SetWithTTL stores a value in the Redis cache under the specified key with
+// an explicit TTL, bypassing the TTL function and the TTL multiplier.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as string or any other type requiring marshaling
+//   - ttl: The time-to-live of the entry, zero meaning no expiration
+//
+// Returns:
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-string values
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Convert the value into the string stored in Redis
+	data, err := cache.encode(key, val)
+	if err != nil {
+		return err
+	}
+
+	// Store the data in Redis
+	if err := cache.Cache.Set(ctx, cache.storedKey(key), data, ttl).Err(); err != nil {
+		return fmt.Errorf("%w: %w", gouache.ErrStorage, err)
+	}
+	return nil
+}
+
+// Add stores a value in the Redis cache under the specified key only if the
+// key does not already exist, using SET NX.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as string or any other type requiring marshaling
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-string values
+func (cache *Cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Resolve the TTL for the value
+	ttl, err := cache.resolveTTL(ctx, key, val)
+	if err != nil {
+		return false, err
+	}
+
+	// Convert the value into the string stored in Redis
+	data, err := cache.encode(key, val)
+	if err != nil {
+		return false, err
+	}
+
+	// Store the data in Redis only if the key is absent
+	ok, err := cache.Cache.SetNX(ctx, cache.storedKey(key), data, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", gouache.ErrStorage, err)
+	}
+	return ok, nil
+}
+
+// resolveTTL determines the TTL for a value using the TTL function, if any,
+// scaled by the context's TTL multiplier and clamped to its TTL ceiling.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - The TTL to store the value with, zero meaning no expiration
+//   - An error if the TTL function fails
+func (cache *Cache) resolveTTL(ctx context.Context, key string, val any) (time.Duration, error) {
 	// Initialize TTL to zero (no expiration)
 	ttl := time.Duration(0)
 
@@ -96,29 +606,98 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 		// Use the TTL function to determine expiration duration
 		ttl, err = cache.TTL(ctx, key, val)
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
 
+	// Apply the per-request TTL multiplier and ceiling, if any
+	return gouache.AdjustTTL(ctx, ttl), nil
+}
+
+// marshal converts a value into the string stored in Redis.
+//
+// Parameters:
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as string or any other type requiring marshaling
+//
+// Returns:
+//   - The string to store
+//   - An error wrapping gouache.ErrMarshal if Marshal fails, or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-string values
+func (cache *Cache) marshal(key string, val any) (string, error) {
 	// Check if the value is already a string
 	if data, ok := val.(string); ok {
 		// Directly store strings without marshaling
-		return cache.Cache.Set(ctx, key, data, ttl).Err()
+		return data, nil
+	}
+
+	// Store values of registered types prefixed with their type tag
+	if cache.Types != nil {
+		if tag, codec, ok := cache.Types.lookup(val); ok {
+			marshal := codec.Marshal
+			if marshal == nil {
+				marshal = cache.Marshal
+			}
+			if marshal == nil {
+				return "", fmt.Errorf("%w: %T requires Marshal", gouache.ErrUnsupportedValue, val)
+			}
+			data, err := marshal(key, val)
+			if err != nil {
+				return "", fmt.Errorf("%w: %w", gouache.ErrMarshal, err)
+			}
+			return typeTagMarker + tag + typeTagMarker + data, nil
+		}
 	}
 
 	// For non-string values, ensure a marshal function is available
 	if cache.Marshal == nil {
-		return errors.New("gouache: Marshal is nil")
+		return "", fmt.Errorf("%w: %T requires Marshal", gouache.ErrUnsupportedValue, val)
 	}
 
 	// Marshal the value into string using the custom marshal function
 	data, err := cache.Marshal(key, val)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", gouache.ErrMarshal, err)
+	}
+	return data, nil
+}
+
+// Touch sets a new time-to-live for the entry stored under key using EXPIRE,
+// or PERSIST if ttl is not positive.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live of the entry, zero meaning no expiration
+//
+// Returns:
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	var ok bool
+	var err error
+	if ttl > 0 {
+		ok, err = cache.Cache.Expire(ctx, cache.storedKey(key), ttl).Result()
+	} else {
+		// PERSIST also reports false for keys without a TTL, so check existence
+		ok, err = cache.Cache.Persist(ctx, cache.storedKey(key)).Result()
+		if err == nil && !ok {
+			var n int64
+			n, err = cache.Cache.Exists(ctx, cache.storedKey(key)).Result()
+			ok = n > 0
+		}
+	}
 	if err != nil {
 		return err
 	}
 
-	// Store the marshaled data in Redis
-	return cache.Cache.Set(ctx, key, data, ttl).Err()
+	// EXPIRE and PERSIST report false for missing keys
+	if !ok {
+		return gouache.ErrCacheMiss
+	}
+	return nil
 }
 
 // Delete removes a value from the Redis cache by its key.
@@ -130,6 +709,307 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 // Returns:
 //   - An error if the operation fails
 func (cache *Cache) Delete(ctx context.Context, key string) error {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
 	// Delegate deletion to the underlying Redis client instance
-	return cache.Cache.Del(ctx, key).Err()
+	return cache.Cache.Del(ctx, cache.storedKey(key)).Err()
+}
+
+// CompareAndDelete deletes the value stored under key if it still equals
+// expected, atomically with a Lua script. expected is marshaled as by Set and
+// compared with the stored string, so values are equal when their serialized
+// forms are.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the value to delete
+//   - expected: The value the key must hold to be deleted
+//
+// Returns:
+//   - true if the value was deleted, false if the key was missing or held another value
+//   - An error wrapping gouache.ErrMarshal if marshaling expected fails, or an error if the operation fails
+func (cache *Cache) CompareAndDelete(ctx context.Context, key string, expected any) (bool, error) {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Convert the expected value into the string Set would have stored
+	data, err := cache.marshal(key, expected)
+	if err != nil {
+		return false, err
+	}
+
+	// Compare and delete in one atomic step, ignoring the set time if any
+	script := compareAndDeleteScript
+	if cache.Timestamp {
+		script = compareAndDeleteStampedScript
+	}
+	deleted, err := script.Run(ctx, cache.Cache, []string{cache.storedKey(key)}, data).Int()
+	if err != nil {
+		return false, contextError(ctx, err)
+	}
+	return deleted > 0, nil
+}
+
+// DeleteMulti removes the values of the given keys from the Redis cache with
+// a single DEL command. In Redis Cluster all keys must hash to the same slot.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	// DEL requires at least one key
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+	return cache.Cache.Del(ctx, cache.storedKeys(keys)...).Err()
+}
+
+// DeleteMultiResult removes the values of the given keys from Redis and
+// reports which of them existed.
+//
+// A multi-key DEL only replies with the number of keys removed, so this
+// sends one DEL per key instead, pipelined in a single round trip, and reads
+// each reply. That costs the server one command per key, which is why
+// DeleteMulti keeps using a single DEL and per-key results are only computed
+// when this method is called. Unlike DeleteMulti, the keys may hash to
+// different slots in Redis Cluster.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - An error if the operation fails
+func (cache *Cache) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Queue one DEL per key and send them together
+	pipe := cache.Cache.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Del(ctx, cache.storedKey(key))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	// A reply of 1 means the key existed
+	var deleted []string
+	for i, cmd := range cmds {
+		if cmd.Val() > 0 {
+			deleted = append(deleted, keys[i])
+		}
+	}
+	return deleted, nil
+}
+
+// Iterate calls fn for every key in Redis matching the glob-style pattern match.
+// An empty match enumerates all keys.
+//
+// Keys are enumerated with SCAN, one batch of roughly ScanCount keys at a time,
+// so the server is never blocked and the full key set is never held in memory.
+// As with SCAN, the order is unspecified and a key may be seen more than once.
+//
+// match is matched against the keys stored in Redis, encoded by KeyEncoder if
+// it is set. The keys passed to fn are decoded by KeyDecoder if it is set,
+// skipping those it can't reverse, so they are the keys to use with Get;
+// otherwise they are the stored keys, which only round-trip through Get when
+// there is no KeyEncoder. Set KeyDecoder along with KeyEncoder before using
+// Migrate or Dump.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - match: The pattern keys must match, or empty to match all keys
+//   - fn: The function called for each key
+//
+// Returns:
+//   - An error if the operation fails, the context is done, or fn fails
+func (cache *Cache) Iterate(ctx context.Context, match string, fn func(key string) error) error {
+	// Resolve the batch size hint
+	count := cache.ScanCount
+	if count <= 0 {
+		count = 100
+	}
+
+	var cursor uint64
+	for {
+		// Stop early if the context is done
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Fetch the next batch of keys, bounding each SCAN with the default timeout
+		scanCtx, cancel := cache.withTimeout(ctx)
+		keys, next, err := cache.Cache.Scan(scanCtx, cursor, match, count).Result()
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		// Hand each key to the callback, decoded back to the key used with Get
+		for _, key := range keys {
+			if cache.KeyDecoder != nil {
+				decoded, ok := cache.KeyDecoder(key)
+				if !ok {
+					continue
+				}
+				key = decoded
+			}
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+
+		// A zero cursor means the iteration is complete
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// ClearByPrefix deletes every key starting with prefix and returns how many
+// keys were deleted. It is the recommended way to clear a cache that shares
+// its Redis DB with other data, which FLUSHDB would wipe as well.
+//
+// Keys are found with SCAN, one batch of roughly ScanCount keys at a time,
+// and each batch is removed with a single DEL. Unlike FLUSHDB, which runs in
+// one command, this walks the whole keyspace of the DB, including keys that
+// don't match, so it costs a round trip per batch and time proportional to
+// the size of the DB rather than the number of keys deleted. In exchange the
+// server is never blocked for long, and the context is checked between
+// batches, so a canceled clear stops early having deleted a prefix of the
+// keys. Keys written while the clear runs may survive it. In Redis Cluster
+// the client must be one that scans and deletes across all nodes.
+//
+// The prefix is matched against stored keys, so with a KeyEncoder it must be
+// a prefix of the encoded keys.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - prefix: The prefix of the keys to delete, which must not be empty
+//
+// Returns:
+//   - The number of keys deleted, also when an error is returned
+//   - An error if prefix is empty, the operation fails, or the context is
+//     done, wrapping gouache.ErrContext in the latter case
+func (cache *Cache) ClearByPrefix(ctx context.Context, prefix string) (int64, error) {
+	// Refuse to clear the whole DB by accident
+	if prefix == "" {
+		return 0, errors.New("redis: ClearByPrefix requires a non-empty prefix")
+	}
+
+	// Resolve the batch size hint
+	count := cache.ScanCount
+	if count <= 0 {
+		count = 100
+	}
+	match := escapeGlob(prefix) + "*"
+
+	var cursor uint64
+	var deleted int64
+	for {
+		// Stop early if the context is done
+		if err := ctx.Err(); err != nil {
+			return deleted, contextError(ctx, err)
+		}
+
+		// Fetch the next batch of matching keys, bounding each command with the default timeout
+		opCtx, cancel := cache.withTimeout(ctx)
+		keys, next, err := cache.Cache.Scan(opCtx, cursor, match, count).Result()
+		if err == nil && len(keys) > 0 {
+			// Delete the batch, counting only keys that still existed
+			var n int64
+			n, err = cache.Cache.Del(opCtx, keys...).Result()
+			deleted += n
+		}
+		cancel()
+		if err != nil {
+			return deleted, contextError(ctx, err)
+		}
+
+		// A zero cursor means the scan is complete
+		if next == 0 {
+			return deleted, nil
+		}
+		cursor = next
+	}
+}
+
+// escapeGlob escapes the characters special to Redis glob-style patterns, so
+// that s only matches itself.
+//
+// Parameters:
+//   - s: The literal string
+//
+// Returns:
+//   - A pattern matching exactly s
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// withTimeout derives a context bounded by DefaultTimeout from ctx if ctx
+// has no deadline. A deadline set by the caller, shorter or longer, is kept.
+//
+// Parameters:
+//   - ctx: Context of the Redis operation
+//
+// Returns:
+//   - The context to run the operation with
+//   - A function releasing the derived context, to be called when the operation is done
+func (cache *Cache) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cache.DefaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, cache.DefaultTimeout)
+}
+
+// contextError wraps err with gouache.ErrContext if the operation failed
+// because ctx is done. go-redis may report an expired deadline as a network
+// timeout, so the context itself is checked as well as the error.
+//
+// Parameters:
+//   - ctx: Context of the failed Redis operation
+//   - err: The error returned by the Redis client
+//
+// Returns:
+//   - An error wrapping gouache.ErrContext and the context error, or err unchanged
+func contextError(ctx context.Context, err error) error {
+	// Prefer the context's own error, which says why it is done
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %w", gouache.ErrContext, ctxErr)
+	}
+	// The client may also report a context error it observed itself
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", gouache.ErrContext, err)
+	}
+	return err
 }
@@ -0,0 +1,1130 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
+	"github.com/soyacen/gouache/clocktest"
+)
+
+// newTestCache creates a Cache backed by an in-process miniredis server.
+func newTestCache(t *testing.T) (*Cache, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return &Cache{Cache: client}, server
+}
+
+// TestCache_GetSetDelete tests basic Get, Set and Delete operations
+func TestCache_GetSetDelete(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx := context.Background()
+
+	// Test Get with non-existent key
+	_, err := cache.Get(ctx, "test-key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+
+	// Test Set
+	err = cache.Set(ctx, "test-key", "test-value")
+	if err != nil {
+		t.Errorf("Failed to set value: %v", err)
+	}
+
+	// Test Get
+	result, err := cache.Get(ctx, "test-key")
+	if err != nil {
+		t.Errorf("Failed to get value: %v", err)
+	}
+	if result != "test-value" {
+		t.Errorf("Expected test-value, got %v", result)
+	}
+
+	// Test Delete
+	err = cache.Delete(ctx, "test-key")
+	if err != nil {
+		t.Errorf("Failed to delete value: %v", err)
+	}
+	_, err = cache.Get(ctx, "test-key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss after delete, got %v", err)
+	}
+}
+
+// TestCache_DeleteMulti tests deleting several keys with one command
+func TestCache_DeleteMulti(t *testing.T) {
+	cache, server := newTestCache(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	// Missing keys are ignored
+	if err := cache.DeleteMulti(ctx, []string{"a", "b", "missing"}); err != nil {
+		t.Fatalf("Failed to delete keys: %v", err)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "c" {
+		t.Errorf("Expected only c to remain, got %v", keys)
+	}
+
+	// An empty batch is a no-op
+	if err := cache.DeleteMulti(ctx, nil); err != nil {
+		t.Errorf("Expected no error for an empty batch, got %v", err)
+	}
+}
+
+// TestCache_DeleteMultiResult tests that only keys that existed are reported
+func TestCache_DeleteMultiResult(t *testing.T) {
+	cache, server := newTestCache(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	deleted, err := cache.DeleteMultiResult(ctx, []string{"missing", "c", "a", "gone"})
+	if err != nil {
+		t.Fatalf("Failed to delete keys: %v", err)
+	}
+	if fmt.Sprint(deleted) != "[c a]" {
+		t.Errorf("Expected [c a] deleted, got %v", deleted)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("Expected only b to remain, got %v", keys)
+	}
+}
+
+// TestCache_Iterate tests enumerating keys matching a pattern
+func TestCache_Iterate(t *testing.T) {
+	cache, _ := newTestCache(t)
+	cache.ScanCount = 2
+	ctx := context.Background()
+
+	// Set up keys in two namespaces
+	for i := 0; i < 5; i++ {
+		if err := cache.Set(ctx, fmt.Sprintf("user:%d", i), "value"); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+	if err := cache.Set(ctx, "session:1", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// Collect matching keys, deduplicating as SCAN may repeat keys
+	seen := map[string]bool{}
+	err := cache.Iterate(ctx, "user:*", func(key string) error {
+		seen[key] = true
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Failed to iterate: %v", err)
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	expected := []string{"user:0", "user:1", "user:2", "user:3", "user:4"}
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
+
+// TestCache_IterateKeyDecoder tests that Iterate decodes the keys it finds
+// with KeyDecoder, skipping hashed keys, so they round-trip through Migrate
+func TestCache_IterateKeyDecoder(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	src := New(client, WithKeyEncoder(SafeKeyEncoder(32)), WithKeyDecoder(DecodeSafeKey))
+	ctx := context.Background()
+
+	long := strings.Repeat("k", 33)
+	for _, key := range []string{"user:1", "with space", long} {
+		if err := src.Set(ctx, key, "value"); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+
+	var keys []string
+	if err := src.Iterate(ctx, "", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to iterate: %v", err)
+	}
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[user:1 with space]" {
+		t.Errorf("Expected the decoded keys without the hashed one, got %q", keys)
+	}
+
+	// Migrated keys are encoded once in the destination
+	dstServer := miniredis.RunT(t)
+	dstClient := redis.NewClient(&redis.Options{Addr: dstServer.Addr()})
+	t.Cleanup(func() { _ = dstClient.Close() })
+	dst := New(dstClient, WithKeyEncoder(SafeKeyEncoder(32)))
+	if n, err := gouache.Migrate(ctx, src, dst); err != nil || n != 2 {
+		t.Fatalf("Expected 2 values migrated, got %d, %v", n, err)
+	}
+	if val, err := dst.Get(ctx, "with space"); err != nil || val != "value" {
+		t.Errorf("Expected the migrated value, got %v, %v", val, err)
+	}
+}
+
+// TestCache_IterateStops tests that Iterate stops on callback errors and canceled contexts
+func TestCache_IterateStops(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx := context.Background()
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// Test callback error is returned
+	stop := errors.New("stop")
+	err := cache.Iterate(ctx, "", func(key string) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected callback error, got %v", err)
+	}
+
+	// Test canceled context is respected
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = cache.Iterate(canceled, "", func(key string) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestCache_ClearByPrefix tests deleting only the keys with a given prefix
+func TestCache_ClearByPrefix(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx := context.Background()
+
+	// Set up keys under the prefix, a literal glob character, and other data
+	for i := 0; i < 5; i++ {
+		if err := cache.Set(ctx, fmt.Sprintf("app:%d", i), "value"); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+	for _, key := range []string{"app*x", "other:1", "ap"} {
+		if err := cache.Set(ctx, key, "value"); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+
+	// miniredis cursors are offsets into the key list, which deleting shifts,
+	// so the default ScanCount keeps the whole keyspace in one batch here;
+	// real Redis tolerates deletes during a SCAN
+	n, err := cache.ClearByPrefix(ctx, "app:")
+	if err != nil {
+		t.Fatalf("Failed to clear: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 keys deleted, got %d", n)
+	}
+	for _, key := range []string{"app*x", "other:1", "ap"} {
+		if _, err := cache.Get(ctx, key); err != nil {
+			t.Errorf("Expected %s to survive, got %v", key, err)
+		}
+	}
+
+	// Glob characters in the prefix match only themselves
+	if n, err := cache.ClearByPrefix(ctx, "app*"); err != nil || n != 1 {
+		t.Errorf("Expected 1 key deleted, got %d, %v", n, err)
+	}
+
+	// An empty prefix is refused
+	if _, err := cache.ClearByPrefix(ctx, ""); err == nil {
+		t.Error("Expected an error for an empty prefix")
+	}
+
+	// Test canceled context is respected
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := cache.ClearByPrefix(canceled, "other:"); !errors.Is(err, gouache.ErrContext) {
+		t.Errorf("Expected gouache.ErrContext, got %v", err)
+	}
+}
+
+// TestCache_UnmarshalFallback tests that the fallback can delete entries that fail to decode and report misses
+func TestCache_UnmarshalFallback(t *testing.T) {
+	cache, server := newTestCache(t)
+	cache.Unmarshal = func(key string, data string) (any, error) {
+		var obj map[string]string
+		err := json.Unmarshal([]byte(data), &obj)
+		return obj, err
+	}
+	WithUnmarshalFallback(func(key string, data []byte, err error) (any, error) {
+		_ = cache.Delete(context.Background(), key)
+		return nil, gouache.ErrCacheMiss
+	})(cache)
+	ctx := context.Background()
+
+	_ = server.Set("old", "not json")
+	_ = server.Set("new", `{"a":"b"}`)
+
+	// The old-format value reads as a miss and is gone afterwards
+	if _, err := cache.Get(ctx, "old"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+	if server.Exists("old") {
+		t.Error("Expected the bad entry to be deleted")
+	}
+
+	// GetMulti omits entries the fallback turns into misses
+	_ = server.Set("old", "not json")
+	vals, err := cache.GetMulti(ctx, []string{"old", "new"})
+	if err != nil {
+		t.Fatalf("Failed to get values: %v", err)
+	}
+	if _, ok := vals["old"]; ok || len(vals) != 1 {
+		t.Errorf("Expected only the new entry, got %v", vals)
+	}
+}
+
+// TestCache_TTLMultiplier tests that the context TTL multiplier scales the resolved TTL
+func TestCache_TTLMultiplier(t *testing.T) {
+	cache, server := newTestCache(t)
+	cache.TTL = func(ctx context.Context, key string, val any) (time.Duration, error) {
+		return 10 * time.Second, nil
+	}
+
+	// Test the default multiplier leaves the TTL unchanged
+	err := cache.Set(context.Background(), "plain-key", "value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if ttl := server.TTL("plain-key"); ttl != 10*time.Second {
+		t.Errorf("Expected TTL 10s, got %v", ttl)
+	}
+
+	// Test a multiplied TTL
+	ctx := gouache.WithTTLMultiplier(context.Background(), 1.5)
+	err = cache.Set(ctx, "premium-key", "value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if ttl := server.TTL("premium-key"); ttl != 15*time.Second {
+		t.Errorf("Expected TTL 15s, got %v", ttl)
+	}
+}
+
+// TestCache_Add tests that Add only stores values for absent keys
+func TestCache_Add(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx := context.Background()
+
+	// Test Add on an absent key
+	ok, err := cache.Add(ctx, "test-key", "first")
+	if err != nil {
+		t.Errorf("Failed to add value: %v", err)
+	}
+	if !ok {
+		t.Error("Expected value to be added")
+	}
+
+	// Test Add on an existing key
+	ok, err = cache.Add(ctx, "test-key", "second")
+	if err != nil {
+		t.Errorf("Failed to add value: %v", err)
+	}
+	if ok {
+		t.Error("Expected value not to be added for existing key")
+	}
+
+	result, err := cache.Get(ctx, "test-key")
+	if err != nil {
+		t.Errorf("Failed to get value: %v", err)
+	}
+	if result != "first" {
+		t.Errorf("Expected first, got %v", result)
+	}
+}
+
+// TestCache_GetContextCanceled tests that a canceled context during a slow call is reported as gouache.ErrContext
+func TestCache_GetContextCanceled(t *testing.T) {
+	// Start a server that accepts connections but never replies
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:                  listener.Addr().String(),
+		ContextTimeoutEnabled: true,
+		MaxRetries:            -1,
+		ReadTimeout:           200 * time.Millisecond,
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	cache := &Cache{Cache: client}
+
+	// Cancel the context while the call is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err = cache.Get(ctx, "test-key")
+	if !errors.Is(err, gouache.ErrContext) {
+		t.Errorf("Expected gouache.ErrContext, got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// A backend failure is not reported as a context error
+	server, _ := newTestCache(t)
+	server.Cache.(*redis.Client).Close()
+	_, err = server.Get(context.Background(), "test-key")
+	if err == nil || errors.Is(err, gouache.ErrContext) {
+		t.Errorf("Expected a non-context error, got %v", err)
+	}
+}
+
+// testUser and testOrder are types used to test heterogeneous typed values
+type testUser struct {
+	Name string `json:"name"`
+}
+
+type testOrder struct {
+	ID int `json:"id"`
+}
+
+// TestCache_Types tests that values of different registered types round-trip as their own types
+func TestCache_Types(t *testing.T) {
+	cache, _ := newTestCache(t)
+	cache.Marshal = func(key string, obj any) (string, error) {
+		data, err := json.Marshal(obj)
+		return string(data), err
+	}
+	cache.Types = NewTypeRegistry()
+	err := cache.Types.Register("user", &testUser{}, TypeCodec{
+		Unmarshal: func(key string, data string) (any, error) {
+			var user testUser
+			err := json.Unmarshal([]byte(data), &user)
+			return &user, err
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+	err = cache.Types.Register("order", &testOrder{}, TypeCodec{
+		Unmarshal: func(key string, data string) (any, error) {
+			var order testOrder
+			err := json.Unmarshal([]byte(data), &order)
+			return &order, err
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := cache.Set(ctx, "user", &testUser{Name: "alice"}); err != nil {
+		t.Fatalf("Failed to set user: %v", err)
+	}
+	if err := cache.Set(ctx, "order", &testOrder{ID: 7}); err != nil {
+		t.Fatalf("Failed to set order: %v", err)
+	}
+	if err := cache.Set(ctx, "raw", "plain"); err != nil {
+		t.Fatalf("Failed to set raw string: %v", err)
+	}
+
+	// Test each value is read back as its registered type
+	user, err := cache.Get(ctx, "user")
+	if err != nil {
+		t.Errorf("Failed to get user: %v", err)
+	}
+	if u, ok := user.(*testUser); !ok || u.Name != "alice" {
+		t.Errorf("Expected *testUser alice, got %#v", user)
+	}
+
+	order, err := cache.Get(ctx, "order")
+	if err != nil {
+		t.Errorf("Failed to get order: %v", err)
+	}
+	if o, ok := order.(*testOrder); !ok || o.ID != 7 {
+		t.Errorf("Expected *testOrder 7, got %#v", order)
+	}
+
+	// Test raw strings stay untagged
+	raw, err := cache.Get(ctx, "raw")
+	if err != nil {
+		t.Errorf("Failed to get raw string: %v", err)
+	}
+	if raw != "plain" {
+		t.Errorf("Expected plain, got %v", raw)
+	}
+}
+
+// TestCache_Touch tests extending and removing the TTL of an entry
+func TestCache_Touch(t *testing.T) {
+	cache, server := newTestCache(t)
+	ctx := context.Background()
+
+	err := cache.SetWithTTL(ctx, "test-key", "test-value", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// Test Touch extends the TTL
+	err = cache.Touch(ctx, "test-key", time.Hour)
+	if err != nil {
+		t.Errorf("Failed to touch value: %v", err)
+	}
+	if ttl := server.TTL("test-key"); ttl != time.Hour {
+		t.Errorf("Expected TTL 1h, got %v", ttl)
+	}
+
+	// Test Touch with zero TTL removes the expiration, and succeeds again when there is none
+	for i := 0; i < 2; i++ {
+		err = cache.Touch(ctx, "test-key", 0)
+		if err != nil {
+			t.Errorf("Failed to persist value: %v", err)
+		}
+		if ttl := server.TTL("test-key"); ttl != 0 {
+			t.Errorf("Expected no TTL, got %v", ttl)
+		}
+	}
+
+	// Test Touch on a missing key
+	for _, ttl := range []time.Duration{time.Hour, 0} {
+		err = cache.Touch(ctx, "non-existent-key", ttl)
+		if !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+		}
+	}
+}
+
+// TestCache_ErrorClassification tests that marshal and storage failures are told apart
+func TestCache_ErrorClassification(t *testing.T) {
+	cache, server := newTestCache(t)
+	ctx := context.Background()
+
+	marshalErr := errors.New("marshal error")
+	cache.Marshal = func(key string, obj any) (string, error) {
+		return "", marshalErr
+	}
+
+	// Test marshal failure
+	err := cache.Set(ctx, "test-key", 42)
+	if !errors.Is(err, gouache.ErrMarshal) || !errors.Is(err, marshalErr) {
+		t.Errorf("Expected gouache.ErrMarshal wrapping %v, got %v", marshalErr, err)
+	}
+
+	// Test storage failure when Redis reports an error
+	server.SetError("READONLY simulated")
+	err = cache.Set(ctx, "test-key", "test-value")
+	if !errors.Is(err, gouache.ErrStorage) || errors.Is(err, gouache.ErrMarshal) {
+		t.Errorf("Expected gouache.ErrStorage, got %v", err)
+	}
+	_, err = cache.Add(ctx, "test-key", "test-value")
+	if !errors.Is(err, gouache.ErrStorage) {
+		t.Errorf("Expected gouache.ErrStorage from Add, got %v", err)
+	}
+}
+
+// TestCache_StandardTypes tests that common values round-trip without a Marshal function
+func TestCache_StandardTypes(t *testing.T) {
+	cache, _ := newTestCache(t)
+	cache.Types = NewTypeRegistry().RegisterStandardTypes()
+	ctx := context.Background()
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.FixedZone("", 2*3600))
+	values := []any{
+		now,
+		90 * time.Second,
+		true,
+		int(-42), int8(-8), int16(-16), int32(-32), int64(-1 << 62),
+		uint(42), uint8(8), uint16(16), uint32(32), uint64(1 << 63),
+		float32(1.5), float64(0.1),
+		"plain",
+	}
+
+	for i, val := range values {
+		key := fmt.Sprintf("key-%d", i)
+		if err := cache.Set(ctx, key, val); err != nil {
+			t.Fatalf("Failed to set %T: %v", val, err)
+		}
+		got, err := cache.Get(ctx, key)
+		if err != nil {
+			t.Errorf("Failed to get %T: %v", val, err)
+			continue
+		}
+		if tm, ok := got.(time.Time); ok {
+			if !tm.Equal(now) {
+				t.Errorf("Expected %v, got %v", now, tm)
+			}
+			continue
+		}
+		if got != val {
+			t.Errorf("Expected %T %v, got %T %v", val, val, got, got)
+		}
+	}
+
+	// Values of unregistered types still need a Marshal function
+	if err := cache.Set(ctx, "struct", struct{}{}); err == nil {
+		t.Error("Expected an error for an unregistered type")
+	}
+}
+
+// TestNewSharded tests building a sharded cache over several Redis instances
+func TestNewSharded(t *testing.T) {
+	servers := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t), miniredis.RunT(t)}
+	options := make([]*redis.Options, 0, len(servers))
+	for _, server := range servers {
+		options = append(options, &redis.Options{Addr: server.Addr()})
+	}
+
+	template := Cache{
+		Marshal: func(key string, obj any) (string, error) {
+			data, err := json.Marshal(obj)
+			return string(data), err
+		},
+		TTL: func(ctx context.Context, key string, val any) (time.Duration, error) {
+			return time.Minute, nil
+		},
+	}
+	cache, clients := NewSharded(options, template)
+	t.Cleanup(func() {
+		for _, client := range clients {
+			_ = client.Close()
+		}
+	})
+	if len(clients) != len(servers) {
+		t.Fatalf("Expected %d clients, got %d", len(servers), len(clients))
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		if err := cache.Set(ctx, fmt.Sprintf("key-%d", i), i); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+
+	// Every server holds some keys, stored with the shared Marshal and TTL
+	total := 0
+	for i, server := range servers {
+		keys := server.Keys()
+		if len(keys) == 0 {
+			t.Errorf("Expected server %d to hold some keys", i)
+		}
+		for _, key := range keys {
+			if ttl := server.TTL(key); ttl != time.Minute {
+				t.Errorf("Expected TTL 1m for %s, got %v", key, ttl)
+			}
+		}
+		total += len(keys)
+	}
+	if total != 30 {
+		t.Errorf("Expected 30 keys in total, got %d", total)
+	}
+
+	// Values are read back through the sharded cache
+	val, err := cache.Get(ctx, "key-7")
+	if err != nil || val != "7" {
+		t.Errorf("Expected 7, got %v, %v", val, err)
+	}
+}
+
+// TestBuckets tests that buckets share the template configuration
+func TestBuckets(t *testing.T) {
+	first, _ := newTestCache(t)
+	second, _ := newTestCache(t)
+	template := Cache{Cache: first.Cache, ScanCount: 10, Types: NewTypeRegistry()}
+
+	buckets := Buckets([]redis.Cmdable{first.Cache, second.Cache}, template)
+	if len(buckets) != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", len(buckets))
+	}
+	for i, bucket := range buckets {
+		c := bucket.(*Cache)
+		if c.ScanCount != 10 || c.Types != template.Types {
+			t.Errorf("Expected bucket %d to share the template configuration, got %+v", i, c)
+		}
+	}
+	if buckets[1].(*Cache).Cache != second.Cache {
+		t.Error("Expected bucket 1 to use the second client")
+	}
+}
+
+// TestCache_GetDel tests atomically consuming a value
+func TestCache_GetDel(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx := context.Background()
+
+	// Test GetDel on a missing key
+	_, err := cache.GetDel(ctx, "token")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+
+	// Test that the value is returned once and then gone
+	if err := cache.Set(ctx, "token", "secret"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	val, err := cache.GetDel(ctx, "token")
+	if err != nil || val != "secret" {
+		t.Errorf("Expected secret, got %v, %v", val, err)
+	}
+	_, err = cache.GetDel(ctx, "token")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss after consumption, got %v", err)
+	}
+}
+
+// TestCache_GetSet tests atomically replacing a value
+func TestCache_GetSet(t *testing.T) {
+	cache, server := newTestCache(t)
+	cache.TTL = func(ctx context.Context, key string, val any) (time.Duration, error) {
+		return time.Minute, nil
+	}
+	ctx := context.Background()
+
+	// Test GetSet on a missing key, which still stores the value
+	_, err := cache.GetSet(ctx, "key", "first")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+	if got, _ := server.Get("key"); got != "first" {
+		t.Errorf("Expected first to be stored, got %q", got)
+	}
+	if ttl := server.TTL("key"); ttl != time.Minute {
+		t.Errorf("Expected TTL of 1m, got %v", ttl)
+	}
+
+	// Test that the previous value is returned
+	val, err := cache.GetSet(ctx, "key", "second")
+	if err != nil || val != "first" {
+		t.Errorf("Expected first, got %v, %v", val, err)
+	}
+	if got, _ := server.Get("key"); got != "second" {
+		t.Errorf("Expected second to be stored, got %q", got)
+	}
+}
+
+// TestCache_UnsupportedValue tests that values needing a missing Marshal are reported with their type
+func TestCache_UnsupportedValue(t *testing.T) {
+	cache, _ := newTestCache(t)
+
+	err := cache.Set(context.Background(), "key", 42)
+	if !errors.Is(err, gouache.ErrUnsupportedValue) {
+		t.Errorf("Expected gouache.ErrUnsupportedValue, got %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "int") {
+		t.Errorf("Expected the value type in the message, got %v", err)
+	}
+}
+
+// TestCache_HashField tests storing an object's fields in a Redis hash
+func TestCache_HashField(t *testing.T) {
+	cache, server := newTestCache(t)
+	cache.Marshal = func(key string, obj any) (string, error) {
+		data, err := json.Marshal(obj)
+		return string(data), err
+	}
+	cache.Unmarshal = func(key string, data string) (any, error) {
+		var obj any
+		err := json.Unmarshal([]byte(data), &obj)
+		return obj, err
+	}
+	ctx := context.Background()
+
+	// Test HGet on a missing hash
+	if _, err := cache.HGet(ctx, "user:1", "name"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+
+	// Store the fields of an object separately
+	fields := map[string]any{"name": map[string]any{"first": "alice"}, "age": float64(30), "tags": []any{"a", "b"}}
+	for field, val := range fields {
+		if err := cache.HSet(ctx, "user:1", field, val); err != nil {
+			t.Fatalf("Failed to set field %s: %v", field, err)
+		}
+	}
+	if got, _ := server.HKeys("user:1"); len(got) != len(fields) {
+		t.Errorf("Expected %d fields in the hash, got %v", len(fields), got)
+	}
+
+	// Update a single field without touching the others
+	if err := cache.HSet(ctx, "user:1", "age", float64(31)); err != nil {
+		t.Fatalf("Failed to update field: %v", err)
+	}
+	age, err := cache.HGet(ctx, "user:1", "age")
+	if err != nil || age != float64(31) {
+		t.Errorf("Expected 31, got %v, %v", age, err)
+	}
+	name, err := cache.HGet(ctx, "user:1", "name")
+	if obj, _ := name.(map[string]any); err != nil || obj["first"] != "alice" {
+		t.Errorf("Expected map[first:alice], got %v, %v", name, err)
+	}
+
+	// Test HGet on a missing field
+	if _, err := cache.HGet(ctx, "user:1", "email"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+
+	// Test HDel of some fields, then of the rest
+	if err := cache.HDel(ctx, "user:1", "age", "missing"); err != nil {
+		t.Fatalf("Failed to delete fields: %v", err)
+	}
+	if _, err := cache.HGet(ctx, "user:1", "age"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss after HDel, got %v", err)
+	}
+	if err := cache.HDel(ctx, "user:1", "name", "tags"); err != nil {
+		t.Fatalf("Failed to delete fields: %v", err)
+	}
+	if server.Exists("user:1") {
+		t.Error("Expected the hash to be removed with its last field")
+	}
+}
+
+// slowCmdable is a Redis client whose GET never replies before the context is done.
+type slowCmdable struct {
+	redis.Cmdable
+	deadline time.Time
+}
+
+func (c *slowCmdable) Get(ctx context.Context, key string) *redis.StringCmd {
+	cmd := redis.NewStringCmd(ctx, "get", key)
+	c.deadline, _ = ctx.Deadline()
+	<-ctx.Done()
+	cmd.SetErr(ctx.Err())
+	return cmd
+}
+
+// TestCache_DefaultTimeout tests that operations without a deadline are bounded
+func TestCache_DefaultTimeout(t *testing.T) {
+	client := &slowCmdable{}
+	cache := New(client, WithDefaultTimeout(20*time.Millisecond))
+
+	// Test that a context without deadline gets the default timeout
+	start := time.Now()
+	_, err := cache.Get(context.Background(), "key")
+	if !errors.Is(err, gouache.ErrContext) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Get to time out after about 20ms, took %v", elapsed)
+	}
+
+	// Test that a shorter caller deadline is kept
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	want, _ := ctx.Deadline()
+	_, _ = cache.Get(ctx, "key")
+	if !client.deadline.Equal(want) {
+		t.Errorf("Expected the caller deadline %v, got %v", want, client.deadline)
+	}
+
+	// Test that a longer caller deadline is kept too
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	want, _ = ctx.Deadline()
+	_, _ = cache.Get(ctx, "key")
+	if !client.deadline.Equal(want) {
+		t.Errorf("Expected the caller deadline %v, got %v", want, client.deadline)
+	}
+}
+
+// slowMGetCmdable is a Redis client whose MGET replies normally for the first
+// batch and never replies before the context is done for later ones.
+type slowMGetCmdable struct {
+	redis.Cmdable
+	calls int
+}
+
+func (c *slowMGetCmdable) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	c.calls++
+	if c.calls == 1 {
+		return c.Cmdable.MGet(ctx, keys...)
+	}
+	cmd := redis.NewSliceCmd(ctx, "mget")
+	<-ctx.Done()
+	cmd.SetErr(ctx.Err())
+	return cmd
+}
+
+// TestCache_GetMulti tests batched reads and partial results on deadline
+func TestCache_GetMulti(t *testing.T) {
+	cache, _ := newTestCache(t)
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "d"} {
+		if err := cache.Set(ctx, key, key+"-value"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	// Test a complete read across several batches
+	cache.GetMultiBatchSize = 2
+	vals, err := cache.GetMulti(ctx, []string{"a", "b", "c", "d"})
+	if err != nil {
+		t.Fatalf("GetMulti failed: %v", err)
+	}
+	want := map[string]any{"a": "a-value", "b": "b-value", "d": "d-value"}
+	if fmt.Sprint(vals) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, vals)
+	}
+
+	// Test that a deadline hit by the second batch returns the first one
+	cache.Cache = &slowMGetCmdable{Cmdable: cache.Cache}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	vals, err = cache.GetMulti(ctx, []string{"a", "b", "c", "d"})
+	if !errors.Is(err, gouache.ErrPartialResult) || !errors.Is(err, gouache.ErrContext) {
+		t.Errorf("Expected a partial result error, got %v", err)
+	}
+	want = map[string]any{"a": "a-value", "b": "b-value"}
+	if fmt.Sprint(vals) != fmt.Sprint(want) {
+		t.Errorf("Expected partial result %v, got %v", want, vals)
+	}
+}
+
+// TestCache_CompareAndDelete tests that a value changed since it was read is not deleted
+func TestCache_CompareAndDelete(t *testing.T) {
+	cache, server := newTestCache(t)
+	ctx := context.Background()
+	if err := cache.Set(ctx, "key", "old"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Read the value, then let a concurrent writer replace it
+	read, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := cache.Set(ctx, "key", "new"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Invalidating with the value read must keep the newer value
+	deleted, err := cache.CompareAndDelete(ctx, "key", read)
+	if err != nil || deleted {
+		t.Errorf("Expected no deletion, got %v, %v", deleted, err)
+	}
+	if got, _ := server.Get("key"); got != "new" {
+		t.Errorf("Expected the newer value to be kept, got %q", got)
+	}
+
+	// Invalidating with the current value deletes it
+	deleted, err = cache.CompareAndDelete(ctx, "key", "new")
+	if err != nil || !deleted {
+		t.Errorf("Expected the value to be deleted, got %v, %v", deleted, err)
+	}
+	if server.Exists("key") {
+		t.Error("Expected the key to be gone")
+	}
+
+	// A missing key is not deleted
+	if deleted, err := cache.CompareAndDelete(ctx, "key", "new"); err != nil || deleted {
+		t.Errorf("Expected no deletion of a missing key, got %v, %v", deleted, err)
+	}
+}
+
+// TestSafeKeyEncoder tests which keys SafeKeyEncoder rewrites and that encoded keys decode
+func TestSafeKeyEncoder(t *testing.T) {
+	encode := SafeKeyEncoder(32)
+	long := strings.Repeat("k", 33)
+	tests := []struct {
+		key    string
+		prefix string
+	}{
+		{"user:1", ""},
+		{"with space", base64KeyPrefix},
+		{"ctl\x00\n", base64KeyPrefix},
+		{"b64:dXNlcjox", base64KeyPrefix},
+		{long, sha256KeyPrefix},
+	}
+	for _, tt := range tests {
+		stored := encode(tt.key)
+		if tt.prefix == "" && stored != tt.key {
+			t.Errorf("Expected %q stored unchanged, got %q", tt.key, stored)
+		}
+		if tt.prefix != "" && !strings.HasPrefix(stored, tt.prefix) {
+			t.Errorf("Expected %q stored with %q, got %q", tt.key, tt.prefix, stored)
+		}
+		if decoded, ok := DecodeSafeKey(stored); tt.prefix != sha256KeyPrefix && (!ok || decoded != tt.key) {
+			t.Errorf("Expected %q to decode to %q, got %q, %v", stored, tt.key, decoded, ok)
+		}
+	}
+}
+
+// FuzzCache_SafeKeyEncoder tests that arbitrary byte-string keys round-trip
+// through Set and Get, are stored as printable bounded keys, and stay distinct
+func FuzzCache_SafeKeyEncoder(f *testing.F) {
+	server := miniredis.RunT(f)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	f.Cleanup(func() { _ = client.Close() })
+	cache := New(client, WithKeyEncoder(SafeKeyEncoder(64)))
+	ctx := context.Background()
+
+	f.Add("user:1", "other")
+	f.Add("", "b64:")
+	f.Add("\x00\xff\r\n", "sha256:")
+	f.Add(strings.Repeat("x", 65), strings.Repeat("x", 64))
+	f.Fuzz(func(t *testing.T, key, other string) {
+		server.FlushAll()
+
+		if err := cache.Set(ctx, key, key); err != nil {
+			t.Fatalf("Failed to set %q: %v", key, err)
+		}
+		if val, err := cache.Get(ctx, key); err != nil || val != key {
+			t.Fatalf("Expected %q to round-trip, got %q, %v", key, val, err)
+		}
+
+		// The stored key is printable and bounded
+		stored := cache.storedKey(key)
+		if len(stored) > 64 && len(stored) != len(sha256KeyPrefix)+64 {
+			t.Errorf("Stored key %q is too long", stored)
+		}
+		for i := 0; i < len(stored); i++ {
+			if stored[i] <= ' ' || stored[i] > '~' {
+				t.Errorf("Stored key %q is not printable", stored)
+				break
+			}
+		}
+
+		// Another key doesn't see the value, and deleting removes it
+		if other != key {
+			if _, err := cache.Get(ctx, other); !errors.Is(err, gouache.ErrCacheMiss) {
+				t.Errorf("Expected %q and %q to be distinct, got %v", key, other, err)
+			}
+		}
+		if err := cache.Delete(ctx, key); err != nil {
+			t.Fatalf("Failed to delete %q: %v", key, err)
+		}
+		if _, err := cache.Get(ctx, key); !errors.Is(err, gouache.ErrCacheMiss) {
+			t.Errorf("Expected %q to be deleted, got %v", key, err)
+		}
+	})
+}
+
+// TestCache_CacheMissContract tests that redis.Nil is reported as gouache.ErrCacheMiss
+func TestCache_CacheMissContract(t *testing.T) {
+	cache, _ := newTestCache(t)
+	cachetest.AssertCacheMissContract(t, cache)
+}
+
+// TestNew tests creating a cache with options and validating its configuration
+func TestNew(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	cache := New(client,
+		WithMarshal(func(key string, obj any) (string, error) {
+			data, err := json.Marshal(obj)
+			return string(data), err
+		}),
+		WithUnmarshal(func(key string, data string) (any, error) {
+			var obj map[string]string
+			err := json.Unmarshal([]byte(data), &obj)
+			return obj, err
+		}),
+		WithTTL(func(ctx context.Context, key string, val any) (time.Duration, error) { return time.Minute, nil }),
+	)
+	if err := cache.validate(); err != nil {
+		t.Errorf("Expected a consistent configuration, got %v", err)
+	}
+
+	// Values round-trip through the codec and are stored with the TTL
+	ctx := context.Background()
+	if err := cache.Set(ctx, "key", map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if got, err := cache.Get(ctx, "key"); err != nil || got.(map[string]string)["a"] != "b" {
+		t.Errorf("Expected the stored value, got %v, %v", got, err)
+	}
+	if ttl := server.TTL("key"); ttl != time.Minute {
+		t.Errorf("Expected a TTL of a minute, got %v", ttl)
+	}
+
+	// A codec missing one direction is reported, unless a type registry decodes
+	unmarshal := func(key string, data string) (any, error) { return data, nil }
+	if err := New(client, WithUnmarshal(unmarshal)).validate(); err == nil || !strings.Contains(err.Error(), "without Marshal") {
+		t.Errorf("Expected Unmarshal without Marshal to be reported, got %v", err)
+	}
+	marshal := func(key string, obj any) (string, error) { return fmt.Sprint(obj), nil }
+	if err := New(client, WithMarshal(marshal)).validate(); err == nil {
+		t.Error("Expected Marshal without Unmarshal to be reported")
+	}
+	typed := New(client, WithMarshal(marshal))
+	typed.Types = NewTypeRegistry()
+	if err := typed.validate(); err != nil {
+		t.Errorf("Expected a type registry to make Marshal alone consistent, got %v", err)
+	}
+}
+
+// TestCache_Timestamp tests that stamped values report their age with a single GET
+func TestCache_Timestamp(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	clock := clocktest.NewClock(time.Unix(1000, 0))
+	cache := New(client, WithTimestamp(true), WithClock(clock))
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// The age is measured on the clock stamping the value
+	clock.Advance(time.Minute)
+	val, meta, err := cache.GetWithMeta(ctx, "key")
+	if err != nil || val != "value" {
+		t.Fatalf("Expected value, got %v, %v", val, err)
+	}
+	if age := clock.Now().Sub(meta.CachedAt); age != time.Minute {
+		t.Errorf("Expected an age of 1m, got %v", age)
+	}
+
+	// Get strips the stamp, which Redis stores in front of the data
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected value, got %v, %v", val, err)
+	}
+	if raw, _ := server.Get("key"); len(raw) != timestampSize+len("value") {
+		t.Errorf("Expected the stored data to carry the stamp, got %q", raw)
+	}
+
+	// CompareAndDelete ignores the stamp
+	if deleted, err := cache.CompareAndDelete(ctx, "key", "value"); err != nil || !deleted {
+		t.Errorf("Expected the stamped value to be deleted, got %v, %v", deleted, err)
+	}
+
+	// Values stored without a stamp fail to decode
+	_ = server.Set("old", "x")
+	if _, err := cache.Get(ctx, "old"); !errors.Is(err, errMissingTimestamp) {
+		t.Errorf("Expected errMissingTimestamp, got %v", err)
+	}
+
+	// Without stamps there is no age to report
+	if _, _, err := New(client).GetWithMeta(ctx, "key"); !errors.Is(err, gouache.ErrUnsupported) {
+		t.Errorf("Expected gouache.ErrUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RegisterStandardTypes registers codecs for the most frequently cached
+// non-string types, so they round-trip through Redis without a custom
+// Marshal or Unmarshal:
+//
+//   - time.Time, stored in RFC 3339 format with nanoseconds under tag "time"
+//   - time.Duration, stored as its String form under tag "duration"
+//   - bool, stored by strconv under tag "bool"
+//   - int, int8, int16, int32 and int64, stored in decimal under tags "int" to "int64"
+//   - uint, uint8, uint16, uint32 and uint64, stored in decimal under tags "uint" to "uint64"
+//   - float32 and float64, stored in the shortest exact decimal form under tags "float32" and "float64"
+//
+// Get reconstructs the concrete type from the tag, so an int8 is read back as
+// an int8 rather than a string or a float64. A time.Time keeps its instant
+// and offset but not its monotonic clock reading or *time.Location name.
+//
+// Returns:
+//   - The registry, for chaining with NewTypeRegistry
+func (registry *TypeRegistry) RegisterStandardTypes() *TypeRegistry {
+	register := func(tag string, example any, marshal func(obj any) string, unmarshal func(data string) (any, error)) {
+		// The tags and codecs are valid, so Register can't fail
+		_ = registry.Register(tag, example, TypeCodec{
+			Marshal: func(key string, obj any) (string, error) {
+				return marshal(obj), nil
+			},
+			Unmarshal: func(key string, data string) (any, error) {
+				obj, err := unmarshal(data)
+				if err != nil {
+					return nil, fmt.Errorf("gouache: decode %s for key %q: %w", tag, key, err)
+				}
+				return obj, nil
+			},
+		})
+	}
+
+	register("time", time.Time{},
+		func(obj any) string { return obj.(time.Time).Format(time.RFC3339Nano) },
+		func(data string) (any, error) { return time.Parse(time.RFC3339Nano, data) })
+	register("duration", time.Duration(0),
+		func(obj any) string { return obj.(time.Duration).String() },
+		func(data string) (any, error) { return time.ParseDuration(data) })
+	register("bool", false,
+		func(obj any) string { return strconv.FormatBool(obj.(bool)) },
+		func(data string) (any, error) { return strconv.ParseBool(data) })
+
+	// Signed integers
+	register("int", int(0),
+		func(obj any) string { return strconv.FormatInt(int64(obj.(int)), 10) },
+		func(data string) (any, error) { v, err := strconv.ParseInt(data, 10, 0); return int(v), err })
+	register("int8", int8(0),
+		func(obj any) string { return strconv.FormatInt(int64(obj.(int8)), 10) },
+		func(data string) (any, error) { v, err := strconv.ParseInt(data, 10, 8); return int8(v), err })
+	register("int16", int16(0),
+		func(obj any) string { return strconv.FormatInt(int64(obj.(int16)), 10) },
+		func(data string) (any, error) { v, err := strconv.ParseInt(data, 10, 16); return int16(v), err })
+	register("int32", int32(0),
+		func(obj any) string { return strconv.FormatInt(int64(obj.(int32)), 10) },
+		func(data string) (any, error) { v, err := strconv.ParseInt(data, 10, 32); return int32(v), err })
+	register("int64", int64(0),
+		func(obj any) string { return strconv.FormatInt(obj.(int64), 10) },
+		func(data string) (any, error) { return strconv.ParseInt(data, 10, 64) })
+
+	// Unsigned integers
+	register("uint", uint(0),
+		func(obj any) string { return strconv.FormatUint(uint64(obj.(uint)), 10) },
+		func(data string) (any, error) { v, err := strconv.ParseUint(data, 10, 0); return uint(v), err })
+	register("uint8", uint8(0),
+		func(obj any) string { return strconv.FormatUint(uint64(obj.(uint8)), 10) },
+		func(data string) (any, error) { v, err := strconv.ParseUint(data, 10, 8); return uint8(v), err })
+	register("uint16", uint16(0),
+		func(obj any) string { return strconv.FormatUint(uint64(obj.(uint16)), 10) },
+		func(data string) (any, error) { v, err := strconv.ParseUint(data, 10, 16); return uint16(v), err })
+	register("uint32", uint32(0),
+		func(obj any) string { return strconv.FormatUint(uint64(obj.(uint32)), 10) },
+		func(data string) (any, error) { v, err := strconv.ParseUint(data, 10, 32); return uint32(v), err })
+	register("uint64", uint64(0),
+		func(obj any) string { return strconv.FormatUint(obj.(uint64), 10) },
+		func(data string) (any, error) { return strconv.ParseUint(data, 10, 64) })
+
+	// Floating-point numbers
+	register("float32", float32(0),
+		func(obj any) string { return strconv.FormatFloat(float64(obj.(float32)), 'g', -1, 32) },
+		func(data string) (any, error) { v, err := strconv.ParseFloat(data, 32); return float32(v), err })
+	register("float64", float64(0),
+		func(obj any) string { return strconv.FormatFloat(obj.(float64), 'g', -1, 64) },
+		func(data string) (any, error) { return strconv.ParseFloat(data, 64) })
+	return registry
+}
@@ -4,11 +4,16 @@ go 1.20
 
 require github.com/redis/go-redis/v9 v9.14.0
 
-require github.com/soyacen/gouache v0.0.0-00010101000000-000000000000
+require (
+	github.com/alicebob/miniredis/v2 v2.35.0
+	github.com/soyacen/gouache v0.0.0-00010101000000-000000000000
+)
 
 require (
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/sync v0.11.0 // indirect
 )
 
 replace github.com/soyacen/gouache => ../
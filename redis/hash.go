@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the HashField interface at compile time.
+var _ HashField = (*Cache)(nil)
+
+// HashField is implemented by caches that can store the fields of a small
+// object separately under one key, as a Redis hash, so that a single field
+// can be read or updated without serializing the whole object.
+type HashField interface {
+	// HGet retrieves the value of a field of the hash stored under key.
+	// It returns gouache.ErrCacheMiss if the key or the field does not exist.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key of the hash
+	//   - field: The field to retrieve the value for
+	//
+	// Returns:
+	//   - The field value or nil if not found
+	//   - An error if the operation fails, or gouache.ErrCacheMiss if the field doesn't exist
+	HGet(ctx context.Context, key, field string) (any, error)
+
+	// HSet stores a value in a field of the hash stored under key, creating
+	// the hash if needed.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key of the hash
+	//   - field: The field under which the value will be stored
+	//   - val: The value to store
+	//
+	// Returns:
+	//   - An error if the operation fails
+	HSet(ctx context.Context, key, field string, val any) error
+
+	// HDel removes fields from the hash stored under key. Fields that do not
+	// exist are ignored.
+	//
+	// Parameters:
+	//   - ctx: Context for the operation
+	//   - key: The key of the hash
+	//   - fields: The fields to remove
+	//
+	// Returns:
+	//   - An error if the operation fails
+	HDel(ctx context.Context, key string, fields ...string) error
+}
+
+// HGet retrieves the value of a field of the hash stored under key using
+// HGET. The value is decoded like a value read by Get, with the hash key
+// passed to Unmarshal.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the hash
+//   - field: The field to retrieve the value for
+//
+// Returns:
+//   - The field value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if the key or field doesn't exist
+func (cache *Cache) HGet(ctx context.Context, key, field string) (any, error) {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Attempt to get the field value from Redis
+	data, err := cache.Cache.HGet(ctx, cache.storedKey(key), field).Result()
+
+	// HGET replies nil for both missing keys and missing fields
+	if errors.Is(err, redis.Nil) {
+		return nil, gouache.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return cache.unmarshal(key, data)
+}
+
+// HSet stores a value in a field of the hash stored under key using HSET.
+// The value is encoded like a value stored by Set, with the hash key passed
+// to Marshal.
+//
+// The TTL function is not applied, since Redis expires whole hashes rather
+// than single fields; use Touch to set the TTL of the hash.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the hash
+//   - field: The field under which the value will be stored
+//   - val: The value to store, either as string or any other type requiring marshaling
+//
+// Returns:
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-string values
+func (cache *Cache) HSet(ctx context.Context, key, field string, val any) error {
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Convert the value into the string stored in Redis
+	data, err := cache.encode(key, val)
+	if err != nil {
+		return err
+	}
+
+	// Store the field in the hash
+	if err := cache.Cache.HSet(ctx, cache.storedKey(key), field, data).Err(); err != nil {
+		return fmt.Errorf("%w: %w", gouache.ErrStorage, err)
+	}
+	return nil
+}
+
+// HDel removes fields from the hash stored under key using HDEL. Redis
+// deletes the hash once its last field is removed.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the hash
+//   - fields: The fields to remove
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *Cache) HDel(ctx context.Context, key string, fields ...string) error {
+	// HDEL requires at least one field
+	if len(fields) == 0 {
+		return nil
+	}
+
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+	return cache.Cache.HDel(ctx, cache.storedKey(key), fields...).Err()
+}
@@ -0,0 +1,374 @@
+// Package invalidate provides an application-level cache invalidation bus on
+// top of Redis pub/sub.
+//
+// An instance that changes or deletes a key publishes the key on a channel
+// with Publisher.PublishInvalidation, and every instance running a Subscriber
+// on that channel deletes the key from its local (L1) cache. Unlike Redis
+// keyspace notifications, this needs no server-side configuration and works
+// for keys that don't live in Redis at all.
+//
+// Pub/sub delivery is at most once: messages published while a subscriber is
+// disconnected are lost. The Subscriber pings the server when the channel has
+// been quiet for a health check interval, reconnects when the connection is
+// broken or the ping goes unanswered, and calls the OnResubscribe hook after
+// every reconnection, where the local cache can be cleared or its entries
+// otherwise treated as suspect.
+package invalidate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/soyacen/gouache"
+)
+
+// Publisher publishes invalidation messages on a Redis channel.
+type Publisher struct {
+	// Client is the Redis client used to publish.
+	Client goredis.Cmdable
+
+	// Channel is the channel invalidations are published on.
+	Channel string
+}
+
+// NewPublisher creates a new Publisher publishing on channel.
+//
+// Parameters:
+//   - client: The Redis client used to publish
+//   - channel: The channel invalidations are published on
+//
+// Returns:
+//   - A Publisher
+func NewPublisher(client goredis.Cmdable, channel string) *Publisher {
+	return &Publisher{Client: client, Channel: channel}
+}
+
+// PublishInvalidation tells every subscriber of the channel to delete key
+// from its local cache. Call it after the change to the shared source of
+// truth, so that subscribers reloading the key see the new value.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key to invalidate
+//
+// Returns:
+//   - An error if publishing fails
+func (publisher *Publisher) PublishInvalidation(ctx context.Context, key string) error {
+	return publisher.Client.Publish(ctx, publisher.Channel, key).Err()
+}
+
+// SubscribeClient is the part of a Redis client a Subscriber uses. It is
+// implemented by *redis.Client, *redis.ClusterClient and redis.UniversalClient.
+type SubscribeClient interface {
+	// Subscribe subscribes the client to the given channels.
+	Subscribe(ctx context.Context, channels ...string) *goredis.PubSub
+}
+
+// options holds configuration options for the subscriber.
+type options struct {
+	// ErrorHandler is called when receiving a message or evicting a key fails.
+	ErrorHandler gouache.ErrorHandler
+
+	// OnResubscribe is called after the subscription is restored following a
+	// disconnection.
+	OnResubscribe func()
+
+	// RetryInterval is how long the subscriber waits after a receive error.
+	RetryInterval time.Duration
+
+	// HealthCheckInterval is how long the channel may be quiet before the
+	// subscriber pings the server, and how long it waits for the reply.
+	HealthCheckInterval time.Duration
+
+	// Clock is used to wait for the retry interval.
+	Clock gouache.Clock
+}
+
+// Option is a function that modifies the subscriber options.
+type Option func(*options)
+
+// WithErrorHandler returns an Option that sets a custom error handler for
+// errors that occur while receiving messages or evicting keys.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f gouache.ErrorHandler) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// WithOnResubscribe returns an Option that sets a function called whenever the
+// subscription is restored after a disconnection. Invalidations published
+// while disconnected are lost, so this is the place to clear the local cache.
+//
+// Parameters:
+//   - f: The function to call after resubscribing
+//
+// Returns:
+//   - An Option function that sets OnResubscribe
+func WithOnResubscribe(f func()) Option {
+	return func(o *options) {
+		o.OnResubscribe = f
+	}
+}
+
+// WithRetryInterval returns an Option that sets how long the subscriber waits
+// before receiving again after an error, such as a lost connection.
+//
+// Parameters:
+//   - d: The retry interval
+//
+// Returns:
+//   - An Option function that sets the RetryInterval
+func WithRetryInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.RetryInterval = d
+	}
+}
+
+// WithHealthCheckInterval returns an Option that sets how long the channel
+// may be quiet before the subscriber pings the server. A ping that isn't
+// answered within another interval makes the subscriber reconnect, which
+// detects connections that died without being closed.
+//
+// Parameters:
+//   - d: The health check interval
+//
+// Returns:
+//   - An Option function that sets the HealthCheckInterval
+func WithHealthCheckInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.HealthCheckInterval = d
+	}
+}
+
+// WithClock returns an Option that sets the clock used to wait between retries.
+// It defaults to gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets the Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = gouache.DefaultErrorHandler("invalidate.Subscriber")
+	}
+
+	// Set default retry interval to 1s if not specified or invalid
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = time.Second
+	}
+
+	// Set default health check interval to 3s if not specified or invalid
+	if o.HealthCheckInterval <= 0 {
+		o.HealthCheckInterval = 3 * time.Second
+	}
+
+	// Use the real clock by default
+	if o.Clock == nil {
+		o.Clock = gouache.RealClock
+	}
+	return o
+}
+
+// Subscriber deletes the keys published on a channel from a local cache.
+type Subscriber struct {
+	// Options contains configuration options for the subscriber
+	Options *options
+
+	// Cache is the local cache keys are deleted from
+	Cache gouache.Cache
+
+	// client is the Redis client used to subscribe.
+	client SubscribeClient
+
+	// channel is the channel invalidations are published on.
+	channel string
+
+	// mu guards pubsub.
+	mu sync.Mutex
+
+	// pubsub is the current subscription.
+	pubsub *goredis.PubSub
+
+	// ctx is canceled by Close.
+	ctx context.Context
+
+	// cancel cancels ctx.
+	cancel context.CancelFunc
+
+	// done is closed when the receive loop has exited.
+	done chan struct{}
+
+	// closeOnce guards Close.
+	closeOnce sync.Once
+}
+
+// Subscribe subscribes to channel and starts deleting every key published on
+// it from cache, until Close is called.
+//
+// Parameters:
+//   - client: The Redis client used to subscribe
+//   - channel: The channel invalidations are published on
+//   - cache: The local cache to delete invalidated keys from
+//   - opts: Variable number of Option functions to configure the subscriber
+//
+// Returns:
+//   - A running Subscriber; Close it to unsubscribe
+func Subscribe(client SubscribeClient, channel string, cache gouache.Cache, opts ...Option) *Subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+	subscriber := &Subscriber{
+		Options: newOptions(opts...),
+		Cache:   cache,
+		client:  client,
+		channel: channel,
+		pubsub:  client.Subscribe(ctx, channel),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go subscriber.loop()
+	return subscriber
+}
+
+// Close unsubscribes and waits for the receive loop to exit.
+//
+// Returns:
+//   - An error if closing the subscription fails
+func (subscriber *Subscriber) Close() error {
+	var err error
+	subscriber.closeOnce.Do(func() {
+		subscriber.cancel()
+		subscriber.mu.Lock()
+		err = subscriber.pubsub.Close()
+		subscriber.mu.Unlock()
+		<-subscriber.done
+	})
+	return err
+}
+
+// loop receives messages and evicts their keys until the subscriber is
+// closed. The PubSub reconnects and resubscribes on its own after a broken
+// connection; a connection that silently stops delivering is detected by an
+// unanswered ping and replaced. Every subscription confirmation after the
+// first one means the subscription was restored.
+func (subscriber *Subscriber) loop() {
+	defer close(subscriber.done)
+	ctx := subscriber.ctx
+	subscribed, pinged := false, false
+	for {
+		subscriber.mu.Lock()
+		pubsub := subscriber.pubsub
+		subscriber.mu.Unlock()
+
+		msg, err := pubsub.ReceiveTimeout(ctx, subscriber.Options.HealthCheckInterval)
+		if err != nil {
+			// Stop once closed
+			if ctx.Err() != nil || errors.Is(err, goredis.ErrClosed) {
+				return
+			}
+
+			// Ping a quiet channel, and replace the connection if the ping went unanswered
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if !pinged {
+					pinged = true
+					if err := pubsub.Ping(ctx); err == nil {
+						continue
+					}
+				}
+				subscriber.Options.ErrorHandler(fmt.Errorf("invalidate: connection unresponsive: %w", err))
+				subscriber.resubscribe()
+				pinged = false
+				continue
+			}
+
+			// Report the error and retry after a pause
+			subscriber.Options.ErrorHandler(err)
+			select {
+			case <-subscriber.Options.Clock.After(subscriber.Options.RetryInterval):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		pinged = false
+
+		switch msg := msg.(type) {
+		case *goredis.Subscription:
+			// A confirmation after the first one follows a reconnection
+			if msg.Kind != "subscribe" {
+				continue
+			}
+			if subscribed && subscriber.Options.OnResubscribe != nil {
+				subscriber.Options.OnResubscribe()
+			}
+			subscribed = true
+		case *goredis.Message:
+			// Evict the published key
+			if err := subscriber.Cache.Delete(ctx, msg.Payload); err != nil {
+				subscriber.Options.ErrorHandler(err)
+			}
+		}
+	}
+}
+
+// resubscribe replaces the subscription with a new one on a new connection,
+// unless the subscriber is closed.
+func (subscriber *Subscriber) resubscribe() {
+	subscriber.mu.Lock()
+	defer subscriber.mu.Unlock()
+	if subscriber.ctx.Err() != nil {
+		return
+	}
+	_ = subscriber.pubsub.Close()
+	subscriber.pubsub = subscriber.client.Subscribe(subscriber.ctx, subscriber.channel)
+}
@@ -0,0 +1,128 @@
+package invalidate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// newTestClient creates a Redis client backed by an in-process miniredis server.
+func newTestClient(t *testing.T) (*goredis.Client, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return client, server
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitSubscribed waits until the server reports n subscribers of channel.
+func waitSubscribed(t *testing.T, server *miniredis.Miniredis, channel string, n int) {
+	waitFor(t, "the subscription", func() bool {
+		return server.PubSubNumSub(channel)[channel] == n
+	})
+}
+
+// TestSubscriber_Invalidate tests that published keys are evicted from every subscriber's cache
+func TestSubscriber_Invalidate(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	// Two instances, each with its own local cache
+	caches := []*sample.Cache{{}, {}}
+	for _, cache := range caches {
+		_ = cache.Set(ctx, "user:1", "alice")
+		_ = cache.Set(ctx, "user:2", "bob")
+		subscriber := Subscribe(client, "invalidations", cache)
+		t.Cleanup(func() { _ = subscriber.Close() })
+	}
+	waitSubscribed(t, server, "invalidations", len(caches))
+
+	// Publish an invalidation
+	if err := NewPublisher(client, "invalidations").PublishInvalidation(ctx, "user:1"); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	for i, cache := range caches {
+		waitFor(t, "the eviction", func() bool {
+			_, err := cache.Get(ctx, "user:1")
+			return errors.Is(err, gouache.ErrCacheMiss)
+		})
+		if val, err := cache.Get(ctx, "user:2"); err != nil || val != "bob" {
+			t.Errorf("Expected cache %d to keep user:2, but got %v, %v", i, val, err)
+		}
+	}
+}
+
+// TestSubscriber_Reconnect tests that the subscriber recovers from a server restart
+func TestSubscriber_Reconnect(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	cache := &sample.Cache{}
+	resubscribed := make(chan struct{}, 1)
+	subscriber := Subscribe(client, "invalidations", cache,
+		WithRetryInterval(10*time.Millisecond),
+		WithHealthCheckInterval(50*time.Millisecond),
+		WithErrorHandler(func(err error) {}),
+		WithOnResubscribe(func() {
+			select {
+			case resubscribed <- struct{}{}:
+			default:
+			}
+		}),
+	)
+	defer subscriber.Close()
+	waitSubscribed(t, server, "invalidations", 1)
+
+	// Drop every connection
+	server.Close()
+	if err := server.Restart(); err != nil {
+		t.Fatalf("Failed to restart the server: %v", err)
+	}
+	select {
+	case <-resubscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected OnResubscribe to be called")
+	}
+	waitSubscribed(t, server, "invalidations", 1)
+
+	// Invalidations are delivered again
+	_ = cache.Set(ctx, "key", "value")
+	if err := NewPublisher(client, "invalidations").PublishInvalidation(ctx, "key"); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	waitFor(t, "the eviction", func() bool {
+		_, err := cache.Get(ctx, "key")
+		return errors.Is(err, gouache.ErrCacheMiss)
+	})
+}
+
+// TestSubscriber_Close tests that Close stops the subscriber
+func TestSubscriber_Close(t *testing.T) {
+	client, server := newTestClient(t)
+	subscriber := Subscribe(client, "invalidations", &sample.Cache{})
+	waitSubscribed(t, server, "invalidations", 1)
+
+	if err := subscriber.Close(); err != nil {
+		t.Errorf("Failed to close: %v", err)
+	}
+	if err := subscriber.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, but got: %v", err)
+	}
+	waitSubscribed(t, server, "invalidations", 0)
+}
@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefixes marking keys rewritten by SafeKeyEncoder.
+const (
+	base64KeyPrefix = "b64:"
+	sha256KeyPrefix = "sha256:"
+)
+
+// WithKeyEncoder returns an Option that sets the function mapping each key to
+// the key stored in Redis. See the KeyEncoder field for which operations
+// apply it.
+//
+// Parameters:
+//   - f: The function returning the stored key of a key
+//
+// Returns:
+//   - An Option function that sets KeyEncoder
+func WithKeyEncoder(f func(key string) string) Option {
+	return func(cache *Cache) {
+		cache.KeyEncoder = f
+	}
+}
+
+// WithKeyDecoder returns an Option that sets the function reversing
+// KeyEncoder, which Iterate applies to the keys it finds. See the KeyDecoder
+// field.
+//
+// Parameters:
+//   - f: The function returning the key a stored key was encoded from, and
+//     false if it can't be reversed
+//
+// Returns:
+//   - An Option function that sets KeyDecoder
+func WithKeyDecoder(f func(stored string) (string, bool)) Option {
+	return func(cache *Cache) {
+		cache.KeyDecoder = f
+	}
+}
+
+// SafeKeyEncoder returns a key encoder for WithKeyEncoder that keeps stored
+// keys printable and bounded in length, for caches whose keys come from
+// untrusted or binary input.
+//
+// Keys of printable ASCII of at most maxLen bytes are stored unchanged, so
+// enabling the encoder on an existing keyspace only moves unusual keys. Keys
+// containing spaces, control characters or non-ASCII bytes, and those that
+// already start with one of the markers, are stored as "b64:" followed by
+// their unpadded base64url encoding, which DecodeSafeKey reverses, so the
+// encoder is usually paired with WithKeyDecoder(DecodeSafeKey). Keys whose
+// stored form would be longer than maxLen are stored as "sha256:" followed by
+// the hex SHA-256 of the key instead, 71 bytes in all. A non-positive maxLen
+// disables hashing.
+//
+// Parameters:
+//   - maxLen: The longest stored key kept without hashing
+//
+// Returns:
+//   - A function returning the stored key of a key
+func SafeKeyEncoder(maxLen int) func(key string) string {
+	return func(key string) string {
+		// Keep plain keys, unless they could be mistaken for encoded ones
+		stored := key
+		if !isPlainKey(key) {
+			stored = base64KeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(key))
+		}
+
+		// Hash keys that end up too long, whatever their content
+		if maxLen > 0 && len(stored) > maxLen {
+			sum := sha256.Sum256([]byte(key))
+			return sha256KeyPrefix + hex.EncodeToString(sum[:])
+		}
+		return stored
+	}
+}
+
+// DecodeSafeKey returns the key a key stored by SafeKeyEncoder was encoded
+// from. Passed to WithKeyDecoder, it maps the keys seen by Iterate back to
+// the keys used with Get. Hashed keys can't be reversed.
+//
+// Parameters:
+//   - stored: The key as stored in Redis
+//
+// Returns:
+//   - The original key
+//   - false if stored is a hashed key or not a valid encoding
+func DecodeSafeKey(stored string) (string, bool) {
+	switch {
+	case strings.HasPrefix(stored, sha256KeyPrefix):
+		return "", false
+	case strings.HasPrefix(stored, base64KeyPrefix):
+		key, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(stored, base64KeyPrefix))
+		if err != nil {
+			return "", false
+		}
+		return string(key), true
+	default:
+		return stored, true
+	}
+}
+
+// isPlainKey reports whether key is made of printable ASCII other than space
+// and doesn't start with one of the markers of encoded keys.
+func isPlainKey(key string) bool {
+	if strings.HasPrefix(key, base64KeyPrefix) || strings.HasPrefix(key, sha256KeyPrefix) {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// StoredKey returns the key stored in Redis for key, encoded with KeyEncoder
+// if it is set, for packages that issue their own commands on the client,
+// such as lock.
+//
+// Parameters:
+//   - key: The key used by the caller
+//
+// Returns:
+//   - The key stored in Redis
+func (cache *Cache) StoredKey(key string) string {
+	return cache.storedKey(key)
+}
+
+// storedKey returns the key stored in Redis for key.
+//
+// Parameters:
+//   - key: The key used by the caller
+//
+// Returns:
+//   - key encoded with KeyEncoder, or key itself if KeyEncoder is nil
+func (cache *Cache) storedKey(key string) string {
+	if cache.KeyEncoder == nil {
+		return key
+	}
+	return cache.KeyEncoder(key)
+}
+
+// storedKeys returns the keys stored in Redis for keys, in the same order.
+//
+// Parameters:
+//   - keys: The keys used by the caller
+//
+// Returns:
+//   - The encoded keys, or keys itself if KeyEncoder is nil
+func (cache *Cache) storedKeys(keys []string) []string {
+	if cache.KeyEncoder == nil {
+		return keys
+	}
+	stored := make([]string, len(keys))
+	for i, key := range keys {
+		stored[i] = cache.KeyEncoder(key)
+	}
+	return stored
+}
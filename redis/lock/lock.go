@@ -0,0 +1,179 @@
+// Package lock provides a best-effort distributed lock built on the redis
+// cache implementation.
+//
+// A lock is acquired with SET NX, the same primitive used by redis.Cache.Add,
+// storing a random token so that only the holder can release it. Release is
+// performed atomically by a Lua script that deletes the key only if it still
+// holds the holder's token.
+//
+// This is a single-instance lock, not Redlock: it offers no guarantees across
+// Redis failover and a holder that outlives the TTL silently loses the lock.
+// It is intended for cache-stampede prevention and for keeping concurrent
+// writers of the same key apart, not for protecting critical correctness.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/redis"
+)
+
+// ErrInvalidTTL is returned by TryLock when the TTL is not positive, since a
+// lock without expiry would be held forever by a holder that crashes.
+var ErrInvalidTTL = errors.New("lock: ttl must be positive")
+
+// releaseScript deletes the lock key only if it still holds the caller's token.
+var releaseScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// options holds configuration options for the locker.
+type options struct {
+	// ErrorHandler is called when releasing a lock fails.
+	ErrorHandler gouache.ErrorHandler
+}
+
+// Option is a function that modifies the locker options.
+type Option func(*options)
+
+// WithErrorHandler returns an Option that sets a custom error handler for
+// errors that occur while releasing a lock.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f gouache.ErrorHandler) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = gouache.DefaultErrorHandler("lock.Locker.release")
+	}
+	return o
+}
+
+// Locker acquires best-effort locks stored in Redis.
+type Locker struct {
+	// Options contains configuration options for the locker
+	Options *options
+
+	// Cache is the redis cache whose client stores the locks
+	Cache *redis.Cache
+}
+
+// New creates a new Locker storing its locks through the given redis cache.
+//
+// Parameters:
+//   - cache: The redis cache whose client stores the locks
+//   - opts: Variable number of Option functions to configure the locker
+//
+// Returns:
+//   - A Locker
+func New(cache *redis.Cache, opts ...Option) *Locker {
+	return &Locker{Options: newOptions(opts...), Cache: cache}
+}
+
+// TryLock attempts to acquire the lock for key without waiting.
+//
+// The lock expires after ttl even if it is never released. The lock key is
+// encoded with the cache's KeyEncoder, like the keys of Add, but the cache's
+// TTL function, Marshal and TTL multiplier do not apply to locks.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key of the lock
+//   - ttl: How long the lock is held before it expires on its own
+//
+// Returns:
+//   - A function that releases the lock; it is a no-op if the lock was not acquired
+//   - true if the lock was acquired, false if it is held by someone else
+//   - ErrInvalidTTL if ttl is not positive, or an error if the operation fails
+func (locker *Locker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	noop := func() {}
+
+	// Refuse locks that would never expire
+	if ttl <= 0 {
+		return noop, false, ErrInvalidTTL
+	}
+
+	// Generate a token identifying this holder
+	token, err := newToken()
+	if err != nil {
+		return noop, false, err
+	}
+
+	// Acquire the lock only if nobody holds it
+	storedKey := locker.Cache.StoredKey(key)
+	ok, err := locker.Cache.Cache.SetNX(ctx, storedKey, token, ttl).Result()
+	if err != nil || !ok {
+		return noop, false, err
+	}
+
+	// Release with a context that survives cancellation of the caller's context
+	release := func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := releaseScript.Run(ctx, locker.Cache.Cache, []string{storedKey}, token).Err(); err != nil {
+			locker.Options.ErrorHandler(err)
+		}
+	}
+	return release, true, nil
+}
+
+// newToken generates a random token identifying a lock holder.
+//
+// Returns:
+//   - A random hex-encoded token
+//   - An error if the random source fails
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
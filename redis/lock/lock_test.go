@@ -0,0 +1,127 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/soyacen/gouache/redis"
+)
+
+// newTestLocker creates a Locker backed by an in-process miniredis server.
+func newTestLocker(t *testing.T) (*Locker, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return New(&redis.Cache{Cache: client}), server
+}
+
+// TestLocker_TryLock tests acquiring, contending for and releasing a lock
+func TestLocker_TryLock(t *testing.T) {
+	locker, _ := newTestLocker(t)
+	ctx := context.Background()
+
+	// Test acquire
+	release, ok, err := locker.TryLock(ctx, "lock-key", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	if !ok {
+		t.Fatal("Expected lock to be acquired")
+	}
+
+	// Test contend
+	_, ok, err = locker.TryLock(ctx, "lock-key", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to try lock: %v", err)
+	}
+	if ok {
+		t.Error("Expected contended lock not to be acquired")
+	}
+
+	// Test release allows the lock to be acquired again
+	release()
+	release, ok, err = locker.TryLock(ctx, "lock-key", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	if !ok {
+		t.Error("Expected lock to be acquired after release")
+	}
+	release()
+}
+
+// TestLocker_StaleRelease tests that an expired holder cannot release a newer holder's lock
+func TestLocker_StaleRelease(t *testing.T) {
+	locker, server := newTestLocker(t)
+	ctx := context.Background()
+
+	// Acquire and let the lock expire
+	staleRelease, ok, err := locker.TryLock(ctx, "lock-key", time.Second)
+	if err != nil || !ok {
+		t.Fatalf("Failed to acquire lock: ok=%v err=%v", ok, err)
+	}
+	server.FastForward(2 * time.Second)
+
+	// A new holder acquires the lock
+	_, ok, err = locker.TryLock(ctx, "lock-key", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("Failed to acquire expired lock: ok=%v err=%v", ok, err)
+	}
+
+	// The stale holder's release must not delete the new holder's lock
+	staleRelease()
+	if !server.Exists("lock-key") {
+		t.Error("Expected lock to survive a stale release")
+	}
+}
+
+// TestLocker_InvalidTTL tests that locks without expiry are refused
+func TestLocker_InvalidTTL(t *testing.T) {
+	locker, server := newTestLocker(t)
+	ctx := context.Background()
+
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		release, ok, err := locker.TryLock(ctx, "lock-key", ttl)
+		if !errors.Is(err, ErrInvalidTTL) {
+			t.Errorf("TryLock with ttl %v: expected ErrInvalidTTL, got %v", ttl, err)
+		}
+		if ok {
+			t.Errorf("TryLock with ttl %v: expected the lock not to be acquired", ttl)
+		}
+		release()
+	}
+	if server.Exists("lock-key") {
+		t.Error("Expected no lock key to be stored")
+	}
+}
+
+// TestLocker_KeyEncoder tests that lock keys are encoded with the cache's KeyEncoder
+func TestLocker_KeyEncoder(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	cache := &redis.Cache{Cache: client, KeyEncoder: func(key string) string { return "app:" + key }}
+	locker := New(cache)
+	ctx := context.Background()
+
+	release, ok, err := locker.TryLock(ctx, "lock-key", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("Failed to acquire lock: ok=%v err=%v", ok, err)
+	}
+	if !server.Exists("app:lock-key") {
+		t.Error("Expected the lock to be stored under the encoded key")
+	}
+	if server.Exists("lock-key") {
+		t.Error("Expected no lock under the raw key")
+	}
+
+	// Release deletes the encoded key
+	release()
+	if server.Exists("app:lock-key") {
+		t.Error("Expected release to delete the encoded key")
+	}
+}
@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"github.com/redis/go-redis/v9"
+	"github.com/soyacen/gouache"
+	sharded "github.com/soyacen/gouache/sharded"
+)
+
+// Buckets builds one Cache per client, each a copy of template with its Cache
+// field set to the client. The Marshal, Unmarshal, TTL, Types, ScanCount and
+// DefaultTimeout configuration of template is shared by every bucket; its
+// Cache field is ignored.
+//
+// Parameters:
+//   - clients: The Redis clients, one per bucket
+//   - template: The configuration shared by every bucket
+//
+// Returns:
+//   - The buckets, in the order of clients, ready to be passed to sharded.New
+func Buckets(clients []redis.Cmdable, template Cache) []gouache.Cache {
+	buckets := make([]gouache.Cache, 0, len(clients))
+	for _, client := range clients {
+		bucket := template
+		bucket.Cache = client
+		buckets = append(buckets, &bucket)
+	}
+	return buckets
+}
+
+// NewSharded creates one Redis client per element of options and returns a
+// sharded cache distributing keys across them, each bucket configured like
+// template as described in Buckets.
+//
+// The order of options determines the bucket of every key, so it must be the
+// same in every process sharing the data. The returned clients are owned by
+// the caller, who must close them when the cache is no longer used.
+//
+// Parameters:
+//   - options: The options of the Redis clients, one per bucket
+//   - template: The configuration shared by every bucket
+//   - opts: Variable number of sharded Option functions to configure the sharded cache
+//
+// Returns:
+//   - A gouache.Cache distributing keys across the Redis instances
+//   - The created clients, in the order of options
+//
+// Panics:
+//   - If options is empty
+func NewSharded(options []*redis.Options, template Cache, opts ...sharded.Option) (gouache.Cache, []*redis.Client) {
+	clients := make([]*redis.Client, 0, len(options))
+	cmdables := make([]redis.Cmdable, 0, len(options))
+	for _, o := range options {
+		client := redis.NewClient(o)
+		clients = append(clients, client)
+		cmdables = append(cmdables, client)
+	}
+	return sharded.New(Buckets(cmdables, template), opts...), clients
+}
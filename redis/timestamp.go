@@ -0,0 +1,156 @@
+package redis
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/meta"
+)
+
+// timestampSize is the size of the set time prepended to stamped values.
+const timestampSize = 8
+
+// errMissingTimestamp is returned, and handed to UnmarshalFallback, when
+// Timestamp is enabled and a stored value is too short to carry a set time.
+var errMissingTimestamp = errors.New("redis: value has no timestamp")
+
+// compareAndDeleteStampedScript deletes a key only if the data after its set
+// time is the expected data.
+var compareAndDeleteStampedScript = redis.NewScript(`
+local data = redis.call("GET", KEYS[1])
+if data and string.sub(data, 9) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// WithTimestamp returns an Option that makes every stored value carry the
+// time it was set, so that GetWithMeta reports the age of a value read with
+// a single GET, as needed for stale-while-revalidate and refresh-ahead,
+// instead of an extra TTL call.
+//
+// The set time is prepended to the stored data as 8 bytes holding the Unix
+// time in nanoseconds, big-endian, and stripped on every read. Values stored
+// without it can't be read while the option is enabled, and vice versa, so
+// enable it on an empty key space or together with UnmarshalFallback. Other
+// clients reading the keys directly see the prefix too.
+//
+// Parameters:
+//   - enabled: Whether stored values carry their set time
+//
+// Returns:
+//   - An Option function that sets Timestamp
+func WithTimestamp(enabled bool) Option {
+	return func(cache *Cache) {
+		cache.Timestamp = enabled
+	}
+}
+
+// WithClock returns an Option that sets the clock providing the set time of
+// stamped values. See the Clock field.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(cache *Cache) {
+		cache.Clock = clock
+	}
+}
+
+// clock returns the configured Clock, or gouache.RealClock if none is set.
+//
+// Returns:
+//   - The clock providing set times
+func (cache *Cache) clock() gouache.Clock {
+	if cache.Clock == nil {
+		return gouache.RealClock
+	}
+	return cache.Clock
+}
+
+// GetWithMeta retrieves a value and the time it was set from Redis with a
+// single GET. The age of the value is the time elapsed since meta.CachedAt
+// on the Clock.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - The metadata of the value, with CachedAt set to the time it was set
+//   - An error wrapping gouache.ErrUnsupported if Timestamp is disabled, or an
+//     error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) GetWithMeta(ctx context.Context, key string) (any, meta.Meta, error) {
+	// Without stamps there is no set time to report
+	if !cache.Timestamp {
+		return nil, meta.Meta{}, fmt.Errorf("%w: GetWithMeta requires Timestamp", gouache.ErrUnsupported)
+	}
+
+	// Bound the operation with the default timeout if ctx has no deadline
+	ctx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	// Attempt to get the value from Redis
+	data, err := cache.Cache.Get(ctx, cache.storedKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, meta.Meta{}, gouache.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, meta.Meta{}, contextError(ctx, err)
+	}
+
+	val, setAt, err := cache.unmarshalStamped(key, data)
+	if err != nil {
+		return nil, meta.Meta{}, err
+	}
+	return val, meta.Meta{CachedAt: setAt}, nil
+}
+
+// encode converts a value into the string stored in Redis, prepending the
+// current time if Timestamp is enabled.
+//
+// Parameters:
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as string or any other type requiring marshaling
+//
+// Returns:
+//   - The string to store
+//   - An error wrapping gouache.ErrMarshal if Marshal fails, or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-string values
+func (cache *Cache) encode(key string, val any) (string, error) {
+	data, err := cache.marshal(key, val)
+	if err != nil || !cache.Timestamp {
+		return data, err
+	}
+	var stamp [timestampSize]byte
+	binary.BigEndian.PutUint64(stamp[:], uint64(cache.clock().Now().UnixNano()))
+	return string(stamp[:]) + data, nil
+}
+
+// unstamp splits the set time off data read from Redis if Timestamp is enabled.
+//
+// Parameters:
+//   - data: The data read from Redis
+//
+// Returns:
+//   - The data without the set time
+//   - The time the value was set, or the zero time if Timestamp is disabled
+//   - errMissingTimestamp if the data is too short to carry a set time
+func (cache *Cache) unstamp(data string) (string, time.Time, error) {
+	if !cache.Timestamp {
+		return data, time.Time{}, nil
+	}
+	if len(data) < timestampSize {
+		return "", time.Time{}, errMissingTimestamp
+	}
+	nanos := binary.BigEndian.Uint64([]byte(data[:timestampSize]))
+	return data[timestampSize:], time.Unix(0, int64(nanos)), nil
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// typeTagMarker delimits the type tag stored in front of a tagged value.
+// A tagged value is stored as marker + tag + marker + data.
+const typeTagMarker = "\x1f"
+
+// TypeCodec describes how values of one registered type are converted to and
+// from the strings stored in Redis.
+type TypeCodec struct {
+	// Marshal is an optional function to serialize values of the type.
+	// If not provided, the Cache's Marshal function is used.
+	Marshal func(key string, obj any) (string, error)
+
+	// Unmarshal deserializes data stored for values of the type.
+	Unmarshal func(key string, data string) (any, error)
+}
+
+// TypeRegistry maps Go types to short type tags and their codecs.
+//
+// When a Cache has a TypeRegistry, values of registered types are stored
+// prefixed with their tag, and Get uses the tag to pick the matching
+// Unmarshal. This lets a single Cache hold values of different types and read
+// each back as its original type. Strings and unregistered types are stored
+// untagged, exactly as without a registry.
+//
+// Untagged strings that happen to begin with the 0x1f unit separator byte are
+// ambiguous with tagged values and should not be stored through a Cache with
+// a TypeRegistry.
+type TypeRegistry struct {
+	// mu guards tags and codecs.
+	mu sync.RWMutex
+
+	// tags maps registered Go types to their type tags.
+	tags map[reflect.Type]string
+
+	// codecs maps type tags to their codecs.
+	codecs map[string]TypeCodec
+}
+
+// NewTypeRegistry creates an empty TypeRegistry.
+//
+// Returns:
+//   - A new TypeRegistry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		tags:   make(map[reflect.Type]string),
+		codecs: make(map[string]TypeCodec),
+	}
+}
+
+// Register associates the dynamic type of example with tag and codec.
+// Registering a tag or type again replaces the previous registration.
+//
+// Parameters:
+//   - tag: A short tag identifying the type; it must not contain the 0x1f byte
+//   - example: A value of the type to register, such as (*User)(nil)
+//   - codec: The codec used for values of the type
+//
+// Returns:
+//   - An error if the tag is empty or contains the 0x1f byte, or codec.Unmarshal is nil
+func (registry *TypeRegistry) Register(tag string, example any, codec TypeCodec) error {
+	if tag == "" || strings.Contains(tag, typeTagMarker) {
+		return errors.New("gouache: invalid type tag")
+	}
+	if codec.Unmarshal == nil {
+		return errors.New("gouache: Unmarshal is nil")
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.tags[reflect.TypeOf(example)] = tag
+	registry.codecs[tag] = codec
+	return nil
+}
+
+// lookup finds the tag and codec registered for the dynamic type of val.
+//
+// Parameters:
+//   - val: The value to look up
+//
+// Returns:
+//   - The type tag and codec
+//   - true if the type is registered
+func (registry *TypeRegistry) lookup(val any) (string, TypeCodec, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	tag, ok := registry.tags[reflect.TypeOf(val)]
+	if !ok {
+		return "", TypeCodec{}, false
+	}
+	return tag, registry.codecs[tag], true
+}
+
+// decode decodes data stored with a type tag.
+//
+// Parameters:
+//   - key: The key the data was stored under
+//   - data: The data read from Redis
+//
+// Returns:
+//   - The decoded value
+//   - true if data carried a registered type tag
+//   - An error if the codec's Unmarshal fails
+func (registry *TypeRegistry) decode(key string, data string) (any, bool, error) {
+	// Split marker + tag + marker + payload
+	if !strings.HasPrefix(data, typeTagMarker) {
+		return nil, false, nil
+	}
+	tag, payload, ok := strings.Cut(data[len(typeTagMarker):], typeTagMarker)
+	if !ok {
+		return nil, false, nil
+	}
+
+	registry.mu.RLock()
+	codec, ok := registry.codecs[tag]
+	registry.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	obj, err := codec.Unmarshal(key, payload)
+	return obj, true, err
+}
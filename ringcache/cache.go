@@ -0,0 +1,386 @@
+// Package ringcache provides a dependency-free implementation of the
+// gouache.Cache interface that stores values in a preallocated byte ring.
+//
+// Like freecache, the cache holds marshaled []byte values in a buffer
+// allocated once at construction, so its memory use has a hard ceiling and
+// values don't create garbage for the GC to scan. Entries are appended to the
+// ring, and when it is full the oldest entries are overwritten, in FIFO order
+// regardless of how recently they were read. Overwritten and deleted entries
+// keep their space until the ring wraps around to them.
+//
+// Only the key index, a map from each key to the position of its entry, lives
+// on the Go heap.
+package ringcache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that Cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*Cache)(nil)
+
+// headerSize is the size of the header preceding each entry in the ring: the
+// key length and value length as uint32 and the expiry as int64 Unix
+// nanoseconds, zero meaning no expiry.
+const headerSize = 16
+
+// Cache is an implementation of gouache.Cache over a fixed-size byte ring.
+type Cache struct {
+	// TTL is an optional function to determine the time-to-live duration for a cache entry.
+	// If not provided, entries only leave the cache when overwritten. A positive
+	// result is scaled by the context's gouache.TTLMultiplier, and any result is
+	// clamped to the context's gouache.TTLCeiling, before it is applied.
+	TTL func(ctx context.Context, key string, val any) (time.Duration, error)
+
+	// Marshal is an optional function to serialize objects into byte slices.
+	// If not provided, only byte slice values can be stored directly.
+	Marshal func(key string, obj any) ([]byte, error)
+
+	// Unmarshal is an optional function to deserialize byte slices into objects.
+	// If not provided, copies of the raw byte slices are returned.
+	Unmarshal func(key string, data []byte) (any, error)
+
+	// Clock is the clock entries expire by. If not provided, gouache.RealClock is used.
+	Clock gouache.Clock
+
+	// mu guards buf, head, tail and index
+	mu sync.Mutex
+
+	// buf is the ring
+	buf []byte
+
+	// head is the total number of bytes ever written, the ring position of the
+	// next entry being head modulo len(buf)
+	head int64
+
+	// tail is the absolute position of the oldest entry still in the ring
+	tail int64
+
+	// index maps each key to the absolute position of its latest entry
+	index map[string]int64
+}
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithTTL returns an Option that sets the function determining the TTL of
+// each entry.
+//
+// Parameters:
+//   - f: The function returning the TTL of an entry
+//
+// Returns:
+//   - An Option function that sets TTL
+func WithTTL(f func(ctx context.Context, key string, val any) (time.Duration, error)) Option {
+	return func(cache *Cache) {
+		cache.TTL = f
+	}
+}
+
+// WithMarshal returns an Option that sets the function serializing non-byte values.
+//
+// Parameters:
+//   - f: The function encoding a value
+//
+// Returns:
+//   - An Option function that sets Marshal
+func WithMarshal(f func(key string, obj any) ([]byte, error)) Option {
+	return func(cache *Cache) {
+		cache.Marshal = f
+	}
+}
+
+// WithUnmarshal returns an Option that sets the function deserializing stored bytes.
+//
+// Parameters:
+//   - f: The function decoding a value
+//
+// Returns:
+//   - An Option function that sets Unmarshal
+func WithUnmarshal(f func(key string, data []byte) (any, error)) Option {
+	return func(cache *Cache) {
+		cache.Unmarshal = f
+	}
+}
+
+// WithClock returns an Option that sets the clock entries expire by.
+// It defaults to gouache.RealClock; tests can pass a clocktest.Clock.
+//
+// Parameters:
+//   - clock: The clock to use
+//
+// Returns:
+//   - An Option function that sets Clock
+func WithClock(clock gouache.Clock) Option {
+	return func(cache *Cache) {
+		cache.Clock = clock
+	}
+}
+
+// New creates a new Cache whose ring holds capacity bytes, allocated up front.
+// Each entry takes 16 bytes of header plus the length of its key and value.
+//
+// Parameters:
+//   - capacity: The size of the ring in bytes, which must be positive
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - An empty Cache
+//   - An error if capacity is not positive
+func New(capacity int, opts ...Option) (*Cache, error) {
+	if capacity <= 0 {
+		return nil, errors.New("ringcache: capacity must be positive")
+	}
+	cache := &Cache{buf: make([]byte, capacity), index: make(map[string]int64)}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	return cache, nil
+}
+
+// Get retrieves a value from the cache by its key.
+// It returns gouache.ErrCacheMiss if the key does not exist, has expired or
+// was overwritten.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
+	data, ok := cache.load(key)
+
+	// Handle case where entry is not found
+	if !ok {
+		return nil, gouache.ErrCacheMiss
+	}
+
+	// If no unmarshal function is defined, return raw data
+	if cache.Unmarshal == nil {
+		return data, nil
+	}
+
+	// Use custom unmarshal function to decode the data
+	return cache.Unmarshal(key, data)
+}
+
+// Set stores a value in the cache under the specified key, overwriting the
+// oldest entries if the ring is full.
+// TTL can be determined dynamically by the TTL function if provided.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as byte slice or any other type requiring marshaling
+//
+// Returns:
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-byte values
+func (cache *Cache) Set(ctx context.Context, key string, val any) error {
+	// Initialize TTL to zero (no expiration)
+	ttl := time.Duration(0)
+
+	// Check if a custom TTL function is configured
+	if cache.TTL != nil {
+		var err error
+		ttl, err = cache.TTL(ctx, key, val)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Apply the per-request TTL multiplier and ceiling, if any
+	ttl = gouache.AdjustTTL(ctx, ttl)
+
+	// Store the value with the resolved TTL
+	return cache.SetWithTTL(ctx, key, val, ttl)
+}
+
+// SetWithTTL stores a value in the cache under the specified key with an
+// explicit TTL, bypassing the TTL function and the TTL multiplier.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, either as byte slice or any other type requiring marshaling
+//   - ttl: The time-to-live of the entry, zero or negative meaning no expiration
+//
+// Returns:
+//   - An error wrapping gouache.ErrMarshal or gouache.ErrStorage if marshaling or storing fails,
+//     or an error wrapping gouache.ErrUnsupportedValue if Marshal is nil for non-byte values
+func (cache *Cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	// Check if the value is already a byte slice
+	data, ok := val.([]byte)
+	if !ok {
+		// For non-byte values, ensure a marshal function is available
+		if cache.Marshal == nil {
+			return fmt.Errorf("%w: %T requires Marshal", gouache.ErrUnsupportedValue, val)
+		}
+
+		// Marshal the value into bytes using the custom marshal function
+		var err error
+		data, err = cache.Marshal(key, val)
+		if err != nil {
+			return fmt.Errorf("%w: %w", gouache.ErrMarshal, err)
+		}
+	}
+
+	// Compute the expiry of the entry
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = cache.clock().Now().Add(ttl).UnixNano()
+	}
+	return cache.store(key, data, expiresAt)
+}
+
+// Delete removes a value from the cache by its key. The space of its entry is
+// reclaimed when the ring wraps around to it.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - Always returns nil as removing from the index can't fail
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.index, key)
+	return nil
+}
+
+// Len returns the number of keys in the cache, including expired entries not
+// yet read or overwritten.
+//
+// Returns:
+//   - The number of keys
+func (cache *Cache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return len(cache.index)
+}
+
+// load returns a copy of the value of the live entry of key.
+//
+// Parameters:
+//   - key: The key to look up
+//
+// Returns:
+//   - The value
+//   - false if the key is missing or its entry has expired
+func (cache *Cache) load(key string) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	pos, ok := cache.index[key]
+	if !ok {
+		return nil, false
+	}
+
+	// Drop entries that have expired
+	var header [headerSize]byte
+	cache.read(pos, header[:])
+	keyLen := int64(binary.BigEndian.Uint32(header[0:4]))
+	valLen := binary.BigEndian.Uint32(header[4:8])
+	expiresAt := int64(binary.BigEndian.Uint64(header[8:16]))
+	if expiresAt != 0 && cache.clock().Now().UnixNano() >= expiresAt {
+		delete(cache.index, key)
+		return nil, false
+	}
+
+	// Copy the value out of the ring, which may overwrite it later
+	data := make([]byte, valLen)
+	cache.read(pos+headerSize+keyLen, data)
+	return data, true
+}
+
+// store appends an entry to the ring, overwriting the oldest entries to make
+// room, and points key at it.
+//
+// Parameters:
+//   - key: The key of the entry
+//   - data: The value of the entry
+//   - expiresAt: The expiry in Unix nanoseconds, zero meaning no expiry
+//
+// Returns:
+//   - An error wrapping gouache.ErrStorage if the entry is larger than the ring
+func (cache *Cache) store(key string, data []byte, expiresAt int64) error {
+	size := int64(headerSize + len(key) + len(data))
+	if size > int64(len(cache.buf)) {
+		return fmt.Errorf("%w: entry of %d bytes exceeds the ring capacity of %d bytes", gouache.ErrStorage, size, len(cache.buf))
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Overwrite the oldest entries until the new one fits
+	for cache.head+size-cache.tail > int64(len(cache.buf)) {
+		cache.evictOldest()
+	}
+
+	// Write the header, key and value
+	var header [headerSize]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(key)))
+	binary.BigEndian.PutUint32(header[4:8], uint32(len(data)))
+	binary.BigEndian.PutUint64(header[8:16], uint64(expiresAt))
+	pos := cache.head
+	cache.write(pos, header[:])
+	cache.write(pos+headerSize, []byte(key))
+	cache.write(pos+headerSize+int64(len(key)), data)
+
+	cache.head += size
+	cache.index[key] = pos
+	return nil
+}
+
+// evictOldest advances the tail past the oldest entry, removing its key from
+// the index if the entry is still the key's latest.
+func (cache *Cache) evictOldest() {
+	var header [headerSize]byte
+	cache.read(cache.tail, header[:])
+	keyLen := int64(binary.BigEndian.Uint32(header[0:4]))
+	valLen := int64(binary.BigEndian.Uint32(header[4:8]))
+
+	key := make([]byte, keyLen)
+	cache.read(cache.tail+headerSize, key)
+	if pos, ok := cache.index[string(key)]; ok && pos == cache.tail {
+		delete(cache.index, string(key))
+	}
+	cache.tail += headerSize + keyLen + valLen
+}
+
+// read copies len(p) bytes starting at the absolute position pos out of the
+// ring, wrapping around its end.
+func (cache *Cache) read(pos int64, p []byte) {
+	off := int(pos % int64(len(cache.buf)))
+	n := copy(p, cache.buf[off:])
+	copy(p[n:], cache.buf)
+}
+
+// write copies p into the ring starting at the absolute position pos,
+// wrapping around its end.
+func (cache *Cache) write(pos int64, p []byte) {
+	off := int(pos % int64(len(cache.buf)))
+	n := copy(cache.buf[off:], p)
+	copy(cache.buf, p[n:])
+}
+
+// clock returns the configured Clock, or gouache.RealClock if none is set.
+func (cache *Cache) clock() gouache.Clock {
+	if cache.Clock == nil {
+		return gouache.RealClock
+	}
+	return cache.Clock
+}
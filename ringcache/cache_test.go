@@ -0,0 +1,137 @@
+package ringcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
+	"github.com/soyacen/gouache/clocktest"
+)
+
+// TestCache_GetSetDelete tests basic operations and marshaling
+func TestCache_GetSetDelete(t *testing.T) {
+	cache, err := New(1024,
+		WithMarshal(func(key string, obj any) ([]byte, error) { return json.Marshal(obj) }),
+		WithUnmarshal(func(key string, data []byte) (any, error) {
+			var obj map[string]int
+			err := json.Unmarshal(data, &obj)
+			return obj, err
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", map[string]int{"a": 1}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	val, err := cache.Get(ctx, "key")
+	if err != nil || val.(map[string]int)["a"] != 1 {
+		t.Errorf("Expected the stored value, got %v, %v", val, err)
+	}
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+
+	// Without Marshal only byte slices are accepted
+	raw, _ := New(64)
+	if err := raw.Set(ctx, "key", 1); !errors.Is(err, gouache.ErrUnsupportedValue) {
+		t.Errorf("Expected gouache.ErrUnsupportedValue, got %v", err)
+	}
+	if err := raw.Set(ctx, "key", make([]byte, 64)); !errors.Is(err, gouache.ErrStorage) {
+		t.Errorf("Expected gouache.ErrStorage for an entry larger than the ring, got %v", err)
+	}
+	if _, err := New(0); err == nil {
+		t.Error("Expected an error for a zero capacity")
+	}
+}
+
+// TestCache_Overflow tests that the oldest entries are overwritten in FIFO order
+func TestCache_Overflow(t *testing.T) {
+	// Each entry takes 16 bytes of header, 2 of key and 14 of value: 32 bytes,
+	// so the ring holds three entries with room to spare that forces wrapping
+	cache, _ := New(100)
+	ctx := context.Background()
+	value := func(i int) []byte { return []byte(fmt.Sprintf("value-%08d", i)) }
+
+	for i := 0; i < 3; i++ {
+		if err := cache.Set(ctx, fmt.Sprintf("k%d", i), value(i)); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+
+	// Reading k0 doesn't protect it: eviction is FIFO
+	if _, err := cache.Get(ctx, "k0"); err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	_ = cache.Set(ctx, "k3", value(3))
+	if _, err := cache.Get(ctx, "k0"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected k0 to be overwritten, got %v", err)
+	}
+
+	// Keep writing around the ring several times; the last three survive intact
+	for i := 4; i < 20; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("k%d", i%10), value(i))
+	}
+	for i := 17; i < 20; i++ {
+		val, err := cache.Get(ctx, fmt.Sprintf("k%d", i%10))
+		if err != nil || !bytes.Equal(val.([]byte), value(i)) {
+			t.Errorf("Expected %s, got %s, %v", value(i), val, err)
+		}
+	}
+	if _, err := cache.Get(ctx, "k6"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected k6 to be overwritten, got %v", err)
+	}
+	if cache.Len() != 3 {
+		t.Errorf("Expected 3 keys, got %d", cache.Len())
+	}
+}
+
+// TestCache_TTL tests that entries expire after the TTL returned by the TTL function
+func TestCache_TTL(t *testing.T) {
+	clock := clocktest.NewClock(time.Unix(0, 0))
+	cache, _ := New(1024,
+		WithClock(clock),
+		WithTTL(func(ctx context.Context, key string, val any) (time.Duration, error) {
+			if key == "forever" {
+				return 0, nil
+			}
+			return time.Minute, nil
+		}),
+	)
+	ctx := context.Background()
+	_ = cache.Set(ctx, "short", []byte("a"))
+	_ = cache.Set(ctx, "forever", []byte("b"))
+
+	clock.Advance(59 * time.Second)
+	if _, err := cache.Get(ctx, "short"); err != nil {
+		t.Errorf("Expected the entry before its TTL, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if _, err := cache.Get(ctx, "short"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the entry to expire, got %v", err)
+	}
+	if _, err := cache.Get(ctx, "forever"); err != nil {
+		t.Errorf("Expected the entry without TTL to remain, got %v", err)
+	}
+}
+
+// TestCache_CacheMissContract tests that misses are reported as gouache.ErrCacheMiss
+func TestCache_CacheMissContract(t *testing.T) {
+	cache, err := New(1024)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	cachetest.AssertCacheMissContract(t, cache, cachetest.WithValueFunc(func(i int) any { return []byte(fmt.Sprint("value-", i)) }))
+}
@@ -0,0 +1,204 @@
+// Package rww (Read Your Writes) provides a cache implementation that lets a
+// request read back its own writes even when the backend is eventually
+// consistent.
+//
+// This package implements the gouache.Cache interface by wrapping another cache
+// and keeping a write log in the request's context. Every Set or Delete made
+// with a context carrying a log is recorded in it, and a later Get for the same
+// key with that context is answered from the log instead of the backend. The
+// log belongs to the context created by NewContext, so it is discarded with the
+// request and is ignored once the context is done. Writes are logged per rww
+// cache, so caches sharing a request context never read each other's writes.
+package rww
+
+import (
+	"context"
+	"sync"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// logKey is the context key under which the write log is stored.
+type logKey struct{}
+
+// entry is a write recorded in the log.
+type entry struct {
+	// val is the value written by Set.
+	val any
+
+	// deleted marks a key removed by Delete.
+	deleted bool
+}
+
+// writeLog records the writes made within one request.
+type writeLog struct {
+	// mu guards entries.
+	mu sync.Mutex
+
+	// entries maps each rww cache to the latest write of each key through it.
+	entries map[*cache]map[string]entry
+}
+
+// lookup returns the latest write of key through c.
+//
+// Parameters:
+//   - c: The rww cache the key was written through
+//   - key: The key to look up
+//
+// Returns:
+//   - The latest write of the key
+//   - true if the key was written through c
+func (log *writeLog) lookup(c *cache, key string) (entry, bool) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	e, ok := log.entries[c][key]
+	return e, ok
+}
+
+// record stores the latest write of key through c.
+//
+// Parameters:
+//   - c: The rww cache the key was written through
+//   - key: The key written
+//   - e: The write to record
+func (log *writeLog) record(c *cache, key string, e entry) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	entries, ok := log.entries[c]
+	if !ok {
+		entries = make(map[string]entry)
+		log.entries[c] = entries
+	}
+	entries[key] = e
+}
+
+// NewContext returns a copy of ctx carrying an empty write log. Operations on a
+// rww cache made with the returned context, or contexts derived from it, read
+// their own writes.
+//
+// Parameters:
+//   - ctx: The parent context, usually the request context
+//
+// Returns:
+//   - A context carrying a new write log
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logKey{}, &writeLog{entries: make(map[*cache]map[string]entry)})
+}
+
+// fromContext returns the write log carried by ctx, if any and if ctx is not done.
+//
+// Parameters:
+//   - ctx: The context to read the log from
+//
+// Returns:
+//   - The write log, or nil if there is none or the context is done
+func fromContext(ctx context.Context) *writeLog {
+	log, ok := ctx.Value(logKey{}).(*writeLog)
+	if !ok || ctx.Err() != nil {
+		return nil
+	}
+	return log
+}
+
+// cache is a cache implementation that answers Gets from the request's write
+// log before consulting the underlying cache.
+type cache struct {
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+}
+
+// New creates a new read-your-writes cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//
+// Returns:
+//   - A gouache.Cache implementation that reads its own writes within a request
+func New(c gouache.Cache) gouache.Cache {
+	return &cache{Cache: c}
+}
+
+// Get retrieves a value by its key. If the key was written earlier with the
+// same request context, the written value is returned, or gouache.ErrCacheMiss
+// if it was deleted; otherwise the underlying cache is consulted.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	// Answer from the write log if this request wrote the key
+	if log := fromContext(ctx); log != nil {
+		if e, ok := log.lookup(cache, key); ok {
+			if e.deleted {
+				return nil, gouache.ErrCacheMiss
+			}
+			return e.val, nil
+		}
+	}
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set stores a value in the underlying cache and, on success, records it in
+// the request's write log.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	if err := cache.Cache.Set(ctx, key, val); err != nil {
+		return err
+	}
+	cache.record(ctx, key, entry{val: val})
+	return nil
+}
+
+// Delete removes a value from the underlying cache and, on success, records
+// the deletion in the request's write log.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	if err := cache.Cache.Delete(ctx, key); err != nil {
+		return err
+	}
+	cache.record(ctx, key, entry{deleted: true})
+	return nil
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// record stores a write in the request's write log, if the context has one.
+//
+// Parameters:
+//   - ctx: Context of the write
+//   - key: The key written
+//   - e: The write to record
+func (cache *cache) record(ctx context.Context, key string, e entry) {
+	if log := fromContext(ctx); log != nil {
+		log.record(cache, key, e)
+	}
+}
@@ -0,0 +1,119 @@
+package rww
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// laggingCache simulates an eventually-consistent backend: writes are accepted
+// but not visible to reads until flushed.
+type laggingCache struct {
+	sample.Cache
+}
+
+func (l *laggingCache) Set(ctx context.Context, key string, val any) error {
+	return nil
+}
+
+func (l *laggingCache) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+// TestCache_ReadYourWrites tests that a request sees its own writes.
+func TestCache_ReadYourWrites(t *testing.T) {
+	backend := &laggingCache{}
+	_ = backend.Cache.Set(context.Background(), "deleted-key", "stale")
+	cache := New(backend)
+	ctx := NewContext(context.Background())
+
+	// A Set is visible within the same request
+	err := cache.Set(ctx, "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	result, err := cache.Get(ctx, "test-key")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if result != "test-value" {
+		t.Errorf("Expected test-value, but got %v", result)
+	}
+
+	// A Delete is visible within the same request
+	err = cache.Delete(ctx, "deleted-key")
+	if err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	_, err = cache.Get(ctx, "deleted-key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss after deletion, but got: %v", err)
+	}
+
+	// Another request only sees the backend
+	_, err = cache.Get(NewContext(context.Background()), "test-key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss in another request, but got: %v", err)
+	}
+}
+
+// TestCache_ContextDone tests that the log is ignored once the request context ends.
+func TestCache_ContextDone(t *testing.T) {
+	cache := New(&laggingCache{})
+	ctx, cancel := context.WithCancel(NewContext(context.Background()))
+
+	err := cache.Set(ctx, "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	cancel()
+
+	_, err = cache.Get(ctx, "test-key")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss after context end, but got: %v", err)
+	}
+}
+
+// TestCache_NoLog tests that contexts without a log pass straight through.
+func TestCache_NoLog(t *testing.T) {
+	backend := &sample.Cache{}
+	cache := New(backend)
+
+	err := cache.Set(context.Background(), "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	result, err := cache.Get(context.Background(), "test-key")
+	if err != nil || result != "test-value" {
+		t.Errorf("Expected test-value, but got %v, %v", result, err)
+	}
+}
+
+// TestCache_SeparateLogs tests that two rww caches sharing a request context
+// don't read each other's writes
+func TestCache_SeparateLogs(t *testing.T) {
+	users := New(&laggingCache{})
+	sessions := New(&laggingCache{})
+	ctx := NewContext(context.Background())
+
+	if err := users.Set(ctx, "1", "alice"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if val, err := users.Get(ctx, "1"); err != nil || val != "alice" {
+		t.Errorf("Expected 'alice' from users, got %v, %v", val, err)
+	}
+	if val, err := sessions.Get(ctx, "1"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss from sessions, got %v, %v", val, err)
+	}
+
+	// A deletion through one cache doesn't hide the key in the other
+	if err := sessions.Delete(ctx, "1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if val, err := users.Get(ctx, "1"); err != nil || val != "alice" {
+		t.Errorf("Expected 'alice' from users after deleting from sessions, got %v, %v", val, err)
+	}
+}
@@ -6,6 +6,9 @@ package sample
 
 import (
 	"context"
+	"fmt"
+	"path"
+	"reflect"
 	"sync"
 
 	"github.com/soyacen/gouache"
@@ -14,9 +17,44 @@ import (
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchSetter interface at compile time.
+var _ gouache.BatchSetter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Iterable interface at compile time.
+var _ gouache.Iterable = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.CompareDeleter interface at compile time.
+var _ gouache.CompareDeleter = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*Cache)(nil)
+
+// Ensure that Cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*Cache)(nil)
+
 // Cache is a simple in-memory cache implementation using sync.Map.
 // It provides thread-safe operations for storing, retrieving, and deleting cached values.
+//
+// The cache itself is safe for concurrent use, but values are stored and
+// returned by reference: a map, slice or pointer passed to Set is the one
+// every later Get returns. A caller mutating such a value races with every
+// other caller holding it. Either treat cached values as read-only, or set
+// Clone so that each caller works on its own copy.
 type Cache struct {
+	// Clone is an optional function returning a deep copy of a value. When
+	// set, values are copied on Set, Add and SetMulti and again on Get and
+	// GetMulti, so neither the value passed in nor a value returned shares
+	// memory with the stored one. Since stored values are copies,
+	// CompareAndDelete only matches values that are equal by ==, such as
+	// strings and numbers, but not pointers.
+	Clone func(val any) any
+
 	// cache is the underlying sync.Map used for storage.
 	// sync.Map provides concurrent-safe operations without external dependencies.
 	cache sync.Map
@@ -41,8 +79,33 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 		return nil, gouache.ErrCacheMiss
 	}
 
-	// Return the found value
-	return val, nil
+	// Return the found value, copied if Clone is set
+	return cache.clone(val), nil
+}
+
+// GetMulti retrieves the values for the given keys from the cache.
+// Keys that do not exist are omitted from the returned map, which avoids
+// producing a gouache.ErrCacheMiss for every missing key.
+//
+// Each key is looked up individually in the sync.Map, so no global lock is
+// held for the duration of the batch.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - Always returns nil error as sync.Map.Load doesn't return errors
+func (cache *Cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	vals := make(map[string]any, len(keys))
+	for _, key := range keys {
+		// Only record keys that are present in the sync.Map
+		if val, ok := cache.cache.Load(key); ok {
+			vals[key] = cache.clone(val)
+		}
+	}
+	return vals, nil
 }
 
 // Set stores a value in the cache under the specified key.
@@ -55,13 +118,59 @@ func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
 // Returns:
 //   - Always returns nil as sync.Map.Store doesn't return errors
 func (cache *Cache) Set(ctx context.Context, key string, val any) error {
-	// Store the value in sync.Map
-	cache.cache.Store(key, val)
+	// Store the value in sync.Map, copied if Clone is set
+	cache.cache.Store(key, cache.clone(val))
 
 	// sync.Map.Store doesn't return errors, so always return nil
 	return nil
 }
 
+// Add stores a value under key only if the key is not already present, so
+// that concurrent initializers don't clobber each other's value. The check
+// and store are atomic.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - Always returns nil error as sync.Map.LoadOrStore doesn't return errors
+func (cache *Cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	_, loaded := cache.cache.LoadOrStore(key, cache.clone(val))
+	return !loaded, nil
+}
+
+// SetMulti stores each value in vals under its key.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - Always returns nil as sync.Map.Store doesn't return errors
+func (cache *Cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	for key, val := range vals {
+		cache.cache.Store(key, cache.clone(val))
+	}
+	return nil
+}
+
+// clone returns a copy of val made by Clone, or val itself if Clone is nil.
+//
+// Parameters:
+//   - val: The value to copy
+//
+// Returns:
+//   - The copy of val
+func (cache *Cache) clone(val any) any {
+	if cache.Clone == nil {
+		return val
+	}
+	return cache.Clone(val)
+}
+
 // Delete removes a value from the cache by its key.
 //
 // Parameters:
@@ -77,3 +186,101 @@ func (cache *Cache) Delete(ctx context.Context, key string) error {
 	// sync.Map.Delete doesn't return errors, so always return nil
 	return nil
 }
+
+// CompareAndDelete deletes the value stored under key if it equals expected,
+// as compared with ==, atomically using sync.Map.CompareAndDelete.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - key: The key of the value to delete
+//   - expected: The value the key must hold to be deleted
+//
+// Returns:
+//   - true if the value was deleted, false if the key was missing or held another value
+//   - An error wrapping gouache.ErrUnsupportedValue if expected isn't comparable
+func (cache *Cache) CompareAndDelete(ctx context.Context, key string, expected any) (bool, error) {
+	// sync.Map panics when comparing values that aren't comparable
+	if expected != nil && !reflect.TypeOf(expected).Comparable() {
+		return false, fmt.Errorf("%w: %T is not comparable", gouache.ErrUnsupportedValue, expected)
+	}
+	return cache.cache.CompareAndDelete(key, expected), nil
+}
+
+// DeleteMulti removes the values of the given keys from the cache.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - Always returns nil as sync.Map.Delete doesn't return errors
+func (cache *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		cache.cache.Delete(key)
+	}
+	return nil
+}
+
+// DeleteMultiResult removes the values of the given keys from the cache and
+// reports which of them existed. Each key is removed with LoadAndDelete, so
+// a key is reported only by the caller that actually removed it.
+//
+// Parameters:
+//   - ctx: Context for the operation (not used in this implementation)
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - Always returns nil error as sync.Map.LoadAndDelete doesn't return errors
+func (cache *Cache) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	var deleted []string
+	for _, key := range keys {
+		if _, loaded := cache.cache.LoadAndDelete(key); loaded {
+			deleted = append(deleted, key)
+		}
+	}
+	return deleted, nil
+}
+
+// Iterate calls fn for every key in the cache matching the pattern match.
+// An empty match enumerates all keys.
+//
+// Patterns follow path.Match, so unlike Redis globs a '*' doesn't match '/'.
+// Keys stored or deleted concurrently may or may not be seen.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - match: The pattern keys must match, or empty to match all keys
+//   - fn: The function called for each key
+//
+// Returns:
+//   - An error if the pattern is malformed, the context is done, or fn fails
+func (cache *Cache) Iterate(ctx context.Context, match string, fn func(key string) error) error {
+	// Reject malformed patterns up front
+	if match != "" {
+		if _, err := path.Match(match, ""); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	cache.cache.Range(func(k, _ any) bool {
+		// Stop early if the context is done
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+
+		// Skip keys not matching the pattern
+		key := k.(string)
+		if match != "" {
+			if ok, _ := path.Match(match, key); !ok {
+				return true
+			}
+		}
+
+		// Hand the key to the callback
+		err = fn(key)
+		return err == nil
+	})
+	return err
+}
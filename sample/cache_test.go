@@ -2,10 +2,13 @@ package sample
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/cachetest"
 )
 
 // TestCache_Get tests the Get method of the Cache implementation.
@@ -173,3 +176,228 @@ func TestCache_ConcurrentAccess(t *testing.T) {
 		<-done
 	}
 }
+
+// TestCache_GetMulti tests the GetMulti method of the Cache implementation.
+func TestCache_GetMulti(t *testing.T) {
+	// Create a new cache instance
+	cache := &Cache{}
+
+	// Setup: store some values
+	values := map[string]any{"key1": "value1", "key2": "value2"}
+	for key, value := range values {
+		err := cache.Set(context.Background(), key, value)
+		if err != nil {
+			t.Fatalf("Failed to set up test value: %v", err)
+		}
+	}
+
+	// Test getting a mix of existing and missing keys
+	result, err := cache.GetMulti(context.Background(), []string{"key1", "key2", "missing"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(result) != len(values) {
+		t.Errorf("Expected %d values, but got %d", len(values), len(result))
+	}
+	for key, value := range values {
+		if result[key] != value {
+			t.Errorf("Expected %v for %s, but got %v", value, key, result[key])
+		}
+	}
+
+	// Verify missing keys are absent from the result
+	if _, ok := result["missing"]; ok {
+		t.Error("Expected missing key to be absent from result, but it was present")
+	}
+}
+
+// TestCache_Iterate tests the Iterate method of the Cache implementation.
+func TestCache_Iterate(t *testing.T) {
+	// Create a new cache instance
+	cache := &Cache{}
+
+	// Setup: store some values
+	err := cache.SetMulti(context.Background(), map[string]any{"user:1": 1, "user:2": 2, "order:1": 3})
+	if err != nil {
+		t.Fatalf("Failed to set up test values: %v", err)
+	}
+
+	// Test iterating over matching keys
+	var keys []string
+	err = cache.Iterate(context.Background(), "user:*", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected 2 keys, but got %v", keys)
+	}
+
+	// Test that the callback error stops iteration
+	stop := errors.New("stop")
+	calls := 0
+	err = cache.Iterate(context.Background(), "", func(key string) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Expected stop after 1 call, but got %v after %d calls", err, calls)
+	}
+}
+
+// TestCache_CompareAndDelete tests that a value changed since it was read is not deleted.
+func TestCache_CompareAndDelete(t *testing.T) {
+	ctx := context.Background()
+	cache := &Cache{}
+	_ = cache.Set(ctx, "key", "old")
+
+	// Read the value, then let a concurrent writer replace it
+	read, _ := cache.Get(ctx, "key")
+	_ = cache.Set(ctx, "key", "new")
+
+	// Invalidating with the value read must keep the newer value
+	if deleted, err := cache.CompareAndDelete(ctx, "key", read); err != nil || deleted {
+		t.Errorf("Expected no deletion, but got %v, %v", deleted, err)
+	}
+	if val, _ := cache.Get(ctx, "key"); val != "new" {
+		t.Errorf("Expected the newer value to be kept, but got %v", val)
+	}
+
+	// Invalidating with the current value deletes it
+	if deleted, err := cache.CompareAndDelete(ctx, "key", "new"); err != nil || !deleted {
+		t.Errorf("Expected the value to be deleted, but got %v, %v", deleted, err)
+	}
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+	}
+
+	// Values that can't be compared are rejected instead of panicking
+	_ = cache.Set(ctx, "bytes", []byte("x"))
+	if _, err := cache.CompareAndDelete(ctx, "bytes", []byte("x")); !errors.Is(err, gouache.ErrUnsupportedValue) {
+		t.Errorf("Expected ErrUnsupportedValue, but got: %v", err)
+	}
+}
+
+// TestCache_AddConcurrent tests that exactly one of many concurrent Adds of a key succeeds
+func TestCache_AddConcurrent(t *testing.T) {
+	cache := &Cache{}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []int
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := cache.Add(ctx, "singleton", i)
+			if err != nil {
+				t.Errorf("Failed to add value: %v", err)
+			}
+			if ok {
+				mu.Lock()
+				winners = append(winners, i)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(winners) != 1 {
+		t.Fatalf("Expected exactly one Add to succeed, got %v", winners)
+	}
+	if val, err := cache.Get(ctx, "singleton"); err != nil || val != winners[0] {
+		t.Errorf("Expected the winner's value %d, got %v, %v", winners[0], val, err)
+	}
+
+	// A later Add leaves the value alone
+	if ok, _ := cache.Add(ctx, "singleton", -1); ok {
+		t.Error("Expected Add of an existing key to fail")
+	}
+}
+
+// TestConformance runs the shared conformance suite against the cache
+func TestConformance(t *testing.T) {
+	cachetest.RunConformance(t, &Cache{})
+}
+
+// TestCache_CacheMissContract tests that misses are reported as gouache.ErrCacheMiss
+func TestCache_CacheMissContract(t *testing.T) {
+	cachetest.AssertCacheMissContract(t, &Cache{})
+}
+
+// BenchmarkCache runs the shared benchmarks against the cache
+func BenchmarkCache(b *testing.B) {
+	cachetest.BenchmarkCache(b, &Cache{})
+}
+
+// TestCache_RaceStress hammers overlapping keys with every operation while
+// callers mutate the values they get back. Run it with -race: with Clone set,
+// no caller may share memory with the stored values or with another caller.
+func TestCache_RaceStress(t *testing.T) {
+	cache := &Cache{Clone: func(val any) any {
+		m, ok := val.(map[string]int)
+		if !ok {
+			return val
+		}
+		cp := make(map[string]int, len(m))
+		for k, v := range m {
+			cp[k] = v
+		}
+		return cp
+	}}
+	ctx := context.Background()
+	keys := []string{"key-0", "key-1", "key-2", "key-3"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				key := keys[(i+j)%len(keys)]
+				switch j % 7 {
+				case 0:
+					// Mutating a value after Set must not change the stored one
+					val := map[string]int{"writer": i}
+					_ = cache.Set(ctx, key, val)
+					val["writer"] = -1
+				case 1:
+					_, _ = cache.Add(ctx, key, map[string]int{"writer": i})
+				case 2:
+					_ = cache.SetMulti(ctx, map[string]any{key: map[string]int{"writer": i}})
+				case 3:
+					// Mutating a value returned by Get must not change the stored one
+					if val, err := cache.Get(ctx, key); err == nil {
+						m := val.(map[string]int)
+						if m["writer"] < 0 {
+							t.Errorf("Expected a stored writer, got %v", m)
+						}
+						m["writer"] = -1
+					}
+				case 4:
+					vals, _ := cache.GetMulti(ctx, keys)
+					for _, val := range vals {
+						val.(map[string]int)["writer"] = -1
+					}
+				case 5:
+					_ = cache.Delete(ctx, key)
+				case 6:
+					_ = cache.Iterate(ctx, "key-*", func(string) error { return nil })
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Whatever survived still holds a value some writer stored
+	for _, key := range keys {
+		if val, err := cache.Get(ctx, key); err == nil {
+			if w := val.(map[string]int)["writer"]; w < 0 || w >= 32 {
+				t.Errorf("Expected a stored writer for %s, got %d", key, w)
+			}
+		}
+	}
+}
@@ -5,18 +5,27 @@
 // cache and using singleflight to ensure only one operation of a given type
 // is performed for a given key at a time. This helps reduce the thundering herd
 // problem when multiple goroutines request the same missing cache entry.
+//
+// The shared Get runs with the merged context of a flight.Group rather than
+// the context of the caller that started it: it carries the values of that
+// first caller but none of its cancellation or deadline, and is canceled only
+// once every caller waiting for the result has given up.
 package sf
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/soyacen/gouache"
-	"golang.org/x/sync/singleflight"
+	"github.com/soyacen/gouache/flight"
 )
 
 // Ensure that Cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*Cache)(nil)
 
+// Ensure that Cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*Cache)(nil)
+
 // Cache is a cache implementation that wraps another cache and uses singleflight
 // to prevent duplicate operations for the same key.
 //
@@ -27,8 +36,64 @@ type Cache struct {
 	// Cache is the underlying cache implementation that stores the actual data.
 	Cache gouache.Cache
 
-	// group is the singleflight group used to deduplicate Get operations.
-	group singleflight.Group
+	// KeyFunc is an optional function deriving the key Gets are shared under
+	// from the context and the cache key. Only Gets with the same derived key
+	// share a call, so including a tenant ID from the context, for example,
+	// keeps one tenant's Get from serving another's. If not provided, Gets are
+	// shared by cache key.
+	KeyFunc func(ctx context.Context, key string) string
+
+	// group shares the Get operations in flight.
+	group flight.Group
+
+	// led counts the operations started, one per Get that started one.
+	led atomic.Uint64
+
+	// shared counts the Gets that joined an operation started by another Get.
+	shared atomic.Uint64
+}
+
+// Option is a function that configures a Cache.
+type Option func(*Cache)
+
+// WithKeyFunc returns an Option that sets the function deriving the key Gets
+// are shared under.
+//
+// Parameters:
+//   - f: A function deriving the flight key from the context and the cache key
+//
+// Returns:
+//   - An Option function that sets KeyFunc
+func WithKeyFunc(f func(ctx context.Context, key string) string) Option {
+	return func(cache *Cache) {
+		cache.KeyFunc = f
+	}
+}
+
+// New creates a new singleflight cache wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A Cache sharing concurrent Gets
+func New(c gouache.Cache, opts ...Option) *Cache {
+	cache := &Cache{Cache: c}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	return cache
+}
+
+// Stats reports how effective singleflight has been for a Cache.
+type Stats struct {
+	// Led is the number of Gets that called the underlying cache.
+	Led uint64
+
+	// Shared is the number of Gets that joined a Get already in flight for
+	// the same key instead of calling the underlying cache.
+	Shared uint64
 }
 
 // Get retrieves a value from the cache by its key.
@@ -38,22 +103,48 @@ type Cache struct {
 // This helps prevent the thundering herd problem when accessing missing or
 // expired cache entries.
 //
+// Each caller waits for the shared result until its own ctx is done, in which
+// case it returns an error wrapping gouache.ErrContext. The shared operation
+// keeps running as long as at least one caller is waiting for it.
+//
 // Parameters:
 //   - ctx: Context for the operation
 //   - key: The key to retrieve the value for
 //
 // Returns:
 //   - The cached value or nil if not found
-//   - An error if the operation fails
+//   - An error if the operation fails, or an error wrapping gouache.ErrContext if ctx is done first
 func (cache *Cache) Get(ctx context.Context, key string) (any, error) {
-	// Use singleflight to ensure only one Get operation for this key runs at a time
-	val, err, _ := cache.group.Do(key, func() (any, error) {
-		// Delegate to the underlying cache
+	// Derive the key the Get is shared under
+	flightKey := key
+	if cache.KeyFunc != nil {
+		flightKey = cache.KeyFunc(ctx, key)
+	}
+
+	// Join the operation in flight for this key, or start one, counting the
+	// operations as they start so that Stats includes those still in flight
+	val, err, shared := cache.group.Do(ctx, flightKey, func(ctx context.Context) (any, error) {
+		cache.led.Add(1)
 		return cache.Cache.Get(ctx, key)
 	})
+
+	// Count the Gets served by an operation another Get started
+	if shared {
+		cache.shared.Add(1)
+	}
 	return val, err
 }
 
+// Stats returns the number of Gets that called the underlying cache and the
+// number that shared a call already in flight. Led counts operations as they
+// start, while Shared counts the Gets joining them as they return.
+//
+// Returns:
+//   - The counters accumulated since the Cache was created
+func (cache *Cache) Stats() Stats {
+	return Stats{Led: cache.led.Load(), Shared: cache.shared.Load()}
+}
+
 // Set stores a value in the cache under the specified key.
 //
 // This operation is passed through directly to the underlying cache without
@@ -87,4 +178,12 @@ func (cache *Cache) Set(ctx context.Context, key string, val any) error {
 func (cache *Cache) Delete(ctx context.Context, key string) error {
 	// Delegate directly to the underlying cache
 	return cache.Cache.Delete(ctx, key)
-}
\ No newline at end of file
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *Cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
@@ -307,3 +307,183 @@ func TestSF_Cache_Get_Singleflight(t *testing.T) {
 		t.Errorf("Expected %v, but got %v", value, firstResult)
 	}
 }
+
+// ctxCache is a cache whose Get blocks until a value is released or its context is done.
+type ctxCache struct {
+	release  chan any
+	canceled chan struct{}
+	calls    int32
+	mu       sync.Mutex
+}
+
+func (c *ctxCache) Get(ctx context.Context, key string) (any, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	select {
+	case val := <-c.release:
+		return val, nil
+	case <-ctx.Done():
+		close(c.canceled)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *ctxCache) Set(ctx context.Context, key string, val any) error {
+	return nil
+}
+
+func (c *ctxCache) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+// TestSF_Cache_Get_MismatchedDeadlines tests that a waiter with a longer deadline outlives a short-lived leader.
+func TestSF_Cache_Get_MismatchedDeadlines(t *testing.T) {
+	underlying := &ctxCache{release: make(chan any), canceled: make(chan struct{})}
+	sfCache := &Cache{Cache: underlying}
+
+	// The leader has a short deadline
+	leaderErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := sfCache.Get(ctx, "key")
+		leaderErr <- err
+	}()
+
+	// A waiter with a longer deadline joins the same operation
+	time.Sleep(5 * time.Millisecond)
+	waiterResult := make(chan any, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		val, err := sfCache.Get(ctx, "key")
+		if err != nil {
+			waiterResult <- err
+			return
+		}
+		waiterResult <- val
+	}()
+
+	// The leader gives up on its own deadline
+	err := <-leaderErr
+	if !errors.Is(err, gouache.ErrContext) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected leader to fail with its deadline, but got %v", err)
+	}
+
+	// The shared operation survives and serves the waiter
+	underlying.release <- "value"
+	if got := <-waiterResult; got != "value" {
+		t.Errorf("Expected value for the waiter, but got %v", got)
+	}
+	if underlying.calls != 1 {
+		t.Errorf("Expected 1 underlying call, but got %d", underlying.calls)
+	}
+}
+
+// TestSF_Cache_Get_AllWaitersLeave tests that the shared operation is canceled once nobody waits for it.
+func TestSF_Cache_Get_AllWaitersLeave(t *testing.T) {
+	underlying := &ctxCache{release: make(chan any), canceled: make(chan struct{})}
+	sfCache := &Cache{Cache: underlying}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			_, _ = sfCache.Get(ctx, "key")
+		}()
+	}
+	wg.Wait()
+
+	// The underlying Get observes the cancellation of the merged context
+	select {
+	case <-underlying.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the shared operation to be canceled")
+	}
+}
+
+// TestSF_Cache_Stats tests that concurrent Gets of one key are counted as shared.
+func TestSF_Cache_Stats(t *testing.T) {
+	underlying := &ctxCache{release: make(chan any), canceled: make(chan struct{})}
+	sfCache := &Cache{Cache: underlying}
+
+	// Launch concurrent Gets of the same key, held until released
+	goroutines := 10
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = sfCache.Get(context.Background(), "test-key")
+		}()
+	}
+
+	// Wait until every Get is blocked on the shared one, then release it
+	deadline := time.Now().Add(5 * time.Second)
+	for sfCache.group.Waiters("test-key") < goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the Gets to join, %d waiting", sfCache.group.Waiters("test-key"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if stats := sfCache.Stats(); stats.Led != 1 {
+		t.Errorf("Expected 1 led Get in flight, but got %+v", stats)
+	}
+	underlying.release <- "test-value"
+	wg.Wait()
+
+	stats := sfCache.Stats()
+	if stats.Led != 1 || stats.Shared != uint64(goroutines-1) {
+		t.Errorf("Expected 1 led and %d shared Gets, but got %+v", goroutines-1, stats)
+	}
+
+	// A later Get leads a new operation
+	go func() { underlying.release <- "test-value" }()
+	_, _ = sfCache.Get(context.Background(), "test-key")
+	if stats := sfCache.Stats(); stats.Led != 2 {
+		t.Errorf("Expected 2 led Gets, but got %+v", stats)
+	}
+}
+
+// tenantKey is the context key of the tenant in TestSF_Cache_KeyFunc.
+type tenantKey struct{}
+
+// TestSF_Cache_KeyFunc tests that Gets are only shared within the flight key derived from the context.
+func TestSF_Cache_KeyFunc(t *testing.T) {
+	underlying := &ctxCache{release: make(chan any), canceled: make(chan struct{})}
+	sfCache := New(underlying, WithKeyFunc(func(ctx context.Context, key string) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant + "/" + key
+	}))
+
+	// Two tenants Get the same key concurrently
+	var wg sync.WaitGroup
+	for _, tenant := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(tenant string) {
+			defer wg.Done()
+			ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+			_, _ = sfCache.Get(ctx, "test-key")
+		}(tenant)
+	}
+
+	// Each tenant leads its own call
+	deadline := time.Now().Add(5 * time.Second)
+	for sfCache.Stats().Led < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 led Gets, but got %+v", sfCache.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	underlying.release <- "value-a"
+	underlying.release <- "value-b"
+	wg.Wait()
+
+	if stats := sfCache.Stats(); stats.Led != 2 || stats.Shared != 0 {
+		t.Errorf("Expected 2 led and 0 shared Gets, but got %+v", stats)
+	}
+}
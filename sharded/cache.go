@@ -10,6 +10,8 @@ package gouache
 import (
 	"context"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"hash"
 	"hash/fnv"
 
@@ -19,6 +21,41 @@ import (
 // Ensure that cache implements the gouache.Cache interface at compile time.
 var _ gouache.Cache = (*cache)(nil)
 
+// Ensure that cache implements the gouache.MultiUnwrapper interface at compile time.
+var _ gouache.MultiUnwrapper = (*cache)(nil)
+
+// ErrShardRouting is matched by the RoutingError returned when a key can't
+// be routed to a bucket, so that routing failures can be told apart from
+// failures of the buckets themselves.
+var ErrShardRouting = errors.New("gouache: shard routing failed")
+
+// RoutingError is returned when the HashFactory or the hash it creates fails
+// for a key. Both errors.Is(err, ErrShardRouting) and errors.Is(err, cause)
+// hold for it.
+type RoutingError struct {
+	// Key is the key that couldn't be routed.
+	Key string
+
+	// Err is the error returned by the HashFactory or the hash.
+	Err error
+}
+
+// Error returns a message describing the key and the cause.
+//
+// Returns:
+//   - The error message
+func (e *RoutingError) Error() string {
+	return fmt.Sprintf("gouache: shard routing for key %q failed: %v", e.Key, e.Err)
+}
+
+// Unwrap returns ErrShardRouting and the cause so that errors.Is matches both.
+//
+// Returns:
+//   - The wrapped errors
+func (e *RoutingError) Unwrap() []error {
+	return []error{ErrShardRouting, e.Err}
+}
+
 // HashFactory is a function type that creates a new hash.Hash instance
 // for a given context and key. This allows customization of the hashing
 // algorithm used for sharding.
@@ -41,6 +78,21 @@ type options struct {
 	// HashFactory is a function that creates hash instances used for
 	// determining which bucket a key should be stored in.
 	HashFactory HashFactory
+
+	// ReadHashFactory creates the hash instances used to route Get operations.
+	ReadHashFactory HashFactory
+
+	// WriteHashFactory creates the hash instances used to route Set and Delete operations.
+	WriteHashFactory HashFactory
+
+	// Replicas is the number of buckets each key is stored in.
+	Replicas int
+
+	// ReadFallback makes a Get whose replicas fail try the next bucket.
+	ReadFallback bool
+
+	// KeyExtractor returns the bytes of a key that are hashed to route it.
+	KeyExtractor func(key string) []byte
 }
 
 // Option is a function that modifies the cache options.
@@ -60,6 +112,103 @@ func WithHashFactory(hashFactory HashFactory) Option {
 	}
 }
 
+// WithReadHashFactory returns an Option that sets the HashFactory used to
+// route Get operations, overriding HashFactory for reads only.
+//
+// Reads and writes must still agree on the bucket of every key: a key that is
+// written to one bucket and read from another is never found, and a Delete
+// routed elsewhere than the Set leaves a stale value behind. Different
+// factories are only safe when they compute the same bucket from different
+// inputs, for example when both derive it from an entity ID carried in the
+// context in a CQRS setup. Changing either factory on a populated cache
+// silently strands the existing entries.
+//
+// Parameters:
+//   - hashFactory: A function that creates hash instances for routing reads
+//
+// Returns:
+//   - An Option function that sets the ReadHashFactory
+func WithReadHashFactory(hashFactory HashFactory) Option {
+	return func(o *options) {
+		o.ReadHashFactory = hashFactory
+	}
+}
+
+// WithWriteHashFactory returns an Option that sets the HashFactory used to
+// route Set and Delete operations, overriding HashFactory for writes only.
+// See WithReadHashFactory for the pitfalls of routing reads and writes
+// differently.
+//
+// Parameters:
+//   - hashFactory: A function that creates hash instances for routing writes
+//
+// Returns:
+//   - An Option function that sets the WriteHashFactory
+func WithWriteHashFactory(hashFactory HashFactory) Option {
+	return func(o *options) {
+		o.WriteHashFactory = hashFactory
+	}
+}
+
+// WithReplicas returns an Option that stores each key in n buckets instead of one.
+// The replicas are the primary bucket chosen by the hash followed by the next
+// n-1 buckets in order, wrapping around at the end of the bucket list.
+//
+// Set and Delete are applied to every replica, so writes are amplified n times.
+// Get tries the replicas in order until one of them has the key, which keeps a
+// key readable when one bucket loses it, for example through eviction.
+//
+// Parameters:
+//   - n: The number of buckets each key is stored in, capped at the number of buckets
+//
+// Returns:
+//   - An Option function that sets Replicas
+func WithReplicas(n int) Option {
+	return func(o *options) {
+		o.Replicas = n
+	}
+}
+
+// WithReadFallback returns an Option that makes a Get try the next bucket
+// after the key's replicas when none of them has the key and at least one
+// failed with an error other than a miss, for example because its Redis node
+// is unreachable.
+//
+// This trades consistency for availability and is off by default. Set and
+// Delete still go to the key's own buckets only, so the fallback bucket holds
+// the key only if it was written there by an earlier layout or replica
+// configuration, and the value it returns may be stale. If the fallback
+// bucket doesn't have the key either, the original error is returned.
+//
+// Parameters:
+//   - enabled: Whether Get falls back to the next bucket
+//
+// Returns:
+//   - An Option function that sets ReadFallback
+func WithReadFallback(enabled bool) Option {
+	return func(o *options) {
+		o.ReadFallback = enabled
+	}
+}
+
+// WithKeyExtractor returns an Option that sets which bytes of a key are
+// hashed to route it, instead of the whole key. Keys with the same extracted
+// bytes land on the same buckets, so hashing only a tenant prefix, for
+// example, co-locates all keys of a tenant. The extractor applies to reads
+// and writes alike, so changing it on a populated cache strands existing
+// entries, as changing the hash factory does.
+//
+// Parameters:
+//   - f: A function returning the bytes of a key to hash
+//
+// Returns:
+//   - An Option function that sets the KeyExtractor
+func WithKeyExtractor(f func(key string) []byte) Option {
+	return func(o *options) {
+		o.KeyExtractor = f
+	}
+}
+
 // newOptions creates a new options instance with default values and applies
 // the provided options.
 //
@@ -88,7 +237,9 @@ func (o *options) Apply(opts ...Option) *options {
 }
 
 // Correct ensures that all options have valid default values.
-// If HashFactory is nil, it sets a default FNV-32a hash factory.
+// If HashFactory is nil, it sets a default FNV-32a hash factory, and the read
+// and write hash factories default to HashFactory. The whole key is hashed
+// unless a KeyExtractor is set.
 //
 // Returns:
 //   - A pointer to the corrected options instance
@@ -98,6 +249,23 @@ func (o *options) Correct() *options {
 			return fnv.New32a(), nil
 		}
 	}
+	// Route reads and writes alike by default
+	if o.ReadHashFactory == nil {
+		o.ReadHashFactory = o.HashFactory
+	}
+	if o.WriteHashFactory == nil {
+		o.WriteHashFactory = o.HashFactory
+	}
+	// Store each key in a single bucket by default
+	if o.Replicas <= 0 {
+		o.Replicas = 1
+	}
+	// Hash the full key by default
+	if o.KeyExtractor == nil {
+		o.KeyExtractor = func(key string) []byte {
+			return []byte(key)
+		}
+	}
 	return o
 }
 
@@ -116,11 +284,17 @@ func New(buckets []gouache.Cache, opts ...Option) gouache.Cache {
 	if len(buckets) == 0 {
 		panic("gouache: buckets is empty")
 	}
-	return &cache{Options: newOptions(opts...), Buckets: buckets}
+	options := newOptions(opts...)
+	// A key cannot be stored in more buckets than there are
+	if options.Replicas > len(buckets) {
+		options.Replicas = len(buckets)
+	}
+	return &cache{Options: options, Buckets: buckets}
 }
 
 // Get retrieves a value from the cache by its key.
-// The key is hashed to determine which bucket contains the value.
+// The key is hashed to determine which buckets contain the value, and the
+// buckets are tried in order until one of them returns the value.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -130,15 +304,43 @@ func New(buckets []gouache.Cache, opts ...Option) gouache.Cache {
 //   - The cached value or nil if not found
 //   - An error if the operation fails
 func (cache *cache) Get(ctx context.Context, key string) (any, error) {
-	bucket, err := cache.bucket(ctx, key)
+	// Include the fallback bucket, if enabled and distinct from the replicas
+	n := cache.Options.Replicas
+	if cache.Options.ReadFallback && n < len(cache.Buckets) {
+		n++
+	}
+	buckets, err := cache.replicas(ctx, key, cache.Options.ReadHashFactory, n)
 	if err != nil {
 		return nil, err
 	}
-	return bucket.Get(ctx, key)
+
+	// Try each replica until one of them has the key, remembering the
+	// first real error in case none of them do
+	var firstErr error
+	for _, bucket := range buckets[:cache.Options.Replicas] {
+		val, err := bucket.Get(ctx, key)
+		if err == nil {
+			return val, nil
+		}
+		if firstErr == nil && !errors.Is(err, gouache.ErrCacheMiss) {
+			firstErr = err
+		}
+	}
+
+	// Fall back to the next bucket when a replica failed
+	if firstErr != nil && len(buckets) > cache.Options.Replicas {
+		if val, err := buckets[cache.Options.Replicas].Get(ctx, key); err == nil {
+			return val, nil
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, gouache.ErrCacheMiss
 }
 
 // Set stores a value in the cache under the specified key.
-// The key is hashed to determine which bucket should store the value.
+// The key is hashed to determine which buckets should store the value.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -146,53 +348,103 @@ func (cache *cache) Get(ctx context.Context, key string) (any, error) {
 //   - val: The value to store
 //
 // Returns:
-//   - An error if the operation fails
+//   - An error if the operation fails on any replica
 func (cache *cache) Set(ctx context.Context, key string, val any) error {
-	bucket, err := cache.bucket(ctx, key)
+	buckets, err := cache.replicas(ctx, key, cache.Options.WriteHashFactory, cache.Options.Replicas)
 	if err != nil {
 		return err
 	}
-	return bucket.Set(ctx, key, val)
+
+	// Write to every replica, collecting failures
+	var errs []error
+	for _, bucket := range buckets {
+		if err := bucket.Set(ctx, key, val); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // Delete removes a value from the cache by its key.
-// The key is hashed to determine which bucket contains the value to delete.
+// The key is hashed to determine which buckets contain the value to delete.
 //
 // Parameters:
 //   - ctx: Context for the operation
 //   - key: The key of the value to delete
 //
 // Returns:
-//   - An error if the operation fails
+//   - An error if the operation fails on any replica
 func (cache *cache) Delete(ctx context.Context, key string) error {
-	bucket, err := cache.bucket(ctx, key)
+	buckets, err := cache.replicas(ctx, key, cache.Options.WriteHashFactory, cache.Options.Replicas)
 	if err != nil {
 		return err
 	}
-	return bucket.Delete(ctx, key)
+
+	// Delete from every replica, collecting failures
+	var errs []error
+	for _, bucket := range buckets {
+		if err := bucket.Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// bucket determines which bucket should handle operations for a given key.
-// It uses the configured HashFactory to hash the key and distribute it
-// across the available buckets.
+// Unwrap returns the buckets of the cache.
+//
+// Returns:
+//   - The underlying caches, in bucket order
+func (cache *cache) Unwrap() []gouache.Cache {
+	return cache.Buckets
+}
+
+// replicas determines which buckets hold the given key. The first bucket is
+// the one chosen by the hash, followed by the next n-1 buckets.
 //
 // Parameters:
 //   - ctx: Context for the operation
-//   - key: The key to determine the bucket for
+//   - key: The key to determine the buckets for
+//   - hashFactory: The HashFactory routing the operation
+//   - n: The number of buckets to return, at most the number of buckets
 //
 // Returns:
-//   - The gouache.Cache bucket that should handle operations for the key
-//   - An error if the hash factory or write operation fails
-func (cache *cache) bucket(ctx context.Context, key string) (gouache.Cache, error) {
-	// Create a new hash instance using the configured HashFactory
-	h, err := cache.Options.HashFactory(ctx, key)
+//   - The gouache.Cache buckets that hold the key, primary bucket first
+//   - A *RoutingError if the hash factory or write operation fails
+func (cache *cache) replicas(ctx context.Context, key string, hashFactory HashFactory, n int) ([]gouache.Cache, error) {
+	index, err := cache.index(ctx, key, hashFactory)
 	if err != nil {
 		return nil, err
 	}
 
-	// Write the key to the hash
-	if _, err := h.Write([]byte(key)); err != nil {
-		return nil, err
+	buckets := make([]gouache.Cache, 0, n)
+	for i := 0; i < n; i++ {
+		buckets = append(buckets, cache.Buckets[(index+i)%len(cache.Buckets)])
+	}
+	return buckets, nil
+}
+
+// index determines which bucket should handle operations for a given key.
+// It uses the given HashFactory to hash the key and distribute it across the
+// available buckets.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to determine the bucket for
+//   - hashFactory: The HashFactory routing the operation
+//
+// Returns:
+//   - The index of the bucket that should handle operations for the key
+//   - A *RoutingError if the hash factory or write operation fails
+func (cache *cache) index(ctx context.Context, key string, hashFactory HashFactory) (int, error) {
+	// Create a new hash instance using the given HashFactory
+	h, err := hashFactory(ctx, key)
+	if err != nil {
+		return 0, &RoutingError{Key: key, Err: err}
+	}
+
+	// Write the routed part of the key to the hash
+	if _, err := h.Write(cache.Options.KeyExtractor(key)); err != nil {
+		return 0, &RoutingError{Key: key, Err: err}
 	}
 
 	// Determine the bucket based on the hash size
@@ -200,20 +452,20 @@ func (cache *cache) bucket(ctx context.Context, key string) (gouache.Cache, erro
 	case 4:
 		// For 32-bit hashes, use the hash's Sum32 method
 		sum32 := h.(hash.Hash32).Sum32()
-		return cache.Buckets[sum32%uint32(len(cache.Buckets))], nil
+		return int(sum32 % uint32(len(cache.Buckets))), nil
 	case 8:
 		// For 64-bit hashes, use the hash's Sum64 method
 		sum64 := h.(hash.Hash64).Sum64()
-		return cache.Buckets[sum64%uint64(len(cache.Buckets))], nil
+		return int(sum64 % uint64(len(cache.Buckets))), nil
 	default:
 		// For other hash sizes, use the raw bytes
 		sum := h.Sum(nil)
 		// If the hash is less than 4 bytes, use the first bucket
 		if len(sum) < 4 {
-			return cache.Buckets[0], nil
+			return 0, nil
 		}
 		// Extract a 32-bit value from the hash and use it to determine the bucket
-		sum32 := int(binary.BigEndian.Uint32(sum[:4]))
-		return cache.Buckets[sum32%len(cache.Buckets)], nil
+		sum32 := binary.BigEndian.Uint32(sum[:4])
+		return int(sum32 % uint32(len(cache.Buckets))), nil
 	}
 }
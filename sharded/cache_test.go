@@ -2,8 +2,11 @@ package gouache
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"hash"
 	"hash/fnv"
+	"strings"
 	"testing"
 
 	"github.com/soyacen/gouache"
@@ -207,3 +210,284 @@ func TestShardedCache_BucketDistribution(t *testing.T) {
 		t.Errorf("Expected total keys to be %d, but got %d", len(keys), bucket1Count+bucket2Count)
 	}
 }
+
+// TestShardedCache_WithKeyExtractor tests that keys with the same extracted prefix share a bucket.
+func TestShardedCache_WithKeyExtractor(t *testing.T) {
+	buckets := []*mockCache{newMockCache(), newMockCache(), newMockCache(), newMockCache()}
+	cache := New([]gouache.Cache{buckets[0], buckets[1], buckets[2], buckets[3]},
+		WithKeyExtractor(func(key string) []byte {
+			tenant, _, _ := strings.Cut(key, ":")
+			return []byte(tenant)
+		}))
+	ctx := context.Background()
+
+	// Every key of a tenant lands on the same bucket
+	tenants := []string{"acme", "globex", "initech", "umbrella", "hooli"}
+	for _, tenant := range tenants {
+		for i := 0; i < 20; i++ {
+			if err := cache.Set(ctx, fmt.Sprintf("%s:%d", tenant, i), i); err != nil {
+				t.Fatalf("Unexpected error when setting a key: %v", err)
+			}
+		}
+		holders := 0
+		for _, bucket := range buckets {
+			if _, ok := bucket.data[tenant+":0"]; ok {
+				holders++
+				for i := 0; i < 20; i++ {
+					if _, ok := bucket.data[fmt.Sprintf("%s:%d", tenant, i)]; !ok {
+						t.Errorf("Expected all keys of %s on one bucket", tenant)
+						break
+					}
+				}
+			}
+		}
+		if holders != 1 {
+			t.Errorf("Expected the keys of %s on exactly one bucket, got %d", tenant, holders)
+		}
+	}
+
+	// Reads are routed the same way
+	if val, err := cache.Get(ctx, "acme:7"); err != nil || val != 7 {
+		t.Errorf("Expected 7, got %v, %v", val, err)
+	}
+}
+
+// TestShardedCache_WithReplicas tests that a replicated key survives one bucket being cleared.
+func TestShardedCache_WithReplicas(t *testing.T) {
+	buckets := []*mockCache{newMockCache(), newMockCache(), newMockCache()}
+	cache := New([]gouache.Cache{buckets[0], buckets[1], buckets[2]}, WithReplicas(2))
+
+	// Set a value, which should be written to exactly two buckets
+	key := "test-key"
+	value := "test-value"
+	err := cache.Set(context.Background(), key, value)
+	if err != nil {
+		t.Fatalf("Failed to set up test value: %v", err)
+	}
+
+	holders := 0
+	for _, bucket := range buckets {
+		if _, ok := bucket.data[key]; ok {
+			holders++
+		}
+	}
+	if holders != 2 {
+		t.Errorf("Expected key to be stored in 2 buckets, but got %d", holders)
+	}
+
+	// Clear each bucket in turn and verify the key is still readable
+	for i, bucket := range buckets {
+		saved := bucket.data
+		bucket.data = make(map[string]any)
+
+		result, err := cache.Get(context.Background(), key)
+		if err != nil {
+			t.Errorf("Unexpected error after clearing bucket %d: %v", i, err)
+		}
+		if result != value {
+			t.Errorf("Expected %v after clearing bucket %d, but got %v", value, i, result)
+		}
+
+		bucket.data = saved
+	}
+
+	// Delete must remove the key from all replicas
+	err = cache.Delete(context.Background(), key)
+	if err != nil {
+		t.Errorf("Unexpected error when deleting value: %v", err)
+	}
+	for i, bucket := range buckets {
+		if _, ok := bucket.data[key]; ok {
+			t.Errorf("Expected key to be deleted from bucket %d", i)
+		}
+	}
+	_, err = cache.Get(context.Background(), key)
+	if err != gouache.ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss after deletion, but got: %v", err)
+	}
+}
+
+// fixedHash is a 32-bit hash that ignores its input and always sums to a fixed value.
+type fixedHash struct {
+	hash.Hash32
+	sum uint32
+}
+
+func (h fixedHash) Write(p []byte) (int, error) { return len(p), nil }
+func (h fixedHash) Size() int                   { return 4 }
+func (h fixedHash) Sum32() uint32               { return h.sum }
+
+// fixedHashFactory returns a HashFactory routing every key to the bucket at index.
+func fixedHashFactory(index uint32) HashFactory {
+	return func(ctx context.Context, key string) (hash.Hash, error) {
+		return fixedHash{Hash32: fnv.New32a(), sum: index}, nil
+	}
+}
+
+// TestShardedCache_WithReadWriteHashFactories tests that reads and writes can be routed differently.
+func TestShardedCache_WithReadWriteHashFactories(t *testing.T) {
+	buckets := []*mockCache{newMockCache(), newMockCache()}
+	cache := New([]gouache.Cache{buckets[0], buckets[1]},
+		WithReadHashFactory(fixedHashFactory(0)),
+		WithWriteHashFactory(fixedHashFactory(1)),
+	)
+
+	// Writes go to the second bucket
+	err := cache.Set(context.Background(), "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+	if _, ok := buckets[1].data["test-key"]; !ok || len(buckets[0].data) != 0 {
+		t.Errorf("Expected the key to be written to bucket 1 only")
+	}
+
+	// Reads go to the first bucket
+	buckets[0].data["test-key"] = "read-value"
+	result, err := cache.Get(context.Background(), "test-key")
+	if err != nil {
+		t.Errorf("Unexpected error when getting value: %v", err)
+	}
+	if result != "read-value" {
+		t.Errorf("Expected read-value from bucket 0, but got %v", result)
+	}
+
+	// Deletes go to the second bucket
+	err = cache.Delete(context.Background(), "test-key")
+	if err != nil {
+		t.Errorf("Unexpected error when deleting value: %v", err)
+	}
+	if _, ok := buckets[1].data["test-key"]; ok {
+		t.Error("Expected the key to be deleted from bucket 1")
+	}
+	if _, ok := buckets[0].data["test-key"]; !ok {
+		t.Error("Expected the key in bucket 0 to be left alone")
+	}
+}
+
+// TestShardedCache_ReadWriteHashFactoryDefaults tests that an unset read or write factory falls back to HashFactory.
+func TestShardedCache_ReadWriteHashFactoryDefaults(t *testing.T) {
+	buckets := []*mockCache{newMockCache(), newMockCache()}
+	cache := New([]gouache.Cache{buckets[0], buckets[1]},
+		WithHashFactory(fixedHashFactory(1)),
+		WithReadHashFactory(fixedHashFactory(1)),
+	)
+
+	err := cache.Set(context.Background(), "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Unexpected error when setting value: %v", err)
+	}
+	if _, ok := buckets[1].data["test-key"]; !ok {
+		t.Error("Expected the write to be routed by HashFactory to bucket 1")
+	}
+	result, err := cache.Get(context.Background(), "test-key")
+	if err != nil || result != "test-value" {
+		t.Errorf("Expected test-value, but got %v, %v", result, err)
+	}
+}
+
+// failingHash is a hash whose Write always fails.
+type failingHash struct {
+	hash.Hash32
+}
+
+func (failingHash) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+// TestShardedCache_RoutingError tests that hash failures are reported as routing errors.
+func TestShardedCache_RoutingError(t *testing.T) {
+	factoryErr := errors.New("factory failed")
+	tests := []struct {
+		name        string
+		hashFactory HashFactory
+		cause       error
+	}{
+		{
+			name:  "HashFactory fails",
+			cause: factoryErr,
+			hashFactory: func(ctx context.Context, key string) (hash.Hash, error) {
+				return nil, factoryErr
+			},
+		},
+		{
+			name: "Write fails",
+			hashFactory: func(ctx context.Context, key string) (hash.Hash, error) {
+				return failingHash{fnv.New32a()}, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := New([]gouache.Cache{newMockCache(), newMockCache()}, WithHashFactory(tt.hashFactory))
+			errs := []error{
+				cache.Set(context.Background(), "test-key", "test-value"),
+				cache.Delete(context.Background(), "test-key"),
+			}
+			_, err := cache.Get(context.Background(), "test-key")
+			errs = append(errs, err)
+
+			for _, err := range errs {
+				if !errors.Is(err, ErrShardRouting) {
+					t.Errorf("Expected ErrShardRouting, but got: %v", err)
+				}
+				var routingErr *RoutingError
+				if !errors.As(err, &routingErr) || routingErr.Key != "test-key" {
+					t.Errorf("Expected a RoutingError for test-key, but got: %v", err)
+				}
+			}
+			if tt.cause != nil && !errors.Is(errs[0], tt.cause) {
+				t.Errorf("Expected the cause to be wrapped, but got: %v", errs[0])
+			}
+		})
+	}
+}
+
+// failingCache is a bucket whose operations always fail.
+type failingCache struct {
+	err error
+}
+
+func (f failingCache) Get(ctx context.Context, key string) (any, error)   { return nil, f.err }
+func (f failingCache) Set(ctx context.Context, key string, val any) error { return f.err }
+func (f failingCache) Delete(ctx context.Context, key string) error       { return f.err }
+
+// TestShardedCache_WithReadFallback tests that Get falls back to the next bucket when its bucket fails.
+func TestShardedCache_WithReadFallback(t *testing.T) {
+	down := errors.New("bucket down")
+	next := newMockCache()
+	next.data["test-key"] = "stale-value"
+	buckets := []gouache.Cache{failingCache{err: down}, next}
+
+	// Without the option the error is returned
+	cache := New(buckets, WithHashFactory(fixedHashFactory(0)))
+	if _, err := cache.Get(context.Background(), "test-key"); !errors.Is(err, down) {
+		t.Errorf("Expected the bucket error, but got: %v", err)
+	}
+
+	// With the option the next bucket is read
+	cache = New(buckets, WithHashFactory(fixedHashFactory(0)), WithReadFallback(true))
+	result, err := cache.Get(context.Background(), "test-key")
+	if err != nil || result != "stale-value" {
+		t.Errorf("Expected stale-value from the fallback bucket, but got %v, %v", result, err)
+	}
+
+	// A miss in the fallback bucket returns the original error
+	if _, err := cache.Get(context.Background(), "other-key"); !errors.Is(err, down) {
+		t.Errorf("Expected the bucket error, but got: %v", err)
+	}
+
+	// Writes still go to the primary bucket only
+	if err := cache.Set(context.Background(), "new-key", "value"); !errors.Is(err, down) {
+		t.Errorf("Expected the bucket error on Set, but got: %v", err)
+	}
+	if _, ok := next.data["new-key"]; ok {
+		t.Error("Expected Set not to write to the fallback bucket")
+	}
+
+	// A plain miss doesn't fall back
+	cache = New([]gouache.Cache{newMockCache(), next}, WithHashFactory(fixedHashFactory(0)), WithReadFallback(true))
+	if _, err := cache.Get(context.Background(), "test-key"); err != gouache.ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+	}
+}
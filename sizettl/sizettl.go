@@ -0,0 +1,155 @@
+// Package sizettl builds TTL functions that give larger values shorter TTLs.
+//
+// A cache holding values of very different sizes frees memory fastest by
+// expiring its largest values first. The function returned by New gives
+// values up to a reference size the maximum TTL, and larger values a TTL
+// inversely proportional to their size, never below the minimum TTL: a value
+// twice the reference size lives half as long.
+//
+// The function has the signature of the TTL fields of the redis, fc, bc and
+// gc backends, so it plugs into any of them:
+//
+//	cache := redis.New(client, redis.WithTTL(sizettl.New(time.Minute, time.Hour)))
+//
+// Backends still scale and clamp the result with the context's TTL
+// multiplier and ceiling.
+package sizettl
+
+import (
+	"context"
+	"time"
+)
+
+// Sizer is a function type that returns the size in bytes of a value, usually
+// its marshaled size. It returns a negative size if the size is unknown.
+type Sizer func(key string, val any) int
+
+// options holds configuration options for the TTL function.
+type options struct {
+	// Sizer estimates the size of each value.
+	Sizer Sizer
+
+	// ReferenceSize is the largest size that still gets the maximum TTL.
+	ReferenceSize int
+}
+
+// Option is a function that modifies the TTL function options.
+type Option func(*options)
+
+// WithSizer returns an Option that sets the function estimating the size of
+// each value, for example by marshaling it with the backend's codec. By
+// default byte slices and strings are measured by their length, and values
+// of other types have an unknown size. Values of unknown size get the maximum
+// TTL.
+//
+// Parameters:
+//   - sizer: A function that returns the size of a value
+//
+// Returns:
+//   - An Option function that sets the Sizer
+func WithSizer(sizer Sizer) Option {
+	return func(o *options) {
+		o.Sizer = sizer
+	}
+}
+
+// WithReferenceSize returns an Option that sets the largest size, in bytes,
+// that still gets the maximum TTL. A value n times larger gets 1/n of it.
+// It defaults to 1 KiB.
+//
+// Parameters:
+//   - size: The reference size in bytes
+//
+// Returns:
+//   - An Option function that sets the ReferenceSize
+func WithReferenceSize(size int) Option {
+	return func(o *options) {
+		o.ReferenceSize = size
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Measure byte slices and strings by default
+	if o.Sizer == nil {
+		o.Sizer = func(key string, val any) int {
+			switch v := val.(type) {
+			case []byte:
+				return len(v)
+			case string:
+				return len(v)
+			default:
+				return -1
+			}
+		}
+	}
+
+	// Set default reference size to 1 KiB if not specified or invalid
+	if o.ReferenceSize <= 0 {
+		o.ReferenceSize = 1024
+	}
+	return o
+}
+
+// New returns a TTL function giving values up to the reference size the
+// maximum TTL, and larger values a TTL inversely proportional to their size,
+// bounded below by the minimum TTL. If minTTL exceeds maxTTL, maxTTL is used
+// for both.
+//
+// Parameters:
+//   - minTTL: The TTL of the largest values
+//   - maxTTL: The TTL of values up to the reference size, and of values of unknown size
+//   - opts: Variable number of Option functions to configure the function
+//
+// Returns:
+//   - A TTL function for the TTL field of a backend
+func New(minTTL, maxTTL time.Duration, opts ...Option) func(ctx context.Context, key string, val any) (time.Duration, error) {
+	o := newOptions(opts...)
+	if minTTL > maxTTL {
+		minTTL = maxTTL
+	}
+	return func(ctx context.Context, key string, val any) (time.Duration, error) {
+		// Values of unknown size and values up to the reference size live longest
+		size := o.Sizer(key, val)
+		if size <= o.ReferenceSize {
+			return maxTTL, nil
+		}
+
+		// Scale the TTL down in proportion to the size
+		ttl := time.Duration(float64(maxTTL) * float64(o.ReferenceSize) / float64(size))
+		if ttl < minTTL {
+			ttl = minTTL
+		}
+		return ttl, nil
+	}
+}
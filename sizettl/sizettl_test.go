@@ -0,0 +1,63 @@
+package sizettl
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNew tests that large values get the minimum TTL and small ones the maximum
+func TestNew(t *testing.T) {
+	ttlFunc := New(time.Minute, time.Hour)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		val  any
+		want time.Duration
+	}{
+		{name: "Tiny", val: "x", want: time.Hour},
+		{name: "Reference size", val: make([]byte, 1024), want: time.Hour},
+		{name: "Twice the reference size", val: make([]byte, 2048), want: 30 * time.Minute},
+		{name: "Large", val: strings.Repeat("x", 1<<20), want: time.Minute},
+		{name: "Unknown size", val: struct{}{}, want: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttl, err := ttlFunc(ctx, "key", tt.val)
+			if err != nil || ttl != tt.want {
+				t.Errorf("Expected %v, got %v, %v", tt.want, ttl, err)
+			}
+		})
+	}
+}
+
+// TestNew_Options tests the sizer and reference size options
+func TestNew_Options(t *testing.T) {
+	// Size values by their JSON encoding, with a small reference size
+	ttlFunc := New(time.Second, time.Minute,
+		WithReferenceSize(10),
+		WithSizer(func(key string, val any) int {
+			data, err := json.Marshal(val)
+			if err != nil {
+				return -1
+			}
+			return len(data)
+		}),
+	)
+	ctx := context.Background()
+
+	if ttl, _ := ttlFunc(ctx, "key", 1); ttl != time.Minute {
+		t.Errorf("Expected the maximum TTL for a small value, got %v", ttl)
+	}
+	if ttl, _ := ttlFunc(ctx, "key", map[string]string{"name": "a somewhat longer value"}); ttl >= time.Minute || ttl <= time.Second {
+		t.Errorf("Expected a TTL between the bounds, got %v", ttl)
+	}
+
+	// A minimum above the maximum is lowered to it
+	if ttl, _ := New(time.Hour, time.Minute)(ctx, "key", strings.Repeat("x", 1<<20)); ttl != time.Minute {
+		t.Errorf("Expected the maximum TTL, got %v", ttl)
+	}
+}
@@ -0,0 +1,302 @@
+// Package sliding provides a cache implementation with sliding expiration.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// extending the time-to-live of an entry every time it is read, so entries
+// that keep being used never expire while idle ones do, as is common for
+// session caches.
+package sliding
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// Gopher is a function type that executes a given function asynchronously.
+// It's used to extend TTLs in the background.
+type Gopher func(f func()) error
+
+// options holds configuration options for the sliding expiration cache.
+type options struct {
+	// Window is the time-to-live an entry is given when it is set or read.
+	Window time.Duration
+
+	// TouchTimeout is the timeout for the background TTL extension.
+	TouchTimeout time.Duration
+
+	// ErrorHandler is called when an error occurs during the background TTL extension.
+	// It is never called with gouache.ErrCacheMiss.
+	ErrorHandler gouache.ErrorHandler
+
+	// Gopher is responsible for executing functions asynchronously.
+	Gopher Gopher
+
+	// Rewrite makes Get extend the TTL of caches without Touch by storing the
+	// value just read again.
+	Rewrite bool
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithWindow returns an Option that sets the sliding window, the time-to-live
+// an entry is given when it is set and every time it is read.
+//
+// Parameters:
+//   - d: The duration of the sliding window
+//
+// Returns:
+//   - An Option function that sets the Window
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.Window = d
+	}
+}
+
+// WithTouchTimeout returns an Option that sets the timeout for the background
+// TTL extension.
+//
+// Parameters:
+//   - d: The timeout duration for the TTL extension
+//
+// Returns:
+//   - An Option function that sets the TouchTimeout
+func WithTouchTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.TouchTimeout = d
+	}
+}
+
+// WithErrorHandler returns an Option that sets a custom error handler for
+// errors that occur during the background TTL extension.
+//
+// Parameters:
+//   - f: A function to handle errors
+//
+// Returns:
+//   - An Option function that sets the ErrorHandler
+func WithErrorHandler(f gouache.ErrorHandler) Option {
+	return func(o *options) {
+		o.ErrorHandler = f
+	}
+}
+
+// WithGopher returns an Option that sets a custom Gopher function for
+// executing the background TTL extension.
+//
+// Parameters:
+//   - gopher: A function that executes other functions asynchronously
+//
+// Returns:
+//   - An Option function that sets the Gopher
+func WithGopher(gopher Gopher) Option {
+	return func(o *options) {
+		o.Gopher = gopher
+	}
+}
+
+// WithRewrite returns an Option that makes Get extend the TTL of entries in
+// caches that don't implement gouache.Toucher by storing the value just read
+// again. It is disabled by default because the rewrite races with concurrent
+// writes: a Delete or Set of the key between the read and the rewrite is
+// undone, bringing a deleted key back or restoring a stale value for a full
+// window. Enable it only when such writes are rare or harmless.
+//
+// Parameters:
+//   - enabled: Whether to rewrite entries when the cache can't Touch them
+//
+// Returns:
+//   - An Option function that sets Rewrite
+func WithRewrite(enabled bool) Option {
+	return func(o *options) {
+		o.Rewrite = enabled
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...).Correct()
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Correct ensures that all options have valid default values.
+//
+// Returns:
+//   - A pointer to the corrected options instance
+func (o *options) Correct() *options {
+	// Set default window to 30 minutes if not specified or invalid
+	if o.Window <= 0 {
+		o.Window = 30 * time.Minute
+	}
+
+	// Set default touch timeout to 5s if not specified or invalid
+	if o.TouchTimeout <= 0 {
+		o.TouchTimeout = 5 * time.Second
+	}
+
+	// Set default error handler if not specified
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = gouache.DefaultErrorHandler("sliding.Cache.Get")
+	}
+
+	// Set default Gopher if not specified
+	if o.Gopher == nil {
+		o.Gopher = func(f func()) error {
+			go f()
+			return nil
+		}
+	}
+	return o
+}
+
+// cache is a cache implementation that extends the TTL of an entry on every
+// successful read.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+}
+
+// New creates a new sliding expiration cache instance wrapping the specified cache.
+//
+// The TTL is extended with Touch if the underlying cache implements
+// gouache.Toucher. Otherwise it is only extended if WithRewrite is enabled,
+// by storing the value just read again, with SetWithTTL if the cache
+// implements gouache.TTLSetter, or with Set, in which case the window is left
+// to the cache's own TTL configuration.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation with sliding expiration
+func New(c gouache.Cache, opts ...Option) gouache.Cache {
+	return &cache{Options: newOptions(opts...), Cache: c}
+}
+
+// Get retrieves a value from the underlying cache by its key. On success, the
+// entry's TTL is extended by the window in the background, so the latency of
+// Get is not affected.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	// Get the value from the underlying cache
+	val, err := cache.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Schedule the TTL extension, reporting scheduling failures without failing the read
+	if err := cache.Options.Gopher(func() {
+		// Create a new context without the original cancellation
+		ctx := context.WithoutCancel(ctx)
+
+		// Add timeout to the context
+		ctx, cancel := context.WithTimeout(ctx, cache.Options.TouchTimeout)
+		defer cancel()
+
+		// Extend the TTL, ignoring keys that are gone in the meantime
+		if err := cache.touch(ctx, key, val); err != nil && !errors.Is(err, gouache.ErrCacheMiss) {
+			cache.Options.ErrorHandler(err)
+		}
+	}); err != nil {
+		cache.Options.ErrorHandler(err)
+	}
+	return val, nil
+}
+
+// Set stores a value in the underlying cache, with the window as its TTL if
+// the cache implements gouache.TTLSetter.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	if setter, ok := cache.Cache.(gouache.TTLSetter); ok && gouache.Capabilities(cache.Cache).SetWithTTL {
+		return setter.SetWithTTL(ctx, key, val, cache.Options.Window)
+	}
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
+
+// touch extends the TTL of an entry by the window, emulating Touch by storing
+// val again when the underlying cache doesn't support it and Rewrite is
+// enabled. Without either, the TTL is left as is.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - val: The value just read for key
+//
+// Returns:
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) touch(ctx context.Context, key string, val any) error {
+	if toucher, ok := cache.Cache.(gouache.Toucher); ok && gouache.Capabilities(cache.Cache).Touch {
+		return toucher.Touch(ctx, key, cache.Options.Window)
+	}
+	if !cache.Options.Rewrite {
+		return nil
+	}
+	return cache.Set(ctx, key, val)
+}
@@ -0,0 +1,192 @@
+package sliding
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// touchCache is a cache that records the TTLs passed to Touch and SetWithTTL.
+type touchCache struct {
+	sample.Cache
+	mu      sync.Mutex
+	touched map[string]time.Duration
+	set     map[string]time.Duration
+}
+
+// newTouchCache creates a new touchCache instance.
+func newTouchCache() *touchCache {
+	return &touchCache{touched: make(map[string]time.Duration), set: make(map[string]time.Duration)}
+}
+
+func (c *touchCache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	c.mu.Lock()
+	c.set[key] = ttl
+	c.mu.Unlock()
+	return c.Cache.Set(ctx, key, val)
+}
+
+func (c *touchCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if _, err := c.Cache.Get(ctx, key); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.touched[key] = ttl
+	c.mu.Unlock()
+	return nil
+}
+
+// syncGopher runs functions synchronously so tests can observe their effects.
+func syncGopher(f func()) error {
+	f()
+	return nil
+}
+
+// TestCache_Touch tests that Get extends the TTL through Touch
+func TestCache_Touch(t *testing.T) {
+	backend := newTouchCache()
+	cache := New(backend, WithWindow(time.Minute), WithGopher(syncGopher))
+	ctx := context.Background()
+
+	// Set stores with the window as TTL
+	err := cache.Set(ctx, "key", "value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if backend.set["key"] != time.Minute {
+		t.Errorf("Expected SetWithTTL with 1m, got %v", backend.set["key"])
+	}
+
+	// Get extends the TTL by the window
+	val, err := cache.Get(ctx, "key")
+	if err != nil || val != "value" {
+		t.Fatalf("Expected value, got %v, %v", val, err)
+	}
+	if backend.touched["key"] != time.Minute {
+		t.Errorf("Expected Touch with 1m, got %v", backend.touched["key"])
+	}
+
+	// A miss doesn't touch anything
+	_, err = cache.Get(ctx, "missing")
+	if !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss, got %v", err)
+	}
+	if _, ok := backend.touched["missing"]; ok {
+		t.Error("Expected missing key not to be touched")
+	}
+}
+
+// countingCache is a cache without Touch that counts Set calls.
+type countingCache struct {
+	sample.Cache
+	mu   sync.Mutex
+	sets int
+}
+
+func (c *countingCache) Set(ctx context.Context, key string, val any) error {
+	c.mu.Lock()
+	c.sets++
+	c.mu.Unlock()
+	return c.Cache.Set(ctx, key, val)
+}
+
+// TestCache_ReSet tests that Get falls back to storing the value again when
+// Rewrite is enabled
+func TestCache_ReSet(t *testing.T) {
+	backend := &countingCache{}
+	cache := New(backend, WithGopher(syncGopher), WithRewrite(true))
+	ctx := context.Background()
+
+	err := cache.Set(ctx, "key", "value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// Get stores the value again
+	val, err := cache.Get(ctx, "key")
+	if err != nil || val != "value" {
+		t.Fatalf("Expected value, got %v, %v", val, err)
+	}
+	if backend.sets != 2 {
+		t.Errorf("Expected 2 sets, got %d", backend.sets)
+	}
+}
+
+// TestCache_NoRewrite tests that, by default, Get doesn't store the value
+// again in a cache without Touch, so it can't undo a concurrent Delete
+func TestCache_NoRewrite(t *testing.T) {
+	backend := &countingCache{}
+	var touch func()
+	cache := New(backend, WithGopher(func(f func()) error {
+		touch = f
+		return nil
+	}))
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Fatalf("Expected value, got %v, %v", val, err)
+	}
+
+	// The key is deleted before the background extension runs
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	touch()
+	if backend.sets != 1 {
+		t.Errorf("Expected 1 set, got %d", backend.sets)
+	}
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected the deleted key to stay deleted, got %v", err)
+	}
+}
+
+// TestCache_Async tests that Get doesn't wait for the TTL extension
+func TestCache_Async(t *testing.T) {
+	backend := newTouchCache()
+	release := make(chan struct{})
+	done := make(chan struct{})
+	cache := New(backend, WithGopher(func(f func()) error {
+		go func() {
+			<-release
+			f()
+			close(done)
+		}()
+		return nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := cache.Set(ctx, "key", "value")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// Get returns before the extension runs
+	_, err = cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	backend.mu.Lock()
+	_, touched := backend.touched["key"]
+	backend.mu.Unlock()
+	if touched {
+		t.Error("Expected Touch not to have run yet")
+	}
+
+	// The extension still runs after the request context is canceled
+	cancel()
+	close(release)
+	<-done
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if backend.touched["key"] != 30*time.Minute {
+		t.Errorf("Expected Touch with the default window, got %v", backend.touched["key"])
+	}
+}
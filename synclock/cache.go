@@ -0,0 +1,403 @@
+// Package synclock provides a cache implementation that serializes all
+// operations on a wrapped cache through a single lock.
+//
+// This package implements the gouache.Cache interface by guarding a cache that
+// is not safe for concurrent use, so that it can be shared between goroutines
+// or wrapped by decorators such as sharded that call it concurrently.
+//
+// The optional interfaces of the wrapped cache are forwarded under the same
+// lock, so wrapping doesn't hide a backend's batch or TTL support.
+package synclock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchSetter interface at compile time.
+var _ gouache.BatchSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Iterable interface at compile time.
+var _ gouache.Iterable = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*cache)(nil)
+
+// Ensure that cache implements the gouache.CompareDeleter interface at compile time.
+var _ gouache.CompareDeleter = (*cache)(nil)
+
+// Ensure that cache forwards capability probing at compile time.
+var _ gouache.CapabilityReporter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// options holds configuration options for the synchronized cache.
+type options struct {
+	// RWMutex allows Get operations to run concurrently with each other.
+	RWMutex bool
+}
+
+// Option is a function that modifies the cache options.
+type Option func(*options)
+
+// WithRWMutex returns an Option that lets Get operations share a read lock.
+//
+// Only enable this when concurrent Gets are safe for the wrapped cache, i.e.
+// its Get does not mutate internal state. An LRU that reorders entries on
+// every read, for example, must keep exclusive locking.
+//
+// Parameters:
+//   - enabled: Whether Get operations take a shared read lock
+//
+// Returns:
+//   - An Option function that sets RWMutex
+func WithRWMutex(enabled bool) Option {
+	return func(o *options) {
+		o.RWMutex = enabled
+	}
+}
+
+// newOptions creates a new options instance with default values and applies
+// the provided options.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the configured options instance
+func newOptions(opts ...Option) *options {
+	options := &options{}
+	return options.Apply(opts...)
+}
+
+// Apply applies the provided options to the options instance.
+//
+// Parameters:
+//   - opts: Variable number of Option functions to apply
+//
+// Returns:
+//   - A pointer to the modified options instance
+func (o *options) Apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// cache is a cache implementation that guards every operation on the
+// underlying cache with a lock.
+type cache struct {
+	// Options contains configuration options for the cache
+	Options *options
+
+	// Forwarder holds the underlying cache and forwards the operations the
+	// cache doesn't intercept to it
+	gouache.Forwarder
+
+	// mu serializes access to the underlying cache
+	mu sync.RWMutex
+}
+
+// New creates a new synchronized cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation, which need not be concurrency-safe
+//   - opts: Variable number of Option functions to configure the cache
+//
+// Returns:
+//   - A gouache.Cache implementation that is safe for concurrent use
+func New(c gouache.Cache, opts ...Option) gouache.Cache {
+	return &cache{Options: newOptions(opts...), Forwarder: gouache.Forwarder{Cache: c}}
+}
+
+// Get retrieves a value from the cache by its key while holding the lock.
+// With WithRWMutex enabled, concurrent Gets share a read lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The cached value or nil if not found
+//   - An error if the operation fails
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	if cache.Options.RWMutex {
+		cache.mu.RLock()
+		defer cache.mu.RUnlock()
+	} else {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+	}
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set stores a value in the cache under the specified key while holding the
+// exclusive lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete removes a value from the cache by its key while holding the
+// exclusive lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.Cache.Delete(ctx, key)
+}
+
+// readLock takes the lock used by read-only operations, which is the shared
+// read lock with WithRWMutex enabled and the exclusive lock otherwise.
+//
+// Returns:
+//   - A function that releases the lock
+func (cache *cache) readLock() func() {
+	if cache.Options.RWMutex {
+		cache.mu.RLock()
+		return cache.mu.RUnlock
+	}
+	cache.mu.Lock()
+	return cache.mu.Unlock
+}
+
+// GetMulti retrieves the values for the given keys while holding the same
+// lock as Get, using the native GetMulti of the underlying cache when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys to retrieve the values for
+//
+// Returns:
+//   - A map from each found key to its cached value
+//   - An error if the operation fails
+func (cache *cache) GetMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	defer cache.readLock()()
+	return gouache.GetMulti(ctx, cache.Cache, keys)
+}
+
+// SetMulti stores each value in vals while holding the exclusive lock, using
+// the native SetMulti of the underlying cache when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return gouache.SetMulti(ctx, cache.Cache, vals)
+}
+
+// DeleteMulti removes the values of the given keys while holding the
+// exclusive lock, using the native DeleteMulti of the underlying cache when
+// available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) DeleteMulti(ctx context.Context, keys []string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return gouache.DeleteMulti(ctx, cache.Cache, keys)
+}
+
+// DeleteMultiResult removes the values of the given keys while holding the
+// exclusive lock and reports which of them existed, using the native
+// DeleteMultiResult of the underlying cache when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - The keys that existed and were deleted, in the order of keys
+//   - An error if the operation fails
+func (cache *cache) DeleteMultiResult(ctx context.Context, keys []string) ([]string, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return gouache.DeleteMultiResult(ctx, cache.Cache, keys)
+}
+
+// Iterate calls fn for every key of the underlying cache matching match.
+//
+// The keys are collected while holding the read lock and fn is called after
+// it is released, so fn may itself use the cache without deadlocking.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - match: The pattern keys must match, or empty to match all keys
+//   - fn: The function called for each key
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't iterable, or an error if the operation fails
+func (cache *cache) Iterate(ctx context.Context, match string, fn func(key string) error) error {
+	iterable, ok := cache.Cache.(gouache.Iterable)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+
+	// Snapshot the keys under the lock
+	var keys []string
+	unlock := cache.readLock()
+	err := iterable.Iterate(ctx, match, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	unlock()
+	if err != nil {
+		return err
+	}
+
+	// Hand each key to the callback without holding the lock
+	for _, key := range keys {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add stores a value only if the key is absent, while holding the exclusive lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - gouache.ErrUnsupported if the underlying cache isn't an Adder, or an error if the operation fails
+func (cache *cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	adder, ok := cache.Cache.(gouache.Adder)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return adder.Add(ctx, key, val)
+}
+
+// SetWithTTL stores a value with an explicit TTL while holding the exclusive lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLSetter, or an error if the operation fails
+func (cache *cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	setter, ok := cache.Cache.(gouache.TTLSetter)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return setter.SetWithTTL(ctx, key, val, ttl)
+}
+
+// ReadTTL returns the remaining time-to-live of an entry while holding the
+// same lock as Get.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//
+// Returns:
+//   - The remaining time-to-live
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLReader, or an error if the operation fails
+func (cache *cache) ReadTTL(ctx context.Context, key string) (time.Duration, error) {
+	reader, ok := cache.Cache.(gouache.TTLReader)
+	if !ok {
+		return 0, gouache.ErrUnsupported
+	}
+	defer cache.readLock()()
+	return reader.ReadTTL(ctx, key)
+}
+
+// Touch sets a new time-to-live for an entry while holding the exclusive lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The new time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a Toucher, or an error if the operation fails
+func (cache *cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	toucher, ok := cache.Cache.(gouache.Toucher)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return toucher.Touch(ctx, key, ttl)
+}
+
+// CompareAndDelete deletes the entry stored under key if it still holds
+// expected, while holding the exclusive lock.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - expected: The value the entry must hold to be deleted
+//
+// Returns:
+//   - true if the entry was deleted, false if it was missing or held another value
+//   - gouache.ErrUnsupported if the underlying cache isn't a CompareDeleter, or an error if the operation fails
+func (cache *cache) CompareAndDelete(ctx context.Context, key string, expected any) (bool, error) {
+	deleter, ok := cache.Cache.(gouache.CompareDeleter)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return deleter.CompareAndDelete(ctx, key, expected)
+}
@@ -0,0 +1,151 @@
+package synclock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// unsafeCache is a map-backed cache that is not safe for concurrent use.
+// It records the peak number of concurrent callers to detect overlapping access.
+type unsafeCache struct {
+	data    map[string]any
+	active  int32
+	peak    int32
+	getWait time.Duration
+}
+
+// newUnsafeCache creates a new unsafeCache instance.
+func newUnsafeCache() *unsafeCache {
+	return &unsafeCache{data: make(map[string]any)}
+}
+
+// enter records a caller entering the cache and returns a function to leave it.
+func (u *unsafeCache) enter() func() {
+	active := atomic.AddInt32(&u.active, 1)
+	for {
+		peak := atomic.LoadInt32(&u.peak)
+		if active <= peak || atomic.CompareAndSwapInt32(&u.peak, peak, active) {
+			break
+		}
+	}
+	return func() { atomic.AddInt32(&u.active, -1) }
+}
+
+func (u *unsafeCache) Get(ctx context.Context, key string) (any, error) {
+	defer u.enter()()
+	time.Sleep(u.getWait)
+	if val, ok := u.data[key]; ok {
+		return val, nil
+	}
+	return nil, gouache.ErrCacheMiss
+}
+
+func (u *unsafeCache) Set(ctx context.Context, key string, val any) error {
+	defer u.enter()()
+	u.data[key] = val
+	return nil
+}
+
+func (u *unsafeCache) Delete(ctx context.Context, key string) error {
+	defer u.enter()()
+	delete(u.data, key)
+	return nil
+}
+
+// TestCache_Serializes tests that concurrent operations never overlap on the underlying cache.
+func TestCache_Serializes(t *testing.T) {
+	underlying := newUnsafeCache()
+	cache := New(underlying)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", index%5)
+			_ = cache.Set(context.Background(), key, index)
+			_, _ = cache.Get(context.Background(), key)
+			_ = cache.Delete(context.Background(), key)
+		}(i)
+	}
+	wg.Wait()
+
+	if peak := atomic.LoadInt32(&underlying.peak); peak != 1 {
+		t.Errorf("Expected at most 1 concurrent caller, but got %d", peak)
+	}
+}
+
+// TestCache_WithRWMutex tests that Gets run concurrently when the read lock is enabled.
+func TestCache_WithRWMutex(t *testing.T) {
+	underlying := newUnsafeCache()
+	underlying.getWait = 50 * time.Millisecond
+	cache := New(underlying, WithRWMutex(true))
+
+	err := cache.Set(context.Background(), "test-key", "test-value")
+	if err != nil {
+		t.Fatalf("Failed to set up test value: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := cache.Get(context.Background(), "test-key")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if result != "test-value" {
+				t.Errorf("Expected test-value, but got %v", result)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak := atomic.LoadInt32(&underlying.peak); peak < 2 {
+		t.Errorf("Expected concurrent Gets, but peak concurrency was %d", peak)
+	}
+}
+
+// TestCache_Capabilities tests that the optional interfaces of the backend survive wrapping.
+func TestCache_Capabilities(t *testing.T) {
+	backend := &sample.Cache{}
+	cache := New(backend)
+	ctx := context.Background()
+
+	if caps := gouache.Capabilities(cache); caps != gouache.Capabilities(backend) {
+		t.Errorf("Expected %+v, but got %+v", gouache.Capabilities(backend), caps)
+	}
+
+	if err := gouache.SetMulti(ctx, cache, map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+	vals, err := gouache.GetMulti(ctx, cache, []string{"a", "b", "c"})
+	if err != nil || len(vals) != 2 {
+		t.Errorf("Expected a and b, but got %v, %v", vals, err)
+	}
+
+	// The iteration callback may use the cache without deadlocking
+	err = cache.(gouache.Iterable).Iterate(ctx, "", func(key string) error {
+		return cache.Delete(ctx, key)
+	})
+	if err != nil {
+		t.Fatalf("Failed to iterate: %v", err)
+	}
+	if _, err := cache.Get(ctx, "a"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss after deletion, but got: %v", err)
+	}
+
+	// Interfaces the backend lacks are reported as unsupported
+	if err := cache.(gouache.Toucher).Touch(ctx, "a", time.Second); !errors.Is(err, gouache.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported from Touch, but got: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package transform provides a cache implementation that passes values
+// through a validated pipeline of reversible stages, such as compression and
+// encryption.
+//
+// Composing compression and encryption by nesting separate decorators makes
+// it easy to encrypt before compressing, which gains nothing since encrypted
+// data doesn't compress. A Pipeline lists the stages once, in write order,
+// and NewPipeline rejects compression after encryption. Values are encoded
+// through the stages in order on Set and decoded in reverse order on Get.
+//
+// The cache stores string and []byte values; other types must be serialized
+// first. Get returns the same type the underlying cache returns, so with
+// backends such as Redis that return strings, values stored as []byte come
+// back as strings.
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// cache is a cache implementation that transforms values through a pipeline.
+type cache struct {
+	// Pipeline transforms the values
+	Pipeline *Pipeline
+
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+}
+
+// New creates a new transforming cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - pipeline: The pipeline values are passed through
+//
+// Returns:
+//   - A gouache.Cache implementation that transforms values
+func New(c gouache.Cache, pipeline *Pipeline) gouache.Cache {
+	return &cache{Pipeline: pipeline, Cache: c}
+}
+
+// Get retrieves a value from the underlying cache and decodes it through the
+// pipeline.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The decoded value, of the same type as the stored one, or nil if not found
+//   - An error if the operation or decoding fails, or gouache.ErrCacheMiss if key doesn't exist
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	val, err := cache.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the stored bytes, keeping the type the underlying cache returned
+	switch data := val.(type) {
+	case []byte:
+		return cache.Pipeline.Decode(data)
+	case string:
+		decoded, err := cache.Pipeline.Decode([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		return string(decoded), nil
+	default:
+		return nil, fmt.Errorf("transform: unexpected %T stored for key %q", val, key)
+	}
+}
+
+// Set encodes a value through the pipeline and stores it in the underlying
+// cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store, a string or a []byte
+//
+// Returns:
+//   - An error wrapping gouache.ErrMarshal if encoding fails, an error wrapping
+//     gouache.ErrUnsupportedValue for other types, or an error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	// Encode the value, keeping its type
+	switch data := val.(type) {
+	case []byte:
+		encoded, err := cache.Pipeline.Encode(data)
+		if err != nil {
+			return fmt.Errorf("%w: %w", gouache.ErrMarshal, err)
+		}
+		return cache.Cache.Set(ctx, key, encoded)
+	case string:
+		encoded, err := cache.Pipeline.Encode([]byte(data))
+		if err != nil {
+			return fmt.Errorf("%w: %w", gouache.ErrMarshal, err)
+		}
+		return cache.Cache.Set(ctx, key, string(encoded))
+	default:
+		return fmt.Errorf("%w: %T must be serialized before transform", gouache.ErrUnsupportedValue, val)
+	}
+}
+
+// Delete removes a value from the underlying cache by its key.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	return cache.Cache.Delete(ctx, key)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
@@ -0,0 +1,60 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// TestCache_SetGet tests that values are stored transformed and read back decoded.
+func TestCache_SetGet(t *testing.T) {
+	backend := &sample.Cache{}
+	cache := New(backend, newTestPipeline(t))
+	ctx := context.Background()
+
+	tests := []struct {
+		key string
+		val any
+	}{
+		{key: "string", val: "secret value"},
+		{key: "bytes", val: []byte("secret bytes")},
+	}
+	for _, tt := range tests {
+		if err := cache.Set(ctx, tt.key, tt.val); err != nil {
+			t.Fatalf("Failed to set %s: %v", tt.key, err)
+		}
+
+		// The backend holds the transformed value
+		stored, _ := backend.Get(ctx, tt.key)
+		if fmt.Sprint(stored) == fmt.Sprint(tt.val) {
+			t.Errorf("Expected %s to be stored transformed", tt.key)
+		}
+
+		val, err := cache.Get(ctx, tt.key)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", tt.key, err)
+		}
+		switch want := tt.val.(type) {
+		case []byte:
+			if got, ok := val.([]byte); !ok || string(got) != string(want) {
+				t.Errorf("Expected %q, but got %v", want, val)
+			}
+		default:
+			if val != want {
+				t.Errorf("Expected %v, but got %v", want, val)
+			}
+		}
+	}
+
+	// Test unsupported values and misses
+	if err := cache.Set(ctx, "int", 1); !errors.Is(err, gouache.ErrUnsupportedValue) {
+		t.Errorf("Expected ErrUnsupportedValue, but got: %v", err)
+	}
+	if _, err := cache.Get(ctx, "missing"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, but got: %v", err)
+	}
+}
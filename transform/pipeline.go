@@ -0,0 +1,209 @@
+package transform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrStageOrder is returned by NewPipeline when a compression stage follows
+// an encryption stage. Encrypted data looks random and doesn't compress, so
+// compressing after encrypting only costs CPU.
+var ErrStageOrder = errors.New("transform: compression stage after encryption stage")
+
+// Kind classifies a stage for the ordering checks of NewPipeline.
+type Kind int
+
+const (
+	// KindOther is a stage with no ordering constraint, such as an encoding.
+	KindOther Kind = iota
+
+	// KindCompress is a stage that compresses data. It must come before
+	// every encryption stage.
+	KindCompress
+
+	// KindEncrypt is a stage that encrypts data. It must come after every
+	// compression stage.
+	KindEncrypt
+)
+
+// String returns the name of the kind.
+func (kind Kind) String() string {
+	switch kind {
+	case KindCompress:
+		return "compress"
+	case KindEncrypt:
+		return "encrypt"
+	default:
+		return "other"
+	}
+}
+
+// Stage is one reversible step of a pipeline.
+type Stage struct {
+	// Name identifies the stage in errors.
+	Name string
+
+	// Kind classifies the stage for the ordering checks.
+	Kind Kind
+
+	// Encode transforms data on write.
+	Encode func(data []byte) ([]byte, error)
+
+	// Decode reverses Encode on read.
+	Decode func(data []byte) ([]byte, error)
+}
+
+// Pipeline applies its stages in order on write and in reverse order on read.
+type Pipeline struct {
+	// stages holds the stages in write order.
+	stages []Stage
+}
+
+// NewPipeline creates a pipeline of the given stages, listed in the order
+// they apply on write. The recommended order is serialization first, then
+// compression, then encryption, then any encoding of the ciphertext:
+//
+//	transform.NewPipeline(transform.Gzip(), aesStage)
+//
+// Parameters:
+//   - stages: The stages, in write order
+//
+// Returns:
+//   - The pipeline
+//   - ErrStageOrder if a compression stage follows an encryption stage, or an
+//     error if a stage lacks Encode or Decode
+func NewPipeline(stages ...Stage) (*Pipeline, error) {
+	encrypted := ""
+	for _, stage := range stages {
+		// Every stage must be reversible
+		if stage.Encode == nil || stage.Decode == nil {
+			return nil, fmt.Errorf("transform: stage %q requires Encode and Decode", stage.Name)
+		}
+
+		// Compression must come before encryption
+		switch stage.Kind {
+		case KindEncrypt:
+			if encrypted == "" {
+				encrypted = stage.Name
+			}
+		case KindCompress:
+			if encrypted != "" {
+				return nil, fmt.Errorf("%w: %q follows %q", ErrStageOrder, stage.Name, encrypted)
+			}
+		}
+	}
+	return &Pipeline{stages: append([]Stage(nil), stages...)}, nil
+}
+
+// Encode applies the stages to data in write order.
+//
+// Parameters:
+//   - data: The data to transform
+//
+// Returns:
+//   - The transformed data
+//   - An error naming the stage that failed
+func (pipeline *Pipeline) Encode(data []byte) ([]byte, error) {
+	for _, stage := range pipeline.stages {
+		var err error
+		data, err = stage.Encode(data)
+		if err != nil {
+			return nil, fmt.Errorf("transform: encode %s: %w", stage.Name, err)
+		}
+	}
+	return data, nil
+}
+
+// Decode reverses Encode, applying the stages in reverse order.
+//
+// Parameters:
+//   - data: The data produced by Encode
+//
+// Returns:
+//   - The original data
+//   - An error naming the stage that failed
+func (pipeline *Pipeline) Decode(data []byte) ([]byte, error) {
+	for i := len(pipeline.stages) - 1; i >= 0; i-- {
+		stage := pipeline.stages[i]
+		var err error
+		data, err = stage.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("transform: decode %s: %w", stage.Name, err)
+		}
+	}
+	return data, nil
+}
+
+// Gzip returns a compression stage using gzip at the default level.
+//
+// Returns:
+//   - The gzip stage
+func Gzip() Stage {
+	return Stage{
+		Name: "gzip",
+		Kind: KindCompress,
+		Encode: func(data []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			if _, err := w.Write(data); err != nil {
+				return nil, err
+			}
+			if err := w.Close(); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		},
+		Decode: func(data []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return io.ReadAll(r)
+		},
+	}
+}
+
+// AESGCM returns an encryption stage using AES-GCM with the given key. Each
+// encoded value is prefixed with its random nonce.
+//
+// Parameters:
+//   - key: The AES key, 16, 24 or 32 bytes long
+//
+// Returns:
+//   - The AES-GCM stage
+//   - An error if the key length is invalid
+func AESGCM(key []byte) (Stage, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return Stage{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return Stage{}, err
+	}
+	return Stage{
+		Name: "aes-gcm",
+		Kind: KindEncrypt,
+		Encode: func(data []byte) ([]byte, error) {
+			nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, err
+			}
+			return aead.Seal(nonce, nonce, data, nil), nil
+		},
+		Decode: func(data []byte) ([]byte, error) {
+			if len(data) < aead.NonceSize() {
+				return nil, errors.New("ciphertext too short")
+			}
+			nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+			return aead.Open(nil, nonce, ciphertext, nil)
+		},
+	}, nil
+}
@@ -0,0 +1,65 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// newTestPipeline creates a compress-then-encrypt pipeline.
+func newTestPipeline(t *testing.T) *Pipeline {
+	encrypt, err := AESGCM(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("Failed to create AES stage: %v", err)
+	}
+	pipeline, err := NewPipeline(Gzip(), encrypt)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+	return pipeline
+}
+
+// TestPipeline_RoundTrip tests that Decode reverses Encode and that the data is compressed.
+func TestPipeline_RoundTrip(t *testing.T) {
+	pipeline := newTestPipeline(t)
+	data := bytes.Repeat([]byte("gouache "), 1000)
+
+	encoded, err := pipeline.Encode(data)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if len(encoded) >= len(data)/10 {
+		t.Errorf("Expected compressed output, but got %d bytes from %d", len(encoded), len(data))
+	}
+	if bytes.Contains(encoded, []byte("gouache")) {
+		t.Error("Expected encrypted output, but found plaintext")
+	}
+
+	decoded, err := pipeline.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("Expected decoded data to equal the original")
+	}
+
+	// Test that tampered data is rejected
+	encoded[len(encoded)-1] ^= 1
+	if _, err := pipeline.Decode(encoded); err == nil {
+		t.Error("Expected an error decoding tampered data")
+	}
+}
+
+// TestNewPipeline_Order tests that compression after encryption is rejected.
+func TestNewPipeline_Order(t *testing.T) {
+	encrypt, err := AESGCM(bytes.Repeat([]byte{1}, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES stage: %v", err)
+	}
+	if _, err := NewPipeline(encrypt, Gzip()); !errors.Is(err, ErrStageOrder) {
+		t.Errorf("Expected ErrStageOrder, but got: %v", err)
+	}
+	if _, err := NewPipeline(Stage{Name: "broken"}); err == nil {
+		t.Error("Expected an error for a stage without Encode and Decode")
+	}
+}
@@ -0,0 +1,131 @@
+package gouache
+
+import (
+	"context"
+	"time"
+)
+
+// ttlMultiplierKey is the context key under which the TTL multiplier is stored.
+type ttlMultiplierKey struct{}
+
+// WithTTLMultiplier returns a copy of ctx carrying a factor by which
+// TTL-supporting backends scale the TTL of values stored with that context.
+//
+// This lets a single cache instance give different callers, such as premium
+// and free tenants, different freshness for the same keys. The factor is
+// applied after the backend's TTL function has resolved the TTL, and only to
+// positive TTLs; entries that never expire or use a backend default are left
+// unchanged.
+//
+// Parameters:
+//   - ctx: The parent context
+//   - factor: The factor to multiply TTLs by; non-positive values are ignored
+//
+// Returns:
+//   - A context carrying the TTL multiplier
+func WithTTLMultiplier(ctx context.Context, factor float64) context.Context {
+	return context.WithValue(ctx, ttlMultiplierKey{}, factor)
+}
+
+// TTLMultiplier returns the TTL multiplier carried by ctx.
+//
+// Parameters:
+//   - ctx: The context to read the multiplier from
+//
+// Returns:
+//   - The multiplier, or 1 if none is set or the set value is not positive
+func TTLMultiplier(ctx context.Context) float64 {
+	factor, ok := ctx.Value(ttlMultiplierKey{}).(float64)
+	if !ok || factor <= 0 {
+		return 1
+	}
+	return factor
+}
+
+// MultiplyTTL scales ttl by the TTL multiplier carried by ctx.
+// Non-positive TTLs are returned unchanged, as they usually encode
+// "no expiration" or "use the backend default".
+//
+// Parameters:
+//   - ctx: The context to read the multiplier from
+//   - ttl: The resolved TTL
+//
+// Returns:
+//   - The scaled TTL
+func MultiplyTTL(ctx context.Context, ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return ttl
+	}
+	return time.Duration(float64(ttl) * TTLMultiplier(ctx))
+}
+
+// ttlCeilingKey is the context key under which the TTL ceiling is stored.
+type ttlCeilingKey struct{}
+
+// WithTTLCeiling returns a copy of ctx carrying a maximum TTL that
+// TTL-supporting backends clamp the TTL of values stored with that context
+// to. Unlike the multiplier, the ceiling also applies to entries that would
+// otherwise never expire, which are given the ceiling as their TTL.
+//
+// The ttlcap decorator sets it on every Set, so that the ceiling applies to
+// whatever TTL a backend's own TTL function resolves.
+//
+// Parameters:
+//   - ctx: The parent context
+//   - max: The maximum TTL; non-positive values are ignored
+//
+// Returns:
+//   - A context carrying the TTL ceiling
+func WithTTLCeiling(ctx context.Context, max time.Duration) context.Context {
+	return context.WithValue(ctx, ttlCeilingKey{}, max)
+}
+
+// TTLCeiling returns the TTL ceiling carried by ctx.
+//
+// Parameters:
+//   - ctx: The context to read the ceiling from
+//
+// Returns:
+//   - The ceiling, or zero if none is set or the set value is not positive
+func TTLCeiling(ctx context.Context) time.Duration {
+	max, ok := ctx.Value(ttlCeilingKey{}).(time.Duration)
+	if !ok || max <= 0 {
+		return 0
+	}
+	return max
+}
+
+// CapTTL clamps ttl to max. A non-positive ttl, which usually encodes "no
+// expiration", is replaced by max as well. A non-positive max leaves ttl
+// unchanged.
+//
+// Parameters:
+//   - ttl: The TTL to clamp
+//   - max: The maximum TTL
+//
+// Returns:
+//   - The clamped TTL
+func CapTTL(ttl time.Duration, max time.Duration) time.Duration {
+	if max <= 0 {
+		return ttl
+	}
+	if ttl <= 0 || ttl > max {
+		return max
+	}
+	return ttl
+}
+
+// AdjustTTL applies the per-request adjustments carried by ctx to a TTL
+// resolved by a backend: it is first scaled by the TTL multiplier, then
+// clamped to the TTL ceiling. Backends call it on the result of their TTL
+// function.
+//
+// Parameters:
+//   - ctx: The context to read the adjustments from
+//   - ttl: The resolved TTL
+//
+// Returns:
+//   - The adjusted TTL
+func AdjustTTL(ctx context.Context, ttl time.Duration) time.Duration {
+	return CapTTL(MultiplyTTL(ctx, ttl), TTLCeiling(ctx))
+}
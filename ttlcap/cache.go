@@ -0,0 +1,188 @@
+// Package ttlcap provides a cache implementation that enforces a maximum
+// time-to-live on every entry written through it.
+//
+// This package implements the gouache.Cache interface by wrapping a cache and
+// clamping TTLs to a configured ceiling, which keeps callers from filling the
+// cache with entries that never expire or live for absurdly long.
+//
+// TTLs are resolved inside the backends, so Set cannot see them. Instead it
+// stores the ceiling in the context with gouache.WithTTLCeiling, and the fc,
+// gocache, bc, redis, ringcache and lrttl backends clamp whatever their TTL
+// function resolves, after the TTL multiplier, through gouache.AdjustTTL.
+// Entries their TTL function would store without expiration get the ceiling
+// as their TTL. Backends that don't apply gouache.AdjustTTL ignore the
+// ceiling on Set; the bc and lrttl backends only apply it when their TTL
+// function is set, and gocache leaves entries stored with go-cache's default
+// expiration unchanged.
+//
+// SetWithTTL and Touch, which take an explicit TTL, are clamped directly.
+package ttlcap
+
+import (
+	"context"
+	"time"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchGetter interface at compile time.
+var _ gouache.BatchGetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchSetter interface at compile time.
+var _ gouache.BatchSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.ResultDeleter interface at compile time.
+var _ gouache.ResultDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Iterable interface at compile time.
+var _ gouache.Iterable = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Adder interface at compile time.
+var _ gouache.Adder = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLSetter interface at compile time.
+var _ gouache.TTLSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.TTLReader interface at compile time.
+var _ gouache.TTLReader = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Toucher interface at compile time.
+var _ gouache.Toucher = (*cache)(nil)
+
+// Ensure that cache implements the gouache.CompareDeleter interface at compile time.
+var _ gouache.CompareDeleter = (*cache)(nil)
+
+// Ensure that cache forwards capability probing at compile time.
+var _ gouache.CapabilityReporter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// cache is a cache implementation that clamps the TTL of every write.
+type cache struct {
+	// Max is the TTL ceiling
+	Max time.Duration
+
+	// Forwarder holds the underlying cache and forwards the operations the
+	// cache doesn't intercept to it
+	gouache.Forwarder
+}
+
+// New creates a new TTL ceiling cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//   - max: The maximum TTL of any entry; a non-positive value disables the ceiling
+//
+// Returns:
+//   - A gouache.Cache implementation that clamps TTLs to max
+func New(c gouache.Cache, max time.Duration) gouache.Cache {
+	return &cache{Max: max, Forwarder: gouache.Forwarder{Cache: c}}
+}
+
+// Set stores a value in the underlying cache with the TTL ceiling carried by
+// the context, so the backend clamps the TTL it resolves.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	return cache.Cache.Set(cache.withCeiling(ctx), key, val)
+}
+
+// SetMulti stores each value in vals in the underlying cache with the TTL
+// ceiling carried by the context, using its native SetMulti when available.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - An error if the operation fails
+func (cache *cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	return gouache.SetMulti(cache.withCeiling(ctx), cache.Cache, vals)
+}
+
+// Add stores a value in the underlying cache only if the key is absent, with
+// the TTL ceiling carried by the context.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - true if the value was stored, false if the key already existed
+//   - gouache.ErrUnsupported if the underlying cache isn't an Adder, or an error if the operation fails
+func (cache *cache) Add(ctx context.Context, key string, val any) (bool, error) {
+	adder, ok := cache.Cache.(gouache.Adder)
+	if !ok {
+		return false, gouache.ErrUnsupported
+	}
+	return adder.Add(cache.withCeiling(ctx), key, val)
+}
+
+// SetWithTTL stores a value in the underlying cache with ttl clamped to the
+// ceiling. A ttl meaning no expiration is replaced by the ceiling.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//   - ttl: The requested time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a TTLSetter, or an error if the operation fails
+func (cache *cache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	setter, ok := cache.Cache.(gouache.TTLSetter)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	return setter.SetWithTTL(ctx, key, val, gouache.CapTTL(ttl, cache.Max))
+}
+
+// Touch sets a new time-to-live, clamped to the ceiling, for an entry of the
+// underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the entry
+//   - ttl: The requested time-to-live of the entry
+//
+// Returns:
+//   - gouache.ErrUnsupported if the underlying cache isn't a Toucher, or an error if the operation fails
+func (cache *cache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	toucher, ok := cache.Cache.(gouache.Toucher)
+	if !ok {
+		return gouache.ErrUnsupported
+	}
+	return toucher.Touch(ctx, key, gouache.CapTTL(ttl, cache.Max))
+}
+
+// withCeiling returns ctx carrying the TTL ceiling, keeping a lower ceiling
+// already carried by ctx.
+//
+// Parameters:
+//   - ctx: The request context
+//
+// Returns:
+//   - A context carrying the effective TTL ceiling
+func (cache *cache) withCeiling(ctx context.Context) context.Context {
+	if cache.Max <= 0 {
+		return ctx
+	}
+	if current := gouache.TTLCeiling(ctx); current > 0 && current <= cache.Max {
+		return ctx
+	}
+	return gouache.WithTTLCeiling(ctx, cache.Max)
+}
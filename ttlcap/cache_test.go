@@ -0,0 +1,96 @@
+package ttlcap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// ttlCache records the TTL a backend would resolve for each write.
+type ttlCache struct {
+	sample.Cache
+	ttls map[string]time.Duration
+}
+
+func (c *ttlCache) Set(ctx context.Context, key string, val any) error {
+	c.ttls[key] = gouache.AdjustTTL(ctx, time.Hour)
+	return c.Cache.Set(ctx, key, val)
+}
+
+func (c *ttlCache) SetWithTTL(ctx context.Context, key string, val any, ttl time.Duration) error {
+	c.ttls[key] = ttl
+	return c.Cache.Set(ctx, key, val)
+}
+
+func (c *ttlCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	c.ttls[key] = ttl
+	return nil
+}
+
+// TestCache_Set tests that Set passes the ceiling to the backend through the context.
+func TestCache_Set(t *testing.T) {
+	backend := &ttlCache{ttls: make(map[string]time.Duration)}
+	cache := New(backend, time.Minute)
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if ttl := backend.ttls["key"]; ttl != time.Minute {
+		t.Errorf("Expected TTL %v, but got %v", time.Minute, ttl)
+	}
+
+	// A lower ceiling already in the context is kept
+	if err := cache.Set(gouache.WithTTLCeiling(ctx, time.Second), "lower", "value"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if ttl := backend.ttls["lower"]; ttl != time.Second {
+		t.Errorf("Expected TTL %v, but got %v", time.Second, ttl)
+	}
+}
+
+// TestCache_ExplicitTTL tests that SetWithTTL and Touch clamp their TTL.
+func TestCache_ExplicitTTL(t *testing.T) {
+	backend := &ttlCache{ttls: make(map[string]time.Duration)}
+	cache := New(backend, time.Minute).(*cache)
+	ctx := context.Background()
+
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{ttl: time.Second, want: time.Second},
+		{ttl: time.Hour, want: time.Minute},
+		{ttl: 0, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if err := cache.SetWithTTL(ctx, "key", "value", tt.ttl); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+		if ttl := backend.ttls["key"]; ttl != tt.want {
+			t.Errorf("SetWithTTL(%v): expected TTL %v, but got %v", tt.ttl, tt.want, ttl)
+		}
+		if err := cache.Touch(ctx, "key", tt.ttl); err != nil {
+			t.Fatalf("Failed to touch key: %v", err)
+		}
+		if ttl := backend.ttls["key"]; ttl != tt.want {
+			t.Errorf("Touch(%v): expected TTL %v, but got %v", tt.ttl, tt.want, ttl)
+		}
+	}
+}
+
+// TestCache_Unsupported tests that missing optional interfaces are reported.
+func TestCache_Unsupported(t *testing.T) {
+	cache := New(&sample.Cache{}, time.Minute).(*cache)
+	err := cache.SetWithTTL(context.Background(), "key", "value", time.Second)
+	if err != gouache.ErrUnsupported {
+		t.Errorf("Expected ErrUnsupported, but got: %v", err)
+	}
+	want := gouache.Capabilities(&sample.Cache{})
+	if caps := gouache.Capabilities(cache); caps != want {
+		t.Errorf("Expected %+v, but got %+v", want, caps)
+	}
+}
@@ -0,0 +1,58 @@
+package gouache
+
+// Unwrapper is an optional interface implemented by decorators, so that tools
+// can walk a chain of decorators down to the backend, much as errors.Unwrap
+// walks a chain of wrapped errors.
+type Unwrapper interface {
+	// Unwrap returns the cache the decorator wraps.
+	//
+	// Returns:
+	//   - The underlying cache
+	Unwrap() Cache
+}
+
+// MultiUnwrapper is an optional interface implemented by decorators that
+// wrap several caches, such as sharded caches.
+type MultiUnwrapper interface {
+	// Unwrap returns the caches the decorator wraps.
+	//
+	// Returns:
+	//   - The underlying caches
+	Unwrap() []Cache
+}
+
+// Unwrap returns the cache c wraps, if c implements Unwrapper. Like
+// errors.Unwrap, it returns nil for caches that wrap several caches, whose
+// layers must be read with a type assertion on MultiUnwrapper instead.
+//
+// Parameters:
+//   - c: The cache to unwrap
+//
+// Returns:
+//   - The next layer of c, or nil if c wraps no single cache
+func Unwrap(c Cache) Cache {
+	u, ok := c.(Unwrapper)
+	if !ok {
+		return nil
+	}
+	return u.Unwrap()
+}
+
+// Base returns the innermost cache of a chain of decorators, by unwrapping c
+// until a cache that wraps no single cache is reached, usually the backend.
+// It returns c itself if c is not a decorator.
+//
+// Parameters:
+//   - c: The outermost cache
+//
+// Returns:
+//   - The innermost cache reachable from c with Unwrap
+func Base(c Cache) Cache {
+	for {
+		next := Unwrap(c)
+		if next == nil {
+			return c
+		}
+		c = next
+	}
+}
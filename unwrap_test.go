@@ -0,0 +1,51 @@
+package gouache_test
+
+import (
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/nonempty"
+	"github.com/soyacen/gouache/sample"
+	sharded "github.com/soyacen/gouache/sharded"
+	"github.com/soyacen/gouache/writebatch"
+)
+
+// TestUnwrap tests walking a chain of decorators with gouache.Unwrap and gouache.Base
+func TestUnwrap(t *testing.T) {
+	backend := &sample.Cache{}
+	middle := writebatch.New(backend)
+	outer := nonempty.New(middle)
+
+	// Unwrap peels one layer at a time
+	if got := gouache.Unwrap(outer); got != middle {
+		t.Errorf("Expected the writebatch layer, got %T", got)
+	}
+	if got := gouache.Unwrap(middle); got != backend {
+		t.Errorf("Expected the backend, got %T", got)
+	}
+	if got := gouache.Unwrap(backend); got != nil {
+		t.Errorf("Expected nil below the backend, got %T", got)
+	}
+
+	// Base walks to the bottom
+	if got := gouache.Base(outer); got != backend {
+		t.Errorf("Expected Base to return the backend, got %T", got)
+	}
+	if got := gouache.Base(backend); got != backend {
+		t.Errorf("Expected Base of a backend to return it, got %T", got)
+	}
+
+	// Caches wrapping several caches expose them through MultiUnwrapper only
+	buckets := []gouache.Cache{&sample.Cache{}, &sample.Cache{}}
+	shards := sharded.New(buckets)
+	if got := gouache.Unwrap(shards); got != nil {
+		t.Errorf("Expected nil from Unwrap of a sharded cache, got %T", got)
+	}
+	if got := gouache.Base(nonempty.New(shards)); got != shards {
+		t.Errorf("Expected Base to stop at the sharded cache, got %T", got)
+	}
+	multi, ok := shards.(gouache.MultiUnwrapper)
+	if !ok || len(multi.Unwrap()) != len(buckets) {
+		t.Errorf("Expected the sharded cache to expose its %d buckets", len(buckets))
+	}
+}
@@ -0,0 +1,152 @@
+// Package writebatch provides a cache implementation that buffers writes made
+// within a request and flushes them as batch operations.
+//
+// This package implements the gouache.Cache interface by wrapping a cache.
+// Sets and Deletes made with a context returned by gouache.BeginBatch are
+// buffered in the context's batch, under the decorator itself, instead of
+// reaching the underlying cache, and the flush function returned by
+// BeginBatch writes them with one SetMulti and one DeleteMulti, saving
+// round-trips in handlers that write many entries. Only the last write of
+// each key is flushed. Gets made with the batch context see the buffered
+// writes. Writes made with other contexts pass straight through.
+package writebatch
+
+import (
+	"context"
+
+	"github.com/soyacen/gouache"
+)
+
+// Ensure that cache implements the gouache.Cache interface at compile time.
+var _ gouache.Cache = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchSetter interface at compile time.
+var _ gouache.BatchSetter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.BatchDeleter interface at compile time.
+var _ gouache.BatchDeleter = (*cache)(nil)
+
+// Ensure that cache implements the gouache.Unwrapper interface at compile time.
+var _ gouache.Unwrapper = (*cache)(nil)
+
+// cache is a cache implementation that buffers writes in the context's batch.
+type cache struct {
+	// Cache is the underlying cache implementation
+	Cache gouache.Cache
+}
+
+// New creates a new write batching cache instance wrapping the specified cache.
+//
+// Parameters:
+//   - c: The underlying cache implementation
+//
+// Returns:
+//   - A gouache.Cache implementation that buffers writes made within a batch
+func New(c gouache.Cache) gouache.Cache {
+	return &cache{Cache: c}
+}
+
+// Get retrieves a value by its key, from the writes buffered in the context's
+// batch if the key was written during the batch, or from the underlying cache.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key to retrieve the value for
+//
+// Returns:
+//   - The buffered or cached value or nil if not found
+//   - An error if the operation fails, or gouache.ErrCacheMiss if key doesn't exist or a Delete of it is buffered
+func (cache *cache) Get(ctx context.Context, key string) (any, error) {
+	if b := gouache.BatchFromContext(ctx); b != nil {
+		if val, deleted, ok := b.Lookup(cache, key); ok {
+			if deleted {
+				return nil, gouache.ErrCacheMiss
+			}
+			return val, nil
+		}
+	}
+	return cache.Cache.Get(ctx, key)
+}
+
+// Set buffers the value in the context's batch, or stores it in the
+// underlying cache if the context carries no batch.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key under which the value will be stored
+//   - val: The value to store
+//
+// Returns:
+//   - An error if storing outside a batch fails
+func (cache *cache) Set(ctx context.Context, key string, val any) error {
+	if b := gouache.BatchFromContext(ctx); b != nil {
+		b.Set(cache, key, val)
+		return nil
+	}
+	return cache.Cache.Set(ctx, key, val)
+}
+
+// Delete buffers the deletion in the context's batch, or removes the value
+// from the underlying cache if the context carries no batch.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - key: The key of the value to delete
+//
+// Returns:
+//   - An error if deleting outside a batch fails
+func (cache *cache) Delete(ctx context.Context, key string) error {
+	if b := gouache.BatchFromContext(ctx); b != nil {
+		b.Delete(cache, key)
+		return nil
+	}
+	return cache.Cache.Delete(ctx, key)
+}
+
+// SetMulti buffers the values in the context's batch, or stores them in the
+// underlying cache, using its native SetMulti when available, if the context
+// carries no batch, as when the batch is flushed.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - vals: The values to store, keyed by the key they are stored under
+//
+// Returns:
+//   - An error if storing outside a batch fails
+func (cache *cache) SetMulti(ctx context.Context, vals map[string]any) error {
+	if b := gouache.BatchFromContext(ctx); b != nil {
+		for key, val := range vals {
+			b.Set(cache, key, val)
+		}
+		return nil
+	}
+	return gouache.SetMulti(ctx, cache.Cache, vals)
+}
+
+// DeleteMulti buffers the deletions in the context's batch, or removes the
+// values from the underlying cache, using its native DeleteMulti when
+// available, if the context carries no batch, as when the batch is flushed.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - keys: The keys of the values to delete
+//
+// Returns:
+//   - An error if deleting outside a batch fails
+func (cache *cache) DeleteMulti(ctx context.Context, keys []string) error {
+	if b := gouache.BatchFromContext(ctx); b != nil {
+		for _, key := range keys {
+			b.Delete(cache, key)
+		}
+		return nil
+	}
+	return gouache.DeleteMulti(ctx, cache.Cache, keys)
+}
+
+// Unwrap returns the underlying cache.
+//
+// Returns:
+//   - The underlying cache
+func (cache *cache) Unwrap() gouache.Cache {
+	return cache.Cache
+}
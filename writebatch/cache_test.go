@@ -0,0 +1,213 @@
+package writebatch
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/soyacen/gouache"
+	"github.com/soyacen/gouache/sample"
+)
+
+// countingCache is a sample.Cache counting its write operations.
+type countingCache struct {
+	sample.Cache
+	sets         atomic.Int32
+	setMultis    atomic.Int32
+	deletes      atomic.Int32
+	deleteMultis atomic.Int32
+}
+
+func (c *countingCache) Set(ctx context.Context, key string, val any) error {
+	c.sets.Add(1)
+	return c.Cache.Set(ctx, key, val)
+}
+
+func (c *countingCache) SetMulti(ctx context.Context, vals map[string]any) error {
+	c.setMultis.Add(1)
+	return c.Cache.SetMulti(ctx, vals)
+}
+
+func (c *countingCache) Delete(ctx context.Context, key string) error {
+	c.deletes.Add(1)
+	return c.Cache.Delete(ctx, key)
+}
+
+func (c *countingCache) DeleteMulti(ctx context.Context, keys []string) error {
+	c.deleteMultis.Add(1)
+	return c.Cache.DeleteMulti(ctx, keys)
+}
+
+// TestCache_Batch tests that buffered Sets are flushed with a single SetMulti
+func TestCache_Batch(t *testing.T) {
+	backend := &countingCache{}
+	cache := New(backend)
+	ctx, flush := gouache.BeginBatch(context.Background())
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		if err := cache.Set(ctx, "key"+strconv.Itoa(i), i); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	// Nothing reaches the backend before the flush, but reads see the writes
+	if _, err := backend.Get(ctx, "key0"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss from backend before flush, got %v", err)
+	}
+	if val, err := cache.Get(ctx, "key0"); err != nil || val != 0 {
+		t.Errorf("Expected buffered value 0, got %v, %v", val, err)
+	}
+
+	if err := flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := backend.setMultis.Load(); got != 1 {
+		t.Errorf("Expected 1 SetMulti, got %d", got)
+	}
+	if got := backend.sets.Load(); got != 0 {
+		t.Errorf("Expected 0 Sets, got %d", got)
+	}
+	for i := 0; i < n; i++ {
+		if val, err := backend.Get(context.Background(), "key"+strconv.Itoa(i)); err != nil || val != i {
+			t.Errorf("Expected %d for key%d, got %v, %v", i, i, val, err)
+		}
+	}
+
+	// The batch is empty after the flush
+	if err := flush(); err != nil {
+		t.Fatalf("Second flush failed: %v", err)
+	}
+	if got := backend.setMultis.Load(); got != 1 {
+		t.Errorf("Expected no SetMulti on an empty batch, got %d in total", got)
+	}
+}
+
+// TestCache_BatchOrdering tests that only the last write of each key is flushed
+func TestCache_BatchOrdering(t *testing.T) {
+	backend := &countingCache{}
+	_ = backend.Cache.Set(context.Background(), "deleted", "old")
+	_ = backend.Cache.Set(context.Background(), "reset", "old")
+	cache := New(backend)
+	ctx, flush := gouache.BeginBatch(context.Background())
+
+	// Set then Delete leaves the key deleted
+	_ = cache.Set(ctx, "deleted", "new")
+	_ = cache.Delete(ctx, "deleted")
+
+	// Delete then Set leaves the key set
+	_ = cache.Delete(ctx, "reset")
+	_ = cache.Set(ctx, "reset", "new")
+
+	if _, err := cache.Get(ctx, "deleted"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected gouache.ErrCacheMiss for buffered Delete, got %v", err)
+	}
+
+	if err := flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if _, err := backend.Get(context.Background(), "deleted"); !errors.Is(err, gouache.ErrCacheMiss) {
+		t.Errorf("Expected deleted key to be missing, got %v", err)
+	}
+	if val, err := backend.Get(context.Background(), "reset"); err != nil || val != "new" {
+		t.Errorf("Expected 'new', got %v, %v", val, err)
+	}
+	if got := backend.deleteMultis.Load(); got != 1 {
+		t.Errorf("Expected 1 DeleteMulti, got %d", got)
+	}
+	if got := backend.deletes.Load(); got != 0 {
+		t.Errorf("Expected 0 Deletes, got %d", got)
+	}
+}
+
+// TestCache_NoBatch tests that writes outside a batch pass through
+func TestCache_NoBatch(t *testing.T) {
+	backend := &countingCache{}
+	cache := New(backend)
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if val, err := backend.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected 'value', got %v, %v", val, err)
+	}
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if got := backend.deletes.Load(); got != 1 {
+		t.Errorf("Expected 1 Delete, got %d", got)
+	}
+}
+
+// mapCache is a cache whose dynamic type, a map, isn't comparable.
+type mapCache map[string]any
+
+func (c mapCache) Get(ctx context.Context, key string) (any, error) {
+	if val, ok := c[key]; ok {
+		return val, nil
+	}
+	return nil, gouache.ErrCacheMiss
+}
+
+func (c mapCache) Set(ctx context.Context, key string, val any) error {
+	c[key] = val
+	return nil
+}
+
+func (c mapCache) Delete(ctx context.Context, key string) error {
+	delete(c, key)
+	return nil
+}
+
+// TestCache_UncomparableBackend tests batching writes to a cache that can't
+// be compared with ==
+func TestCache_UncomparableBackend(t *testing.T) {
+	backend := mapCache{"old": 1}
+	cache := New(backend)
+	ctx, flush := gouache.BeginBatch(context.Background())
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := cache.Delete(ctx, "old"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if val, err := cache.Get(ctx, "key"); err != nil || val != "value" {
+		t.Errorf("Expected the buffered value, got %v, %v", val, err)
+	}
+
+	if err := flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if val, ok := backend["key"]; !ok || val != "value" {
+		t.Errorf("Expected the value flushed, got %v", val)
+	}
+	if _, ok := backend["old"]; ok {
+		t.Error("Expected old to be deleted")
+	}
+}
+
+// TestBeginBatch_Nested tests that a nested batch is flushed by the outermost caller
+func TestBeginBatch_Nested(t *testing.T) {
+	backend := &countingCache{}
+	cache := New(backend)
+	ctx, flush := gouache.BeginBatch(context.Background())
+	inner, innerFlush := gouache.BeginBatch(ctx)
+
+	_ = cache.Set(inner, "key", "value")
+	if err := innerFlush(); err != nil {
+		t.Fatalf("Inner flush failed: %v", err)
+	}
+	if got := backend.setMultis.Load(); got != 0 {
+		t.Errorf("Expected inner flush to write nothing, got %d SetMulti", got)
+	}
+	if err := flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := backend.setMultis.Load(); got != 1 {
+		t.Errorf("Expected 1 SetMulti, got %d", got)
+	}
+}